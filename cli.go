@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ironpark/tons/internal/cache"
+	"github.com/ironpark/tons/internal/config"
+	"github.com/ironpark/tons/internal/instance"
+	"github.com/ironpark/tons/internal/metrics"
+	"github.com/ironpark/tons/internal/services"
+	"github.com/ironpark/tons/internal/tm"
+	"github.com/ironpark/tons/internal/usage"
+)
+
+// runCommand runs a command line subcommand and returns the exit code.
+// ok is false if args do not name a subcommand and the GUI should start.
+// A leading --config-dir DIR applies to the GUI and every subcommand.
+func runCommand(args []string) (code int, ok bool) {
+	args, err := configDirFlag(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "tons:", err)
+		return 2, true
+	}
+	if len(args) == 0 {
+		return 0, false
+	}
+	switch args[0] {
+	case "translate":
+		return translateCommand(args[1:]), true
+	case "serve":
+		return serveCommand(args[1:]), true
+	case "doctor":
+		return doctorCommand(args[1:]), true
+	case services.CommandSelection, services.CommandSwap, services.CommandReplace:
+		return sendCommand(args[0]), true
+	default:
+		return 0, false
+	}
+}
+
+// configDirFlag applies a leading --config-dir DIR (or -config-dir, or
+// --config-dir=DIR) and returns the remaining arguments
+func configDirFlag(args []string) ([]string, error) {
+	if len(args) == 0 {
+		return args, nil
+	}
+	name, dir, hasValue := strings.Cut(strings.TrimLeft(args[0], "-"), "=")
+	if name != "config-dir" || !strings.HasPrefix(args[0], "-") {
+		return args, nil
+	}
+	rest := args[1:]
+	if !hasValue {
+		if len(rest) == 0 {
+			return nil, fmt.Errorf("%s needs a directory", args[0])
+		}
+		dir, rest = rest[0], rest[1:]
+	}
+	if dir == "" {
+		return nil, fmt.Errorf("%s needs a directory", args[0])
+	}
+	return rest, config.SetDir(dir)
+}
+
+// sendCommand passes a command to the running app, so system keyboard
+// shortcuts can trigger it:
+//
+//	tons selection    translate the selected text into a popup
+//	tons swap         swap the languages and translate the last output back
+//	tons replace      translate the selected text onto the clipboard and paste it in place
+func sendCommand(command string) int {
+	if err := instance.Send(services.InstanceSocket(), command); err != nil {
+		fmt.Fprintln(os.Stderr, "tons:", err)
+		return 1
+	}
+	return 0
+}
+
+// translateCommand translates files (Markdown, PO, ...) with the configured engine:
+//
+//	tons translate -to ko [-from en] [-o out.md] README.md ...
+func translateCommand(args []string) int {
+	flags := flag.NewFlagSet("translate", flag.ContinueOnError)
+	from := flags.String("from", "auto", "source language")
+	to := flags.String("to", "", "target language (required)")
+	out := flags.String("o", "", "output file, or - for stdout (single input only); defaults to NAME.LANG.EXT")
+	full := flags.Bool("full", false, "translate without reusing remembered translations of unchanged segments")
+	codeMode := flags.String("code", "", "what to translate in source files: comments, strings or all (default from settings)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: tons translate -to LANG [-from LANG] [-o FILE] [-full] [-code MODE] FILE...")
+		fmt.Fprintln(flags.Output(), "supported files:", strings.Join(services.SupportedExtensions(), " "))
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *to == "" || flags.NArg() == 0 || (*out != "" && flags.NArg() > 1) {
+		flags.Usage()
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "tons:", err)
+		return 1
+	}
+	translation := cfg.Snapshot().Translation
+	if *full {
+		translation.Incremental = false
+	}
+	if *codeMode != "" {
+		translation.CodeMode = *codeMode
+	}
+	cfg.SetTranslation(translation)
+	ts, err := newCommandTranslator(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "tons:", err)
+		return 1
+	}
+
+	code := 0
+	for _, path := range flags.Args() {
+		translated, err := services.TranslateDocument(context.Background(), ts, path, *from, *to)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "tons:", err)
+			code = 1
+			continue
+		}
+		outPath := *out
+		if outPath == "-" {
+			os.Stdout.Write(translated)
+			continue
+		}
+		if outPath == "" {
+			outPath = services.TranslatedPath(path, *to)
+		}
+		if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+			fmt.Fprintln(os.Stderr, "tons:", err)
+			code = 1
+			continue
+		}
+		if err := os.WriteFile(outPath, translated, 0644); err != nil {
+			fmt.Fprintln(os.Stderr, "tons:", err)
+			code = 1
+			continue
+		}
+		fmt.Fprintln(os.Stderr, path, "->", outPath)
+	}
+	return code
+}
+
+// serveCommand translates requests from other programs until stdin ends:
+//
+//	tons serve --stdio
+//	tons serve --lsp
+//
+// With --stdio, each stdin line is a JSON request such as
+// {"id":"1","text":"Hello","targetLang":"ko"}, or {"id":"1","cancel":true}.
+// Responses are JSON lines {"id":"1","event":"delta","data":{...}} ending with
+// a "done" or "error" event per request.
+//
+// With --lsp, tons speaks the Language Server Protocol, offering translation
+// code actions to editors.
+func serveCommand(args []string) int {
+	flags := flag.NewFlagSet("serve", flag.ContinueOnError)
+	stdio := flags.Bool("stdio", false, "read JSON line requests from stdin and write JSON line responses to stdout")
+	lspMode := flags.Bool("lsp", false, "run a language server with translation code actions on stdin and stdout")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: tons serve --stdio | --lsp")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *stdio == *lspMode || flags.NArg() > 0 {
+		flags.Usage()
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "tons:", err)
+		return 1
+	}
+	ts, err := newCommandTranslator(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "tons:", err)
+		return 1
+	}
+	defer ts.ServiceShutdown()
+	serve := services.ServeStdio
+	if *lspMode {
+		serve = services.ServeLSP
+	}
+	if err := serve(ts, os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "tons:", err)
+		return 1
+	}
+	return 0
+}
+
+// doctorCommand checks the settings, engines and model files and prints a
+// report without credentials, for bug reports. Exits with 1 if a check failed.
+//
+//	tons doctor [-o report.txt] [-json]
+func doctorCommand(args []string) int {
+	flags := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	out := flags.String("o", "", "write the report to a file instead of stdout")
+	asJSON := flags.Bool("json", false, "write the report as JSON")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "tons: loading settings:", err)
+		return 1
+	}
+	ts, err := newCommandTranslator(cfg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "tons:", err)
+		return 1
+	}
+	defer ts.ServiceShutdown()
+	status := services.NewStatusService(cfg, metrics.NewRecorder(), ts)
+	report := services.NewDoctorService(cfg, status).Diagnose()
+
+	data := []byte(report.String())
+	if *asJSON {
+		if data, err = json.MarshalIndent(report, "", "  "); err != nil {
+			fmt.Fprintln(os.Stderr, "tons:", err)
+			return 1
+		}
+		data = append(data, '\n')
+	}
+	if *out != "" {
+		err = os.WriteFile(*out, data, 0644)
+	} else {
+		_, err = os.Stdout.Write(data)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "tons:", err)
+		return 1
+	}
+	if report.Failed() {
+		return 1
+	}
+	return 0
+}
+
+// newCommandTranslator creates the translate service used by subcommands,
+// sharing the translation memory, cache and usage records of the app
+func newCommandTranslator(cfg *config.Config) (*services.TranslateService, error) {
+	memory, err := tm.Open(filepath.Join(config.Dir(), "memory.json"))
+	if err != nil {
+		return nil, err
+	}
+	usageStore, err := usage.Open(filepath.Join(config.Dir(), "usage.json"))
+	if err != nil {
+		return nil, err
+	}
+	translations, err := cache.Open(filepath.Join(config.Dir(), "cache.json"))
+	if err != nil {
+		return nil, err
+	}
+	return services.NewTranslateService(cfg, metrics.NewRecorder(), nil, memory, usageStore, translations), nil
+}
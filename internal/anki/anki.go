@@ -0,0 +1,163 @@
+// Package anki adds flashcards to Anki through the AnkiConnect add-on, or
+// writes them as a TSV file Anki can import
+package anki
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultURL is where AnkiConnect listens by default
+const DefaultURL = "http://127.0.0.1:8765"
+
+// ErrUnavailable is returned when AnkiConnect does not answer, e.g. because Anki is closed
+var ErrUnavailable = errors.New("AnkiConnect is not reachable; is Anki running with the AnkiConnect add-on?")
+
+// Note is a flashcard note: field name to content
+type Note struct {
+	Fields map[string]string `json:"fields"`
+	Tags   []string          `json:"tags,omitempty"`
+}
+
+// Client talks to AnkiConnect
+type Client struct {
+	URL    string
+	client *http.Client
+}
+
+// NewClient creates a client for the AnkiConnect URL (DefaultURL if empty)
+func NewClient(url string) *Client {
+	if url == "" {
+		url = DefaultURL
+	}
+	return &Client{
+		URL:    url,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Available reports whether AnkiConnect answers
+func (c *Client) Available(ctx context.Context) bool {
+	var version int
+	return c.call(ctx, "version", nil, &version) == nil
+}
+
+// AddNotes creates the deck if needed and adds the notes to it, skipping
+// duplicates. Returns the number of added notes.
+func (c *Client) AddNotes(ctx context.Context, deck, noteType string, notes []Note) (int, error) {
+	if err := c.call(ctx, "createDeck", map[string]any{"deck": deck}, nil); err != nil {
+		return 0, err
+	}
+
+	type ankiNote struct {
+		DeckName  string            `json:"deckName"`
+		ModelName string            `json:"modelName"`
+		Fields    map[string]string `json:"fields"`
+		Tags      []string          `json:"tags"`
+		Options   map[string]any    `json:"options"`
+	}
+	params := make([]ankiNote, len(notes))
+	for i, n := range notes {
+		params[i] = ankiNote{
+			DeckName:  deck,
+			ModelName: noteType,
+			Fields:    n.Fields,
+			Tags:      n.Tags,
+			Options:   map[string]any{"allowDuplicate": false, "duplicateScope": "deck"},
+		}
+	}
+
+	// canAddNotes filters out duplicates, which would fail the whole addNotes call
+	var addable []bool
+	if err := c.call(ctx, "canAddNotes", map[string]any{"notes": params}, &addable); err != nil {
+		return 0, err
+	}
+	var add []ankiNote
+	for i, ok := range addable {
+		if ok && i < len(params) {
+			add = append(add, params[i])
+		}
+	}
+	if len(add) == 0 {
+		return 0, nil
+	}
+
+	var ids []*int64
+	if err := c.call(ctx, "addNotes", map[string]any{"notes": add}, &ids); err != nil {
+		return 0, err
+	}
+	added := 0
+	for _, id := range ids {
+		if id != nil {
+			added++
+		}
+	}
+	return added, nil
+}
+
+// call invokes an AnkiConnect action and decodes its result into result (if not nil)
+func (c *Client) call(ctx context.Context, action string, params any, result any) error {
+	body, err := json.Marshal(map[string]any{"action": action, "version": 6, "params": params})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w (%v)", ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	var reply struct {
+		Result json.RawMessage `json:"result"`
+		Error  *string         `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&reply); err != nil {
+		return fmt.Errorf("invalid AnkiConnect response: %w", err)
+	}
+	if reply.Error != nil {
+		return fmt.Errorf("AnkiConnect %s: %s", action, *reply.Error)
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(reply.Result, result)
+}
+
+// TSV renders notes as tab-separated lines in the given field order, with
+// the "#separator" and "#columns" headers Anki's importer understands.
+// Tags go in a last column.
+func TSV(fields []string, notes []Note) []byte {
+	var b strings.Builder
+	b.WriteString("#separator:tab\n#html:true\n")
+	b.WriteString("#columns:" + strings.Join(append(fields[:len(fields):len(fields)], "Tags"), "\t") + "\n")
+	b.WriteString(fmt.Sprintf("#tags column:%d\n", len(fields)+1))
+	for _, n := range notes {
+		for i, field := range fields {
+			if i > 0 {
+				b.WriteByte('\t')
+			}
+			b.WriteString(tsvField(n.Fields[field]))
+		}
+		b.WriteString("\t" + strings.Join(n.Tags, " ") + "\n")
+	}
+	return []byte(b.String())
+}
+
+// tsvField makes a value safe for one TSV cell, using HTML line breaks
+func tsvField(s string) string {
+	s = html.EscapeString(strings.ReplaceAll(s, "\r\n", "\n"))
+	s = strings.ReplaceAll(s, "\t", " ")
+	return strings.ReplaceAll(s, "\n", "<br>")
+}
@@ -0,0 +1,115 @@
+// Package bilingual renders texts together with their translations as
+// Markdown, HTML or Word documents, for reading or sharing them side by side.
+//
+// Texts are aligned by paragraph when the text and its translation have as
+// many paragraphs, and shown whole otherwise.
+package bilingual
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Formats of exported documents
+const (
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+	FormatDOCX     = "docx"
+)
+
+// Layouts of exported documents
+const (
+	// SideBySide puts the text and the translation in two columns
+	SideBySide = "side-by-side"
+	// Interleaved puts each paragraph of the translation below the original
+	Interleaved = "interleaved"
+)
+
+// Pair is a text and its translation
+type Pair struct {
+	SourceLang  string
+	TargetLang  string
+	Text        string
+	Translation string
+}
+
+// segment is a paragraph of a text and its translation
+type segment struct {
+	text, translation string
+}
+
+// blankLines separates paragraphs
+var blankLines = regexp.MustCompile(`\n[ \t]*\n\s*`)
+
+// FormatForPath returns the format for a file's extension, or "" if none
+func FormatForPath(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".md", ".markdown":
+		return FormatMarkdown
+	case ".html", ".htm":
+		return FormatHTML
+	case ".docx":
+		return FormatDOCX
+	}
+	return ""
+}
+
+// Render returns the pairs as a document of the given format and layout.
+// The layout defaults to side by side.
+func Render(format, layout, title string, pairs []Pair) ([]byte, error) {
+	switch layout {
+	case "":
+		layout = SideBySide
+	case SideBySide, Interleaved:
+	default:
+		return nil, fmt.Errorf("unsupported layout %q", layout)
+	}
+	switch format {
+	case FormatMarkdown:
+		return renderMarkdown(layout, title, pairs), nil
+	case FormatHTML:
+		return renderHTML(layout, title, pairs), nil
+	case FormatDOCX:
+		return renderDOCX(layout, title, pairs)
+	default:
+		return nil, fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+// segments splits a pair into aligned paragraphs
+func (p Pair) segments() []segment {
+	texts := paragraphs(p.Text)
+	translations := paragraphs(p.Translation)
+	if len(texts) != len(translations) {
+		return []segment{{strings.TrimSpace(p.Text), strings.TrimSpace(p.Translation)}}
+	}
+	segs := make([]segment, len(texts))
+	for i := range texts {
+		segs[i] = segment{texts[i], translations[i]}
+	}
+	return segs
+}
+
+// heading names the languages of a pair
+func (p Pair) heading() string {
+	return p.source() + " → " + p.TargetLang
+}
+
+// source returns the source language, "auto" if it was detected
+func (p Pair) source() string {
+	if p.SourceLang == "" {
+		return "auto"
+	}
+	return p.SourceLang
+}
+
+// paragraphs splits text at blank lines
+func paragraphs(text string) []string {
+	text = strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+	if text == "" {
+		return nil
+	}
+	return blankLines.Split(text, -1)
+}
@@ -0,0 +1,107 @@
+package bilingual
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"strings"
+)
+
+// The parts of a minimal Word package around the document
+const (
+	contentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/word/document.xml" ContentType="application/vnd.openxmlformats-officedocument.wordprocessingml.document.main+xml"/></Types>`
+	packageRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="word/document.xml"/></Relationships>`
+	documentStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>`
+	documentEnd = `<w:sectPr/></w:body></w:document>`
+
+	// tableStart begins a two-column table spanning the page, with borders
+	tableStart = `<w:tbl><w:tblPr><w:tblW w:w="5000" w:type="pct"/><w:tblLayout w:type="fixed"/><w:tblBorders>` +
+		`<w:top w:val="single" w:sz="4" w:color="CCCCCC"/><w:left w:val="single" w:sz="4" w:color="CCCCCC"/>` +
+		`<w:bottom w:val="single" w:sz="4" w:color="CCCCCC"/><w:right w:val="single" w:sz="4" w:color="CCCCCC"/>` +
+		`<w:insideH w:val="single" w:sz="4" w:color="CCCCCC"/><w:insideV w:val="single" w:sz="4" w:color="CCCCCC"/>` +
+		`</w:tblBorders></w:tblPr><w:tblGrid><w:gridCol w:w="4680"/><w:gridCol w:w="4680"/></w:tblGrid>`
+	tableEnd = `</w:tbl>`
+)
+
+// Run properties of the document's text
+const (
+	plainRun       = ""
+	boldRun        = `<w:rPr><w:b/></w:rPr>`
+	translationRun = `<w:rPr><w:color w:val="1F4E79"/></w:rPr>`
+	headingRun     = `<w:rPr><w:b/><w:sz w:val="28"/></w:rPr>`
+	titleRun       = `<w:rPr><w:b/><w:sz w:val="36"/></w:rPr>`
+)
+
+func renderDOCX(layout, title string, pairs []Pair) ([]byte, error) {
+	var doc strings.Builder
+	doc.WriteString(documentStart)
+	if title != "" {
+		doc.WriteString(docxParagraph(title, titleRun))
+	}
+	for _, p := range pairs {
+		doc.WriteString(docxParagraph(p.heading(), headingRun))
+		segs := p.segments()
+		if layout == SideBySide {
+			doc.WriteString(tableStart)
+			doc.WriteString(docxRow(docxParagraph(p.source(), boldRun), docxParagraph(p.TargetLang, boldRun)))
+			for _, s := range segs {
+				doc.WriteString(docxRow(docxParagraph(s.text, plainRun), docxParagraph(s.translation, translationRun)))
+			}
+			doc.WriteString(tableEnd)
+			// Keeps the next table from joining this one
+			doc.WriteString(docxParagraph("", plainRun))
+			continue
+		}
+		for _, s := range segs {
+			doc.WriteString(docxParagraph(s.text, plainRun))
+			doc.WriteString(docxParagraph(s.translation, translationRun))
+		}
+	}
+	doc.WriteString(documentEnd)
+
+	var out bytes.Buffer
+	zw := zip.NewWriter(&out)
+	for _, f := range []struct{ name, content string }{
+		{"[Content_Types].xml", contentTypes},
+		{"_rels/.rels", packageRels},
+		{"word/document.xml", doc.String()},
+	} {
+		w, err := zw.Create(f.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write([]byte(f.content)); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// docxParagraph returns a paragraph of text with the given run properties,
+// its line breaks kept
+func docxParagraph(text, props string) string {
+	var b strings.Builder
+	b.WriteString("<w:p><w:r>" + props)
+	for i, line := range strings.Split(text, "\n") {
+		if i > 0 {
+			b.WriteString("<w:br/>")
+		}
+		b.WriteString(`<w:t xml:space="preserve">`)
+		xml.EscapeText(&b, []byte(line))
+		b.WriteString("</w:t>")
+	}
+	b.WriteString("</w:r></w:p>")
+	return b.String()
+}
+
+// docxRow returns a table row of two cells with the given contents
+func docxRow(left, right string) string {
+	cell := `<w:tc><w:tcPr><w:tcW w:w="2500" w:type="pct"/></w:tcPr>`
+	return "<w:tr>" + cell + left + "</w:tc>" + cell + right + "</w:tc></w:tr>"
+}
@@ -0,0 +1,46 @@
+package bilingual
+
+import (
+	"html"
+	"strings"
+)
+
+// htmlStyle lays out the exported page; translations are set apart by color
+const htmlStyle = `body { font-family: system-ui, sans-serif; max-width: 60rem; margin: 2rem auto; padding: 0 1rem; line-height: 1.5; }
+table { width: 100%; border-collapse: collapse; table-layout: fixed; margin-bottom: 2rem; }
+th, td { border: 1px solid #ccc; padding: .5rem; vertical-align: top; text-align: left; }
+.translation { color: #1f4e79; }
+p.translation { margin-top: -.5rem; }`
+
+func renderHTML(layout, title string, pairs []Pair) []byte {
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	b.WriteString("<title>" + html.EscapeString(title) + "</title>\n")
+	b.WriteString("<style>\n" + htmlStyle + "\n</style>\n</head>\n<body>\n")
+	if title != "" {
+		b.WriteString("<h1>" + html.EscapeString(title) + "</h1>\n")
+	}
+	for _, p := range pairs {
+		b.WriteString("<h2>" + html.EscapeString(p.heading()) + "</h2>\n")
+		segs := p.segments()
+		if layout == SideBySide {
+			b.WriteString("<table>\n<tr><th>" + html.EscapeString(p.source()) + "</th><th>" + html.EscapeString(p.TargetLang) + "</th></tr>\n")
+			for _, s := range segs {
+				b.WriteString("<tr><td>" + htmlText(s.text) + "</td><td class=\"translation\">" + htmlText(s.translation) + "</td></tr>\n")
+			}
+			b.WriteString("</table>\n")
+			continue
+		}
+		for _, s := range segs {
+			b.WriteString("<p>" + htmlText(s.text) + "</p>\n")
+			b.WriteString("<p class=\"translation\">" + htmlText(s.translation) + "</p>\n")
+		}
+	}
+	b.WriteString("</body>\n</html>\n")
+	return []byte(b.String())
+}
+
+// htmlText escapes text, keeping its line breaks
+func htmlText(text string) string {
+	return strings.ReplaceAll(html.EscapeString(text), "\n", "<br>")
+}
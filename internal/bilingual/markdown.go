@@ -0,0 +1,34 @@
+package bilingual
+
+import (
+	"strings"
+)
+
+// markdownCell escapes text for a table cell, where line breaks end the row
+var markdownCell = strings.NewReplacer("|", `\|`, "\n", "<br>")
+
+func renderMarkdown(layout, title string, pairs []Pair) []byte {
+	var b strings.Builder
+	if title != "" {
+		b.WriteString("# " + title + "\n\n")
+	}
+	for _, p := range pairs {
+		b.WriteString("## " + p.heading() + "\n\n")
+		segs := p.segments()
+		if layout == SideBySide {
+			b.WriteString("| " + markdownCell.Replace(p.source()) + " | " + markdownCell.Replace(p.TargetLang) + " |\n")
+			b.WriteString("| --- | --- |\n")
+			for _, s := range segs {
+				b.WriteString("| " + markdownCell.Replace(s.text) + " | " + markdownCell.Replace(s.translation) + " |\n")
+			}
+			b.WriteString("\n")
+			continue
+		}
+		for _, s := range segs {
+			b.WriteString(s.text + "\n\n")
+			// The translation is quoted below its original
+			b.WriteString("> " + strings.ReplaceAll(s.translation, "\n", "\n> ") + "\n\n")
+		}
+	}
+	return []byte(strings.TrimRight(b.String(), "\n") + "\n")
+}
@@ -0,0 +1,187 @@
+// Package cache keeps completed translations on disk, keyed by a hash of
+// the request, so repeated requests can be answered without an engine and
+// translations stay available offline
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// maxEntries bounds the cache; the least recently used entries are dropped
+const maxEntries = 5000
+
+// saveDelay batches the writes of translations cached in quick succession
+const saveDelay = 5 * time.Second
+
+// Entry is a cached translation
+type Entry struct {
+	Translation  string    `json:"translation"`
+	Engine       string    `json:"engine"`
+	DetectedLang string    `json:"detectedLang,omitempty"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UsedAt       time.Time `json:"usedAt"`
+}
+
+// Store keeps entries in memory and on disk
+type Store struct {
+	mu      sync.Mutex
+	path    string
+	entries map[string]Entry
+	dirty   bool
+	pending *time.Timer // scheduled save of new entries
+}
+
+// Open loads the cache file at path, starting empty if it doesn't exist. A
+// corrupt file is logged and moved aside to path.corrupt; the cache then
+// starts empty, as it only saves engine calls.
+func Open(path string) (*Store, error) {
+	s := &Store{
+		path:    path,
+		entries: make(map[string]Entry),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.entries); err != nil {
+		slog.Warn("translation cache is corrupt, starting empty", "path", path, "error", err)
+		s.entries = make(map[string]Entry)
+		if err := os.Rename(path, path+".corrupt"); err != nil {
+			return nil, err
+		}
+	}
+	return s, nil
+}
+
+// Key returns the cache key of a request, given as the values that decide
+// its translation (text, languages, prompts, ...)
+func Key(parts ...any) string {
+	data, _ := json.Marshal(parts)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Get returns the cached translation for key and marks it as used
+func (s *Store) Get(key string) (Entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if ok {
+		e.UsedAt = time.Now()
+		s.entries[key] = e
+		s.dirty = true
+	}
+	return e, ok
+}
+
+// Put caches a translation. It is written to disk shortly after, together
+// with the translations cached in the meantime; call Save to write it now.
+func (s *Store) Put(key string, e Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	e.CreatedAt, e.UsedAt = now, now
+	s.entries[key] = e
+	if len(s.entries) > maxEntries {
+		keys := slices.SortedFunc(maps.Keys(s.entries), func(a, b string) int {
+			return s.entries[a].UsedAt.Compare(s.entries[b].UsedAt)
+		})
+		for _, k := range keys[:len(keys)-maxEntries] {
+			delete(s.entries, k)
+		}
+	}
+	s.dirty = true
+	if s.pending == nil {
+		s.pending = time.AfterFunc(saveDelay, s.savePending)
+	}
+}
+
+// savePending runs the scheduled save
+func (s *Store) savePending() {
+	s.mu.Lock()
+	s.pending = nil
+	s.mu.Unlock()
+	if err := s.Save(); err != nil {
+		slog.Warn("failed to save translation cache", "error", err)
+	}
+}
+
+// Len returns the number of cached translations
+func (s *Store) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.entries)
+}
+
+// Clear removes all cached translations and saves the empty cache
+func (s *Store) Clear() error {
+	s.mu.Lock()
+	s.entries = make(map[string]Entry)
+	s.dirty = true
+	s.mu.Unlock()
+	return s.Save()
+}
+
+// Save writes the cache to disk if it changed. The file is replaced
+// atomically, so a crash never leaves a partial cache behind.
+func (s *Store) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.dirty {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(s.entries)
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(s.path, data); err != nil {
+		return err
+	}
+	s.dirty = false
+	return nil
+}
+
+// writeFileAtomic replaces the file at path with data, writing a temporary
+// file in the same directory and renaming it over path
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // fails harmlessly after the rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,115 @@
+// Package capture takes interactive screen region screenshots using the
+// platform's screenshot tools.
+package capture
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+var (
+	// ErrCancelled is returned when the user aborts the region selection
+	ErrCancelled = errors.New("capture: cancelled")
+	// ErrUnsupported is returned when no screenshot tool is available
+	ErrUnsupported = errors.New("capture: no screenshot tool available")
+)
+
+// tool is a screenshot command writing a PNG of a user-selected region to {file}
+type tool struct {
+	name string
+	args func(file string) []string
+}
+
+// tools lists region capture commands per platform, in order of preference
+var tools = map[string][]tool{
+	"darwin": {
+		{"screencapture", func(file string) []string { return []string{"-i", "-x", "-t", "png", file} }},
+	},
+	"linux": {
+		{"gnome-screenshot", func(file string) []string { return []string{"-a", "-f", file} }},
+		{"spectacle", func(file string) []string { return []string{"-r", "-b", "-n", "-o", file} }},
+		{"maim", func(file string) []string { return []string{"-s", file} }},
+		{"scrot", func(file string) []string { return []string{"-s", "-o", file} }},
+		{"flameshot", func(file string) []string { return []string{"gui", "-r", "--path", file} }},
+	},
+}
+
+// waylandTool captures a region with grim, using slurp for the selection
+func waylandTool(ctx context.Context, file string) error {
+	if _, err := exec.LookPath("slurp"); err != nil {
+		return ErrUnsupported
+	}
+	if _, err := exec.LookPath("grim"); err != nil {
+		return ErrUnsupported
+	}
+	geometry, err := exec.CommandContext(ctx, "slurp").Output()
+	if err != nil || len(strings.TrimSpace(string(geometry))) == 0 {
+		return ErrCancelled
+	}
+	return exec.CommandContext(ctx, "grim", "-g", strings.TrimSpace(string(geometry)), file).Run()
+}
+
+// Available reports whether region capture is supported on this system
+func Available() bool {
+	if runtime.GOOS == "linux" && os.Getenv("WAYLAND_DISPLAY") != "" {
+		if _, err := exec.LookPath("grim"); err == nil {
+			return true
+		}
+	}
+	_, ok := findTool()
+	return ok
+}
+
+// findTool returns the first installed screenshot tool for this platform
+func findTool() (tool, bool) {
+	for _, t := range tools[runtime.GOOS] {
+		if _, err := exec.LookPath(t.name); err == nil {
+			return t, true
+		}
+	}
+	return tool{}, false
+}
+
+// Region lets the user select a screen region and returns it as PNG data
+func Region(ctx context.Context) ([]byte, error) {
+	dir, err := os.MkdirTemp("", "tons-capture")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "region.png")
+
+	err = ErrUnsupported
+	if runtime.GOOS == "linux" && os.Getenv("WAYLAND_DISPLAY") != "" {
+		err = waylandTool(ctx, file)
+	}
+	if errors.Is(err, ErrUnsupported) {
+		t, ok := findTool()
+		if !ok {
+			return nil, ErrUnsupported
+		}
+		err = exec.CommandContext(ctx, t.name, t.args(file)...).Run()
+		if err != nil {
+			err = fmt.Errorf("%s: %w", t.name, err)
+		}
+	}
+	if errors.Is(err, ErrCancelled) {
+		return nil, err
+	}
+
+	// Tools exit successfully without writing a file when the selection is aborted
+	data, readErr := os.ReadFile(file)
+	if readErr != nil || len(data) == 0 {
+		if err != nil {
+			return nil, fmt.Errorf("capture failed: %w", err)
+		}
+		return nil, ErrCancelled
+	}
+	return data, nil
+}
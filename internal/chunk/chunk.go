@@ -0,0 +1,217 @@
+// Package chunk splits long texts into translation-sized pieces and reassembles them.
+package chunk
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Chunk is a piece of the original text
+type Chunk struct {
+	Text    string // text to translate, without trailing separator
+	Sep     string // whitespace that followed Text in the original
+	Context string // tail of the preceding text, for disambiguation only
+}
+
+// unit is an indivisible segment (paragraph, sentence or hard-split piece)
+type unit struct {
+	text string
+	sep  string
+}
+
+// Split breaks text into chunks of at most maxBytes bytes, preferring paragraph,
+// then sentence, then word boundaries. Each chunk after the first carries up to
+// overlap bytes of preceding text as Context.
+// Text that fits is returned as a single chunk.
+func Split(text string, maxBytes, overlap int) []Chunk {
+	if maxBytes <= 0 || len(text) <= maxBytes {
+		return []Chunk{{Text: text}}
+	}
+
+	var units []unit
+	for _, para := range splitKeepSep(text, isParagraphBreak) {
+		if len(para.text) <= maxBytes {
+			units = append(units, para)
+			continue
+		}
+		sentences := splitSentences(para.text)
+		sentences[len(sentences)-1].sep += para.sep
+		for _, s := range sentences {
+			if len(s.text) <= maxBytes {
+				units = append(units, s)
+				continue
+			}
+			units = append(units, hardSplit(s, maxBytes)...)
+		}
+	}
+
+	var chunks []Chunk
+	var current strings.Builder
+	var lastSep string
+	for _, u := range units {
+		if current.Len() > 0 && current.Len()+len(lastSep)+len(u.text) > maxBytes {
+			chunks = append(chunks, Chunk{Text: current.String(), Sep: lastSep})
+			current.Reset()
+		} else if current.Len() > 0 {
+			current.WriteString(lastSep)
+		}
+		current.WriteString(u.text)
+		lastSep = u.sep
+	}
+	if current.Len() > 0 || len(chunks) == 0 {
+		chunks = append(chunks, Chunk{Text: current.String(), Sep: lastSep})
+	}
+
+	if overlap > 0 {
+		for i := 1; i < len(chunks); i++ {
+			chunks[i].Context = tail(chunks[i-1].Text, overlap)
+		}
+	}
+	return chunks
+}
+
+// Join reassembles translated chunk texts using the original separators
+func Join(chunks []Chunk, translated []string) string {
+	var b strings.Builder
+	for i, c := range chunks {
+		if i < len(translated) {
+			b.WriteString(translated[i])
+		}
+		b.WriteString(c.Sep)
+	}
+	return b.String()
+}
+
+// CountSentences returns the number of sentences in text, counting each
+// paragraph as ending one
+func CountSentences(text string) int {
+	n := 0
+	for _, para := range splitKeepSep(strings.TrimSpace(text), isParagraphBreak) {
+		if strings.TrimSpace(para.text) != "" {
+			n += len(splitSentences(para.text))
+		}
+	}
+	return n
+}
+
+// isParagraphBreak reports whether a whitespace run separates paragraphs
+func isParagraphBreak(ws string) bool {
+	return strings.Count(ws, "\n") >= 2
+}
+
+// splitKeepSep splits text at whitespace runs accepted by isBreak, keeping the runs as separators
+func splitKeepSep(text string, isBreak func(ws string) bool) []unit {
+	var units []unit
+	start := 0
+	i := 0
+	for i < len(text) {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if !unicode.IsSpace(r) {
+			i += size
+			continue
+		}
+		j := i
+		for j < len(text) {
+			r, size := utf8.DecodeRuneInString(text[j:])
+			if !unicode.IsSpace(r) {
+				break
+			}
+			j += size
+		}
+		if isBreak(text[i:j]) && i > start {
+			units = append(units, unit{text: text[start:i], sep: text[i:j]})
+			start = j
+		}
+		i = j
+	}
+	if start < len(text) || len(units) == 0 {
+		units = append(units, unit{text: text[start:]})
+	} else {
+		units[len(units)-1].sep += text[start:]
+	}
+	return units
+}
+
+// sentenceEnders are runes that end a sentence
+const sentenceEnders = ".!?。！？…"
+
+// splitSentences splits a paragraph after sentence-ending punctuation
+func splitSentences(text string) []unit {
+	var units []unit
+	start := 0
+	for i, r := range text {
+		if !strings.ContainsRune(sentenceEnders, r) {
+			continue
+		}
+		end := i + utf8.RuneLen(r)
+		// Absorb closing quotes/brackets after the punctuation
+		for end < len(text) {
+			next, size := utf8.DecodeRuneInString(text[end:])
+			if !strings.ContainsRune(`"'”’)]」』`, next) {
+				break
+			}
+			end += size
+		}
+		// CJK punctuation ends a sentence on its own; Latin needs following whitespace
+		wsEnd := end
+		for wsEnd < len(text) {
+			next, size := utf8.DecodeRuneInString(text[wsEnd:])
+			if !unicode.IsSpace(next) {
+				break
+			}
+			wsEnd += size
+		}
+		if wsEnd == end && r < utf8.RuneSelf && end < len(text) {
+			continue
+		}
+		if end > start {
+			units = append(units, unit{text: text[start:end], sep: text[end:wsEnd]})
+		}
+		start = wsEnd
+	}
+	if start < len(text) || len(units) == 0 {
+		units = append(units, unit{text: text[start:]})
+	}
+	return units
+}
+
+// hardSplit cuts an oversized unit at word (or rune) boundaries
+func hardSplit(u unit, maxBytes int) []unit {
+	var units []unit
+	text := u.text
+	for len(text) > maxBytes {
+		cut := maxBytes
+		for cut > 0 && !utf8.RuneStart(text[cut]) {
+			cut--
+		}
+		if cut == 0 {
+			// A rune larger than maxBytes still has to go somewhere
+			_, cut = utf8.DecodeRuneInString(text)
+		}
+		if space := strings.LastIndexFunc(text[:cut], unicode.IsSpace); space > maxBytes/2 {
+			cut = space
+		}
+		piece := text[:cut]
+		rest := strings.TrimLeftFunc(text[cut:], unicode.IsSpace)
+		units = append(units, unit{text: piece, sep: text[cut : len(text)-len(rest)]})
+		text = rest
+	}
+	units = append(units, unit{text: text, sep: u.sep})
+	return units
+}
+
+// tail returns at most n bytes from the end of s, starting at a word boundary when possible
+func tail(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	start := len(s) - n
+	for start < len(s) && !utf8.RuneStart(s[start]) {
+		start++
+	}
+	if space := strings.IndexFunc(s[start:], unicode.IsSpace); space >= 0 && space < n/2 {
+		start += space + 1
+	}
+	return s[start:]
+}
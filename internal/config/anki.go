@@ -0,0 +1,49 @@
+package config
+
+// Contents an Anki note field can be filled with
+const (
+	AnkiSource             = "source"
+	AnkiTranslation        = "translation"
+	AnkiRomanization       = "romanization"
+	AnkiExample            = "example"
+	AnkiExampleTranslation = "exampleTranslation"
+	AnkiSourceLang         = "sourceLang"
+	AnkiTargetLang         = "targetLang"
+)
+
+// AnkiField maps a field of the Anki note type to its content
+type AnkiField struct {
+	Name    string `json:"name"`    // field name in the note type, e.g. "Front"
+	Content string `json:"content"` // one of the Anki* contents
+}
+
+// AnkiConfig holds flashcard export settings
+type AnkiConfig struct {
+	URL      string      `json:"url"`      // AnkiConnect address
+	Deck     string      `json:"deck"`     // created if missing
+	NoteType string      `json:"noteType"` // Anki note type ("model"), e.g. "Basic"
+	Fields   []AnkiField `json:"fields"`
+	Tags     []string    `json:"tags"`
+}
+
+// DefaultAnkiConfig returns default flashcard export settings
+func DefaultAnkiConfig() AnkiConfig {
+	return AnkiConfig{
+		URL:      "http://127.0.0.1:8765",
+		Deck:     "tons",
+		NoteType: "Basic",
+		Fields: []AnkiField{
+			{Name: "Front", Content: AnkiSource},
+			{Name: "Back", Content: AnkiTranslation},
+		},
+		Tags: []string{"tons"},
+	}
+}
+
+// SetAnki sets the entire flashcard export config
+func (c *Config) SetAnki(anki AnkiConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Anki = anki
+}
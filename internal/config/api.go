@@ -0,0 +1,29 @@
+package config
+
+// DefaultAPIPort is the port of the local HTTP API unless configured otherwise
+const DefaultAPIPort = 47821
+
+// APIConfig holds settings of the local HTTP API
+type APIConfig struct {
+	Enabled bool   `json:"enabled"`
+	Port    int    `json:"port"`  // listens on 127.0.0.1 only
+	Token   string `json:"token"` // required as "Authorization: Bearer <token>"; generated when empty
+}
+
+// DefaultAPIConfig returns default API settings
+func DefaultAPIConfig() APIConfig {
+	return APIConfig{
+		Port: DefaultAPIPort,
+	}
+}
+
+// SetAPI sets the entire API config
+func (c *Config) SetAPI(api APIConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if api.Port <= 0 || api.Port > 65535 {
+		api.Port = DefaultAPIPort
+	}
+	c.API = api
+}
@@ -0,0 +1,115 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"slices"
+	"time"
+)
+
+// maxRevisions bounds the saved states kept for Undo
+const maxRevisions = 50
+
+// ErrNothingToUndo is returned by Undo when no change was saved since the
+// configuration was loaded, or all of them were undone
+var ErrNothingToUndo = errors.New("no settings change to undo")
+
+// revision is the configuration as it was saved at one time
+type revision struct {
+	time time.Time
+	data []byte // see fileJSON
+}
+
+// ConfigChange is a saved change of the configuration
+type ConfigChange struct {
+	Time     time.Time `json:"time"`
+	Sections []string  `json:"sections"` // JSON names of the changed sections
+}
+
+// record adds the saved configuration data to the revisions, unless it is
+// the same as the last one
+func (c *Config) record(data []byte) {
+	c.revMu.Lock()
+	defer c.revMu.Unlock()
+
+	if n := len(c.revisions); n > 0 && bytes.Equal(c.revisions[n-1].data, data) {
+		return
+	}
+	c.revisions = append(c.revisions, revision{time: time.Now(), data: data})
+	if len(c.revisions) > maxRevisions {
+		c.revisions = slices.Delete(c.revisions, 0, len(c.revisions)-maxRevisions)
+	}
+}
+
+// lastRevision returns the data of the last recorded revision, or nil
+func (c *Config) lastRevision() []byte {
+	c.revMu.Lock()
+	defer c.revMu.Unlock()
+
+	if len(c.revisions) == 0 {
+		return nil
+	}
+	return c.revisions[len(c.revisions)-1].data
+}
+
+// Changes returns the changes saved since the configuration was loaded,
+// newest first. Only the last maxRevisions saves are kept.
+func (c *Config) Changes() []ConfigChange {
+	c.revMu.Lock()
+	defer c.revMu.Unlock()
+
+	changes := []ConfigChange{}
+	for i := len(c.revisions) - 1; i > 0; i-- {
+		changes = append(changes, ConfigChange{
+			Time:     c.revisions[i].time,
+			Sections: changedSections(c.revisions[i-1].data, c.revisions[i].data),
+		})
+	}
+	return changes
+}
+
+// Undo reverts the last saved change, saves the configuration and returns
+// the JSON names of the sections that changed back. Settings overridden by
+// environment variables keep their overrides.
+func (c *Config) Undo() ([]string, error) {
+	c.revMu.Lock()
+	n := len(c.revisions)
+	if n < 2 {
+		c.revMu.Unlock()
+		return nil, ErrNothingToUndo
+	}
+	last, previous := c.revisions[n-1], c.revisions[n-2]
+	c.revisions = c.revisions[:n-1]
+	c.revMu.Unlock()
+
+	restored := Default()
+	if err := json.Unmarshal(previous.data, restored); err != nil {
+		return nil, err
+	}
+	if err := restored.applyEnv(); err != nil {
+		return nil, err
+	}
+	c.Restore(restored)
+	if err := c.Save(); err != nil {
+		return nil, err
+	}
+	return changedSections(last.data, previous.data), nil
+}
+
+// changedSections returns the JSON names of the sections that differ
+// between two saved configurations
+func changedSections(a, b []byte) []string {
+	var before, after map[string]json.RawMessage
+	if json.Unmarshal(a, &before) != nil || json.Unmarshal(b, &after) != nil {
+		return nil
+	}
+	var changed []string
+	for name, data := range after {
+		if !bytes.Equal(before[name], data) {
+			changed = append(changed, name)
+		}
+	}
+	slices.Sort(changed)
+	return changed
+}
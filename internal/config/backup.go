@@ -0,0 +1,98 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// backupCount is the number of previous config files kept as
+// config.json.bak.1 (newest) to config.json.bak.N
+const backupCount = 3
+
+// backupPath returns the path of the nth backup of the config file at path
+func backupPath(path string, n int) string {
+	return fmt.Sprintf("%s.bak.%d", path, n)
+}
+
+// writeFileAtomic replaces the file at path with data. The data is written to
+// a temporary file in the same directory first and renamed over path, so a
+// crash leaves either the old or the new file, never a partial one.
+func writeFileAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // fails harmlessly after the rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// rotateBackups shifts the backups of the config file at path by one and
+// keeps its current contents as the newest backup. Nothing is rotated if the
+// file does not exist yet or already holds data.
+func rotateBackups(path string, data []byte) error {
+	current, err := os.ReadFile(path)
+	if os.IsNotExist(err) || (err == nil && string(current) == string(data)) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for n := backupCount - 1; n >= 1; n-- {
+		if err := os.Rename(backupPath(path, n), backupPath(path, n+1)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return writeFileAtomic(backupPath(path, 1), current)
+}
+
+// loadBackup loads the newest backup of the config file at path that can be
+// parsed, for when the file itself is corrupt. The broken file is moved
+// aside to path.corrupt so the next save neither rotates it into the
+// backups nor loses it.
+func loadBackup(path string, cause error) (*Config, error) {
+	for n := 1; n <= backupCount; n++ {
+		backup := backupPath(path, n)
+		data, err := os.ReadFile(backup)
+		if err != nil {
+			continue
+		}
+		cfg, _, err := parseFile(path, data)
+		if err != nil {
+			continue
+		}
+		if err := os.Rename(path, path+".corrupt"); err != nil {
+			return nil, err
+		}
+		cfg.restoredFrom = backup
+		// The restored settings, upgraded if need be, replace the corrupt file
+		if err := cfg.Save(); err != nil {
+			return nil, err
+		}
+		return cfg, nil
+	}
+	return nil, cause
+}
+
+// RestoredFrom returns the path of the backup the configuration was loaded
+// from because the config file was corrupt, or "" if it loaded normally
+func (c *Config) RestoredFrom() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.restoredFrom
+}
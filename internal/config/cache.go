@@ -0,0 +1,32 @@
+package config
+
+// CacheConfig holds the on-disk cache of completed translations
+type CacheConfig struct {
+	Enabled bool `json:"enabled"` // store completed translations
+	Reuse   bool `json:"reuse"`   // answer repeated requests from the cache instead of the engine
+	Offline bool `json:"offline"` // serve cached translations while the engine is unavailable
+
+	// HistoryMinutes answers a request repeated within this many minutes
+	// from the history, even without reuse, e.g. a shortcut pressed twice;
+	// 0 = off
+	HistoryMinutes int `json:"historyMinutes"`
+}
+
+// DefaultCacheConfig returns default cache settings: translations are
+// cached for offline use but requested again while the engine works, unless
+// they were made in the last few minutes
+func DefaultCacheConfig() CacheConfig {
+	return CacheConfig{
+		Enabled:        true,
+		Offline:        true,
+		HistoryMinutes: 10,
+	}
+}
+
+// SetCache sets the entire cache config
+func (c *Config) SetCache(cache CacheConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Cache = cache
+}
@@ -0,0 +1,32 @@
+package config
+
+// ClipboardConfig holds clipboard monitor settings
+type ClipboardConfig struct {
+	Enabled       bool   `json:"enabled"`       // watch the clipboard for new text
+	AutoTranslate bool   `json:"autoTranslate"` // translate new text right away instead of on tap
+	WriteBack     bool   `json:"writeBack"`     // replace the clipboard text with its translation
+	SourceLang    string `json:"sourceLang"`
+	TargetLang    string `json:"targetLang"`
+	MinLength     int    `json:"minLength"`  // ignore text shorter than this many characters
+	MaxLength     int    `json:"maxLength"`  // ignore text longer than this many characters
+	IntervalMs    int    `json:"intervalMs"` // how often the clipboard is checked
+}
+
+// DefaultClipboardConfig returns default clipboard settings
+func DefaultClipboardConfig() ClipboardConfig {
+	return ClipboardConfig{
+		SourceLang: "auto",
+		TargetLang: "en",
+		MinLength:  2,
+		MaxLength:  5000,
+		IntervalMs: 500,
+	}
+}
+
+// SetClipboard sets the entire clipboard config
+func (c *Config) SetClipboard(clipboard ClipboardConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Clipboard = clipboard
+}
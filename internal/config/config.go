@@ -1,7 +1,11 @@
 package config
 
 import (
+	"crypto/sha256"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
 	"os"
 	"path/filepath"
 	"sync"
@@ -9,18 +13,59 @@ import (
 
 // Config holds all application configuration
 type Config struct {
-	mu      sync.RWMutex  `json:"-"`
-	General GeneralConfig `json:"general"`
-	Engine  EngineConfig  `json:"engine"`
-	Prompt  PromptConfig  `json:"prompt"`
+	mu          sync.RWMutex      `json:"-"`
+	Version     int               `json:"version"` // file layout, see SchemaVersion
+	General     GeneralConfig     `json:"general"`
+	Engine      EngineConfig      `json:"engine"`
+	Prompt      PromptConfig      `json:"prompt"`
+	Network     NetworkConfig     `json:"network"`
+	Translation TranslationConfig `json:"translation"`
+	OCR         OCRConfig         `json:"ocr"`
+	Speech      SpeechConfig      `json:"speech"`
+	Clipboard   ClipboardConfig   `json:"clipboard"`
+	Selection   SelectionConfig   `json:"selection"`
+	Tray        TrayConfig        `json:"tray"`
+	MiniWindow  MiniWindowConfig  `json:"miniWindow"`
+	Anki        AnkiConfig        `json:"anki"`
+	Usage       UsageConfig       `json:"usage"`
+	API         APIConfig         `json:"api"`
+	Hotkeys     HotkeysConfig     `json:"hotkeys"`
+	Telemetry   TelemetryConfig   `json:"telemetry"`
+	Cache       CacheConfig       `json:"cache"`
+	Log         LogConfig         `json:"log"`
+
+	overrides    []envOverride // settings replaced by environment variables, see applyEnv
+	restoredFrom string        // backup loaded in place of a corrupt config file
+
+	revMu     sync.Mutex
+	revisions []revision // saved states for Undo, oldest first
+
+	fileMu  sync.Mutex
+	fileSum [sha256.Size]byte // checksum of config.json as last read or written, see Watch
 }
 
 // Default returns a Config with default values
 func Default() *Config {
 	return &Config{
-		General: DefaultGeneralConfig(),
-		Engine:  DefaultEngineConfig(),
-		Prompt:  DefaultPromptConfig(),
+		Version:     SchemaVersion,
+		General:     DefaultGeneralConfig(),
+		Engine:      DefaultEngineConfig(),
+		Prompt:      DefaultPromptConfig(),
+		Network:     DefaultNetworkConfig(),
+		Translation: DefaultTranslationConfig(),
+		OCR:         DefaultOCRConfig(),
+		Speech:      DefaultSpeechConfig(),
+		Clipboard:   DefaultClipboardConfig(),
+		Selection:   DefaultSelectionConfig(),
+		Tray:        DefaultTrayConfig(),
+		MiniWindow:  DefaultMiniWindowConfig(),
+		Anki:        DefaultAnkiConfig(),
+		Usage:       DefaultUsageConfig(),
+		API:         DefaultAPIConfig(),
+		Hotkeys:     DefaultHotkeysConfig(),
+		Telemetry:   DefaultTelemetryConfig(),
+		Cache:       DefaultCacheConfig(),
+		Log:         DefaultLogConfig(),
 	}
 }
 
@@ -29,9 +74,19 @@ var (
 	configOnce sync.Once
 )
 
-// getConfigDir returns the configuration directory path
+// getConfigDir returns the configuration directory path: the one passed to
+// SetDir, else $TONS_CONFIG_DIR, else tons in $XDG_CONFIG_HOME (on every
+// platform), else tons in the OS config directory
 func getConfigDir() string {
 	configOnce.Do(func() {
+		if dir := os.Getenv("TONS_CONFIG_DIR"); dir != "" {
+			configDir = dir
+			return
+		}
+		if xdg := os.Getenv("XDG_CONFIG_HOME"); filepath.IsAbs(xdg) {
+			configDir = filepath.Join(xdg, "tons")
+			return
+		}
 		userConfigDir, err := os.UserConfigDir()
 		if err != nil {
 			// Fallback to home directory
@@ -44,32 +99,107 @@ func getConfigDir() string {
 	return configDir
 }
 
-// configPath returns the full path to the config file
+// SetDir moves the configuration and app data to dir, e.g. to run separate
+// work and personal instances. It must be called before anything is loaded
+// and returns an error otherwise.
+func SetDir(dir string) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	set := false
+	configOnce.Do(func() {
+		configDir, set = abs, true
+	})
+	if !set && configDir != abs {
+		return errors.New("config directory already in use: " + configDir)
+	}
+	return nil
+}
+
+// Dir returns the directory holding the configuration and other app data
+func Dir() string {
+	return getConfigDir()
+}
+
+// configPath returns the full path to the config file: config.yaml,
+// config.yml or config.toml if the user created one, otherwise config.json
 func configPath() string {
-	return filepath.Join(getConfigDir(), "config.json")
+	return findConfigFile(getConfigDir())
 }
 
 // Load reads the configuration from disk
 // Returns default config if file doesn't exist
+// Files from older versions are upgraded and saved, keeping a backup
+// A config file that cannot be parsed is replaced by its newest valid backup
+// TONS_* environment variables override the loaded values (see envKeys)
 func Load() (*Config, error) {
+	return load(true)
+}
+
+// load reads the configuration. At startup a corrupt file is replaced by a
+// backup and an old one is upgraded on disk; otherwise the file is only
+// read, so e.g. a reload never overwrites a file the user is still editing.
+func load(startup bool) (*Config, error) {
 	cfg := Default()
 
-	data, err := os.ReadFile(configPath())
-	if err != nil {
-		if os.IsNotExist(err) {
-			return cfg, nil
-		}
+	path := configPath()
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
 		return nil, err
 	}
+	if err == nil {
+		var from int
+		cfg, from, err = parseFile(path, data)
+		upgraded := err == nil && from < SchemaVersion
+		if err != nil && startup {
+			cfg, err = loadBackup(path, err)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if upgraded && startup {
+			if err := keepVersionBackup(path, from, data); err != nil {
+				return nil, err
+			}
+			if err := cfg.Save(); err != nil {
+				return nil, err
+			}
+		}
+	}
 
-	if err := json.Unmarshal(data, cfg); err != nil {
+	// The loaded state is the first one Undo can return to
+	if saved, err := cfg.fileJSON(); err == nil {
+		cfg.record(saved)
+	}
+	if err := cfg.applyEnv(); err != nil {
 		return nil, err
 	}
-
 	return cfg, nil
 }
 
-// Save writes the configuration to disk
+// parseFile parses the contents of the config file at path, upgraded to
+// SchemaVersion, and returns the version they had
+func parseFile(path string, data []byte) (*Config, int, error) {
+	decoded, err := decodeFile(path, data)
+	if err != nil {
+		return nil, 0, err
+	}
+	current, from, err := upgradeFile(decoded)
+	if err != nil {
+		return nil, 0, err
+	}
+	cfg := Default()
+	if err := json.Unmarshal(current, cfg); err != nil {
+		return nil, 0, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	cfg.fileSum = sha256.Sum256(data)
+	return cfg, from, nil
+}
+
+// Save writes the configuration to disk. The file is replaced atomically and
+// its previous contents are kept as a backup (see backupCount). Saved
+// changes are recorded for Undo.
 func (c *Config) Save() error {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
@@ -79,12 +209,25 @@ func (c *Config) Save() error {
 		return err
 	}
 
-	data, err := json.MarshalIndent(c, "", "  ")
+	path := configPath()
+	saved, err := c.fileJSON()
+	var data []byte
+	if err == nil {
+		data, err = encodeFile(path, saved)
+	}
 	if err != nil {
 		return err
 	}
 
-	return os.WriteFile(configPath(), data, 0644)
+	if err := rotateBackups(path, data); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, data); err != nil {
+		return err
+	}
+	c.setFileSum(sha256.Sum256(data))
+	c.record(saved)
+	return nil
 }
 
 // Reset restores the configuration to default values and saves
@@ -94,6 +237,21 @@ func (c *Config) Reset() error {
 	c.General = defaultCfg.General
 	c.Engine = defaultCfg.Engine
 	c.Prompt = defaultCfg.Prompt
+	c.Network = defaultCfg.Network
+	c.Translation = defaultCfg.Translation
+	c.OCR = defaultCfg.OCR
+	c.Speech = defaultCfg.Speech
+	c.Clipboard = defaultCfg.Clipboard
+	c.Selection = defaultCfg.Selection
+	c.Tray = defaultCfg.Tray
+	c.MiniWindow = defaultCfg.MiniWindow
+	c.Anki = defaultCfg.Anki
+	c.Usage = defaultCfg.Usage
+	c.API = defaultCfg.API
+	c.Hotkeys = defaultCfg.Hotkeys
+	c.Telemetry = defaultCfg.Telemetry
+	c.Cache = defaultCfg.Cache
+	c.Log = defaultCfg.Log
 	c.mu.Unlock()
 
 	return c.Save()
@@ -105,9 +263,25 @@ func (c *Config) Snapshot() *Config {
 	defer c.mu.RUnlock()
 
 	snapshot := &Config{
-		General: c.General,
-		Engine:  c.Engine,
-		Prompt:  c.Prompt,
+		Version:     c.Version,
+		General:     c.General,
+		Engine:      c.Engine,
+		Prompt:      c.Prompt,
+		Network:     c.Network,
+		Translation: c.Translation,
+		OCR:         c.OCR,
+		Speech:      c.Speech,
+		Clipboard:   c.Clipboard,
+		Selection:   c.Selection,
+		Tray:        c.Tray,
+		MiniWindow:  c.MiniWindow,
+		Anki:        c.Anki,
+		Usage:       c.Usage,
+		API:         c.API,
+		Hotkeys:     c.Hotkeys,
+		Telemetry:   c.Telemetry,
+		Cache:       c.Cache,
+		Log:         c.Log,
 	}
 
 	// Deep copy slices in TerminalAgentConfig
@@ -123,6 +297,57 @@ func (c *Config) Snapshot() *Config {
 		snapshot.Engine.TerminalAgent.Codex.Args = make([]string, len(c.Engine.TerminalAgent.Codex.Args))
 		copy(snapshot.Engine.TerminalAgent.Codex.Args, c.Engine.TerminalAgent.Codex.Args)
 	}
+	snapshot.Engine.Ollama.Options = copyOptions(c.Engine.Ollama.Options)
+	if c.Translation.MultiTargets != nil {
+		snapshot.Translation.MultiTargets = make([]string, len(c.Translation.MultiTargets))
+		copy(snapshot.Translation.MultiTargets, c.Translation.MultiTargets)
+	}
+	if c.Translation.Processors != nil {
+		snapshot.Translation.Processors = make([]string, len(c.Translation.Processors))
+		copy(snapshot.Translation.Processors, c.Translation.Processors)
+	}
+	if c.Translation.LocalizePunctuation != nil {
+		snapshot.Translation.LocalizePunctuation = make([]string, len(c.Translation.LocalizePunctuation))
+		copy(snapshot.Translation.LocalizePunctuation, c.Translation.LocalizePunctuation)
+	}
+	if c.OCR.Languages != nil {
+		snapshot.OCR.Languages = make([]string, len(c.OCR.Languages))
+		copy(snapshot.OCR.Languages, c.OCR.Languages)
+	}
+	if c.Prompt.Examples != nil {
+		snapshot.Prompt.Examples = make([]PromptExample, len(c.Prompt.Examples))
+		copy(snapshot.Prompt.Examples, c.Prompt.Examples)
+	}
+	if c.Prompt.Templates != nil {
+		snapshot.Prompt.Templates = make([]PromptTemplate, len(c.Prompt.Templates))
+		copy(snapshot.Prompt.Templates, c.Prompt.Templates)
+	}
+	if c.Prompt.Presets != nil {
+		snapshot.Prompt.Presets = make([]PromptPreset, len(c.Prompt.Presets))
+		copy(snapshot.Prompt.Presets, c.Prompt.Presets)
+	}
+	if c.Prompt.Glossary != nil {
+		snapshot.Prompt.Glossary = make([]GlossaryTerm, len(c.Prompt.Glossary))
+		copy(snapshot.Prompt.Glossary, c.Prompt.Glossary)
+	}
+	if c.Tray.LanguagePairs != nil {
+		snapshot.Tray.LanguagePairs = make([]LanguagePair, len(c.Tray.LanguagePairs))
+		copy(snapshot.Tray.LanguagePairs, c.Tray.LanguagePairs)
+	}
+	if c.Anki.Fields != nil {
+		snapshot.Anki.Fields = make([]AnkiField, len(c.Anki.Fields))
+		copy(snapshot.Anki.Fields, c.Anki.Fields)
+	}
+	if c.Anki.Tags != nil {
+		snapshot.Anki.Tags = make([]string, len(c.Anki.Tags))
+		copy(snapshot.Anki.Tags, c.Anki.Tags)
+	}
+	if c.Usage.Budgets != nil {
+		snapshot.Usage.Budgets = make([]Budget, len(c.Usage.Budgets))
+		copy(snapshot.Usage.Budgets, c.Usage.Budgets)
+	}
+	snapshot.Prompt.Variables = maps.Clone(c.Prompt.Variables)
+	snapshot.Speech.Voices = maps.Clone(c.Speech.Voices)
 
 	return snapshot
 }
@@ -139,6 +364,21 @@ func (c *Config) Restore(snapshot *Config) {
 	c.General = snapshot.General
 	c.Engine = snapshot.Engine
 	c.Prompt = snapshot.Prompt
+	c.Network = snapshot.Network
+	c.Translation = snapshot.Translation
+	c.OCR = snapshot.OCR
+	c.Speech = snapshot.Speech
+	c.Clipboard = snapshot.Clipboard
+	c.Selection = snapshot.Selection
+	c.Tray = snapshot.Tray
+	c.MiniWindow = snapshot.MiniWindow
+	c.Anki = snapshot.Anki
+	c.Usage = snapshot.Usage
+	c.API = snapshot.API
+	c.Hotkeys = snapshot.Hotkeys
+	c.Telemetry = snapshot.Telemetry
+	c.Cache = snapshot.Cache
+	c.Log = snapshot.Log
 
 	// Deep copy slices
 	if snapshot.Engine.TerminalAgent.ClaudeCode.Args != nil {
@@ -153,4 +393,67 @@ func (c *Config) Restore(snapshot *Config) {
 		c.Engine.TerminalAgent.Codex.Args = make([]string, len(snapshot.Engine.TerminalAgent.Codex.Args))
 		copy(c.Engine.TerminalAgent.Codex.Args, snapshot.Engine.TerminalAgent.Codex.Args)
 	}
+	c.Engine.Ollama.Options = copyOptions(snapshot.Engine.Ollama.Options)
+	if snapshot.Translation.MultiTargets != nil {
+		c.Translation.MultiTargets = make([]string, len(snapshot.Translation.MultiTargets))
+		copy(c.Translation.MultiTargets, snapshot.Translation.MultiTargets)
+	}
+	if snapshot.Translation.Processors != nil {
+		c.Translation.Processors = make([]string, len(snapshot.Translation.Processors))
+		copy(c.Translation.Processors, snapshot.Translation.Processors)
+	}
+	if snapshot.Translation.LocalizePunctuation != nil {
+		c.Translation.LocalizePunctuation = make([]string, len(snapshot.Translation.LocalizePunctuation))
+		copy(c.Translation.LocalizePunctuation, snapshot.Translation.LocalizePunctuation)
+	}
+	if snapshot.OCR.Languages != nil {
+		c.OCR.Languages = make([]string, len(snapshot.OCR.Languages))
+		copy(c.OCR.Languages, snapshot.OCR.Languages)
+	}
+	if snapshot.Prompt.Examples != nil {
+		c.Prompt.Examples = make([]PromptExample, len(snapshot.Prompt.Examples))
+		copy(c.Prompt.Examples, snapshot.Prompt.Examples)
+	}
+	if snapshot.Prompt.Templates != nil {
+		c.Prompt.Templates = make([]PromptTemplate, len(snapshot.Prompt.Templates))
+		copy(c.Prompt.Templates, snapshot.Prompt.Templates)
+	}
+	if snapshot.Prompt.Presets != nil {
+		c.Prompt.Presets = make([]PromptPreset, len(snapshot.Prompt.Presets))
+		copy(c.Prompt.Presets, snapshot.Prompt.Presets)
+	}
+	if snapshot.Prompt.Glossary != nil {
+		c.Prompt.Glossary = make([]GlossaryTerm, len(snapshot.Prompt.Glossary))
+		copy(c.Prompt.Glossary, snapshot.Prompt.Glossary)
+	}
+	if snapshot.Tray.LanguagePairs != nil {
+		c.Tray.LanguagePairs = make([]LanguagePair, len(snapshot.Tray.LanguagePairs))
+		copy(c.Tray.LanguagePairs, snapshot.Tray.LanguagePairs)
+	}
+	if snapshot.Anki.Fields != nil {
+		c.Anki.Fields = make([]AnkiField, len(snapshot.Anki.Fields))
+		copy(c.Anki.Fields, snapshot.Anki.Fields)
+	}
+	if snapshot.Anki.Tags != nil {
+		c.Anki.Tags = make([]string, len(snapshot.Anki.Tags))
+		copy(c.Anki.Tags, snapshot.Anki.Tags)
+	}
+	if snapshot.Usage.Budgets != nil {
+		c.Usage.Budgets = make([]Budget, len(snapshot.Usage.Budgets))
+		copy(c.Usage.Budgets, snapshot.Usage.Budgets)
+	}
+	c.Prompt.Variables = maps.Clone(snapshot.Prompt.Variables)
+	c.Speech.Voices = maps.Clone(snapshot.Speech.Voices)
+}
+
+// copyOptions returns a shallow copy of an options map
+func copyOptions(options map[string]any) map[string]any {
+	if options == nil {
+		return nil
+	}
+	copied := make(map[string]any, len(options))
+	for k, v := range options {
+		copied[k] = v
+	}
+	return copied
 }
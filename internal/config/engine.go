@@ -1,6 +1,10 @@
 package config
 
-import "time"
+import (
+	"fmt"
+	"path/filepath"
+	"time"
+)
 
 // EngineType represents the type of translation engine
 type EngineType string
@@ -9,6 +13,7 @@ const (
 	EngineInternal      EngineType = "internal"
 	EngineTerminalAgent EngineType = "terminal-agent"
 	EngineOllama        EngineType = "ollama"
+	EngineSmart         EngineType = "smart" // routes each text to one of two engines by size
 )
 
 // TerminalAgentType represents the terminal agent type
@@ -22,16 +27,89 @@ const (
 
 // EngineConfig holds translation engine settings
 type EngineConfig struct {
-	Type          EngineType          `json:"type"`
-	Internal      InternalConfig      `json:"internal"`
-	TerminalAgent TerminalAgentConfig `json:"terminalAgent"`
-	Ollama        OllamaConfig        `json:"ollama"`
+	Type           EngineType          `json:"type"`
+	Internal       InternalConfig      `json:"internal"`
+	TerminalAgent  TerminalAgentConfig `json:"terminalAgent"`
+	Ollama         OllamaConfig        `json:"ollama"`
+	Smart          SmartConfig         `json:"smart"`
+	MaxConcurrency int                 `json:"maxConcurrency"` // requests run at once; 0 uses the engine's default
+	Warmup         bool                `json:"warmup"`         // load the engine at startup and when it changes, so the first translation isn't slow
+}
+
+// SmartConfig holds the settings of the smart engine, which sends short
+// interactive texts to a fast engine and long texts and documents to one
+// with a large context
+type SmartConfig struct {
+	Short     EngineType `json:"short"`     // engine for short texts, e.g. a local model
+	Long      EngineType `json:"long"`      // engine for long texts
+	Threshold int        `json:"threshold"` // estimated tokens from which a text is long
+	Documents bool       `json:"documents"` // send file and batch translations to the long engine regardless of size
+}
+
+// SmartEngine returns the settings with the smart engine's engine for long
+// or short texts as the engine type. It fails if that engine is not one of
+// the engines texts can be routed to, e.g. the smart engine itself.
+func (e EngineConfig) SmartEngine(long bool) (EngineConfig, error) {
+	field, t := "short", e.Smart.Short
+	if long {
+		field, t = "long", e.Smart.Long
+	}
+	switch t {
+	case EngineInternal, EngineTerminalAgent, EngineOllama:
+		e.Type = t
+		return e, nil
+	default:
+		return e, fmt.Errorf("engine.smart.%s: the smart engine cannot route texts to engine %q", field, t)
+	}
+}
+
+// Uses reports whether the configured engine is t, or the smart engine
+// routes texts to t
+func (e EngineConfig) Uses(t EngineType) bool {
+	if e.Type == EngineSmart {
+		return e.Smart.Short == t || e.Smart.Long == t
+	}
+	return e.Type == t
+}
+
+// SamplingConfig holds text generation parameters of an LLM engine
+type SamplingConfig struct {
+	Temperature   float32 `json:"temperature"`
+	TopP          float32 `json:"topP"`
+	TopK          int     `json:"topK"` // 0 disables top-k sampling
+	RepeatPenalty float32 `json:"repeatPenalty"`
+	MaxTokens     int     `json:"maxTokens"`
+}
+
+// DefaultSamplingConfig returns default text generation parameters
+func DefaultSamplingConfig() SamplingConfig {
+	return SamplingConfig{
+		Temperature:   0.7,
+		TopP:          0.9,
+		TopK:          40,
+		RepeatPenalty: 1.1,
+		MaxTokens:     512,
+	}
 }
 
 // InternalConfig holds internal (Yzma) engine settings
 type InternalConfig struct {
-	ModelPath   string `json:"modelPath"`
-	ContextSize int    `json:"contextSize"`
+	ModelPath   string         `json:"modelPath"`
+	ContextSize int            `json:"contextSize"`
+	ModelsDir   string         `json:"modelsDir"`   // managed GGUF models (empty = "models" in the config directory)
+	Startup     int            `json:"startup"`     // seconds to the first token, after which timeout and maxDuration count; 0 = counted in them
+	Timeout     int            `json:"timeout"`     // seconds without a new token
+	MaxDuration int            `json:"maxDuration"` // seconds, overall limit of a translation
+	MaxInput    int            `json:"maxInput"`    // characters translated at once, longer texts are refused; 0 = no limit
+	Sampling    SamplingConfig `json:"sampling"`
+}
+
+// ModelsDirectory returns the directory of managed GGUF models
+func (i InternalConfig) ModelsDirectory() string {
+	if i.ModelsDir != "" {
+		return i.ModelsDir
+	}
+	return filepath.Join(Dir(), "models")
 }
 
 // TerminalAgentConfig holds terminal agent settings
@@ -44,55 +122,128 @@ type TerminalAgentConfig struct {
 
 // TerminalAgentOption holds settings for a terminal agent
 type TerminalAgentOption struct {
-	Executable string   `json:"executable"` // path to executable (empty = use PATH)
-	Args       []string `json:"args"`       // additional arguments
-	Timeout    int      `json:"timeout"`    // seconds
+	Executable  string   `json:"executable"`  // path to executable (empty = use PATH)
+	Args        []string `json:"args"`        // additional arguments
+	Startup     int      `json:"startup"`     // seconds to the first output, after which timeout and maxDuration count; 0 = counted in them
+	Timeout     int      `json:"timeout"`     // seconds without output
+	MaxDuration int      `json:"maxDuration"` // seconds, overall limit of a translation
+	MaxInput    int      `json:"maxInput"`    // characters translated at once, longer texts are refused; 0 = no limit
 }
 
 // OllamaConfig holds Ollama engine settings
 type OllamaConfig struct {
-	Host    string `json:"host"`
-	Model   string `json:"model"`
-	Timeout int    `json:"timeout"` // seconds
+	Host        string `json:"host"`
+	Model       string `json:"model"`
+	Startup     int    `json:"startup"`     // seconds to connect and load the model, after which timeout and maxDuration count; 0 = counted in them
+	Timeout     int    `json:"timeout"`     // seconds without output
+	MaxDuration int    `json:"maxDuration"` // seconds, overall limit of a translation
+	MaxInput    int    `json:"maxInput"`    // characters translated at once, longer texts are refused; 0 = no limit
+
+	Sampling SamplingConfig `json:"sampling"`
+
+	// Authentication for hosts behind a reverse proxy (bearer token wins over basic auth)
+	BearerToken string `json:"bearerToken"`
+	Username    string `json:"username"`
+	Password    string `json:"password"`
+
+	// TLS settings for HTTPS hosts
+	CACertFile         string `json:"caCertFile"` // PEM bundle added to system roots
+	InsecureSkipVerify bool   `json:"insecureSkipVerify"`
+
+	// Options are passed through to Ollama as model options (num_ctx, seed, stop, mirostat, ...).
+	// "keep_alive" is sent as the request keep-alive duration instead.
+	Options map[string]any `json:"options"`
 }
 
 // DefaultEngineConfig returns default engine settings
 func DefaultEngineConfig() EngineConfig {
 	return EngineConfig{
-		Type: EngineInternal,
+		Type:   EngineInternal,
+		Warmup: true,
 		Internal: InternalConfig{
 			ContextSize: 2048,
+			Startup:     120,
+			Timeout:     120,
+			MaxDuration: 900,
+			MaxInput:    50_000,
+			Sampling:    DefaultSamplingConfig(),
 		},
 		TerminalAgent: TerminalAgentConfig{
 			Selected: AgentClaudeCode,
 			ClaudeCode: TerminalAgentOption{
-				Executable: "claude",
-				Timeout:    60,
+				Executable:  "claude",
+				Startup:     60,
+				Timeout:     60,
+				MaxDuration: 600,
+				MaxInput:    200_000,
 			},
 			GeminiCLI: TerminalAgentOption{
-				Executable: "gemini",
-				Timeout:    60,
+				Executable:  "gemini",
+				Startup:     60,
+				Timeout:     60,
+				MaxDuration: 600,
+				MaxInput:    200_000,
 			},
 			Codex: TerminalAgentOption{
-				Executable: "codex",
-				Timeout:    60,
+				Executable:  "codex",
+				Startup:     60,
+				Timeout:     60,
+				MaxDuration: 600,
+				MaxInput:    200_000,
 			},
 		},
 		Ollama: OllamaConfig{
-			Host:    "http://localhost:11434",
-			Model:   "llama3.2",
-			Timeout: 120,
+			Host:        "http://localhost:11434",
+			Model:       "llama3.2",
+			Startup:     300,
+			Timeout:     120,
+			MaxDuration: 900,
+			MaxInput:    50_000,
+			Sampling:    DefaultSamplingConfig(),
+		},
+		Smart: SmartConfig{
+			Short:     EngineInternal,
+			Long:      EngineTerminalAgent,
+			Threshold: 1000,
+			Documents: true,
 		},
 	}
 }
 
+// Concurrency returns how many requests the engine runs at once: a local
+// model handles one at a time, remote engines can take more
+func (e EngineConfig) Concurrency() int {
+	if e.MaxConcurrency > 0 {
+		return e.MaxConcurrency
+	}
+	switch e.Type {
+	case EngineOllama:
+		return 2
+	case EngineTerminalAgent:
+		return 4
+	case EngineSmart:
+		// The queue can't tell which engine a request will reach
+		short, err := e.SmartEngine(false)
+		if err != nil {
+			return 1
+		}
+		long, err := e.SmartEngine(true)
+		if err != nil {
+			return 1
+		}
+		return min(short.Concurrency(), long.Concurrency())
+	default:
+		return 1
+	}
+}
+
 // SetEngineType sets the engine type with validation
 func (c *Config) SetEngineType(engine EngineType) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
 	switch engine {
-	case EngineInternal, EngineTerminalAgent, EngineOllama:
+	case EngineInternal, EngineTerminalAgent, EngineOllama, EngineSmart:
 		c.Engine.Type = engine
 	default:
 		c.Engine.Type = EngineInternal
@@ -0,0 +1,136 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// envKeys maps the TONS_* environment variables to the JSON paths of the
+// settings they override. TONS_CONFIG_DIR moves the config directory itself.
+var envKeys = map[string][]string{
+	"TONS_THEME":           {"general", "theme"},
+	"TONS_LANGUAGE":        {"general", "language"},
+	"TONS_ENGINE_TYPE":     {"engine", "type"},
+	"TONS_MAX_CONCURRENCY": {"engine", "maxConcurrency"},
+	"TONS_MODEL_PATH":      {"engine", "internal", "modelPath"},
+	"TONS_MODELS_DIR":      {"engine", "internal", "modelsDir"},
+	"TONS_TERMINAL_AGENT":  {"engine", "terminalAgent", "selected"},
+	"TONS_OLLAMA_HOST":     {"engine", "ollama", "host"},
+	"TONS_OLLAMA_MODEL":    {"engine", "ollama", "model"},
+	"TONS_OLLAMA_TIMEOUT":  {"engine", "ollama", "timeout"},
+	"TONS_OLLAMA_TOKEN":    {"engine", "ollama", "bearerToken"},
+	"TONS_PROXY_MODE":      {"network", "proxyMode"},
+	"TONS_PROXY_URL":       {"network", "proxyUrl"},
+	"TONS_NO_PROXY":        {"network", "noProxy"},
+	"TONS_API_ENABLED":     {"api", "enabled"},
+	"TONS_API_PORT":        {"api", "port"},
+	"TONS_API_TOKEN":       {"api", "token"},
+	"TONS_LOG_LEVEL":       {"log", "level"},
+}
+
+// envOverride is a setting replaced by an environment variable
+type envOverride struct {
+	name  string   // environment variable
+	key   []string // JSON path of the setting
+	saved any      // value to keep in config.json
+}
+
+// applyEnv overrides settings with the TONS_* environment variables that are
+// set. Overridden settings keep their previous value in config.json.
+func (c *Config) applyEnv() error {
+	names := make([]string, 0, len(envKeys))
+	for name := range envKeys {
+		if _, ok := os.LookupEnv(name); ok {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	slices.Sort(names)
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	for _, name := range names {
+		key := envKeys[name]
+		current, _ := lookupKey(doc, key)
+		value, err := parseEnvValue(os.Getenv(name), current)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		setKey(doc, key, value)
+		c.overrides = append(c.overrides, envOverride{name: name, key: key, saved: current})
+	}
+	if data, err = json.Marshal(doc); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, c)
+}
+
+// parseEnvValue converts an environment variable to the JSON type of the
+// setting's current value
+func parseEnvValue(raw string, current any) (any, error) {
+	switch current.(type) {
+	case bool:
+		return strconv.ParseBool(strings.TrimSpace(raw))
+	case float64:
+		n, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%q is not a number", raw)
+		}
+		return n, nil
+	default:
+		return raw, nil
+	}
+}
+
+// EnvOverrides returns the environment variables overriding settings in this run
+func (c *Config) EnvOverrides() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	names := make([]string, len(c.overrides))
+	for i, o := range c.overrides {
+		names[i] = o.name
+	}
+	return names
+}
+
+// fileJSON returns the configuration as written to config.json: overridden
+// settings are replaced by the values they had before the override.
+// Must be called with c.mu held.
+func (c *Config) fileJSON() ([]byte, error) {
+	if len(c.overrides) == 0 {
+		return json.MarshalIndent(c, "", "  ")
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	for _, o := range c.overrides {
+		setKey(doc, o.key, o.saved)
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// setKey sets the value at a path of nested JSON objects
+func setKey(doc map[string]any, key []string, value any) {
+	parent, ok := lookupKey(doc, key[:len(key)-1])
+	if obj, isObj := parent.(map[string]any); ok && isObj {
+		obj[key[len(key)-1]] = value
+	}
+}
@@ -0,0 +1,155 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+)
+
+// ExportOptions selects what an exported configuration includes
+type ExportOptions struct {
+	IncludeSecrets bool `json:"includeSecrets"` // credentials such as the Ollama token, proxy password and API token
+	IncludePaths   bool `json:"includePaths"`   // model files, executables and window placement of this machine
+}
+
+// secretKeys are the JSON paths of credentials
+var secretKeys = [][]string{
+	{"engine", "ollama", "bearerToken"},
+	{"engine", "ollama", "username"},
+	{"engine", "ollama", "password"},
+	{"api", "token"},
+}
+
+// pathKeys are the JSON paths of settings that only make sense on this machine
+var pathKeys = [][]string{
+	{"engine", "internal", "modelPath"},
+	{"engine", "internal", "modelsDir"},
+	{"engine", "ollama", "caCertFile"},
+	{"engine", "terminalAgent", "claudeCode", "executable"},
+	{"engine", "terminalAgent", "geminiCli", "executable"},
+	{"engine", "terminalAgent", "codex", "executable"},
+	{"speech", "whisperModel"},
+	{"telemetry", "installId"},
+	{"miniWindow"},
+}
+
+// mapKeys are the JSON paths of maps, which an import replaces instead of merging into
+var mapKeys = [][]string{
+	{"engine", "ollama", "options"},
+	{"prompt", "variables"},
+	{"speech", "voices"},
+}
+
+// Export returns the configuration as JSON. Left out settings are omitted
+// rather than emptied, so importing the file keeps the importer's own values.
+func (c *Config) Export(opts ExportOptions) ([]byte, error) {
+	data, err := json.Marshal(c.Snapshot())
+	if err != nil {
+		return nil, err
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	if !opts.IncludeSecrets {
+		for _, key := range secretKeys {
+			deleteKey(doc, key)
+		}
+		// Keep a proxy URL unless it carries a password
+		proxyKey := []string{"network", "proxyUrl"}
+		if raw, ok := lookupKey(doc, proxyKey); ok {
+			if u, err := url.Parse(fmt.Sprint(raw)); err == nil && u.User != nil {
+				deleteKey(doc, proxyKey)
+			}
+		}
+	}
+	if !opts.IncludePaths {
+		for _, key := range pathKeys {
+			deleteKey(doc, key)
+		}
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+// Secrets returns the credentials set in the configuration, including a
+// proxy password, so they can be redacted from logs and reports
+func (c *Config) Secrets() []string {
+	snapshot := c.Snapshot()
+	secrets := []string{
+		snapshot.Engine.Ollama.BearerToken,
+		snapshot.Engine.Ollama.Username,
+		snapshot.Engine.Ollama.Password,
+		snapshot.API.Token,
+	}
+	if u, err := url.Parse(snapshot.Network.ProxyURL); err == nil && u.User != nil {
+		password, _ := u.User.Password()
+		secrets = append(secrets, u.User.Username(), password)
+	}
+	return slices.DeleteFunc(secrets, func(s string) bool { return s == "" })
+}
+
+// Import applies JSON produced by Export. Settings missing from the file keep
+// their current values.
+func (c *Config) Import(data []byte) error {
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("invalid settings file: %w", err)
+	}
+	// Settings exported by older versions use the old layout
+	if _, err := migrate(doc); err != nil {
+		return fmt.Errorf("invalid settings file: %w", err)
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+
+	snapshot := c.Snapshot()
+	for _, key := range mapKeys {
+		if _, ok := lookupKey(doc, key); ok {
+			clearMap(snapshot, key)
+		}
+	}
+	if err := json.Unmarshal(data, snapshot); err != nil {
+		return fmt.Errorf("invalid settings file: %w", err)
+	}
+	c.Restore(snapshot)
+	return nil
+}
+
+// clearMap empties the map at key so that unmarshaling replaces it
+func clearMap(snapshot *Config, key []string) {
+	switch key[0] + "." + key[len(key)-1] {
+	case "engine.options":
+		snapshot.Engine.Ollama.Options = nil
+	case "prompt.variables":
+		snapshot.Prompt.Variables = nil
+	case "speech.voices":
+		snapshot.Speech.Voices = nil
+	}
+}
+
+// lookupKey returns the value at a path of nested JSON objects
+func lookupKey(doc map[string]any, key []string) (any, bool) {
+	var v any = doc
+	for _, k := range key {
+		obj, ok := v.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if v, ok = obj[k]; !ok {
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// deleteKey removes the value at a path of nested JSON objects
+func deleteKey(doc map[string]any, key []string) {
+	parent, ok := lookupKey(doc, key[:len(key)-1])
+	if obj, isObj := parent.(map[string]any); ok && isObj {
+		delete(obj, key[len(key)-1])
+	}
+}
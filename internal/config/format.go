@@ -0,0 +1,134 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// configNames are the config file names tried in order. YAML and TOML files
+// are only there if the user created one, so they win over config.json.
+var configNames = []string{"config.yaml", "config.yml", "config.toml", "config.json"}
+
+// findConfigFile returns the path of the config file in dir, or config.json
+// if there is none yet
+func findConfigFile(dir string) string {
+	for _, name := range configNames {
+		path := filepath.Join(dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return filepath.Join(dir, "config.json")
+}
+
+// decodeFile converts the contents of a YAML or TOML config file to JSON,
+// chosen by the extension of path. JSON files are returned as they are.
+func decodeFile(path string, data []byte) ([]byte, error) {
+	var doc map[string]any
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+	case ".toml":
+		if err := toml.Unmarshal(data, &doc); err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+	default:
+		return data, nil
+	}
+	if doc == nil {
+		doc = map[string]any{} // empty file
+	}
+	return json.Marshal(doc)
+}
+
+// encodeFile converts JSON to the format of the config file at path
+func encodeFile(path string, data []byte) ([]byte, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		// Decoding the JSON as YAML keeps the order of the settings
+		var node yaml.Node
+		if err := yaml.Unmarshal(data, &node); err != nil {
+			return nil, err
+		}
+		blockStyle(&node)
+		var buf bytes.Buffer
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(&node); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), enc.Close()
+	case ".toml":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		var doc map[string]any
+		if err := dec.Decode(&doc); err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := toml.NewEncoder(&buf).Encode(tomlValue(doc)); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	default:
+		return data, nil
+	}
+}
+
+// blockStyle clears the JSON flow and quoting styles of a YAML node tree, so
+// it is written as plain YAML with multi-line strings as literal blocks
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	if node.Kind == yaml.MappingNode || node.Kind == yaml.SequenceNode {
+		if len(node.Content) == 0 {
+			node.Style = yaml.FlowStyle // keep [] and {}
+		}
+	}
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+// tomlValue prepares decoded JSON for TOML, which has no null and tells
+// integers from floats
+func tomlValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for k, item := range v {
+			if item != nil {
+				out[k] = tomlValue(item)
+			}
+		}
+		return out
+	case []any:
+		out := make([]any, 0, len(v))
+		for _, item := range v {
+			if item != nil {
+				out = append(out, tomlValue(item))
+			}
+		}
+		return out
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n
+		}
+		f, _ := v.Float64()
+		if f == math.Trunc(f) && math.Abs(f) < 1<<53 {
+			return int64(f)
+		}
+		return f
+	default:
+		return v
+	}
+}
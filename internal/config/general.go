@@ -11,15 +11,17 @@ const (
 
 // GeneralConfig holds general application settings
 type GeneralConfig struct {
-	Theme    Theme  `json:"theme"`
-	Language string `json:"language"`
+	Theme         Theme  `json:"theme"`
+	Language      string `json:"language"`
+	Notifications bool   `json:"notifications"` // notify when file translations finish while tons is in the background
 }
 
 // DefaultGeneralConfig returns default general settings
 func DefaultGeneralConfig() GeneralConfig {
 	return GeneralConfig{
-		Theme:    ThemeSystem,
-		Language: "system",
+		Theme:         ThemeSystem,
+		Language:      "system",
+		Notifications: true,
 	}
 }
 
@@ -44,6 +46,14 @@ func (c *Config) SetLanguage(lang string) {
 	c.General.Language = lang
 }
 
+// SetNotifications turns notifications on or off
+func (c *Config) SetNotifications(enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.General.Notifications = enabled
+}
+
 // SetGeneral sets the entire general config
 func (c *Config) SetGeneral(general GeneralConfig) {
 	c.mu.Lock()
@@ -0,0 +1,149 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// HotkeyAction is an action that can be bound to a key chord
+type HotkeyAction string
+
+const (
+	HotkeyTranslateClipboard HotkeyAction = "translateClipboard"
+	HotkeyCaptureScreen      HotkeyAction = "captureScreen"
+	HotkeyToggleWindow       HotkeyAction = "toggleWindow"
+	HotkeySwapLanguages      HotkeyAction = "swapLanguages"
+)
+
+// HotkeysConfig holds the key chords of the hotkey actions, e.g.
+// "CmdOrCtrl+Shift+T". An empty chord leaves the action unbound.
+type HotkeysConfig struct {
+	TranslateClipboard string `json:"translateClipboard"`
+	CaptureScreen      string `json:"captureScreen"`
+	ToggleWindow       string `json:"toggleWindow"` // the mini window
+	SwapLanguages      string `json:"swapLanguages"`
+}
+
+// DefaultHotkeysConfig returns default hotkey settings
+func DefaultHotkeysConfig() HotkeysConfig {
+	return HotkeysConfig{
+		TranslateClipboard: "CmdOrCtrl+Alt+C",
+		CaptureScreen:      "CmdOrCtrl+Alt+S",
+		ToggleWindow:       "CmdOrCtrl+Alt+T",
+		SwapLanguages:      "CmdOrCtrl+Alt+X",
+	}
+}
+
+// HotkeyBinding is a key chord bound to an action
+type HotkeyBinding struct {
+	Action HotkeyAction `json:"action"`
+	Chord  string       `json:"chord"`
+}
+
+// Bindings returns the bound actions in a fixed order
+func (h HotkeysConfig) Bindings() []HotkeyBinding {
+	all := []HotkeyBinding{
+		{HotkeyTranslateClipboard, h.TranslateClipboard},
+		{HotkeyCaptureScreen, h.CaptureScreen},
+		{HotkeyToggleWindow, h.ToggleWindow},
+		{HotkeySwapLanguages, h.SwapLanguages},
+	}
+	return slices.DeleteFunc(all, func(b HotkeyBinding) bool { return b.Chord == "" })
+}
+
+// SetHotkeys sets the entire hotkeys config
+func (c *Config) SetHotkeys(hotkeys HotkeysConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Hotkeys = hotkeys
+}
+
+// modifierNames maps the accepted modifier names to their canonical form
+var modifierNames = map[string]string{
+	"cmdorctrl":        "CmdOrCtrl",
+	"commandorcontrol": "CmdOrCtrl",
+	"cmd":              "Cmd",
+	"command":          "Cmd",
+	"super":            "Cmd",
+	"meta":             "Cmd",
+	"ctrl":             "Ctrl",
+	"control":          "Ctrl",
+	"alt":              "Alt",
+	"option":           "Alt",
+	"optionoralt":      "Alt",
+	"shift":            "Shift",
+}
+
+// modifierOrder is the order of modifiers in a normalized chord
+var modifierOrder = []string{"CmdOrCtrl", "Cmd", "Ctrl", "Alt", "Shift"}
+
+// keyNames maps the accepted named keys to their canonical form
+var keyNames = map[string]string{
+	"space": "Space", "tab": "Tab", "enter": "Enter", "return": "Enter",
+	"escape": "Escape", "esc": "Escape", "backspace": "Backspace",
+	"delete": "Delete", "insert": "Insert", "home": "Home", "end": "End",
+	"pageup": "PageUp", "pagedown": "PageDown",
+	"up": "Up", "down": "Down", "left": "Left", "right": "Right",
+}
+
+// NormalizeChord checks a key chord such as "shift+ctrl+t" and returns it in
+// canonical form, "Ctrl+Shift+T", so equal chords compare equal
+func NormalizeChord(chord string) (string, error) {
+	parts := strings.Split(chord, "+")
+	key, mods := strings.TrimSpace(parts[len(parts)-1]), parts[:len(parts)-1]
+	if key == "" && len(parts) > 1 && strings.TrimSpace(parts[len(parts)-2]) == "" {
+		key, mods = "+", parts[:len(parts)-2] // "Ctrl++"
+	}
+
+	seen := make(map[string]bool)
+	for _, m := range mods {
+		name, ok := modifierNames[strings.ToLower(strings.TrimSpace(m))]
+		if !ok {
+			return "", fmt.Errorf("unknown modifier %q", strings.TrimSpace(m))
+		}
+		if seen[name] {
+			return "", fmt.Errorf("modifier %s is repeated", name)
+		}
+		seen[name] = true
+	}
+	if seen["CmdOrCtrl"] && (seen["Cmd"] || seen["Ctrl"]) {
+		return "", errors.New("CmdOrCtrl cannot be combined with Cmd or Ctrl")
+	}
+
+	canonical, function := canonicalKey(key)
+	if canonical == "" {
+		return "", fmt.Errorf("unknown key %q", key)
+	}
+	if len(seen) == 0 && !function {
+		return "", errors.New("needs a modifier such as CmdOrCtrl, Alt or Shift, except for function keys")
+	}
+
+	var out []string
+	for _, m := range modifierOrder {
+		if seen[m] {
+			out = append(out, m)
+		}
+	}
+	return strings.Join(append(out, canonical), "+"), nil
+}
+
+// canonicalKey returns the canonical name of a key, or "" if it is unknown,
+// and whether it is a function key
+func canonicalKey(key string) (string, bool) {
+	if name, ok := keyNames[strings.ToLower(key)]; ok {
+		return name, false
+	}
+	if len(key) == 1 && key[0] > ' ' && key[0] < 0x7f {
+		return strings.ToUpper(key), false
+	}
+	if len(key) > 1 && (key[0] == 'F' || key[0] == 'f') {
+		if n, err := strconv.Atoi(key[1:]); err == nil && n >= 1 && n <= 24 && key[1] != '0' {
+			return "F" + key[1:], true
+		}
+	}
+	return "", false
+}
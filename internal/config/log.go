@@ -0,0 +1,30 @@
+package config
+
+// LogConfig holds the app's logging
+type LogConfig struct {
+	Level         string `json:"level"`         // "debug", "info", "warn" or "error"
+	RedactContent bool   `json:"redactContent"` // log the length of translated text instead of the text
+	File          bool   `json:"file"`          // also write logs to tons.log in the config directory
+	MaxSizeMB     int    `json:"maxSizeMb"`     // size at which the log file is rotated
+	MaxFiles      int    `json:"maxFiles"`      // rotated log files kept besides the current one
+}
+
+// DefaultLogConfig returns default log settings: info level, redacted,
+// written to a 5 MB log file with three older files kept
+func DefaultLogConfig() LogConfig {
+	return LogConfig{
+		Level:         "info",
+		RedactContent: true,
+		File:          true,
+		MaxSizeMB:     5,
+		MaxFiles:      3,
+	}
+}
+
+// SetLog sets the entire log config
+func (c *Config) SetLog(log LogConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Log = log
+}
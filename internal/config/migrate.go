@@ -0,0 +1,99 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+)
+
+// SchemaVersion is the version of the config file layout written by this
+// build. Bump it together with a new entry in migrations when settings are
+// renamed, moved or need a new default for existing users.
+const SchemaVersion = 2
+
+// migration upgrades a config file from version to-1 to version to. It edits
+// the decoded JSON, so settings under old names can still be read.
+type migration struct {
+	to      int
+	migrate func(doc map[string]any)
+}
+
+// migrations are applied in order to files older than SchemaVersion
+var migrations = []migration{
+	// Files written before versioning already use the version 1 layout
+	{to: 1, migrate: func(doc map[string]any) {}},
+	// engine.sampling was split into engine.internal.sampling and
+	// engine.ollama.sampling, so each engine can be tuned on its own
+	{to: 2, migrate: func(doc map[string]any) {
+		eng, _ := doc["engine"].(map[string]any)
+		sampling, ok := eng["sampling"].(map[string]any)
+		if !ok {
+			return
+		}
+		delete(eng, "sampling")
+		for _, name := range []string{"internal", "ollama"} {
+			section, ok := eng[name].(map[string]any)
+			if !ok {
+				section = map[string]any{}
+				eng[name] = section
+			}
+			section["sampling"] = maps.Clone(sampling)
+		}
+	}},
+}
+
+// migrate upgrades a decoded config file to SchemaVersion and returns the
+// version it had. Files from newer builds are left as they are.
+func migrate(doc map[string]any) (from int, err error) {
+	if v, ok := doc["version"]; ok {
+		n, isNumber := v.(float64)
+		if !isNumber || n < 0 || n != float64(int(n)) {
+			return 0, fmt.Errorf("invalid config version %v", v)
+		}
+		from = int(n)
+	}
+	for _, m := range migrations {
+		if m.to > from {
+			m.migrate(doc)
+		}
+	}
+	if from < SchemaVersion {
+		doc["version"] = SchemaVersion
+	}
+	return from, nil
+}
+
+// upgradeFile migrates the config file data (as JSON) if it is older than
+// SchemaVersion. It returns the data to load and the version it had.
+func upgradeFile(data []byte) ([]byte, int, error) {
+	var doc map[string]any
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, 0, err
+	}
+	from, err := migrate(doc)
+	if err != nil || from >= SchemaVersion {
+		return data, from, err
+	}
+	upgraded, err := json.Marshal(doc)
+	return upgraded, from, err
+}
+
+// keepVersionBackup keeps the config file data from before an upgrade as
+// e.g. config.json.v<N>.bak. An existing backup of that version is left
+// alone: it holds the user's original file.
+func keepVersionBackup(path string, from int, original []byte) error {
+	backup := fmt.Sprintf("%s.v%d.bak", path, from)
+	f, err := os.OpenFile(backup, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("backing up config before upgrade: %w", err)
+	}
+	if _, err := f.Write(original); err != nil {
+		f.Close()
+		return fmt.Errorf("backing up config before upgrade: %w", err)
+	}
+	return f.Close()
+}
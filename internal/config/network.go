@@ -0,0 +1,38 @@
+package config
+
+// ProxyMode represents how network engines reach the internet
+type ProxyMode string
+
+const (
+	ProxySystem ProxyMode = "system" // honor HTTP_PROXY/HTTPS_PROXY/NO_PROXY
+	ProxyNone   ProxyMode = "none"   // always connect directly
+	ProxyManual ProxyMode = "manual" // use ProxyURL
+)
+
+// NetworkConfig holds proxy settings shared by all HTTP-based engines
+type NetworkConfig struct {
+	ProxyMode ProxyMode `json:"proxyMode"`
+	ProxyURL  string    `json:"proxyUrl"` // http://, https:// or socks5:// URL (manual mode)
+	NoProxy   string    `json:"noProxy"`  // comma-separated hosts bypassing the proxy (manual mode)
+}
+
+// DefaultNetworkConfig returns default network settings
+func DefaultNetworkConfig() NetworkConfig {
+	return NetworkConfig{
+		ProxyMode: ProxySystem,
+		NoProxy:   "localhost,127.0.0.1,::1",
+	}
+}
+
+// SetNetwork sets the entire network config
+func (c *Config) SetNetwork(network NetworkConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	switch network.ProxyMode {
+	case ProxySystem, ProxyNone, ProxyManual:
+	default:
+		network.ProxyMode = ProxySystem
+	}
+	c.Network = network
+}
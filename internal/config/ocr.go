@@ -0,0 +1,22 @@
+package config
+
+// OCRConfig holds image text recognition settings
+type OCRConfig struct {
+	Command   string   `json:"command"`   // tesseract executable, empty for "tesseract" on PATH
+	Languages []string `json:"languages"` // expected languages (ISO 639-1), e.g. ["en", "ko"]
+}
+
+// DefaultOCRConfig returns default OCR settings
+func DefaultOCRConfig() OCRConfig {
+	return OCRConfig{
+		Languages: []string{"en"},
+	}
+}
+
+// SetOCR sets the entire OCR config
+func (c *Config) SetOCR(ocr OCRConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.OCR = ocr
+}
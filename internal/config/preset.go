@@ -0,0 +1,167 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var (
+	// ErrPresetNotFound is returned when no preset has the given name
+	ErrPresetNotFound = errors.New("prompt preset not found")
+	// ErrPresetExists is returned when a preset name is already taken
+	ErrPresetExists = errors.New("prompt preset already exists")
+)
+
+// PromptPreset is a named, reusable set of prompt settings.
+// Empty fields fall back to the regular prompt and translation settings.
+type PromptPreset struct {
+	Name         string `json:"name"`
+	Description  string `json:"description"`
+	Template     string `json:"template"`
+	SystemPrompt string `json:"systemPrompt"`
+	Formality    string `json:"formality"`
+	Tone         string `json:"tone"`
+	Hotkey       string `json:"hotkey"` // accelerator that translates with this preset, e.g. "CmdOrCtrl+Shift+L"
+}
+
+// DefaultPresets returns the built-in prompt presets
+func DefaultPresets() []PromptPreset {
+	return []PromptPreset{
+		{
+			Name:        "literary",
+			Description: "Prose and fiction, favoring natural rhythm over literal wording",
+			SystemPrompt: `You are a literary translator. Preserve voice, imagery and rhythm; ` +
+				`prefer natural phrasing in the target language over literal wording. Only output the translation.`,
+		},
+		{
+			Name:        "technical",
+			Description: "Documentation and UI text with precise terminology",
+			SystemPrompt: `You are a technical translator. Keep terminology precise and consistent, ` +
+				`never translate code, identifiers, commands or product names. Only output the translation.`,
+			Formality: "formal",
+		},
+		{
+			Name:        "subtitles",
+			Description: "Short, readable lines that keep the original line breaks",
+			SystemPrompt: `You are a subtitle translator. Keep each line short and easy to read, ` +
+				`keep the original line breaks, and drop filler words when needed. Only output the translation.`,
+		},
+		{
+			Name:        "chat-casual",
+			Description: "Messages between friends",
+			SystemPrompt: `You translate chat messages. Sound like a native speaker texting a friend, ` +
+				`keep emoji and slang natural. Only output the translation.`,
+			Formality: "informal",
+			Tone:      "casual",
+		},
+	}
+}
+
+// Preset returns the preset with the given name
+func (p PromptConfig) Preset(name string) (PromptPreset, bool) {
+	i := slices.IndexFunc(p.Presets, func(preset PromptPreset) bool { return preset.Name == name })
+	if i < 0 {
+		return PromptPreset{}, false
+	}
+	return p.Presets[i], true
+}
+
+// SavePreset adds a preset or replaces the one with the same name
+func (c *Config) SavePreset(preset PromptPreset) error {
+	if preset.Name == "" {
+		return errors.New("prompt preset name is required")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	i := slices.IndexFunc(c.Prompt.Presets, func(p PromptPreset) bool { return p.Name == preset.Name })
+	if i < 0 {
+		c.Prompt.Presets = append(c.Prompt.Presets, preset)
+	} else {
+		c.Prompt.Presets[i] = preset
+	}
+	return nil
+}
+
+// DuplicatePreset copies a preset under a new name
+func (c *Config) DuplicatePreset(name, newName string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	preset, ok := c.Prompt.Preset(name)
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrPresetNotFound, name)
+	}
+	if _, exists := c.Prompt.Preset(newName); exists {
+		return fmt.Errorf("%w: %s", ErrPresetExists, newName)
+	}
+	preset.Name = newName
+	preset.Hotkey = ""
+	c.Prompt.Presets = append(c.Prompt.Presets, preset)
+	return nil
+}
+
+// DeletePreset removes a preset and clears it if it was active
+func (c *Config) DeletePreset(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	i := slices.IndexFunc(c.Prompt.Presets, func(p PromptPreset) bool { return p.Name == name })
+	if i < 0 {
+		return fmt.Errorf("%w: %s", ErrPresetNotFound, name)
+	}
+	c.Prompt.Presets = slices.Delete(c.Prompt.Presets, i, i+1)
+	if c.Prompt.ActivePreset == name {
+		c.Prompt.ActivePreset = ""
+	}
+	return nil
+}
+
+// SetActivePreset selects the preset used when a request names none ("" for none)
+func (c *Config) SetActivePreset(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.Prompt.Preset(name); name != "" && !ok {
+		return fmt.Errorf("%w: %s", ErrPresetNotFound, name)
+	}
+	c.Prompt.ActivePreset = name
+	return nil
+}
+
+// ExportPresets returns the named presets as JSON, or all presets if names is empty
+func (c *Config) ExportPresets(names ...string) ([]byte, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	presets := make([]PromptPreset, 0, len(c.Prompt.Presets))
+	for _, preset := range c.Prompt.Presets {
+		if len(names) == 0 || slices.Contains(names, preset.Name) {
+			presets = append(presets, preset)
+		}
+	}
+	return json.MarshalIndent(presets, "", "  ")
+}
+
+// ImportPresets adds presets from JSON produced by ExportPresets, replacing
+// presets with the same name. Returns the number of imported presets.
+func (c *Config) ImportPresets(data []byte) (int, error) {
+	var presets []PromptPreset
+	if err := json.Unmarshal(data, &presets); err != nil {
+		return 0, fmt.Errorf("invalid preset file: %w", err)
+	}
+	for _, preset := range presets {
+		if preset.Name == "" {
+			return 0, errors.New("invalid preset file: preset without a name")
+		}
+	}
+	for _, preset := range presets {
+		if err := c.SavePreset(preset); err != nil {
+			return 0, err
+		}
+	}
+	return len(presets), nil
+}
@@ -1,6 +1,14 @@
 package config
 
-// DefaultPrompt is the default translation prompt template
+import (
+	"strings"
+
+	"github.com/ironpark/tons/internal/langdetect"
+)
+
+// DefaultPrompt is the default translation prompt template.
+// Templates use text/template syntax, e.g. {{if .SourceLang}}...{{end}}, {{range .Glossary}} and {{.Vars.name}}.
+// Legacy variables: {{text}}, {{source_lang}}, {{target_lang}}, {{formality}}, {{tone}}, {{context}}, {{examples}}
 const DefaultPrompt = `Translate the following text from {{source_lang}} to {{target_lang}}.
 Keep the original formatting and tone.
 Only return the translated text without any explanations.
@@ -11,10 +19,41 @@ Text to translate:
 // DefaultSystemPrompt is the default system prompt for translation
 const DefaultSystemPrompt = `You are a professional translator. Translate accurately while preserving the original tone, style, and formatting. Only output the translation without explanations.`
 
+// PromptExample is a source/target pair shown to the model as a few-shot example.
+// Empty languages match any language pair.
+type PromptExample struct {
+	SourceLang string `json:"sourceLang"`
+	TargetLang string `json:"targetLang"`
+	Source     string `json:"source"`
+	Target     string `json:"target"`
+}
+
+// GlossaryTerm is a fixed translation for a term, available to templates as .Glossary
+type GlossaryTerm struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+// PromptTemplate is a named template used when its selectors match the request.
+// Empty selectors match anything.
+type PromptTemplate struct {
+	Name       string     `json:"name"`
+	Template   string     `json:"template"`
+	Engine     EngineType `json:"engine"`
+	SourceLang string     `json:"sourceLang"`
+	TargetLang string     `json:"targetLang"`
+}
+
 // PromptConfig holds prompt settings
 type PromptConfig struct {
-	Template     string `json:"template"`
-	SystemPrompt string `json:"systemPrompt"`
+	Template     string            `json:"template"` // fallback when no named template matches
+	SystemPrompt string            `json:"systemPrompt"`
+	Templates    []PromptTemplate  `json:"templates"`
+	Presets      []PromptPreset    `json:"presets"`
+	ActivePreset string            `json:"activePreset"` // preset applied when a request names none
+	Examples     []PromptExample   `json:"examples"`
+	Glossary     []GlossaryTerm    `json:"glossary"`
+	Variables    map[string]string `json:"variables"` // custom template variables, available as .Vars
 }
 
 // DefaultPromptConfig returns default prompt settings
@@ -22,9 +61,52 @@ func DefaultPromptConfig() PromptConfig {
 	return PromptConfig{
 		Template:     DefaultPrompt,
 		SystemPrompt: DefaultSystemPrompt,
+		Presets:      DefaultPresets(),
 	}
 }
 
+// ResolveTemplate returns the most specific template matching the engine and
+// language pair. Fallback order: engine+pair, pair, engine+target, target,
+// engine+source, source, engine, then the default template.
+// A source language of "auto" only matches templates without a source selector.
+func (p PromptConfig) ResolveTemplate(engine EngineType, sourceLang, targetLang string) string {
+	best, bestScore := p.Template, -1
+	for _, t := range p.Templates {
+		score := 0
+		if t.Engine != "" {
+			if t.Engine != engine {
+				continue
+			}
+			score++
+		}
+		if t.SourceLang != "" {
+			if !sameLang(t.SourceLang, sourceLang) {
+				continue
+			}
+			score += 2
+		}
+		if t.TargetLang != "" {
+			if !sameLang(t.TargetLang, targetLang) {
+				continue
+			}
+			score += 4
+		}
+		if score > bestScore {
+			best, bestScore = t.Template, score
+		}
+	}
+	return best
+}
+
+// sameLang compares language codes or names, e.g. "ko" and "Korean"
+func sameLang(a, b string) bool {
+	if strings.EqualFold(a, b) {
+		return true
+	}
+	code := langdetect.Code(a)
+	return code != langdetect.Unknown && code == langdetect.Code(b)
+}
+
 // SetPrompt sets the prompt template
 func (c *Config) SetPrompt(prompt string) {
 	c.mu.Lock()
@@ -50,6 +132,14 @@ func (c *Config) ResetPrompt() {
 	c.Prompt.SystemPrompt = DefaultSystemPrompt
 }
 
+// SetPromptExamples sets the few-shot examples
+func (c *Config) SetPromptExamples(examples []PromptExample) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Prompt.Examples = examples
+}
+
 // SetPromptConfig sets the entire prompt config
 func (c *Config) SetPromptConfig(prompt PromptConfig) {
 	c.mu.Lock()
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"os"
+	"slices"
+	"time"
+)
+
+// Reload reads config.json again after it was edited outside the app and
+// returns the JSON names of the sections that changed. The file itself is
+// never written. The configuration is left as is if the file cannot be read
+// or parsed, if its settings are invalid (see Validate), or if validate
+// reports other problems, e.g. with prompt templates, that were not there
+// before.
+func (c *Config) Reload(validate func(*Config) ValidationError) ([]string, error) {
+	fresh, err := load(false) // the file is left alone: a broken edit is reported, not replaced by a backup
+	if err != nil {
+		return nil, err
+	}
+	if err := fresh.Validate(); err != nil {
+		return nil, err
+	}
+	if errs := validate(fresh).Without(validate(c.Snapshot())); len(errs) > 0 {
+		return nil, errs
+	}
+	before, err := sections(c.Snapshot())
+	if err != nil {
+		return nil, err
+	}
+	after, err := sections(fresh)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Restore(fresh)
+	c.mu.Lock()
+	c.overrides = fresh.overrides
+	c.mu.Unlock()
+	c.setFileSum(fresh.fileSum)
+	if data := fresh.lastRevision(); data != nil {
+		c.record(data)
+	}
+
+	var changed []string
+	for name, data := range after {
+		if !bytes.Equal(before[name], data) {
+			changed = append(changed, name)
+		}
+	}
+	slices.Sort(changed)
+	return changed, nil
+}
+
+// Watch checks config.json every interval until ctx is cancelled. When
+// another program changed it, the file is reloaded (see Reload) and onChange
+// is called with the changed sections, or with the error that kept it from loading.
+//
+// The file is polled rather than watched with file system notifications:
+// editors save by renaming a new file over the old one, which ends a watch
+// on the file, synced and network folders often deliver no events at all,
+// and a stat every few seconds costs nothing next to a dependency for it.
+func (c *Config) Watch(ctx context.Context, interval time.Duration, validate func(*Config) ValidationError, onChange func(sections []string, err error)) {
+	var last os.FileInfo
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+
+		fi, err := os.Stat(configPath())
+		if err != nil {
+			continue // not saved yet, or being replaced
+		}
+		if last != nil && fi.ModTime().Equal(last.ModTime()) && fi.Size() == last.Size() {
+			continue
+		}
+		last = fi
+		data, err := os.ReadFile(configPath())
+		if err != nil || sha256.Sum256(data) == c.getFileSum() {
+			continue // our own save, or the file as loaded
+		}
+		changed, err := c.Reload(validate)
+		if err != nil || len(changed) > 0 {
+			onChange(changed, err)
+		}
+	}
+}
+
+// sections returns the JSON of each configuration section
+func sections(c *Config) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]json.RawMessage
+	err = json.Unmarshal(data, &m)
+	return m, err
+}
+
+// setFileSum records the checksum of config.json as last read or written
+func (c *Config) setFileSum(sum [sha256.Size]byte) {
+	c.fileMu.Lock()
+	defer c.fileMu.Unlock()
+
+	c.fileSum = sum
+}
+
+func (c *Config) getFileSum() [sha256.Size]byte {
+	c.fileMu.Lock()
+	defer c.fileMu.Unlock()
+
+	return c.fileSum
+}
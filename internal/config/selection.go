@@ -0,0 +1,31 @@
+package config
+
+// SelectionConfig holds settings for translating the text selected in other apps
+type SelectionConfig struct {
+	SourceLang  string `json:"sourceLang"`
+	TargetLang  string `json:"targetLang"`
+	PopupWidth  int    `json:"popupWidth"`
+	PopupHeight int    `json:"popupHeight"`
+
+	// PasteInPlace pastes the translation over the selection after "tons
+	// replace" has put it on the clipboard
+	PasteInPlace bool `json:"pasteInPlace"`
+}
+
+// DefaultSelectionConfig returns default selection settings
+func DefaultSelectionConfig() SelectionConfig {
+	return SelectionConfig{
+		SourceLang:  "auto",
+		TargetLang:  "en",
+		PopupWidth:  360,
+		PopupHeight: 180,
+	}
+}
+
+// SetSelection sets the entire selection config
+func (c *Config) SetSelection(selection SelectionConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Selection = selection
+}
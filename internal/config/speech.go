@@ -0,0 +1,27 @@
+package config
+
+// SpeechConfig holds microphone input, transcription and text-to-speech settings
+type SpeechConfig struct {
+	InputDevice    string `json:"inputDevice"`    // ffmpeg input device, empty for the default microphone
+	WhisperCommand string `json:"whisperCommand"` // whisper.cpp executable, empty for "whisper-cli" on PATH
+	WhisperModel   string `json:"whisperModel"`   // path to a whisper GGML model, e.g. ggml-base.bin
+
+	// Text-to-speech: voice ID per language code (e.g. "ko": "Yuna") and speaking rate (1.0 is normal)
+	Voices map[string]string `json:"voices"`
+	Rate   float64           `json:"rate"`
+}
+
+// DefaultSpeechConfig returns default speech settings
+func DefaultSpeechConfig() SpeechConfig {
+	return SpeechConfig{
+		Rate: 1.0,
+	}
+}
+
+// SetSpeech sets the entire speech config
+func (c *Config) SetSpeech(speech SpeechConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Speech = speech
+}
@@ -0,0 +1,22 @@
+package config
+
+// TelemetryConfig holds the opt-in anonymous usage reports. Reports only hold
+// engine types, translation counts and error classes, never translated text.
+type TelemetryConfig struct {
+	Enabled   bool   `json:"enabled"`   // off unless the user turns it on
+	Endpoint  string `json:"endpoint"`  // where reports are posted; nothing is sent while empty
+	InstallID string `json:"installId"` // random ID generated for the first report
+}
+
+// DefaultTelemetryConfig returns default telemetry settings: disabled
+func DefaultTelemetryConfig() TelemetryConfig {
+	return TelemetryConfig{}
+}
+
+// SetTelemetry sets the entire telemetry config
+func (c *Config) SetTelemetry(telemetry TelemetryConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Telemetry = telemetry
+}
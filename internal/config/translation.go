@@ -0,0 +1,63 @@
+package config
+
+// TranslationConfig holds text processing settings applied around every engine
+type TranslationConfig struct {
+	ProtectPlaceholders bool     `json:"protectPlaceholders"` // mask code, URLs and variables before translating
+	Processors          []string `json:"processors"`          // ordered text processors applied around the engine
+	Formality           string   `json:"formality"`           // default register: "", formal, informal, honorific
+	Tone                string   `json:"tone"`                // default tone, e.g. "friendly"
+	QualityEstimation   bool     `json:"qualityEstimation"`   // rate each translation with a second engine pass
+	SkipSameLanguage    bool     `json:"skipSameLanguage"`    // return text already in the target language as is
+	MarkFuzzy           bool     `json:"markFuzzy"`           // flag machine-translated catalog entries for review
+	ReviewBelow         float64  `json:"reviewBelow"`         // flag catalog entries translated with less confidence (0-1) for review, e.g. from quality estimation
+	Incremental         bool     `json:"incremental"`         // only retranslate file segments whose source changed since the last run
+	CodeMode            string   `json:"codeMode"`            // what to translate in source files: comments, strings or all
+	Annotate            bool     `json:"annotate"`            // add furigana, pinyin or romanization to Japanese, Chinese and Korean translations
+	BlockUnsupported    bool     `json:"blockUnsupported"`    // refuse language pairs the engine can't translate instead of warning
+	ScrubPII            bool     `json:"scrubPii"`            // mask emails, phone and ID numbers before text leaves the machine
+
+	// LocalizePunctuation lists the target languages (e.g. "ja", "fr") whose
+	// translations get local quotes, dashes, number and date formats
+	LocalizePunctuation []string `json:"localizePunctuation"`
+
+	// Multi-target mode: languages to translate into at once and how many run concurrently
+	MultiTargets        []string `json:"multiTargets"`
+	MultiTargetParallel int      `json:"multiTargetParallel"`
+
+	BatchParallel int `json:"batchParallel"` // files translated at once by batch jobs
+
+	// MaxConcurrent caps the translations running at once from all sources
+	// (UI, clipboard, API, jobs), below the engine's own limit; 0 = no cap
+	MaxConcurrent int `json:"maxConcurrent"`
+
+	// Translate-as-you-type: typing pause before translating and the
+	// characters that must change since the last translation
+	LiveDelayMs   int `json:"liveDelayMs"`
+	LiveMinChange int `json:"liveMinChange"`
+}
+
+// DefaultTranslationConfig returns default translation settings
+func DefaultTranslationConfig() TranslationConfig {
+	return TranslationConfig{
+		ProtectPlaceholders: true,
+		SkipSameLanguage:    true,
+		MarkFuzzy:           true,
+		ReviewBelow:         0.6,
+		Incremental:         true,
+		CodeMode:            "comments",
+		Processors:          []string{"normalize-whitespace", "strip-boilerplate"},
+		MultiTargetParallel: 2,
+		BatchParallel:       2,
+		MaxConcurrent:       4,
+		LiveDelayMs:         400,
+		LiveMinChange:       1,
+	}
+}
+
+// SetTranslation sets the entire translation config
+func (c *Config) SetTranslation(translation TranslationConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Translation = translation
+}
@@ -0,0 +1,50 @@
+package config
+
+// LanguagePair is a source and target language offered for quick switching
+type LanguagePair struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+// TrayConfig holds system tray settings
+type TrayConfig struct {
+	Enabled       bool           `json:"enabled"`
+	LanguagePairs []LanguagePair `json:"languagePairs"` // pairs listed in the tray menu
+}
+
+// DefaultTrayConfig returns default tray settings
+func DefaultTrayConfig() TrayConfig {
+	return TrayConfig{
+		Enabled: true,
+		LanguagePairs: []LanguagePair{
+			{Source: "auto", Target: "en"},
+			{Source: "en", Target: "ko"},
+			{Source: "ko", Target: "en"},
+		},
+	}
+}
+
+// SetTray sets the entire tray config
+func (c *Config) SetTray(tray TrayConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Tray = tray
+}
+
+// SetLanguagePair sets the languages used by the clipboard monitor and selection popup
+func (c *Config) SetLanguagePair(pair LanguagePair) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Clipboard.SourceLang, c.Clipboard.TargetLang = pair.Source, pair.Target
+	c.Selection.SourceLang, c.Selection.TargetLang = pair.Source, pair.Target
+}
+
+// SetClipboardEnabled turns the clipboard monitor on or off
+func (c *Config) SetClipboardEnabled(enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Clipboard.Enabled = enabled
+}
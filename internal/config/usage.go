@@ -0,0 +1,30 @@
+package config
+
+// Budget is a soft usage limit; exceeding it only warns
+type Budget struct {
+	Engine     string `json:"engine"`     // engine name, e.g. "ollama:llama3.2" (empty = all engines)
+	Period     string `json:"period"`     // "day" or "month"
+	Characters int    `json:"characters"` // 0 = no character limit
+	Tokens     int    `json:"tokens"`     // 0 = no token limit
+}
+
+// UsageConfig holds usage budget settings
+type UsageConfig struct {
+	Budgets     []Budget `json:"budgets"`
+	WarnPercent int      `json:"warnPercent"` // warn once usage reaches this share of a budget
+}
+
+// DefaultUsageConfig returns default usage settings
+func DefaultUsageConfig() UsageConfig {
+	return UsageConfig{
+		WarnPercent: 80,
+	}
+}
+
+// SetUsage sets the entire usage config
+func (c *Config) SetUsage(usage UsageConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Usage = usage
+}
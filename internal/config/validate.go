@@ -0,0 +1,229 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+)
+
+// FieldError is a problem with one setting
+type FieldError struct {
+	Field   string `json:"field"` // JSON path, e.g. "engine.ollama.host"
+	Message string `json:"message"`
+}
+
+// ValidationError lists the invalid settings of a configuration
+type ValidationError []FieldError
+
+func (e ValidationError) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Field + ": " + fe.Message
+	}
+	return "invalid settings: " + strings.Join(msgs, "; ")
+}
+
+// Without returns the errors that are not in other, e.g. to tell the
+// problems a change introduces from those the configuration already had
+func (e ValidationError) Without(other ValidationError) ValidationError {
+	var rest ValidationError
+	for _, fe := range e {
+		if !slices.Contains(other, fe) {
+			rest = append(rest, fe)
+		}
+	}
+	return rest
+}
+
+// Validate checks the settings and returns a ValidationError listing every
+// invalid one, or nil. Prompt templates are checked by the engine package.
+func (c *Config) Validate() error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	v := &validator{}
+	oneOf(v, "general.theme", string(c.General.Theme), ThemeLight, ThemeDark, ThemeSystem)
+
+	e := c.Engine
+	oneOf(v, "engine.type", string(e.Type), EngineInternal, EngineTerminalAgent, EngineOllama, EngineSmart)
+	v.check("engine.maxConcurrency", e.MaxConcurrency >= 0 && e.MaxConcurrency <= 64, "must be between 0 (engine default) and 64")
+
+	v.sampling("engine.internal.sampling", e.Internal.Sampling)
+	v.check("engine.internal.contextSize", e.Internal.ContextSize >= 0 && e.Internal.ContextSize <= 1<<20, "must be between 0 and 1048576")
+	v.timeouts("engine.internal", e.Internal.Startup, e.Internal.Timeout, e.Internal.MaxDuration)
+	v.check("engine.internal.maxInput", e.Internal.MaxInput >= 0, "must not be negative")
+	if e.Uses(EngineInternal) {
+		v.file("engine.internal.modelPath", e.Internal.ModelPath, true)
+	}
+
+	oneOf(v, "engine.terminalAgent.selected", string(e.TerminalAgent.Selected), AgentClaudeCode, AgentGeminiCLI, AgentCodex)
+	v.timeouts("engine.terminalAgent.claudeCode", e.TerminalAgent.ClaudeCode.Startup, e.TerminalAgent.ClaudeCode.Timeout, e.TerminalAgent.ClaudeCode.MaxDuration)
+	v.timeouts("engine.terminalAgent.geminiCli", e.TerminalAgent.GeminiCLI.Startup, e.TerminalAgent.GeminiCLI.Timeout, e.TerminalAgent.GeminiCLI.MaxDuration)
+	v.timeouts("engine.terminalAgent.codex", e.TerminalAgent.Codex.Startup, e.TerminalAgent.Codex.Timeout, e.TerminalAgent.Codex.MaxDuration)
+	v.check("engine.terminalAgent.claudeCode.maxInput", e.TerminalAgent.ClaudeCode.MaxInput >= 0, "must not be negative")
+	v.check("engine.terminalAgent.geminiCli.maxInput", e.TerminalAgent.GeminiCLI.MaxInput >= 0, "must not be negative")
+	v.check("engine.terminalAgent.codex.maxInput", e.TerminalAgent.Codex.MaxInput >= 0, "must not be negative")
+
+	v.url("engine.ollama.host", e.Ollama.Host, true, "http", "https")
+	if e.Uses(EngineOllama) {
+		v.check("engine.ollama.model", strings.TrimSpace(e.Ollama.Model) != "", "is required")
+	}
+	v.timeouts("engine.ollama", e.Ollama.Startup, e.Ollama.Timeout, e.Ollama.MaxDuration)
+	v.check("engine.ollama.maxInput", e.Ollama.MaxInput >= 0, "must not be negative")
+	v.sampling("engine.ollama.sampling", e.Ollama.Sampling)
+	v.file("engine.ollama.caCertFile", e.Ollama.CACertFile, false)
+
+	oneOf(v, "engine.smart.short", string(e.Smart.Short), EngineInternal, EngineTerminalAgent, EngineOllama)
+	oneOf(v, "engine.smart.long", string(e.Smart.Long), EngineInternal, EngineTerminalAgent, EngineOllama)
+	v.check("engine.smart.threshold", e.Smart.Threshold > 0, "must be positive")
+
+	oneOf(v, "network.proxyMode", string(c.Network.ProxyMode), ProxySystem, ProxyNone, ProxyManual)
+	v.url("network.proxyUrl", c.Network.ProxyURL, c.Network.ProxyMode == ProxyManual, "http", "https", "socks5")
+
+	v.check("translation.multiTargetParallel", c.Translation.MultiTargetParallel >= 0, "must not be negative")
+	v.check("translation.batchParallel", c.Translation.BatchParallel >= 0, "must not be negative")
+	v.check("translation.maxConcurrent", c.Translation.MaxConcurrent >= 0 && c.Translation.MaxConcurrent <= 64, "must be between 0 (no cap) and 64")
+	v.check("translation.liveDelayMs", c.Translation.LiveDelayMs >= 0 && c.Translation.LiveDelayMs <= 5000, "must be between 0 and 5000")
+	v.check("translation.liveMinChange", c.Translation.LiveMinChange >= 0, "must not be negative")
+	v.check("translation.reviewBelow", c.Translation.ReviewBelow >= 0 && c.Translation.ReviewBelow <= 1, "must be between 0 and 1")
+	v.check("translation.localizePunctuation", !slices.Contains(c.Translation.LocalizePunctuation, ""), "must not contain empty languages")
+
+	v.check("cache.historyMinutes", c.Cache.HistoryMinutes >= 0, "must not be negative")
+
+	v.check("clipboard.intervalMs", c.Clipboard.IntervalMs >= 100, "must be at least 100")
+	v.check("clipboard.maxLength", c.Clipboard.MaxLength == 0 || c.Clipboard.MaxLength >= c.Clipboard.MinLength, "must not be less than the minimum length")
+
+	v.check("speech.rate", c.Speech.Rate > 0 && c.Speech.Rate <= 4, "must be between 0 and 4")
+	v.file("speech.whisperModel", c.Speech.WhisperModel, false)
+
+	v.url("anki.url", c.Anki.URL, false, "http", "https")
+	for i, f := range c.Anki.Fields {
+		oneOf(v, fmt.Sprintf("anki.fields.%d.content", i), f.Content,
+			AnkiSource, AnkiTranslation, AnkiRomanization, AnkiExample, AnkiExampleTranslation, AnkiSourceLang, AnkiTargetLang)
+	}
+
+	v.check("usage.warnPercent", c.Usage.WarnPercent >= 0 && c.Usage.WarnPercent <= 100, "must be between 0 and 100")
+	for i, b := range c.Usage.Budgets {
+		oneOf(v, fmt.Sprintf("usage.budgets.%d.period", i), b.Period, "day", "month")
+	}
+
+	chords := make(map[string]string)
+	for _, b := range c.Hotkeys.Bindings() {
+		v.hotkey("hotkeys."+string(b.Action), b.Chord, chords)
+	}
+	for i, p := range c.Prompt.Presets {
+		if p.Hotkey != "" {
+			v.hotkey(fmt.Sprintf("prompt.presets.%d.hotkey", i), p.Hotkey, chords)
+		}
+	}
+
+	oneOf(v, "log.level", c.Log.Level, "debug", "info", "warn", "error")
+	v.check("log.maxSizeMb", c.Log.MaxSizeMB >= 1 && c.Log.MaxSizeMB <= 100, "must be between 1 and 100")
+	v.check("log.maxFiles", c.Log.MaxFiles >= 0 && c.Log.MaxFiles <= 20, "must be between 0 and 20")
+
+	v.url("telemetry.endpoint", c.Telemetry.Endpoint, false, "https")
+
+	v.check("api.port", c.API.Port > 0 && c.API.Port <= 65535, "must be between 1 and 65535")
+
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+// validator collects field errors
+type validator struct {
+	errs ValidationError
+}
+
+func (v *validator) add(field, format string, args ...any) {
+	v.errs = append(v.errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) check(field string, ok bool, message string) {
+	if !ok {
+		v.add(field, "%s", message)
+	}
+}
+
+// oneOf checks that value is one of the allowed values
+func oneOf[T ~string](v *validator, field, value string, allowed ...T) {
+	names := make([]string, len(allowed))
+	for i, a := range allowed {
+		if string(a) == value {
+			return
+		}
+		names[i] = fmt.Sprintf("%q", a)
+	}
+	v.add(field, "unknown value %q, expected one of %s", value, strings.Join(names, ", "))
+}
+
+// timeout checks a timeout in seconds
+func (v *validator) timeout(field string, seconds int) {
+	v.check(field, seconds >= 1 && seconds <= 3600, "must be between 1 and 3600 seconds")
+}
+
+// timeouts checks the startup and idle timeouts and the overall limit of an engine
+func (v *validator) timeouts(field string, startup, idle, total int) {
+	v.check(field+".startup", startup >= 0 && startup <= 3600, "must be between 0 (counted in the timeout) and 3600 seconds")
+	v.timeout(field+".timeout", idle)
+	v.timeout(field+".maxDuration", total)
+	v.check(field+".maxDuration", total >= idle, "must not be shorter than the timeout")
+}
+
+// sampling checks the text generation parameters of an engine
+func (v *validator) sampling(field string, s SamplingConfig) {
+	v.check(field+".temperature", s.Temperature >= 0 && s.Temperature <= 2, "must be between 0 and 2")
+	v.check(field+".topP", s.TopP >= 0 && s.TopP <= 1, "must be between 0 and 1")
+	v.check(field+".topK", s.TopK >= 0, "must not be negative")
+	v.check(field+".repeatPenalty", s.RepeatPenalty >= 0 && s.RepeatPenalty <= 2, "must be between 0 and 2")
+	v.check(field+".maxTokens", s.MaxTokens >= 0, "must not be negative")
+}
+
+// hotkey checks a key chord and that no other field in chords (normalized
+// chord to field) uses it, then adds it there
+func (v *validator) hotkey(field, chord string, chords map[string]string) {
+	normalized, err := NormalizeChord(chord)
+	if err != nil {
+		v.add(field, "invalid key chord %q: %v", chord, err)
+		return
+	}
+	if other, taken := chords[normalized]; taken {
+		v.add(field, "%s is already used by %s", normalized, other)
+		return
+	}
+	chords[normalized] = field
+}
+
+// url checks that raw is an absolute URL with one of the schemes
+func (v *validator) url(field, raw string, required bool, schemes ...string) {
+	if raw == "" {
+		v.check(field, !required, "is required")
+		return
+	}
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" {
+		v.add(field, "%q is not a valid URL, e.g. %s://localhost:8080", raw, schemes[0])
+		return
+	}
+	if !slices.Contains(schemes, u.Scheme) {
+		v.add(field, "unsupported scheme %q, expected %s", u.Scheme, strings.Join(schemes, ", "))
+	}
+}
+
+// file checks that path names an existing file
+func (v *validator) file(field, path string, required bool) {
+	if path == "" {
+		v.check(field, !required, "is required")
+		return
+	}
+	fi, err := os.Stat(path)
+	switch {
+	case err != nil:
+		v.add(field, "file not found: %s", path)
+	case fi.IsDir():
+		v.add(field, "is a directory, not a file: %s", path)
+	}
+}
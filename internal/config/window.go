@@ -0,0 +1,28 @@
+package config
+
+// MiniWindowConfig holds the mini translator window's placement
+type MiniWindowConfig struct {
+	X           int  `json:"x"`
+	Y           int  `json:"y"`
+	Width       int  `json:"width"`
+	Height      int  `json:"height"`
+	AlwaysOnTop bool `json:"alwaysOnTop"`
+	Placed      bool `json:"placed"` // X and Y were saved; otherwise the window is centered
+}
+
+// DefaultMiniWindowConfig returns default mini window settings
+func DefaultMiniWindowConfig() MiniWindowConfig {
+	return MiniWindowConfig{
+		Width:       380,
+		Height:      220,
+		AlwaysOnTop: true,
+	}
+}
+
+// SetMiniWindow sets the entire mini window config
+func (c *Config) SetMiniWindow(mini MiniWindowConfig) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.MiniWindow = mini
+}
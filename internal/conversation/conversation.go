@@ -0,0 +1,224 @@
+// Package conversation keeps two-way conversations for interpreting a chat:
+// two parties, each writing in their own language, whose turns are
+// translated into the other party's language with the dialogue so far as
+// context. Conversations live in memory until ended or idle for too long.
+package conversation
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Party is one side of a conversation
+type Party string
+
+const (
+	PartyA Party = "a"
+	PartyB Party = "b"
+)
+
+// Other returns the other party
+func (p Party) Other() Party {
+	if p == PartyA {
+		return PartyB
+	}
+	return PartyA
+}
+
+const (
+	// maxConversations bounds the conversations kept; the least recently
+	// used one is dropped for a new one
+	maxConversations = 32
+	// idleTimeout is how long an unused conversation is kept
+	idleTimeout = 12 * time.Hour
+	// contextTurns is how many recent turns are passed as context
+	contextTurns = 8
+	// maxContextBytes bounds the context, dropping the oldest turns first
+	maxContextBytes = 2000
+)
+
+// ErrNotFound is returned for unknown, ended or expired conversations
+var ErrNotFound = errors.New("conversation not found")
+
+// Turn is a message of one party and its translation
+type Turn struct {
+	Party       Party     `json:"party"`
+	Text        string    `json:"text"`
+	Translation string    `json:"translation"`
+	Engine      string    `json:"engine,omitempty"`
+	At          time.Time `json:"at"`
+}
+
+// Conversation is a dialogue between two languages
+type Conversation struct {
+	ID       string    `json:"id"`
+	LangA    string    `json:"langA"`
+	LangB    string    `json:"langB"`
+	Turns    []Turn    `json:"turns"`
+	Started  time.Time `json:"started"`
+	LastUsed time.Time `json:"lastUsed"`
+}
+
+// Lang returns the language a party writes in
+func (c Conversation) Lang(p Party) string {
+	if p == PartyA {
+		return c.LangA
+	}
+	return c.LangB
+}
+
+// Next returns the party expected to write next: the other one than in
+// the last turn, or A to start
+func (c Conversation) Next() Party {
+	if len(c.Turns) == 0 {
+		return PartyA
+	}
+	return c.Turns[len(c.Turns)-1].Party.Other()
+}
+
+// Context renders the recent turns as dialogue for the engine, in the
+// language each party wrote
+func (c Conversation) Context() string {
+	var lines []string
+	size := 0
+	for i := len(c.Turns) - 1; i >= 0 && len(lines) < contextTurns; i-- {
+		t := c.Turns[i]
+		line := fmt.Sprintf("%s (%s): %s", strings.ToUpper(string(t.Party)), c.Lang(t.Party), strings.Join(strings.Fields(t.Text), " "))
+		if size+len(line) > maxContextBytes {
+			break
+		}
+		size += len(line)
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	for i, j := 0, len(lines)-1; i < j; i, j = i+1, j-1 {
+		lines[i], lines[j] = lines[j], lines[i]
+	}
+	return "Chat between two people, most recent message last:\n" + strings.Join(lines, "\n")
+}
+
+// Manager keeps the conversations
+type Manager struct {
+	mu            sync.Mutex
+	conversations map[string]*Conversation
+}
+
+// NewManager creates an empty manager
+func NewManager() *Manager {
+	return &Manager{conversations: map[string]*Conversation{}}
+}
+
+// Start begins a conversation between langA and langB
+func (m *Manager) Start(langA, langB string) (Conversation, error) {
+	if langA == "" || langB == "" {
+		return Conversation{}, errors.New("both languages are required")
+	}
+	id, err := newID()
+	if err != nil {
+		return Conversation{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.expire()
+	if len(m.conversations) >= maxConversations {
+		var oldest *Conversation
+		for _, c := range m.conversations {
+			if oldest == nil || c.LastUsed.Before(oldest.LastUsed) {
+				oldest = c
+			}
+		}
+		delete(m.conversations, oldest.ID)
+	}
+	now := time.Now()
+	c := &Conversation{ID: id, LangA: langA, LangB: langB, Started: now, LastUsed: now}
+	m.conversations[id] = c
+	return c.copy(), nil
+}
+
+// Get returns a conversation
+func (m *Manager) Get(id string) (Conversation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.expire()
+	c, ok := m.conversations[id]
+	if !ok {
+		return Conversation{}, ErrNotFound
+	}
+	return c.copy(), nil
+}
+
+// List returns the conversations, most recently used first
+func (m *Manager) List() []Conversation {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.expire()
+	list := make([]Conversation, 0, len(m.conversations))
+	for _, c := range m.conversations {
+		list = append(list, c.copy())
+	}
+	slices.SortFunc(list, func(a, b Conversation) int { return b.LastUsed.Compare(a.LastUsed) })
+	return list
+}
+
+// AddTurn appends a translated turn and returns the conversation
+func (m *Manager) AddTurn(id string, turn Turn) (Conversation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	c, ok := m.conversations[id]
+	if !ok {
+		return Conversation{}, ErrNotFound
+	}
+	if turn.At.IsZero() {
+		turn.At = time.Now()
+	}
+	c.Turns = append(c.Turns, turn)
+	c.LastUsed = turn.At
+	return c.copy(), nil
+}
+
+// End forgets a conversation
+func (m *Manager) End(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.conversations, id)
+}
+
+// expire drops idle conversations. Must be called with m.mu held.
+func (m *Manager) expire() {
+	cutoff := time.Now().Add(-idleTimeout)
+	for id, c := range m.conversations {
+		if c.LastUsed.Before(cutoff) {
+			delete(m.conversations, id)
+		}
+	}
+}
+
+// copy returns a copy that doesn't share the turns
+func (c *Conversation) copy() Conversation {
+	cp := *c
+	cp.Turns = slices.Clone(c.Turns)
+	return cp
+}
+
+// newID returns a random conversation ID
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
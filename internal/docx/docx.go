@@ -0,0 +1,364 @@
+// Package docx translates the text of Word (.docx) documents in place.
+//
+// Paragraph text is collected from the <w:t> elements of its runs. Where the
+// formatting changes between runs, or a tab, break or drawing sits between
+// them, a placeholder token marks the boundary so the translation can be put
+// back into the matching runs. The rest of the package is copied unchanged.
+package docx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ironpark/tons/internal/placeholder"
+)
+
+// ErrNotDocx is returned for files that are not Word documents
+var ErrNotDocx = errors.New("not a Word document")
+
+// wordNS is the WordprocessingML namespace
+const wordNS = "http://schemas.openxmlformats.org/wordprocessingml/2006/main"
+
+// textParts matches the package parts whose text is translated
+var textParts = regexp.MustCompile(`^word/(?:document|header\d*|footer\d*|footnotes|endnotes)\.xml$`)
+
+// Document is a parsed Word document
+type Document struct {
+	files      []*zip.File
+	parts      map[string]*part
+	paragraphs []*paragraph
+}
+
+// part is an XML part of the package with its paragraphs
+type part struct {
+	data       []byte
+	paragraphs []*paragraph
+}
+
+// paragraph is the text of a <w:p>, split into groups of equally formatted runs
+type paragraph struct {
+	groups  [][]*slot
+	text    string
+	mapping placeholder.Mapping
+}
+
+// slot is a <w:t> element
+type slot struct {
+	elem span
+	text string
+}
+
+// span is a byte range in a part
+type span struct {
+	start, end int
+}
+
+// Parse reads a .docx file
+func Parse(data []byte) (*Document, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrNotDocx, err)
+	}
+	doc := &Document{files: zr.File, parts: map[string]*part{}}
+	found := false
+	for _, f := range zr.File {
+		if f.Name == "word/document.xml" {
+			found = true
+		}
+		if !textParts.MatchString(f.Name) {
+			continue
+		}
+		content, err := readFile(f)
+		if err != nil {
+			return nil, err
+		}
+		p := &part{data: content}
+		if p.paragraphs, err = parseParagraphs(content); err != nil {
+			return nil, fmt.Errorf("%s: %w", path.Base(f.Name), err)
+		}
+		doc.parts[f.Name] = p
+		doc.paragraphs = append(doc.paragraphs, p.paragraphs...)
+	}
+	if !found {
+		return nil, fmt.Errorf("%w: missing word/document.xml", ErrNotDocx)
+	}
+	return doc, nil
+}
+
+// Texts returns the text of every paragraph that has any
+func (d *Document) Texts() []string {
+	texts := make([]string, len(d.paragraphs))
+	for i, p := range d.paragraphs {
+		texts[i] = p.text
+	}
+	return texts
+}
+
+// Render returns the document with the paragraphs replaced by their translations
+func (d *Document) Render(translated []string) ([]byte, error) {
+	if len(translated) != len(d.paragraphs) {
+		return nil, fmt.Errorf("docx: got %d translations for %d paragraphs", len(translated), len(d.paragraphs))
+	}
+	byParagraph := make(map[*paragraph]string, len(d.paragraphs))
+	for i, p := range d.paragraphs {
+		byParagraph[p] = translated[i]
+	}
+
+	var out bytes.Buffer
+	zw := zip.NewWriter(&out)
+	for _, f := range d.files {
+		p, ok := d.parts[f.Name]
+		if !ok {
+			if err := zw.Copy(f); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		var edits []edit
+		for _, para := range p.paragraphs {
+			edits = append(edits, para.edits(byParagraph[para])...)
+		}
+		header := f.FileHeader
+		w, err := zw.CreateHeader(&header)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write(applyEdits(p.data, edits)); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// parseParagraphs collects the paragraphs of a part. Paragraphs nested in
+// text boxes are collected on their own.
+func parseParagraphs(data []byte) ([]*paragraph, error) {
+	type state struct {
+		groups   [][]*slot
+		boundary bool   // the next text starts a new group
+		rPr      string // formatting of the current run
+		lastRPr  string // formatting of the last text
+		inRun    bool
+	}
+	var (
+		paragraphs []*paragraph
+		stack      []*state
+	)
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		start := int(dec.InputOffset())
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		var cur *state
+		if len(stack) > 0 {
+			cur = stack[len(stack)-1]
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Space != wordNS {
+				continue
+			}
+			switch t.Name.Local {
+			case "p":
+				stack = append(stack, &state{})
+			case "r":
+				if cur != nil {
+					cur.inRun = true
+					cur.rPr = ""
+				}
+			case "rPr":
+				if cur == nil || !cur.inRun {
+					continue
+				}
+				if err := dec.Skip(); err != nil {
+					return nil, err
+				}
+				cur.rPr = string(data[start:dec.InputOffset()])
+			case "t":
+				if cur == nil || !cur.inRun {
+					continue
+				}
+				text, err := readText(dec)
+				if err != nil {
+					return nil, err
+				}
+				s := &slot{elem: span{start, int(dec.InputOffset())}, text: text}
+				if len(cur.groups) == 0 || cur.boundary || cur.rPr != cur.lastRPr {
+					cur.groups = append(cur.groups, nil)
+				}
+				cur.groups[len(cur.groups)-1] = append(cur.groups[len(cur.groups)-1], s)
+				cur.boundary = false
+				cur.lastRPr = cur.rPr
+			case "tab", "br", "cr", "drawing", "pict", "object", "sym", "fldChar", "footnoteReference", "endnoteReference":
+				if cur != nil {
+					cur.boundary = true
+				}
+			}
+		case xml.EndElement:
+			if t.Name.Space != wordNS || cur == nil {
+				continue
+			}
+			switch t.Name.Local {
+			case "r":
+				cur.inRun = false
+			case "p":
+				stack = stack[:len(stack)-1]
+				if para := newParagraph(cur.groups); para != nil {
+					paragraphs = append(paragraphs, para)
+				}
+				// Text boxes split the text of the outer paragraph
+				if len(stack) > 0 {
+					stack[len(stack)-1].boundary = true
+				}
+			}
+		}
+	}
+	return paragraphs, nil
+}
+
+// readText reads the character data of the current element
+func readText(dec *xml.Decoder) (string, error) {
+	var b strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return "", err
+		}
+		switch t := tok.(type) {
+		case xml.CharData:
+			b.Write(t)
+		case xml.EndElement:
+			return b.String(), nil
+		}
+	}
+}
+
+// newParagraph joins the groups of a paragraph with boundary tokens, or
+// returns nil if the paragraph has no text
+func newParagraph(groups [][]*slot) *paragraph {
+	p := &paragraph{groups: groups}
+	var b strings.Builder
+	for i, group := range groups {
+		if i > 0 {
+			var tok string
+			tok, p.mapping = p.mapping.Add(boundary(i))
+			b.WriteString(tok)
+		}
+		for _, s := range group {
+			b.WriteString(s.text)
+		}
+	}
+	p.text = b.String()
+	if strings.TrimSpace(p.text) == "" || len(groups) == 0 {
+		return nil
+	}
+	return p
+}
+
+// boundary is the marker a boundary token restores to
+func boundary(group int) string {
+	return string(rune(0xF0000 + group))
+}
+
+// edits puts a translation back into the paragraph's runs. Each group's text
+// goes into its first <w:t>; when the boundaries did not survive translation,
+// the whole text goes into the first run.
+func (p *paragraph) edits(translated string) []edit {
+	restored := p.mapping.Restore(translated)
+	parts := make([]string, len(p.groups))
+	pos, current := 0, 0
+	ok := true
+	for i, r := range restored {
+		if r < 0xF0000 {
+			continue
+		}
+		next := int(r - 0xF0000)
+		if next <= current || next >= len(p.groups) {
+			ok = false
+			break
+		}
+		parts[current] = restored[pos:i]
+		current, pos = next, i+len(string(r))
+	}
+	parts[current] = restored[pos:]
+	if !ok {
+		parts = make([]string, len(p.groups))
+		parts[0] = strings.Map(func(r rune) rune {
+			if r >= 0xF0000 {
+				return -1
+			}
+			return r
+		}, restored)
+	}
+
+	var edits []edit
+	for i, group := range p.groups {
+		for j, s := range group {
+			text := ""
+			if j == 0 {
+				text = parts[i]
+			}
+			edits = append(edits, edit{s.elem, textElement(text)})
+		}
+	}
+	return edits
+}
+
+// textElement returns a <w:t> element holding text
+func textElement(text string) string {
+	if text == "" {
+		return "<w:t/>"
+	}
+	text = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+	return `<w:t xml:space="preserve">` + text + `</w:t>`
+}
+
+// edit replaces a byte range of a part
+type edit struct {
+	span
+	text string
+}
+
+// applyEdits splices non-overlapping edits into data
+func applyEdits(data []byte, edits []edit) []byte {
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+	var out bytes.Buffer
+	pos := 0
+	for _, e := range edits {
+		if e.start < pos {
+			continue
+		}
+		out.Write(data[pos:e.start])
+		out.WriteString(e.text)
+		pos = e.end
+	}
+	out.Write(data[pos:])
+	return out.Bytes()
+}
+
+// readFile reads a file from the package
+func readFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
@@ -0,0 +1,42 @@
+package engine
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Accumulator builds the full text of a streamed translation from its deltas
+type Accumulator struct {
+	text strings.Builder
+}
+
+// Add appends the text of a streamed response and returns the text so far
+func (a *Accumulator) Add(res Response) string {
+	a.text.WriteString(res.Text)
+	return a.text.String()
+}
+
+// String returns the text so far
+func (a *Accumulator) String() string {
+	return a.text.String()
+}
+
+// Len returns the length of the text so far in bytes
+func (a *Accumulator) Len() int {
+	return a.text.Len()
+}
+
+// completeRunes returns text up to an incomplete UTF-8 sequence at its end and
+// the incomplete rest, to be prepended to the next piece, so streamed deltas
+// never split a character
+func completeRunes(text string) (complete, rest string) {
+	for i := len(text) - 1; i >= 0 && i >= len(text)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(text[i]) {
+			if !utf8.FullRuneInString(text[i:]) {
+				return text[:i], text[i:]
+			}
+			break
+		}
+	}
+	return text, ""
+}
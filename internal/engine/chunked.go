@@ -0,0 +1,280 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/ironpark/tons/internal/chunk"
+	"github.com/ironpark/tons/internal/tokens"
+)
+
+const (
+	// defaultChunkOverlap is the amount of preceding text passed as context to each chunk
+	defaultChunkOverlap = 200
+	// terminalArgLimit is a conservative prompt size for CLI arguments
+	terminalArgLimit = 100_000
+	// windowsArgLimit accounts for the 32K command line limit on Windows
+	windowsArgLimit = 24_000
+)
+
+// InputLimiter is implemented by engines that know the largest input they can handle
+type InputLimiter interface {
+	// MaxInputBytes returns the maximum source text size in bytes, or 0 if unlimited
+	MaxInputBytes(ctx context.Context) int
+}
+
+// TokenLimiter is implemented by engines whose input is limited by a model
+// context window
+type TokenLimiter interface {
+	// MaxInputTokens returns the maximum source text size in tokens, or 0 if unknown
+	MaxInputTokens(ctx context.Context) int
+}
+
+// Chunked wraps an engine and splits texts exceeding its input limit, or the
+// size limit of the engine they are routed to (see SizeLimiter), into
+// chunks. A chunk still over the size limit is refused with a *TooLargeError.
+type Chunked struct {
+	Engine
+	Parallel int // number of chunks translated concurrently (minimum 1)
+	Overlap  int // bytes of preceding text passed as context
+}
+
+// NewChunked wraps e with automatic chunking
+func NewChunked(e Engine, parallel int) *Chunked {
+	if parallel < 1 {
+		parallel = 1
+	}
+	return &Chunked{
+		Engine:   e,
+		Parallel: parallel,
+		Overlap:  defaultChunkOverlap,
+	}
+}
+
+// split returns the chunks for req, or nil if the text fits in one request,
+// and the engine to translate them with. A Router picks the engine once for
+// the whole text. Chunks fit both the engine's input limit and its
+// configured size limit (see SizeLimiter).
+func (c *Chunked) split(ctx context.Context, req Request) ([]chunk.Chunk, Engine) {
+	target := c.Engine
+	if router, ok := target.(Router); ok {
+		target = router.Route(req)
+	}
+	var limit int
+	switch limiter := target.(type) {
+	case TokenLimiter:
+		// Convert with the text's own ratio; CJK text has far fewer bytes per token
+		limit = limiter.MaxInputTokens(ctx) * tokens.BytesPerToken(req.Text)
+	case InputLimiter:
+		limit = limiter.MaxInputBytes(ctx)
+	}
+	if (limit <= 0 || len(req.Text) <= limit) && CheckSize(target, req) == nil {
+		return nil, target
+	}
+	if limiter, ok := target.(SizeLimiter); ok && limiter.MaxTextChars() > 0 {
+		// Chunks are sized in bytes, and no text has more characters than bytes
+		if limit <= 0 || limiter.MaxTextChars() < limit {
+			limit = limiter.MaxTextChars()
+		}
+	}
+	return chunk.Split(req.Text, limit, c.Overlap), target
+}
+
+// chunkSizes returns the source text size of each chunk
+func chunkSizes(chunks []chunk.Chunk) []int {
+	sizes := make([]int, len(chunks))
+	for i, ch := range chunks {
+		sizes[i] = len(ch.Text)
+	}
+	return sizes
+}
+
+// checkChunkSizes checks each chunk against the size limit of e; the whole
+// text may be over it, as long as every chunk fits
+func checkChunkSizes(e Engine, req Request, chunks []chunk.Chunk) error {
+	for _, ch := range chunks {
+		if err := CheckSize(e, chunkRequest(req, ch)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// chunkRequest builds the request for a single chunk, passing overlap as
+// context. The overlap is data, never part of the prompt template, so text
+// like "{{" can't break it.
+func chunkRequest(req Request, ch chunk.Chunk) Request {
+	if req.Constraints.MaxLength > 0 && len(req.Text) > 0 {
+		// Each chunk gets its share of the length limit
+		req.Constraints.MaxLength = max(1, req.Constraints.MaxLength*len(ch.Text)/len(req.Text))
+	}
+	req.Text = ch.Text
+	if ch.Context != "" {
+		passage := "the text continues from this passage:\n" + ch.Context
+		if req.Context != "" {
+			passage = req.Context + "\n" + passage
+		}
+		req.Context = passage
+	}
+	return req
+}
+
+// translateChunks translates chunks with e, with bounded parallelism.
+// onDone is called in chunk order as soon as each chunk and all its predecessors are complete.
+func (c *Chunked) translateChunks(ctx context.Context, e Engine, req Request, chunks []chunk.Chunk, onDone func(i int, text string)) ([]string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([]string, len(chunks))
+	errs := make([]error, len(chunks))
+	done := make([]chan struct{}, len(chunks))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, c.Parallel)
+
+	for i, ch := range chunks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer close(done[i])
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				errs[i] = ctx.Err()
+				return
+			}
+
+			res, err := e.Translate(ctx, chunkRequest(req, ch))
+			if err == nil && res.Error != "" {
+				err = errors.New(res.Error)
+			}
+			if err != nil {
+				errs[i] = fmt.Errorf("chunk %d/%d: %w", i+1, len(chunks), err)
+				cancel() // stop the remaining chunks
+				return
+			}
+			results[i] = res.Text
+		}()
+	}
+
+	var firstErr error
+	for i := range chunks {
+		<-done[i]
+		if errs[i] != nil {
+			firstErr = errs[i]
+			break
+		}
+		if onDone != nil {
+			onDone(i, results[i])
+		}
+	}
+	cancel()
+	wg.Wait()
+
+	if firstErr != nil {
+		// Prefer the error that caused the cancellation over context.Canceled
+		for _, err := range errs {
+			if err != nil && !errors.Is(err, context.Canceled) {
+				return nil, err
+			}
+		}
+		return nil, firstErr
+	}
+	return results, nil
+}
+
+// Translate splits long texts and reassembles the translated chunks
+func (c *Chunked) Translate(ctx context.Context, req Request) (Response, error) {
+	chunks, target := c.split(ctx, req)
+	if chunks == nil {
+		if err := CheckSize(target, req); err != nil {
+			return Response{}, err
+		}
+		return c.Engine.Translate(ctx, req)
+	}
+	if err := checkChunkSizes(target, req, chunks); err != nil {
+		return Response{}, err
+	}
+
+	progress := newProgressTracker(ctx, chunkSizes(chunks))
+	results, err := c.translateChunks(WithProgress(ctx, nil), target, req, chunks, func(i int, text string) {
+		progress.add(len(chunks[i].Text))
+	})
+	if err != nil {
+		return Response{}, err
+	}
+	return Response{Text: chunk.Join(chunks, results), Done: true}, nil
+}
+
+// TranslateStream splits long texts and streams each chunk, in order, as it completes.
+// Short texts are streamed by the wrapped engine unchanged.
+func (c *Chunked) TranslateStream(ctx context.Context, req Request) (<-chan Response, error) {
+	chunks, target := c.split(ctx, req)
+	if chunks == nil {
+		if err := CheckSize(target, req); err != nil {
+			return nil, err
+		}
+		return c.Engine.TranslateStream(ctx, req)
+	}
+	if err := checkChunkSizes(target, req, chunks); err != nil {
+		return nil, err
+	}
+
+	ch := make(chan Response)
+	go func() {
+		defer close(ch)
+
+		// Announce the chunk count, so a progress bar can show before the
+		// first chunk is done
+		progress := newProgressTracker(ctx, chunkSizes(chunks))
+		start := progress.progress()
+		ch <- Response{Progress: &start}
+
+		streamed := false
+		_, err := c.translateChunks(WithProgress(ctx, nil), target, req, chunks, func(i int, text string) {
+			streamed = true
+			p := progress.add(len(chunks[i].Text))
+			ch <- Response{
+				Text:     text + chunks[i].Sep,
+				Progress: &p,
+			}
+		})
+		if err != nil {
+			res := ErrorResponse(err.Error())
+			res.Partial = streamed // the completed chunks were sent
+			ch <- res
+			return
+		}
+		ch <- Response{Done: true}
+	}()
+	return ch, nil
+}
+
+// MaxInputTokens returns the prompt size that fits in the Ollama context window.
+// Half the window is reserved for the generated translation.
+func (e *Ollama) MaxInputTokens(ctx context.Context) int {
+	numCtx := e.numCtx(ctx)
+	return tokens.Budget{Context: numCtx, Reserve: numCtx / 2}.Input()
+}
+
+// MaxInputTokens returns the prompt size that fits in the Yzma context window.
+// Half the window is reserved for the generated translation.
+func (e *Yzma) MaxInputTokens(ctx context.Context) int {
+	return tokens.Budget{Context: e.ContextSize, Reserve: e.ContextSize / 2}.Input()
+}
+
+// MaxInputBytes returns a safe command line argument size for the current OS
+func (e *TerminalEngine) MaxInputBytes(ctx context.Context) int {
+	if runtime.GOOS == "windows" {
+		return windowsArgLimit
+	}
+	return terminalArgLimit
+}
@@ -0,0 +1,125 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ironpark/tons/internal/chunk"
+)
+
+// Constraints restrict the shape of a translation, e.g. for subtitles or UI
+// strings that must fit their space. They are asked for in the prompt and
+// checked on the result; see Constrained.
+type Constraints struct {
+	MaxLength      int  `json:"maxLength,omitempty"`      // in characters, 0 for no limit
+	KeepLineBreaks bool `json:"keepLineBreaks,omitempty"` // as many lines as the source
+	SingleLine     bool `json:"singleLine,omitempty"`     // no line breaks; enforced by joining lines
+	KeepSentences  bool `json:"keepSentences,omitempty"`  // as many sentences as the source
+}
+
+// instructions returns prompt sentences asking for the constraints
+func (c Constraints) instructions(source string) []string {
+	var s []string
+	if c.MaxLength > 0 {
+		s = append(s, fmt.Sprintf("The translation must not exceed %d characters; shorten the wording if needed.", c.MaxLength))
+	}
+	if c.SingleLine {
+		s = append(s, "Return the translation on a single line.")
+	} else if c.KeepLineBreaks {
+		s = append(s, fmt.Sprintf("Keep the line breaks of the source: the translation must have exactly %d lines.", countLines(source)))
+	}
+	if c.KeepSentences {
+		s = append(s, fmt.Sprintf("Keep the sentence structure: the translation must have exactly %d sentences.", chunk.CountSentences(source)))
+	}
+	return s
+}
+
+// Check returns the constraints translation breaks, as messages for the
+// user, or nil
+func (c Constraints) Check(source, translation string) []string {
+	var violations []string
+	if n := utf8.RuneCountInString(strings.TrimSpace(translation)); c.MaxLength > 0 && n > c.MaxLength {
+		violations = append(violations, fmt.Sprintf("%d characters exceed the limit of %d", n, c.MaxLength))
+	}
+	if c.SingleLine {
+		if n := countLines(translation); n > 1 {
+			violations = append(violations, fmt.Sprintf("%d lines instead of one", n))
+		}
+	} else if c.KeepLineBreaks {
+		if want, got := countLines(source), countLines(translation); got != want {
+			violations = append(violations, fmt.Sprintf("%d lines instead of %d", got, want))
+		}
+	}
+	if c.KeepSentences {
+		if want, got := chunk.CountSentences(source), chunk.CountSentences(translation); got != want {
+			violations = append(violations, fmt.Sprintf("%d sentences instead of %d", got, want))
+		}
+	}
+	return violations
+}
+
+// countLines returns the number of lines of text, ignoring leading and
+// trailing line breaks
+func countLines(text string) int {
+	text = strings.TrimSpace(strings.ReplaceAll(text, "\r\n", "\n"))
+	if text == "" {
+		return 0
+	}
+	return strings.Count(text, "\n") + 1
+}
+
+// joinLines replaces line breaks of a streamed delta with spaces
+var joinLines = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ")
+
+// Constrained wraps an engine and checks translations against the request's
+// Constraints, reporting broken ones as Violations of the final response.
+// Line breaks of single-line translations are replaced with spaces.
+type Constrained struct {
+	Engine
+}
+
+// NewConstrained wraps e with constraint checking
+func NewConstrained(e Engine) *Constrained {
+	return &Constrained{Engine: e}
+}
+
+// Translate translates and checks the result
+func (c *Constrained) Translate(ctx context.Context, req Request) (Response, error) {
+	res, err := c.Engine.Translate(ctx, req)
+	if err != nil || res.Error != "" || req.Constraints == (Constraints{}) {
+		return res, err
+	}
+	if req.Constraints.SingleLine {
+		res.Text = strings.TrimSpace(joinLines.Replace(res.Text))
+	}
+	res.Violations = req.Constraints.Check(req.Text, res.Text)
+	return res, nil
+}
+
+// TranslateStream streams the translation and checks it before sending the
+// final response
+func (c *Constrained) TranslateStream(ctx context.Context, req Request) (<-chan Response, error) {
+	inner, err := c.Engine.TranslateStream(ctx, req)
+	if err != nil || req.Constraints == (Constraints{}) {
+		return inner, err
+	}
+
+	ch := make(chan Response)
+	go func() {
+		defer close(ch)
+		var full Accumulator
+		for res := range inner {
+			if req.Constraints.SingleLine {
+				res.Text = joinLines.Replace(res.Text)
+			}
+			full.Add(res)
+			if res.Done && res.Error == "" {
+				res.Violations = req.Constraints.Check(req.Text, full.String())
+			}
+			ch <- res
+		}
+	}()
+	return ch, nil
+}
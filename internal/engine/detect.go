@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"context"
+
+	"github.com/ironpark/tons/internal/langdetect"
+)
+
+// AutoDetect wraps an engine and resolves the "auto" source language before translating
+type AutoDetect struct {
+	Engine
+}
+
+// NewAutoDetect wraps e with automatic source language detection
+func NewAutoDetect(e Engine) *AutoDetect {
+	return &AutoDetect{Engine: e}
+}
+
+// resolve replaces an "auto" source language with the detected language name.
+// Returns the detected language code, or "" if no detection was performed.
+func (a *AutoDetect) resolve(req *Request) string {
+	if req.SourceLang != langdetect.Auto {
+		return ""
+	}
+	result := langdetect.Detect(req.Text)
+	if result.Lang == langdetect.Unknown {
+		// Let the model figure it out rather than failing
+		req.SourceLang = "the detected source language"
+		return ""
+	}
+	req.SourceLang = langdetect.Name(result.Lang)
+	return result.Lang
+}
+
+// Translate detects the source language if requested, then delegates
+func (a *AutoDetect) Translate(ctx context.Context, req Request) (Response, error) {
+	detected := a.resolve(&req)
+	res, err := a.Engine.Translate(ctx, req)
+	res.DetectedLang = detected
+	return res, err
+}
+
+// TranslateStream detects the source language if requested, then delegates.
+// Every streamed response carries the detected language.
+func (a *AutoDetect) TranslateStream(ctx context.Context, req Request) (<-chan Response, error) {
+	detected := a.resolve(&req)
+	inner, err := a.Engine.TranslateStream(ctx, req)
+	if err != nil || detected == "" {
+		return inner, err
+	}
+
+	ch := make(chan Response)
+	go func() {
+		defer close(ch)
+		for res := range inner {
+			res.DetectedLang = detected
+			ch <- res
+		}
+	}()
+	return ch, nil
+}
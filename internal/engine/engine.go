@@ -3,16 +3,27 @@ package engine
 import (
 	"context"
 	"fmt"
-	"strings"
+	"time"
 )
 
 // Request represents a translation request
 type Request struct {
-	Text         string `json:"text"`
-	SourceLang   string `json:"sourceLang"`
-	TargetLang   string `json:"targetLang"`
-	Prompt       string `json:"prompt"`
-	SystemPrompt string `json:"systemPrompt"`
+	ID           string            `json:"id,omitempty"`    // identifies the request in streamed events, not sent to the engine
+	Pane         string            `json:"pane,omitempty"`  // UI pane showing the result; a new request cancels the previous one in the same pane
+	Fresh        bool              `json:"fresh,omitempty"` // translate even if history has a recent identical translation, not sent to the engine
+	Text         string            `json:"text"`
+	SourceLang   string            `json:"sourceLang"`
+	TargetLang   string            `json:"targetLang"`
+	Prompt       string            `json:"prompt"`
+	SystemPrompt string            `json:"systemPrompt"`
+	Format       Format            `json:"format,omitempty"`
+	Formality    Formality         `json:"formality,omitempty"`
+	Tone         string            `json:"tone,omitempty"`    // free-form, e.g. "friendly", "technical"
+	Context      string            `json:"context,omitempty"` // domain or surrounding text, e.g. "medical report"
+	Examples     []Example         `json:"examples,omitempty"`
+	Glossary     []GlossaryTerm    `json:"glossary,omitempty"`
+	Variables    map[string]string `json:"variables,omitempty"` // custom prompt template variables
+	Constraints  Constraints       `json:"constraints,omitzero"`
 }
 
 // Response represents a translation response.
@@ -22,16 +33,55 @@ type Request struct {
 //   - Done is always true for the final response
 //
 // In streaming mode (TranslateStream method):
-//   - Text contains an incremental token/chunk
+//   - Text contains only what was generated since the previous response (a
+//     delta), never the text so far; engines whose tools report cumulative
+//     text must send the difference
 //   - Done is false for intermediate responses
 //   - Done is true for the final response (Text may be empty)
-//   - Consumers must concatenate Text values to build the full result
+//   - Consumers concatenate Text values to build the full result, see Accumulator
 //
 // Error is set when an error occurs; treat as terminal regardless of Done.
+// Usage is only set on the final response of engines that report generation statistics.
+// DetectedLang is set when the request's source language was "auto".
+// Progress is set by chunked translations as each chunk completes.
+// Quality is set on the final response when quality estimation is enabled.
+// Confidence is set on the final response when the engine or a quality
+// estimate tells how likely the translation is right, from 0 to 1.
+// Skipped means the text was returned as is because no translation was needed.
+// Violations lists the request's Constraints the final translation breaks.
+// Partial is set with Error when a timeout or cancellation stopped the
+// generation after some text arrived: the text streamed so far (or Text of a
+// non-streaming result) is incomplete but usable.
 type Response struct {
-	Text  string `json:"text"`
-	Done  bool   `json:"done"`
-	Error string `json:"error,omitempty"`
+	Text         string    `json:"text"`
+	Done         bool      `json:"done"`
+	Error        string    `json:"error,omitempty"`
+	Usage        *Usage    `json:"usage,omitempty"`
+	DetectedLang string    `json:"detectedLang,omitempty"`
+	Progress     *Progress `json:"progress,omitempty"`
+	Quality      *Quality  `json:"quality,omitempty"`
+	Confidence   *float64  `json:"confidence,omitempty"`
+	Skipped      bool      `json:"skipped,omitempty"`
+	Partial      bool      `json:"partial,omitempty"`
+	Violations   []string  `json:"violations,omitempty"`
+}
+
+// Usage holds generation statistics reported by an engine
+type Usage struct {
+	PromptTokens       int           `json:"promptTokens"`
+	CompletionTokens   int           `json:"completionTokens"`
+	LoadDuration       time.Duration `json:"loadDuration"`
+	PromptEvalDuration time.Duration `json:"promptEvalDuration"`
+	EvalDuration       time.Duration `json:"evalDuration"`
+	TotalDuration      time.Duration `json:"totalDuration"`
+}
+
+// TokensPerSecond returns the generation speed, or 0 if unknown
+func (u Usage) TokensPerSecond() float64 {
+	if u.EvalDuration <= 0 {
+		return 0
+	}
+	return float64(u.CompletionTokens) / u.EvalDuration.Seconds()
 }
 
 // ErrorResponse creates an error response with the given message
@@ -46,17 +96,21 @@ func ErrorResponsef(format string, args ...any) Response {
 
 // SamplingConfig holds sampling parameters for LLM generation
 type SamplingConfig struct {
-	Temperature float32
-	TopP        float32
-	MaxTokens   int
+	Temperature   float32
+	TopP          float32
+	TopK          int     // 0 disables top-k sampling
+	RepeatPenalty float32 // 0 or 1 disables the penalty
+	MaxTokens     int
 }
 
 // DefaultSamplingConfig returns default sampling parameters
 func DefaultSamplingConfig() SamplingConfig {
 	return SamplingConfig{
-		Temperature: 0.7,
-		TopP:        0.9,
-		MaxTokens:   512,
+		Temperature:   0.7,
+		TopP:          0.9,
+		TopK:          40,
+		RepeatPenalty: 1.1,
+		MaxTokens:     512,
 	}
 }
 
@@ -68,12 +122,3 @@ type Engine interface {
 	Available() bool
 	Close() error
 }
-
-// BuildPrompt replaces template variables with actual values
-func BuildPrompt(template, text, sourceLang, targetLang string) string {
-	return strings.NewReplacer(
-		"{{text}}", text,
-		"{{source_lang}}", sourceLang,
-		"{{target_lang}}", targetLang,
-	).Replace(template)
-}
@@ -0,0 +1,61 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Explanation is a translation together with a short grammar and nuance
+// explanation of the source text, for language learners
+type Explanation struct {
+	Translation string `json:"translation"`
+	Explanation string `json:"explanation"`
+}
+
+// explainPrompt asks the engine for a translation and an explanation as JSON
+const explainPrompt = `Translate the following text from {{.SourceLang}} to {{.TargetLang}}, then briefly explain
+its grammar and nuance for a language learner: key structures, idioms, and word choices that do not
+translate literally. Write the explanation in {{.Vars.explain_lang}}, in at most five short sentences.
+
+Text:
+{{.Text}}
+
+Respond with JSON only, in this exact form:
+{"translation": "...", "explanation": "..."}`
+
+// Explain translates req.Text and explains it in explainLang (the user's UI language)
+func Explain(ctx context.Context, e Engine, req Request, explainLang string) (Explanation, error) {
+	exReq := req
+	exReq.Prompt = explainPrompt
+	exReq.SystemPrompt = "You are a patient language teacher and professional translator."
+	exReq.Variables = map[string]string{"explain_lang": explainLang}
+
+	res, err := e.Translate(ctx, exReq)
+	if err != nil {
+		return Explanation{}, fmt.Errorf("explanation failed: %w", err)
+	}
+
+	var ex Explanation
+	if err := extractJSON(res.Text, &ex); err != nil {
+		return Explanation{}, fmt.Errorf("explanation failed: %w", err)
+	}
+	if ex.Translation == "" {
+		return Explanation{}, fmt.Errorf("explanation failed: no translation in %q", res.Text)
+	}
+	return ex, nil
+}
+
+// extractJSON decodes the outermost JSON object in engine output into v
+func extractJSON(output string, v any) error {
+	start := strings.Index(output, "{")
+	end := strings.LastIndex(output, "}")
+	if start < 0 || end < start {
+		return fmt.Errorf("engine returned no JSON: %q", output)
+	}
+	if err := json.Unmarshal([]byte(output[start:end+1]), v); err != nil {
+		return fmt.Errorf("invalid JSON from engine: %w", err)
+	}
+	return nil
+}
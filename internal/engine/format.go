@@ -0,0 +1,191 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+
+	"github.com/ironpark/tons/internal/htmldoc"
+	"github.com/ironpark/tons/internal/markdown"
+)
+
+// Format identifies the markup of the request text
+type Format string
+
+const (
+	FormatText     Format = ""         // plain text (default)
+	FormatMarkdown Format = "markdown" // translate prose only, keep Markdown structure
+	FormatHTML     Format = "html"     // translate text nodes only, keep tags and attributes
+)
+
+// segmentedDocument is a parsed structured document with translatable segments
+type segmentedDocument interface {
+	Texts() []string
+	Render(translated []string) string
+}
+
+// paragraphBreak separates segments in a batched request
+var paragraphBreak = regexp.MustCompile(`\n[ \t]*\n\s*`)
+
+// FormatAware wraps an engine and handles structured formats by translating
+// only their text segments
+type FormatAware struct {
+	Engine
+}
+
+// NewFormatAware wraps e with structured format handling
+func NewFormatAware(e Engine) *FormatAware {
+	return &FormatAware{Engine: e}
+}
+
+// Translate translates structured formats segment-wise and plain text directly
+func (f *FormatAware) Translate(ctx context.Context, req Request) (Response, error) {
+	var doc segmentedDocument
+	switch req.Format {
+	case FormatMarkdown:
+		doc = markdown.Parse(req.Text)
+	case FormatHTML:
+		doc = htmldoc.Parse(req.Text)
+	default:
+		return f.Engine.Translate(ctx, req)
+	}
+
+	translated, err := TranslateSegments(ctx, f.Engine, req, doc.Texts())
+	if err != nil {
+		return Response{}, err
+	}
+	return Response{Text: doc.Render(translated), Done: true}, nil
+}
+
+// TranslateStream streams plain text; structured formats are translated in one
+// pass and delivered as a single response
+func (f *FormatAware) TranslateStream(ctx context.Context, req Request) (<-chan Response, error) {
+	if req.Format == FormatText {
+		return f.Engine.TranslateStream(ctx, req)
+	}
+
+	ch := make(chan Response)
+	go func() {
+		defer close(ch)
+		res, err := f.Translate(ctx, req)
+		if err != nil {
+			ch <- ErrorResponse(err.Error())
+			return
+		}
+		ch <- Response{Text: res.Text}
+		ch <- Response{Done: true}
+	}()
+	return ch, nil
+}
+
+// segmentBatch is the maximum number of segments sent in one request
+const segmentBatch = 40
+
+// TranslateSegments translates independent text segments (e.g. catalog
+// messages), batching them into requests separated by blank lines. The result
+// has one translation per segment, in order. Leading and trailing whitespace
+// of each segment is kept and blank segments are returned unchanged.
+func TranslateSegments(ctx context.Context, e Engine, req Request, segments []string) ([]string, error) {
+	translated, _, err := TranslateSegmentsConfidence(ctx, e, req, segments)
+	return translated, err
+}
+
+// TranslateSegmentsConfidence is TranslateSegments that also returns the
+// confidence in each translation where the engine reports one (see
+// Response.Confidence), nil otherwise. Segments translated in one batch
+// share the confidence of the batch.
+func TranslateSegmentsConfidence(ctx context.Context, e Engine, req Request, segments []string) ([]string, []*float64, error) {
+	translated := make([]string, len(segments))
+	confidence := make([]*float64, len(segments))
+	var texts []string
+	var index []int
+	for i, segment := range segments {
+		if strings.TrimSpace(segment) == "" {
+			translated[i] = segment
+			continue
+		}
+		texts = append(texts, strings.TrimSpace(segment))
+		index = append(index, i)
+	}
+
+	batches := slices.Collect(slices.Chunk(texts, segmentBatch))
+	sizes := make([]int, len(batches))
+	for i, batch := range batches {
+		sizes[i] = len(strings.Join(batch, "\n\n"))
+	}
+	// Batches report progress as a whole, not the chunks of their requests
+	progress := newProgressTracker(ctx, sizes)
+	inner := WithProgress(ctx, nil)
+	var parts []string
+	var scores []*float64
+	for i, batch := range batches {
+		out, conf, err := translateBatch(inner, e, req, batch)
+		if err != nil {
+			return nil, nil, err
+		}
+		parts = append(parts, out...)
+		scores = append(scores, conf...)
+		progress.add(sizes[i])
+	}
+	for j, i := range index {
+		segment := segments[i]
+		lead := segment[:len(segment)-len(strings.TrimLeftFunc(segment, unicode.IsSpace))]
+		trail := segment[len(strings.TrimRightFunc(segment, unicode.IsSpace)):]
+		translated[i] = lead + parts[j] + trail
+		confidence[i] = scores[j]
+	}
+	return translated, confidence, nil
+}
+
+// translateBatch translates segments in a single request separated by blank
+// lines, returning the translations and their confidence. If the engine
+// merges or splits paragraphs, it falls back to one request per segment.
+func translateBatch(ctx context.Context, e Engine, req Request, segments []string) ([]string, []*float64, error) {
+	req.Format = FormatText
+	req.Text = strings.Join(segments, "\n\n")
+	res, err := e.Translate(ctx, req)
+	if err == nil && res.Error != "" {
+		err = errors.New(res.Error)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	confidence := make([]*float64, len(segments))
+	parts := paragraphBreak.Split(strings.TrimSpace(res.Text), -1)
+	if len(parts) == len(segments) && !hasBlankLine(segments) {
+		for i := range confidence {
+			confidence[i] = res.Confidence
+		}
+		return parts, confidence, nil
+	}
+
+	translated := make([]string, len(segments))
+	for i, segment := range segments {
+		req.Text = segment
+		res, err := e.Translate(ctx, req)
+		if err == nil && res.Error != "" {
+			err = errors.New(res.Error)
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		translated[i] = strings.TrimSpace(res.Text)
+		confidence[i] = res.Confidence
+	}
+	return translated, confidence, nil
+}
+
+// hasBlankLine reports whether any segment itself contains a paragraph break,
+// which would make the batched result ambiguous
+func hasBlankLine(segments []string) bool {
+	for _, s := range segments {
+		if paragraphBreak.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
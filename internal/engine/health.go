@@ -0,0 +1,107 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+)
+
+// Health is the state of an engine as seen by a quick check
+type Health struct {
+	Installed   bool   `json:"installed"`         // executable, server or model file found
+	Reachable   bool   `json:"reachable"`         // answered the check
+	Version     string `json:"version,omitempty"` // reported version, if any
+	ModelLoaded bool   `json:"modelLoaded"`       // model is in memory (local models only)
+	Error       string `json:"error,omitempty"`   // why the engine can't be used
+}
+
+// HealthChecker is implemented by engines that can report their health
+type HealthChecker interface {
+	Health(ctx context.Context) Health
+}
+
+// CheckHealth reports the health of e, falling back to Available for engines
+// without a HealthChecker
+func CheckHealth(ctx context.Context, e Engine) Health {
+	if checker, ok := e.(HealthChecker); ok {
+		return checker.Health(ctx)
+	}
+	available := e.Available()
+	h := Health{Installed: available, Reachable: available}
+	if !available {
+		h.Error = "engine is not available"
+	}
+	return h
+}
+
+// Health implements HealthChecker by looking up the command and asking for its version
+func (e *TerminalEngine) Health(ctx context.Context) Health {
+	path, err := exec.LookPath(e.config.Command)
+	if err != nil {
+		return Health{Error: fmt.Sprintf("%s not found", e.config.Command)}
+	}
+	h := Health{Installed: true}
+	out, err := exec.CommandContext(ctx, path, "--version").Output()
+	if err != nil {
+		h.Error = fmt.Sprintf("%s --version: %v", e.config.Command, err)
+		return h
+	}
+	h.Reachable = true
+	h.Version, _, _ = strings.Cut(strings.TrimSpace(string(out)), "\n")
+	return h
+}
+
+// Health implements HealthChecker by querying the server for its version and models
+func (e *Ollama) Health(ctx context.Context) Health {
+	version, err := e.client.Version(ctx)
+	if err != nil {
+		return Health{Error: err.Error()}
+	}
+	h := Health{Installed: true, Reachable: true, Version: version}
+
+	list, err := e.client.List(ctx)
+	if err != nil {
+		h.Error = err.Error()
+		return h
+	}
+	if !slices.ContainsFunc(list.Models, func(m api.ListModelResponse) bool { return sameModel(m.Name, e.Model) }) {
+		h.Error = fmt.Sprintf("model %q is not pulled", e.Model)
+		return h
+	}
+	if running, err := e.client.ListRunning(ctx); err == nil {
+		h.ModelLoaded = slices.ContainsFunc(running.Models, func(m api.ProcessModelResponse) bool { return sameModel(m.Name, e.Model) })
+	}
+	return h
+}
+
+// sameModel compares Ollama model names, treating a missing tag as "latest"
+func sameModel(a, b string) bool {
+	withTag := func(name string) string {
+		if !strings.Contains(name, ":") {
+			return name + ":latest"
+		}
+		return name
+	}
+	return withTag(a) == withTag(b)
+}
+
+// Health implements HealthChecker by checking the model file
+func (e *Yzma) Health(ctx context.Context) Health {
+	if _, err := os.Stat(e.ModelPath); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return Health{Error: fmt.Sprintf("model file %s not found", e.ModelPath)}
+		}
+		return Health{Error: err.Error()}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return Health{Installed: true, Reachable: true, ModelLoaded: e.initialized}
+}
@@ -0,0 +1,186 @@
+package engine
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// HTTPAuth holds credentials sent with every request to a network engine.
+// BearerToken takes precedence over basic auth when both are set.
+type HTTPAuth struct {
+	BearerToken string
+	Username    string
+	Password    string
+}
+
+// header returns the Authorization header value, or "" if no credentials are set
+func (a HTTPAuth) header() string {
+	if a.BearerToken != "" {
+		return "Bearer " + a.BearerToken
+	}
+	if a.Username != "" || a.Password != "" {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(a.Username+":"+a.Password))
+	}
+	return ""
+}
+
+// TLSOptions configures certificate verification for HTTPS engines
+type TLSOptions struct {
+	CACertFile         string // PEM bundle appended to the system roots
+	InsecureSkipVerify bool
+}
+
+// config builds a tls.Config, or returns nil when defaults suffice
+func (o TLSOptions) config() (*tls.Config, error) {
+	if o.CACertFile == "" && !o.InsecureSkipVerify {
+		return nil, nil
+	}
+
+	cfg := &tls.Config{InsecureSkipVerify: o.InsecureSkipVerify}
+	if o.CACertFile != "" {
+		pem, err := os.ReadFile(o.CACertFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no valid certificates found in %s", o.CACertFile)
+		}
+		cfg.RootCAs = pool
+	}
+	return cfg, nil
+}
+
+// ProxyOptions configures the outbound proxy for network engines.
+// With an empty URL the standard HTTP_PROXY/HTTPS_PROXY/NO_PROXY variables are honored,
+// unless Direct is set.
+type ProxyOptions struct {
+	URL     string // http://, https:// or socks5:// proxy URL
+	NoProxy string // comma-separated hosts or domain suffixes that bypass URL
+	Direct  bool   // ignore environment variables and connect directly
+}
+
+// proxyFunc returns the http.Transport proxy function for these options
+func (o ProxyOptions) proxyFunc() (func(*http.Request) (*url.URL, error), error) {
+	if o.Direct {
+		return nil, nil
+	}
+	if o.URL == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+
+	proxyURL, err := url.Parse(o.URL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %w", err)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q", proxyURL.Scheme)
+	}
+
+	bypass := splitNoProxy(o.NoProxy)
+	return func(req *http.Request) (*url.URL, error) {
+		if matchNoProxy(req.URL.Hostname(), bypass) {
+			return nil, nil
+		}
+		return proxyURL, nil
+	}, nil
+}
+
+// splitNoProxy parses a comma-separated NO_PROXY style list
+func splitNoProxy(list string) []string {
+	var entries []string
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		if entry != "" {
+			entries = append(entries, strings.TrimPrefix(entry, "*"))
+		}
+	}
+	return entries
+}
+
+// matchNoProxy reports whether host is covered by a bypass entry
+func matchNoProxy(host string, entries []string) bool {
+	host = strings.ToLower(host)
+	for _, entry := range entries {
+		switch {
+		case entry == "":
+			return true // "*" matches everything
+		case strings.HasPrefix(entry, "."):
+			if strings.HasSuffix(host, entry) || host == entry[1:] {
+				return true
+			}
+		case host == entry || strings.HasSuffix(host, "."+entry):
+			return true
+		}
+	}
+	return false
+}
+
+// authTransport injects an Authorization header into outgoing requests
+type authTransport struct {
+	base   http.RoundTripper
+	header string
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", t.header)
+	return t.base.RoundTrip(req)
+}
+
+// httpClientConfig collects the connection settings shared by network engines
+type httpClientConfig struct {
+	Timeout time.Duration
+	Auth    HTTPAuth
+	TLS     TLSOptions
+	Proxy   ProxyOptions
+}
+
+// connectTimeout bounds connecting to a network engine, so an unreachable
+// host fails fast rather than using up the time allowed for a translation
+const connectTimeout = 10 * time.Second
+
+// newHTTPClient creates an HTTP client for network engines
+func newHTTPClient(cfg httpClientConfig) (*http.Client, error) {
+	transport := &http.Transport{
+		DialContext:         (&net.Dialer{Timeout: connectTimeout, KeepAlive: 30 * time.Second}).DialContext,
+		TLSHandshakeTimeout: connectTimeout,
+		MaxIdleConns:        10,
+		IdleConnTimeout:     90 * time.Second,
+		DisableCompression:  true,
+		MaxIdleConnsPerHost: 5,
+	}
+
+	tlsConfig, err := cfg.TLS.config()
+	if err != nil {
+		return nil, err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	proxy, err := cfg.Proxy.proxyFunc()
+	if err != nil {
+		return nil, err
+	}
+	transport.Proxy = proxy
+
+	var rt http.RoundTripper = transport
+	if header := cfg.Auth.header(); header != "" {
+		rt = &authTransport{base: transport, header: header}
+	}
+
+	return &http.Client{Transport: rt, Timeout: cfg.Timeout}, nil
+}
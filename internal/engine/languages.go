@@ -0,0 +1,129 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ironpark/tons/internal/langdetect"
+)
+
+// Support is how well an engine translates a language
+type Support string
+
+const (
+	SupportGood        Support = "good"
+	SupportLimited     Support = "limited"     // works, but expect errors and unnatural phrasing
+	SupportUnsupported Support = "unsupported" // output is likely unusable
+)
+
+// modelFamily is the language coverage of a group of models, matched by the
+// start of the engine or model name
+type modelFamily struct {
+	prefixes []string
+	langs    string // codes handled well; empty for broad multilingual coverage
+	others   Support
+}
+
+// modelFamilies lists what the models realistically support, from their
+// model cards. Agents run frontier models with broad coverage; the
+// multilingual open models handle a fixed set well and others passably;
+// English-only models produce unusable output in other languages.
+var modelFamilies = []modelFamily{
+	{prefixes: []string{"claude-code", "gemini-cli", "codex", "gemma", "gpt-oss"}},
+	{prefixes: []string{"qwen"}, langs: "zh en fr es pt de it ru ja ko vi th ar id tr nl pl cs", others: SupportLimited},
+	{prefixes: []string{"llama3", "llama-3"}, langs: "en de fr it pt hi es th", others: SupportLimited},
+	{prefixes: []string{"mistral", "mixtral"}, langs: "en fr de es it", others: SupportLimited},
+	{prefixes: []string{"aya"}, langs: "ar zh cs nl en fr de el he hi id it ja ko fa pl pt ro ru es tr uk vi", others: SupportLimited},
+	{prefixes: []string{"phi"}, langs: "en", others: SupportLimited},
+	{prefixes: []string{"tinyllama", "orca-mini"}, langs: "en", others: SupportUnsupported},
+}
+
+// generalLangs are the languages any general-purpose LLM handles well
+const generalLangs = "en zh es fr de ja ko pt it ru nl"
+
+// extraLanguages names languages of the capability tables that langdetect
+// does not know
+var extraLanguages = map[string]string{
+	"vietnamese": "vi", "indonesian": "id", "turkish": "tr", "polish": "pl",
+	"czech": "cs", "persian": "fa", "farsi": "fa", "romanian": "ro", "ukrainian": "uk",
+}
+
+// LanguageSupport is the rating of a language pair for an engine
+type LanguageSupport struct {
+	Support Support `json:"support"`
+	Lang    string  `json:"lang,omitempty"`    // the worst-rated language of the pair
+	Message string  `json:"message,omitempty"` // why, if it isn't good
+}
+
+// CheckLanguages rates a language pair for a model, given as an engine name
+// such as "ollama:qwen2.5:7b" or a model file name. An "auto" source is not
+// rated; languages the tables don't know are rated like any other language
+// outside the model's well-supported set.
+func CheckLanguages(model, sourceLang, targetLang string) LanguageSupport {
+	name := strings.ToLower(model)
+	name = strings.TrimPrefix(name, "ollama:")
+	name = name[strings.LastIndexAny(name, `/\`)+1:]
+
+	langs, others := generalLangs, SupportLimited
+	for _, f := range modelFamilies {
+		if hasAnyPrefix(name, f.prefixes) {
+			langs, others = f.langs, f.others
+			break
+		}
+	}
+
+	result := LanguageSupport{Support: SupportGood}
+	for _, lang := range []string{sourceLang, targetLang} {
+		if lang == "" || strings.EqualFold(lang, langdetect.Auto) || langs == "" {
+			continue
+		}
+		if code := languageCode(lang); code != "" && strings.Contains(" "+langs+" ", " "+code+" ") {
+			continue
+		}
+		if rank(others) > rank(result.Support) {
+			result = LanguageSupport{Support: others, Lang: lang}
+			if others == SupportUnsupported {
+				result.Message = fmt.Sprintf("%s does not support %s", model, lang)
+			} else {
+				result.Message = fmt.Sprintf("%s has limited support for %s; expect a rough translation", model, lang)
+			}
+		}
+	}
+	return result
+}
+
+// languageCode returns the ISO 639-1 code of a language code or name, or ""
+func languageCode(lang string) string {
+	if code := langdetect.Code(lang); code != langdetect.Unknown {
+		return code
+	}
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if code, ok := extraLanguages[lang]; ok {
+		return code
+	}
+	if base, _, _ := strings.Cut(strings.ReplaceAll(lang, "_", "-"), "-"); len(base) == 2 {
+		return base
+	}
+	return ""
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// rank orders supports from best to worst
+func rank(s Support) int {
+	switch s {
+	case SupportLimited:
+		return 1
+	case SupportUnsupported:
+		return 2
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// SizeLimiter is implemented by engines configured to refuse texts over a
+// size, before they hit the command line limit of an agent or keep a local
+// model busy for a very long time
+type SizeLimiter interface {
+	// MaxTextChars returns the most characters translated at once, or 0 if unlimited
+	MaxTextChars() int
+}
+
+// TooLargeError is returned for texts over the size limit of an engine
+type TooLargeError struct {
+	Engine      string   `json:"engine"`
+	Chars       int      `json:"chars"`
+	Limit       int      `json:"limit"`
+	Suggestions []string `json:"suggestions"` // what the user can do instead
+}
+
+// tooLargeSuggestions are the ways around an engine's size limit
+var tooLargeSuggestions = []string{
+	"split the text and translate the parts one at a time",
+	"translate it as a file, which is sent to the engine in parts",
+	"raise the engine's input limit in the settings, or use the smart engine to send long texts to another engine",
+}
+
+func (e *TooLargeError) Error() string {
+	return fmt.Sprintf("text is too long for %s: %d characters, the limit is %d; %s",
+		e.Engine, e.Chars, e.Limit, strings.Join(e.Suggestions, ", or "))
+}
+
+// CheckSize returns a *TooLargeError if req's text is over the size limit of e
+func CheckSize(e Engine, req Request) error {
+	limiter, ok := e.(SizeLimiter)
+	if !ok || limiter.MaxTextChars() <= 0 || len(req.Text) <= limiter.MaxTextChars() {
+		return nil
+	}
+	chars := utf8.RuneCountInString(req.Text)
+	if chars <= limiter.MaxTextChars() {
+		return nil
+	}
+	return &TooLargeError{
+		Engine:      e.Name(),
+		Chars:       chars,
+		Limit:       limiter.MaxTextChars(),
+		Suggestions: tooLargeSuggestions,
+	}
+}
+
+// MaxTextChars returns the longest text accepted
+func (e *Ollama) MaxTextChars() int {
+	return e.MaxChars
+}
+
+// MaxTextChars returns the longest text accepted
+func (e *Yzma) MaxTextChars() int {
+	return e.MaxChars
+}
+
+// MaxTextChars returns the longest text accepted
+func (e *TerminalEngine) MaxTextChars() int {
+	return e.config.MaxChars
+}
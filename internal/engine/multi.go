@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"context"
+	"sync"
+)
+
+// TargetResponse is a streamed response tagged with its target language
+type TargetResponse struct {
+	TargetLang string `json:"targetLang"`
+	Response
+}
+
+// TranslateMulti translates req into every target language, running at most
+// parallel translations at once. Responses of all targets are interleaved on
+// the returned channel, which is closed when every target has finished.
+func TranslateMulti(ctx context.Context, e Engine, req Request, targets []string, parallel int) <-chan TargetResponse {
+	if parallel < 1 {
+		parallel = 1
+	}
+
+	ch := make(chan TargetResponse)
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+
+	for _, target := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				ch <- TargetResponse{TargetLang: target, Response: ErrorResponse(ctx.Err().Error())}
+				return
+			}
+
+			targetReq := req
+			targetReq.TargetLang = target
+			resCh, err := e.TranslateStream(ctx, targetReq)
+			if err != nil {
+				ch <- TargetResponse{TargetLang: target, Response: ErrorResponse(err.Error())}
+				return
+			}
+			for res := range resCh {
+				ch <- TargetResponse{TargetLang: target, Response: res}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(ch)
+	}()
+	return ch
+}
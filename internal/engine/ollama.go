@@ -2,22 +2,40 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"net/http"
+	"log/slog"
 	"net/url"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/ironpark/tons/internal/tokens"
 	"github.com/ollama/ollama/api"
 )
 
 // Ollama uses Ollama for translation
 type Ollama struct {
-	Host     string
-	Model    string
-	Timeout  time.Duration
-	Sampling SamplingConfig
-	client   *api.Client
+	Host        string
+	Model       string
+	Startup     time.Duration // time to the first output, e.g. loading the model; 0 to count it in Timeout and MaxDuration
+	Timeout     time.Duration // time without output before giving up
+	MaxDuration time.Duration // overall limit of a translation, 0 for none
+	MaxChars    int           // longest text accepted, 0 for no limit
+	Sampling    SamplingConfig
+	Auth        HTTPAuth
+	TLS         TLSOptions
+	Proxy       ProxyOptions
+	Options     map[string]any // extra model options merged over Sampling (num_ctx, seed, stop, ...)
+	client      *api.Client
+	log         *slog.Logger
+
+	infoMu    sync.Mutex
+	info      *OllamaModelInfo // cached Show result for Model
+	infoErr   error            // last Show failure, retried after showRetryDelay
+	infoErrAt time.Time
+	infoFetch chan struct{} // closed when the Show call in flight returns
 }
 
 // OllamaOption is a functional option for configuring Ollama
@@ -30,13 +48,35 @@ func WithOllamaHost(host string) OllamaOption {
 	}
 }
 
-// WithOllamaTimeout sets the request timeout
+// WithOllamaTimeout sets how long a request may go without output
 func WithOllamaTimeout(timeout time.Duration) OllamaOption {
 	return func(o *Ollama) {
 		o.Timeout = timeout
 	}
 }
 
+// WithOllamaStartupTimeout sets the time allowed for connecting and loading
+// the model up to the first output, after which the other timeouts start
+func WithOllamaStartupTimeout(startup time.Duration) OllamaOption {
+	return func(o *Ollama) {
+		o.Startup = startup
+	}
+}
+
+// WithOllamaMaxDuration sets the overall limit of a translation
+func WithOllamaMaxDuration(limit time.Duration) OllamaOption {
+	return func(o *Ollama) {
+		o.MaxDuration = limit
+	}
+}
+
+// WithOllamaMaxChars sets the longest text accepted
+func WithOllamaMaxChars(limit int) OllamaOption {
+	return func(o *Ollama) {
+		o.MaxChars = limit
+	}
+}
+
 // WithOllamaSampling sets the sampling configuration
 func WithOllamaSampling(cfg SamplingConfig) OllamaOption {
 	return func(o *Ollama) {
@@ -44,13 +84,53 @@ func WithOllamaSampling(cfg SamplingConfig) OllamaOption {
 	}
 }
 
+// WithOllamaAuth sets bearer or basic auth credentials, for hosts behind an authenticating reverse proxy
+func WithOllamaAuth(auth HTTPAuth) OllamaOption {
+	return func(o *Ollama) {
+		o.Auth = auth
+	}
+}
+
+// WithOllamaTLS sets a custom CA certificate and/or disables certificate verification
+func WithOllamaTLS(opts TLSOptions) OllamaOption {
+	return func(o *Ollama) {
+		o.TLS = opts
+	}
+}
+
+// WithOllamaProxy sets the outbound proxy
+func WithOllamaProxy(opts ProxyOptions) OllamaOption {
+	return func(o *Ollama) {
+		o.Proxy = opts
+	}
+}
+
+// WithOllamaLogger sets the logger of the engine
+func WithOllamaLogger(logger *slog.Logger) OllamaOption {
+	return func(o *Ollama) {
+		if logger != nil {
+			o.log = logger
+		}
+	}
+}
+
+// WithOllamaOptions sets additional model options passed through to Ollama.
+// The special key "keep_alive" is sent as the request's keep-alive duration.
+func WithOllamaOptions(options map[string]any) OllamaOption {
+	return func(o *Ollama) {
+		o.Options = options
+	}
+}
+
 // NewOllama creates a new Ollama engine with optional configuration
 func NewOllama(model string, opts ...OllamaOption) *Ollama {
 	o := &Ollama{
-		Host:     "http://localhost:11434",
-		Model:    model,
-		Timeout:  120 * time.Second,
-		Sampling: DefaultSamplingConfig(),
+		Host:        "http://localhost:11434",
+		Model:       model,
+		Timeout:     120 * time.Second,
+		MaxDuration: 15 * time.Minute,
+		Sampling:    DefaultSamplingConfig(),
+		log:         slog.Default(),
 	}
 	for _, opt := range opts {
 		opt(o)
@@ -61,14 +141,12 @@ func NewOllama(model string, opts ...OllamaOption) *Ollama {
 	if err != nil {
 		hostURL, _ = url.Parse("http://localhost:11434")
 	}
-	o.client = api.NewClient(hostURL, &http.Client{
-		Transport: &http.Transport{
-			MaxIdleConns:        10,
-			IdleConnTimeout:     90 * time.Second,
-			DisableCompression:  true,
-			MaxIdleConnsPerHost: 5,
-		},
-	})
+	httpClient, err := newHTTPClient(httpClientConfig{Auth: o.Auth, TLS: o.TLS, Proxy: o.Proxy})
+	if err != nil {
+		o.log.Warn("invalid ollama connection settings, using system defaults", "error", err)
+		httpClient, _ = newHTTPClient(httpClientConfig{Auth: o.Auth})
+	}
+	o.client = api.NewClient(hostURL, httpClient)
 
 	return o
 }
@@ -98,9 +176,9 @@ func (e *Ollama) Close() error {
 	return nil
 }
 
-// buildGenerateRequest creates a GenerateRequest with sampling options
+// buildGenerateRequest creates a GenerateRequest with sampling and user options
 func (e *Ollama) buildGenerateRequest(prompt string) *api.GenerateRequest {
-	return &api.GenerateRequest{
+	req := &api.GenerateRequest{
 		Model:  e.Model,
 		Prompt: prompt,
 		Options: map[string]any{
@@ -109,6 +187,56 @@ func (e *Ollama) buildGenerateRequest(prompt string) *api.GenerateRequest {
 			"num_predict": e.Sampling.MaxTokens,
 		},
 	}
+	if e.Sampling.TopK > 0 {
+		req.Options["top_k"] = e.Sampling.TopK
+	}
+	if e.Sampling.RepeatPenalty > 0 {
+		req.Options["repeat_penalty"] = e.Sampling.RepeatPenalty
+	}
+
+	for key, value := range e.Options {
+		if key == "keep_alive" {
+			if keepAlive, ok := parseKeepAlive(value); ok {
+				req.KeepAlive = &api.Duration{Duration: keepAlive}
+			} else {
+				e.log.Warn("ignoring invalid ollama keep_alive", "value", value)
+			}
+			continue
+		}
+		req.Options[key] = value
+	}
+
+	return req
+}
+
+// ollamaUsage converts Ollama eval statistics into a Usage
+func ollamaUsage(m api.Metrics) *Usage {
+	return &Usage{
+		PromptTokens:       m.PromptEvalCount,
+		CompletionTokens:   m.EvalCount,
+		LoadDuration:       m.LoadDuration,
+		PromptEvalDuration: m.PromptEvalDuration,
+		EvalDuration:       m.EvalDuration,
+		TotalDuration:      m.TotalDuration,
+	}
+}
+
+// parseKeepAlive accepts a duration string ("5m", "-1") or a number of seconds
+func parseKeepAlive(value any) (time.Duration, bool) {
+	switch v := value.(type) {
+	case string:
+		if d, err := time.ParseDuration(v); err == nil {
+			return d, true
+		}
+		if n, err := strconv.Atoi(v); err == nil {
+			return time.Duration(n) * time.Second, true
+		}
+	case float64:
+		return time.Duration(v * float64(time.Second)), true
+	case int:
+		return time.Duration(v) * time.Second, true
+	}
+	return 0, false
 }
 
 // Translate performs translation using Ollama (non-streaming)
@@ -117,27 +245,37 @@ func (e *Ollama) Translate(ctx context.Context, req Request) (Response, error) {
 		return Response{Text: "", Done: true}, nil
 	}
 
-	prompt := BuildPrompt(req.Prompt, req.Text, req.SourceLang, req.TargetLang)
+	prompt, err := req.RenderPrompt()
+	if err != nil {
+		return Response{}, err
+	}
 
-	ctx, cancel := context.WithTimeout(ctx, e.Timeout)
-	defer cancel()
+	t := withTimeouts(ctx, e.Startup, e.Timeout, e.MaxDuration)
+	defer t.stop()
 
+	e.warnIfPromptTooLong(t.ctx, prompt)
 	genReq := e.buildGenerateRequest(prompt)
 
 	var result strings.Builder
-	err := e.client.Generate(ctx, genReq, func(resp api.GenerateResponse) error {
+	var usage *Usage
+	err = e.client.Generate(t.ctx, genReq, func(resp api.GenerateResponse) error {
+		t.touch()
 		result.WriteString(resp.Response)
+		if resp.Done {
+			usage = ollamaUsage(resp.Metrics)
+		}
 		return nil
 	})
 
 	if err != nil {
-		if ctx.Err() == context.DeadlineExceeded {
-			return Response{}, fmt.Errorf("translation timed out")
+		if t.timedOut() {
+			text := strings.TrimSpace(result.String())
+			return Response{Text: text, Done: true, Partial: text != ""}, errors.New(t.timeoutMessage())
 		}
 		return Response{}, fmt.Errorf("ollama error: %w", err)
 	}
 
-	return Response{Text: strings.TrimSpace(result.String()), Done: true}, nil
+	return Response{Text: strings.TrimSpace(result.String()), Done: true, Usage: usage}, nil
 }
 
 // TranslateStream performs streaming translation using Ollama
@@ -152,26 +290,39 @@ func (e *Ollama) TranslateStream(ctx context.Context, req Request) (<-chan Respo
 			return
 		}
 
-		prompt := BuildPrompt(req.Prompt, req.Text, req.SourceLang, req.TargetLang)
+		prompt, err := req.RenderPrompt()
+		if err != nil {
+			ch <- ErrorResponse(err.Error())
+			return
+		}
 
-		ctx, cancel := context.WithTimeout(ctx, e.Timeout)
-		defer cancel()
+		t := withTimeouts(ctx, e.Startup, e.Timeout, e.MaxDuration)
+		defer t.stop()
+		ctx := t.ctx
 
+		e.warnIfPromptTooLong(ctx, prompt)
 		genReq := e.buildGenerateRequest(prompt)
 
-		err := e.client.Generate(ctx, genReq, func(resp api.GenerateResponse) error {
+		streamed := false
+		err = e.client.Generate(ctx, genReq, func(resp api.GenerateResponse) error {
+			t.touch()
 			select {
 			case <-ctx.Done():
 				return ctx.Err()
 			default:
-				ch <- Response{Text: resp.Response, Done: resp.Done}
+				streamed = streamed || resp.Response != ""
+				res := Response{Text: resp.Response, Done: resp.Done}
+				if resp.Done {
+					res.Usage = ollamaUsage(resp.Metrics)
+				}
+				ch <- res
 				return nil
 			}
 		})
 
 		if err != nil {
-			if ctx.Err() == context.DeadlineExceeded {
-				ch <- ErrorResponse("translation timed out")
+			if ctx.Err() != nil {
+				ch <- stoppedResponse(t, streamed)
 			} else {
 				ch <- ErrorResponsef("ollama error: %v", err)
 			}
@@ -210,21 +361,156 @@ func (e *Ollama) GetModels() ([]Model, error) {
 	return models, nil
 }
 
-// OllamaModels returns available Ollama model names from a host
-func OllamaModels(host string) ([]string, error) {
-	hostURL, err := url.Parse(host)
+// OllamaModelInfo holds metadata reported by the Ollama Show API
+type OllamaModelInfo struct {
+	Name              string `json:"name"`
+	Family            string `json:"family"`
+	Format            string `json:"format"`
+	ParameterSize     string `json:"parameterSize"`
+	QuantizationLevel string `json:"quantizationLevel"`
+	ContextLength     int    `json:"contextLength"` // 0 if unknown
+}
+
+// showRetryDelay is how long a failed Show call is remembered, so an
+// unreachable endpoint doesn't add a round trip to every request
+const showRetryDelay = time.Minute
+
+// ShowModel fetches metadata for the configured model.
+// The result is cached for the lifetime of the engine, a failure for
+// showRetryDelay. Concurrent callers share one Show call.
+func (e *Ollama) ShowModel(ctx context.Context) (OllamaModelInfo, error) {
+	for {
+		e.infoMu.Lock()
+		if e.info != nil {
+			info := *e.info
+			e.infoMu.Unlock()
+			return info, nil
+		}
+		if e.infoErr != nil && time.Since(e.infoErrAt) < showRetryDelay {
+			err := e.infoErr
+			e.infoMu.Unlock()
+			return OllamaModelInfo{}, err
+		}
+		if fetch := e.infoFetch; fetch != nil {
+			e.infoMu.Unlock()
+			select {
+			case <-fetch:
+				continue
+			case <-ctx.Done():
+				return OllamaModelInfo{}, ctx.Err()
+			}
+		}
+		fetch := make(chan struct{})
+		e.infoFetch = fetch
+		e.infoMu.Unlock()
+
+		info, err := showModel(ctx, e.client, e.Model)
+
+		e.infoMu.Lock()
+		e.infoFetch = nil
+		switch {
+		case err == nil:
+			e.info, e.infoErr = &info, nil
+		case ctx.Err() == nil:
+			// The caller giving up says nothing about the endpoint
+			e.infoErr, e.infoErrAt = err, time.Now()
+		}
+		e.infoMu.Unlock()
+		close(fetch)
+		return info, err
+	}
+}
+
+// ContextLength returns the model's maximum context length in tokens, or 0 if unknown
+func (e *Ollama) ContextLength(ctx context.Context) int {
+	info, err := e.ShowModel(ctx)
 	if err != nil {
-		hostURL, _ = url.Parse("http://localhost:11434")
+		return 0
 	}
+	return info.ContextLength
+}
 
-	client := api.NewClient(hostURL, &http.Client{
-		Timeout: 10 * time.Second,
-	})
+// warnIfPromptTooLong logs a warning when the estimated prompt size exceeds the model context
+func (e *Ollama) warnIfPromptTooLong(ctx context.Context, prompt string) {
+	limit := e.numCtx(ctx)
+	// Ollama has no tokenizer endpoint; estimate
+	if n := tokens.Estimate(prompt); n > limit {
+		e.log.Warn("prompt may not fit model context", "model", e.Model, "estimatedTokens", n, "contextLength", limit)
+	}
+}
+
+// showModel queries the Show API and extracts the fields tons cares about
+func showModel(ctx context.Context, client *api.Client, model string) (OllamaModelInfo, error) {
+	resp, err := client.Show(ctx, &api.ShowRequest{Model: model})
+	if err != nil {
+		return OllamaModelInfo{}, fmt.Errorf("ollama show error: %w", err)
+	}
+
+	return OllamaModelInfo{
+		Name:              model,
+		Family:            resp.Details.Family,
+		Format:            resp.Details.Format,
+		ParameterSize:     resp.Details.ParameterSize,
+		QuantizationLevel: resp.Details.QuantizationLevel,
+		ContextLength:     contextLengthFromModelInfo(resp.ModelInfo),
+	}, nil
+}
+
+// contextLengthFromModelInfo finds the "<arch>.context_length" entry in model_info
+func contextLengthFromModelInfo(modelInfo map[string]any) int {
+	for key, value := range modelInfo {
+		if !strings.HasSuffix(key, ".context_length") {
+			continue
+		}
+		switch v := value.(type) {
+		case float64:
+			return int(v)
+		case int:
+			return v
+		case int64:
+			return int(v)
+		}
+	}
+	return 0
+}
+
+// defaultOllamaNumCtx is the context window Ollama allocates when num_ctx is not set
+const defaultOllamaNumCtx = 4096
+
+// numCtx returns the effective context window: the num_ctx option if set,
+// otherwise Ollama's default capped by the model's maximum
+func (e *Ollama) numCtx(ctx context.Context) int {
+	switch v := e.Options["num_ctx"].(type) {
+	case float64:
+		return int(v)
+	case int:
+		return v
+	}
+	limit := e.ContextLength(ctx)
+	if limit == 0 || limit > defaultOllamaNumCtx {
+		return defaultOllamaNumCtx
+	}
+	return limit
+}
+
+// GetOllamaModelInfo returns metadata for a model on the given host
+func GetOllamaModelInfo(host, model string, opts ...OllamaOption) (OllamaModelInfo, error) {
+	e := NewOllama(model, append([]OllamaOption{WithOllamaHost(host)}, opts...)...)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	return showModel(ctx, e.client, model)
+}
+
+// OllamaModels returns available Ollama model names from a host
+func OllamaModels(host string, opts ...OllamaOption) ([]string, error) {
+	e := NewOllama("", append([]OllamaOption{WithOllamaHost(host)}, opts...)...)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
-	listResp, err := client.List(ctx)
+	listResp, err := e.client.List(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,60 @@
+package engine_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ironpark/tons/internal/engine"
+	"github.com/ironpark/tons/pkg/enginetest"
+	"github.com/ironpark/tons/pkg/tons"
+)
+
+// fakeOllama serves the parts of the Ollama API the engine uses, streaming
+// a fixed translation word by word
+func fakeOllama(t *testing.T) *httptest.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/tags", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{"models": []map[string]any{{"name": "fake", "model": "fake"}}})
+	})
+	mux.HandleFunc("/api/show", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]any{
+			"details":    map[string]any{"family": "fake"},
+			"model_info": map[string]any{"fake.context_length": 8192},
+		})
+	})
+	mux.HandleFunc("/api/generate", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Prompt string `json:"prompt"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Prompt == "" {
+			http.Error(w, `{"error":"no prompt"}`, http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		enc := json.NewEncoder(w)
+		for _, word := range []string{"Das ", "Wetter ", "ist ", "heute ", "schön."} {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(5 * time.Millisecond):
+			}
+			enc.Encode(map[string]any{"model": "fake", "response": word, "done": false})
+			w.(http.Flusher).Flush()
+		}
+		enc.Encode(map[string]any{"model": "fake", "response": "", "done": true, "eval_count": 5})
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestOllamaConformance(t *testing.T) {
+	srv := fakeOllama(t)
+	enginetest.RunConformance(t, func(t *testing.T) tons.Engine {
+		return engine.NewOllama("fake", engine.WithOllamaHost(srv.URL))
+	})
+}
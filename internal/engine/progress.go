@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"context"
+	"time"
+)
+
+// Progress reports how far a long translation is, in chunks of a long text
+// or segments of a document
+type Progress struct {
+	Chunk      int           `json:"chunk"` // number of chunks completed
+	Total      int           `json:"total"`
+	Bytes      int           `json:"bytes"`      // source text of the completed chunks
+	TotalBytes int           `json:"totalBytes"` // source text of all chunks
+	ETA        time.Duration `json:"eta"`        // estimated time left from the pace so far, 0 if unknown
+}
+
+// Percent returns the completed share of the source text from 0 to 100
+func (p Progress) Percent() float64 {
+	if p.TotalBytes > 0 {
+		return float64(p.Bytes) * 100 / float64(p.TotalBytes)
+	}
+	if p.Total > 0 {
+		return float64(p.Chunk) * 100 / float64(p.Total)
+	}
+	return 0
+}
+
+// progressKey is the context key of the progress callback
+type progressKey struct{}
+
+// WithProgress returns a context whose long translations report their
+// progress to fn, for callers of Translate that get no streamed Progress.
+// A nil fn stops reports from reaching an outer callback.
+func WithProgress(ctx context.Context, fn func(Progress)) context.Context {
+	return context.WithValue(ctx, progressKey{}, fn)
+}
+
+// progressTracker measures the progress of a translation in parts
+type progressTracker struct {
+	start      time.Time
+	total      int
+	totalBytes int
+	done       int
+	doneBytes  int
+	report     func(Progress) // from the context, may be nil
+}
+
+// newProgressTracker starts tracking a translation of parts with the given sizes
+func newProgressTracker(ctx context.Context, sizes []int) *progressTracker {
+	t := &progressTracker{start: time.Now(), total: len(sizes)}
+	for _, n := range sizes {
+		t.totalBytes += n
+	}
+	t.report, _ = ctx.Value(progressKey{}).(func(Progress))
+	return t
+}
+
+// add records a completed part of size bytes, reports the progress to the
+// context's callback and returns it
+func (t *progressTracker) add(size int) Progress {
+	t.done++
+	t.doneBytes += size
+	p := t.progress()
+	if t.report != nil {
+		t.report(p)
+	}
+	return p
+}
+
+// progress returns the progress so far, reporting nothing
+func (t *progressTracker) progress() Progress {
+	p := Progress{Chunk: t.done, Total: t.total, Bytes: t.doneBytes, TotalBytes: t.totalBytes}
+	if t.doneBytes > 0 && t.doneBytes < t.totalBytes {
+		elapsed := time.Since(t.start)
+		p.ETA = time.Duration(float64(elapsed) * float64(t.totalBytes-t.doneBytes) / float64(t.doneBytes)).Round(time.Second)
+	}
+	return p
+}
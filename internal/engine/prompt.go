@@ -0,0 +1,123 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ironpark/tons/internal/langdetect"
+)
+
+// Formality is the requested register of the translation
+type Formality string
+
+const (
+	FormalityDefault   Formality = ""          // let the engine decide
+	FormalityFormal    Formality = "formal"    // polite, professional register
+	FormalityInformal  Formality = "informal"  // casual register
+	FormalityHonorific Formality = "honorific" // honorific speech (Korean 존댓말, Japanese 敬語)
+)
+
+// instruction returns a prompt sentence describing the formality
+func (f Formality) instruction() string {
+	switch f {
+	case FormalityFormal:
+		return "Use a formal, polite register."
+	case FormalityInformal:
+		return "Use a casual, informal register."
+	case FormalityHonorific:
+		return "Use honorific speech (e.g. Korean 존댓말 or Japanese 敬語) throughout."
+	default:
+		return ""
+	}
+}
+
+// Example is a few-shot source/target pair. Empty languages match any pair.
+type Example struct {
+	SourceLang string `json:"sourceLang,omitempty"`
+	TargetLang string `json:"targetLang,omitempty"`
+	Source     string `json:"source"`
+	Target     string `json:"target"`
+}
+
+// matchLang reports whether an example language matches a request language
+func matchLang(exampleLang, reqLang string) bool {
+	if exampleLang == "" || reqLang == "" || reqLang == langdetect.Auto {
+		return true
+	}
+	if strings.EqualFold(exampleLang, reqLang) {
+		return true
+	}
+	code := langdetect.Code(exampleLang)
+	return code != langdetect.Unknown && code == langdetect.Code(reqLang)
+}
+
+// renderExamples formats the examples matching the request's language pair
+func (r Request) renderExamples() string {
+	var b strings.Builder
+	for _, ex := range r.Examples {
+		if !matchLang(ex.SourceLang, r.SourceLang) || !matchLang(ex.TargetLang, r.TargetLang) {
+			continue
+		}
+		if b.Len() == 0 {
+			b.WriteString("Examples:\n")
+		}
+		b.WriteString("\nInput: " + ex.Source + "\nOutput: " + ex.Target + "\n")
+	}
+	return b.String()
+}
+
+// RenderPrompt builds the final prompt from the request template.
+//
+// Templates use text/template syntax with PromptData; the legacy {{text}},
+// {{source_lang}}, {{target_lang}}, {{formality}}, {{tone}}, {{context}} and
+// {{examples}} placeholders still work. Options the template does not
+// reference, and the output constraints, are turned into instructions
+// prepended to the prompt.
+func (r Request) RenderPrompt() (string, error) {
+	t, err := parsePrompt(r.Prompt)
+	if err != nil {
+		return "", fmt.Errorf("invalid prompt template: %w", err)
+	}
+
+	examples := r.renderExamples()
+	var b strings.Builder
+	err = t.Execute(&b, PromptData{
+		Text:       r.Text,
+		SourceLang: r.SourceLang,
+		TargetLang: r.TargetLang,
+		Formality:  string(r.Formality),
+		Tone:       r.Tone,
+		Context:    r.Context,
+		Examples:   examples,
+		Glossary:   r.Glossary,
+		Vars:       r.Variables,
+	})
+	if err != nil {
+		return "", fmt.Errorf("prompt template: %w", err)
+	}
+	prompt := b.String()
+
+	var instructions []string
+	if r.Context != "" && !referencesField(t, "Context") {
+		instructions = append(instructions, "Context for disambiguation (do not translate it): "+r.Context)
+	}
+	if !referencesField(t, "Formality") && !referencesField(t, "Tone") {
+		if s := r.Formality.instruction(); s != "" {
+			instructions = append(instructions, s)
+		}
+		if r.Tone != "" {
+			instructions = append(instructions, "Use a "+r.Tone+" tone.")
+		}
+	}
+	if glossary := renderGlossary(r.Glossary); glossary != "" && !referencesField(t, "Glossary") {
+		instructions = append(instructions, glossary)
+	}
+	instructions = append(instructions, r.Constraints.instructions(r.Text)...)
+	if examples != "" && !referencesField(t, "Examples") {
+		instructions = append(instructions, examples)
+	}
+	if len(instructions) == 0 {
+		return prompt, nil
+	}
+	return strings.Join(instructions, "\n") + "\n\n" + prompt, nil
+}
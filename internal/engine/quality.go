@@ -0,0 +1,120 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+)
+
+// Quality is an engine's assessment of a translation
+type Quality struct {
+	Adequacy int    `json:"adequacy"` // 1-5: how much of the meaning is preserved
+	Fluency  int    `json:"fluency"`  // 1-5: how natural the translation reads
+	Critique string `json:"critique"`
+}
+
+// Score returns the mean of adequacy and fluency
+func (q Quality) Score() float64 {
+	return float64(q.Adequacy+q.Fluency) / 2
+}
+
+// Confidence maps the score to the 0 to 1 range of Response.Confidence
+func (q Quality) Confidence() float64 {
+	return (q.Score() - 1) / 4
+}
+
+// qualityPrompt asks the engine to rate a translation as JSON
+const qualityPrompt = `Rate the following translation from {{.SourceLang}} to {{.TargetLang}}.
+
+Source:
+{{.Text}}
+
+Translation:
+{{.Vars.translation}}
+
+Score adequacy (meaning preserved) and fluency (natural target language) from 1 (worst) to 5 (best)
+and give a one-sentence critique. Respond with JSON only, in this exact form:
+{"adequacy": 4, "fluency": 5, "critique": "..."}`
+
+// EstimateQuality asks e to rate translation as a translation of req.Text
+func EstimateQuality(ctx context.Context, e Engine, req Request, translation string) (Quality, error) {
+	qeReq := Request{
+		Text:         req.Text,
+		SourceLang:   req.SourceLang,
+		TargetLang:   req.TargetLang,
+		Prompt:       qualityPrompt,
+		SystemPrompt: "You are a meticulous translation reviewer.",
+		Variables:    map[string]string{"translation": translation},
+	}
+
+	res, err := e.Translate(ctx, qeReq)
+	if err != nil {
+		return Quality{}, fmt.Errorf("quality estimation failed: %w", err)
+	}
+	return parseQuality(res.Text)
+}
+
+// parseQuality extracts the JSON object from the engine output
+func parseQuality(output string) (Quality, error) {
+	var q Quality
+	if err := extractJSON(output, &q); err != nil {
+		return Quality{}, fmt.Errorf("quality estimation failed: %w", err)
+	}
+	q.Adequacy = clampScore(q.Adequacy)
+	q.Fluency = clampScore(q.Fluency)
+	return q, nil
+}
+
+// clampScore limits a score to the 1-5 range
+func clampScore(n int) int {
+	return min(max(n, 1), 5)
+}
+
+// QualityEstimator wraps an engine and rates every completed translation
+// with a second pass, attaching the result to the final response
+type QualityEstimator struct {
+	Engine
+	Judge Engine // engine used for rating; defaults to the wrapped engine
+}
+
+// NewQualityEstimator wraps e with quality estimation
+func NewQualityEstimator(e Engine) *QualityEstimator {
+	return &QualityEstimator{Engine: e, Judge: e}
+}
+
+// Translate translates and rates the result. Rating failures are not fatal.
+func (q *QualityEstimator) Translate(ctx context.Context, req Request) (Response, error) {
+	res, err := q.Engine.Translate(ctx, req)
+	if err != nil || res.Text == "" {
+		return res, err
+	}
+	if quality, err := EstimateQuality(ctx, q.Judge, req, res.Text); err == nil {
+		confidence := quality.Confidence()
+		res.Quality, res.Confidence = &quality, &confidence
+	}
+	return res, nil
+}
+
+// TranslateStream streams the translation and rates it before sending the final response
+func (q *QualityEstimator) TranslateStream(ctx context.Context, req Request) (<-chan Response, error) {
+	inner, err := q.Engine.TranslateStream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan Response)
+	go func() {
+		defer close(ch)
+		var full Accumulator
+		for res := range inner {
+			full.Add(res)
+			if res.Done && res.Error == "" && full.Len() > 0 {
+				if quality, err := EstimateQuality(ctx, q.Judge, req, full.String()); err == nil {
+					confidence := quality.Confidence()
+					res.Quality, res.Confidence = &quality, &confidence
+				}
+			}
+			ch <- res
+		}
+	}()
+	return ch, nil
+}
@@ -0,0 +1,206 @@
+package engine
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// Priority orders requests waiting in a Queue
+type Priority int
+
+const (
+	PriorityInteractive Priority = iota // the user is waiting for the result
+	PriorityBackground                  // batch and file translations
+)
+
+// Queue limits how many requests run at once, starting waiting requests by
+// priority and then in arrival order
+type Queue struct {
+	mu      sync.Mutex
+	limit   int
+	running int
+	waiting []*waiter
+	seq     uint64
+}
+
+// waiter is a request waiting for a slot
+type waiter struct {
+	priority   Priority
+	seq        uint64
+	ready      chan struct{}
+	onPosition func(position int)
+}
+
+// NewQueue creates a queue running at most limit requests at once (minimum 1)
+func NewQueue(limit int) *Queue {
+	return &Queue{limit: max(1, limit)}
+}
+
+// SetLimit changes how many requests run at once (minimum 1)
+func (q *Queue) SetLimit(limit int) {
+	q.mu.Lock()
+	q.limit = max(1, limit)
+	notify := q.dispatch()
+	q.mu.Unlock()
+	notify()
+}
+
+// Acquire waits for a slot and returns a function releasing it.
+// While waiting, onPosition (optional) is called with the 1-based position in
+// the queue whenever it changes, and with 0 once the request starts.
+func (q *Queue) Acquire(ctx context.Context, priority Priority, onPosition func(position int)) (release func(), err error) {
+	q.mu.Lock()
+	if q.running < q.limit && len(q.waiting) == 0 {
+		q.running++
+		q.mu.Unlock()
+		return q.releaser(), nil
+	}
+	q.seq++
+	w := &waiter{priority: priority, seq: q.seq, ready: make(chan struct{}), onPosition: onPosition}
+	i, _ := slices.BinarySearchFunc(q.waiting, w, compareWaiters)
+	q.waiting = slices.Insert(q.waiting, i, w)
+	notify := q.positions()
+	q.mu.Unlock()
+	notify()
+
+	select {
+	case <-w.ready:
+		return q.releaser(), nil
+	case <-ctx.Done():
+		q.mu.Lock()
+		i := slices.Index(q.waiting, w)
+		if i < 0 {
+			// Started just as the context ended; hand the slot on
+			q.mu.Unlock()
+			q.releaser()()
+			return nil, ctx.Err()
+		}
+		q.waiting = slices.Delete(q.waiting, i, i+1)
+		notify := q.positions()
+		q.mu.Unlock()
+		notify()
+		return nil, ctx.Err()
+	}
+}
+
+// Waiting returns the number of requests waiting for a slot
+func (q *Queue) Waiting() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.waiting)
+}
+
+// releaser returns a function freeing one slot, safe to call more than once
+func (q *Queue) releaser() func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			q.mu.Lock()
+			q.running--
+			notify := q.dispatch()
+			q.mu.Unlock()
+			notify()
+		})
+	}
+}
+
+// dispatch starts waiting requests while slots are free and returns the
+// position callbacks to run after unlocking. Must be called with q.mu held.
+func (q *Queue) dispatch() (notify func()) {
+	var started []*waiter
+	for q.running < q.limit && len(q.waiting) > 0 {
+		w := q.waiting[0]
+		q.waiting = q.waiting[1:]
+		q.running++
+		close(w.ready)
+		started = append(started, w)
+	}
+	if len(started) == 0 {
+		return func() {}
+	}
+	moved := q.positions()
+	return func() {
+		for _, w := range started {
+			if w.onPosition != nil {
+				w.onPosition(0)
+			}
+		}
+		moved()
+	}
+}
+
+// positions returns a function reporting the current position of every
+// waiting request. Must be called with q.mu held.
+func (q *Queue) positions() (notify func()) {
+	waiting := slices.Clone(q.waiting)
+	return func() {
+		for i, w := range waiting {
+			if w.onPosition != nil {
+				w.onPosition(i + 1)
+			}
+		}
+	}
+}
+
+// compareWaiters orders waiters by priority, then arrival
+func compareWaiters(a, b *waiter) int {
+	if a.priority != b.priority {
+		return int(a.priority) - int(b.priority)
+	}
+	return int(a.seq) - int(b.seq)
+}
+
+// Queued wraps an engine so that its requests wait for a slot in a Queue
+type Queued struct {
+	Engine
+	Queue      *Queue
+	Priority   Priority
+	OnPosition func(position int) // optional, see Queue.Acquire
+}
+
+// NewQueued wraps e so that its requests go through q with the given priority
+func NewQueued(e Engine, q *Queue, priority Priority) *Queued {
+	return &Queued{
+		Engine:   e,
+		Queue:    q,
+		Priority: priority,
+	}
+}
+
+// Translate waits for a slot, then translates
+func (q *Queued) Translate(ctx context.Context, req Request) (Response, error) {
+	release, err := q.Queue.Acquire(ctx, q.Priority, q.OnPosition)
+	if err != nil {
+		return Response{}, err
+	}
+	defer release()
+	return q.Engine.Translate(ctx, req)
+}
+
+// TranslateStream waits for a slot, then streams the translation. The slot is
+// held until the stream ends.
+func (q *Queued) TranslateStream(ctx context.Context, req Request) (<-chan Response, error) {
+	ch := make(chan Response)
+	go func() {
+		defer close(ch)
+
+		release, err := q.Queue.Acquire(ctx, q.Priority, q.OnPosition)
+		if err != nil {
+			ch <- ErrorResponse(err.Error())
+			return
+		}
+		defer release()
+
+		stream, err := q.Engine.TranslateStream(ctx, req)
+		if err != nil {
+			ch <- ErrorResponse(err.Error())
+			return
+		}
+		for res := range stream {
+			ch <- res
+		}
+	}()
+	return ch, nil
+}
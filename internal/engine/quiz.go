@@ -0,0 +1,156 @@
+package engine
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Kinds of quiz questions
+const (
+	QuizCloze          = "cloze"           // fill in the word missing from the translation
+	QuizMultipleChoice = "multiple-choice" // pick the right answer among choices
+)
+
+// clozeBlank marks the missing word of a cloze question
+const clozeBlank = "___"
+
+// QuizItem is a translated text to make questions from
+type QuizItem struct {
+	ID          string `json:"id"`
+	SourceLang  string `json:"sourceLang"`
+	TargetLang  string `json:"targetLang"`
+	Text        string `json:"text"`
+	Translation string `json:"translation"`
+}
+
+// QuizQuestion is a question on one of the items of a quiz
+type QuizQuestion struct {
+	ItemID      string   `json:"itemId"`
+	Kind        string   `json:"kind"`
+	Question    string   `json:"question"` // for cloze questions, the translation with "___" for the missing word
+	Hint        string   `json:"hint,omitempty"`
+	Choices     []string `json:"choices,omitempty"` // for multiple choice, in random order
+	Answer      string   `json:"answer"`
+	Explanation string   `json:"explanation,omitempty"`
+}
+
+// Quiz is a language-learning quiz on translated texts
+type Quiz struct {
+	Questions []QuizQuestion `json:"questions"`
+}
+
+// quizPrompt asks the engine for quiz questions as JSON
+const quizPrompt = `A language learner translated the following texts. Write a quiz that tests the vocabulary and
+grammar of the translations, one question per text.
+
+{{.Text}}
+
+{{.Vars.quiz_kind}}
+Write hints and explanations in {{.Vars.explain_lang}}, in one short sentence each.
+
+Respond with JSON only, in this exact form:
+{"questions": [{"item": 1, "kind": "cloze", "question": "...", "hint": "...", "choices": [], "answer": "...", "explanation": "..."}]}`
+
+// quizKinds describes the kinds of questions to the engine
+var quizKinds = map[string]string{
+	QuizCloze: `Make every question a cloze question ("kind": "cloze"): the translation with one important word
+replaced by ` + clozeBlank + ` as the question, that word as the answer and the original text as the hint.`,
+	QuizMultipleChoice: `Make every question a multiple-choice question ("kind": "multiple-choice"): ask what a word or
+phrase of the translation means or which word completes it, with four plausible choices including the answer.`,
+	"": `Mix cloze questions ("kind": "cloze": the translation with one important word replaced by ` + clozeBlank + `
+as the question, that word as the answer and the original text as the hint) and multiple-choice questions
+("kind": "multiple-choice": four plausible choices including the answer).`,
+}
+
+// GenerateQuiz asks the engine for a question of the given kind, or of any
+// kind if empty, on each item. The questions are written in the items'
+// target languages, with hints and explanations in explainLang. Questions
+// the engine got malformed are dropped.
+func GenerateQuiz(ctx context.Context, e Engine, kind string, items []QuizItem, explainLang string) (Quiz, error) {
+	instructions, ok := quizKinds[kind]
+	if !ok {
+		return Quiz{}, fmt.Errorf("unknown quiz kind %q", kind)
+	}
+	if len(items) == 0 {
+		return Quiz{}, errors.New("nothing to make a quiz from")
+	}
+
+	var text strings.Builder
+	for i, item := range items {
+		fmt.Fprintf(&text, "%d. %s → %s\nText: %s\nTranslation: %s\n\n", i+1, item.SourceLang, item.TargetLang,
+			strings.TrimSpace(item.Text), strings.TrimSpace(item.Translation))
+	}
+	res, err := e.Translate(ctx, Request{
+		Text:         strings.TrimSpace(text.String()),
+		SourceLang:   items[0].SourceLang,
+		TargetLang:   items[0].TargetLang,
+		Prompt:       quizPrompt,
+		SystemPrompt: "You are a patient language teacher.",
+		Variables:    map[string]string{"quiz_kind": instructions, "explain_lang": explainLang},
+	})
+	if err != nil {
+		return Quiz{}, fmt.Errorf("quiz failed: %w", err)
+	}
+
+	var out struct {
+		Questions []struct {
+			Item json.Number `json:"item"`
+			QuizQuestion
+		} `json:"questions"`
+	}
+	if err := extractJSON(res.Text, &out); err != nil {
+		return Quiz{}, fmt.Errorf("quiz failed: %w", err)
+	}
+	quiz := Quiz{Questions: []QuizQuestion{}}
+	for _, q := range out.Questions {
+		n, err := strconv.Atoi(q.Item.String())
+		if err != nil || n < 1 || n > len(items) {
+			continue
+		}
+		q.ItemID = items[n-1].ID
+		if kind != "" && q.Kind != kind {
+			continue
+		}
+		if checkQuestion(&q.QuizQuestion) {
+			quiz.Questions = append(quiz.Questions, q.QuizQuestion)
+		}
+	}
+	if len(quiz.Questions) == 0 {
+		return Quiz{}, fmt.Errorf("quiz failed: no usable questions in %q", res.Text)
+	}
+	return quiz, nil
+}
+
+// checkQuestion reports whether q can be asked, shuffling its choices
+func checkQuestion(q *QuizQuestion) bool {
+	q.Question = strings.TrimSpace(q.Question)
+	q.Answer = strings.TrimSpace(q.Answer)
+	if q.Question == "" || q.Answer == "" {
+		return false
+	}
+	switch q.Kind {
+	case QuizCloze:
+		q.Choices = nil
+		return strings.Contains(q.Question, clozeBlank)
+	case QuizMultipleChoice:
+		var choices []string
+		for _, c := range q.Choices {
+			if c = strings.TrimSpace(c); c != "" && !slices.Contains(choices, c) {
+				choices = append(choices, c)
+			}
+		}
+		if len(choices) < 2 || !slices.Contains(choices, q.Answer) {
+			return false
+		}
+		rand.Shuffle(len(choices), func(i, j int) { choices[i], choices[j] = choices[j], choices[i] })
+		q.Choices = choices
+		return true
+	}
+	return false
+}
@@ -0,0 +1,109 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Reading systems used to annotate texts
+const (
+	ReadingFurigana     = "furigana"     // Japanese kanji
+	ReadingPinyin       = "pinyin"       // Chinese
+	ReadingRomanization = "romanization" // Korean
+)
+
+// Reading is the pronunciation of one part of a text
+type Reading struct {
+	Text    string `json:"text"`    // the annotated part, as it appears in the text
+	Reading string `json:"reading"` // e.g. furigana or pinyin with tone marks
+	Start   int    `json:"start"`   // byte offsets of Text in the annotated text
+	End     int    `json:"end"`
+}
+
+// Annotation is a text with readings to render over it, e.g. as ruby
+type Annotation struct {
+	Text     string    `json:"text"`
+	System   string    `json:"system"`
+	Readings []Reading `json:"readings"`
+}
+
+// ReadingSystem returns the reading system for a language code, or "" if the
+// language needs no reading aid
+func ReadingSystem(lang string) string {
+	switch lang {
+	case "ja":
+		return ReadingFurigana
+	case "zh":
+		return ReadingPinyin
+	case "ko":
+		return ReadingRomanization
+	default:
+		return ""
+	}
+}
+
+// readingInstructions describe what to annotate in each system
+var readingInstructions = map[string]string{
+	ReadingFurigana:     "Give the furigana reading (in hiragana) of every word containing kanji. Skip words written only in kana, Latin letters or digits.",
+	ReadingPinyin:       "Give the Hanyu Pinyin with tone marks of every word written in Chinese characters.",
+	ReadingRomanization: "Give the Revised Romanization of Korean for every word written in Hangul.",
+}
+
+// readingPrompt asks the engine for readings as JSON
+const readingPrompt = `{{.Vars.instructions}}
+
+Text:
+{{.Text}}
+
+List the words in the order they appear, copying each exactly as written in the text.
+Respond with JSON only, in this exact form:
+{"readings": [{"text": "...", "reading": "..."}]}`
+
+// Annotate asks e for the readings of text, which is in the language with the
+// given code. Readings that can't be found in the text are dropped.
+func Annotate(ctx context.Context, e Engine, text, lang string) (Annotation, error) {
+	system := ReadingSystem(lang)
+	if system == "" {
+		return Annotation{}, fmt.Errorf("no reading system for language %q", lang)
+	}
+	res, err := e.Translate(ctx, Request{
+		Text:         text,
+		SourceLang:   lang,
+		TargetLang:   lang,
+		Prompt:       readingPrompt,
+		SystemPrompt: "You are a precise language teacher.",
+		Variables:    map[string]string{"instructions": readingInstructions[system]},
+	})
+	if err != nil {
+		return Annotation{}, fmt.Errorf("annotation failed: %w", err)
+	}
+	var out struct {
+		Readings []Reading `json:"readings"`
+	}
+	if err := extractJSON(res.Text, &out); err != nil {
+		return Annotation{}, fmt.Errorf("annotation failed: %w", err)
+	}
+	return Annotation{Text: text, System: system, Readings: locateReadings(text, out.Readings)}, nil
+}
+
+// locateReadings sets the offsets of readings listed in text order, dropping
+// readings whose text does not occur after the previous one
+func locateReadings(text string, readings []Reading) []Reading {
+	located := make([]Reading, 0, len(readings))
+	pos := 0
+	for _, r := range readings {
+		if r.Text == "" || r.Reading == "" {
+			continue
+		}
+		i := strings.Index(text[pos:], r.Text)
+		if i < 0 {
+			continue
+		}
+		r.Start = pos + i
+		r.End = r.Start + len(r.Text)
+		pos = r.End
+		located = append(located, r)
+	}
+	return located
+}
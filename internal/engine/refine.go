@@ -0,0 +1,45 @@
+package engine
+
+import (
+	"maps"
+	"strings"
+)
+
+// refinePrompt asks the engine to revise its translation. The instructions
+// given before carry the conversation, as none of the engines keep one.
+const refinePrompt = `You translated the following text from {{.SourceLang}} to {{.TargetLang}}.
+
+Text:
+{{.Text}}
+
+Your translation:
+{{.Vars.previous}}
+{{with .Vars.earlier_instructions}}
+You already revised it following these instructions, which still apply:
+{{.}}
+{{end}}
+Revise your translation following this instruction: {{.Vars.instruction}}
+Keep everything the instruction does not ask to change. Only return the revised translation.`
+
+// RefineRequest returns a request revising previous, a translation of
+// req.Text, following the last of instructions. Earlier instructions were
+// applied to previous already and are passed as context; instructions must
+// not be empty. The request keeps req's options, such as the glossary and
+// formality.
+func RefineRequest(req Request, previous string, instructions []string) Request {
+	vars := maps.Clone(req.Variables)
+	if vars == nil {
+		vars = map[string]string{}
+	}
+	vars["previous"] = previous
+	vars["instruction"] = instructions[len(instructions)-1]
+	var earlier []string
+	for _, s := range instructions[:len(instructions)-1] {
+		earlier = append(earlier, "- "+s)
+	}
+	vars["earlier_instructions"] = strings.Join(earlier, "\n")
+
+	req.Prompt = refinePrompt
+	req.Variables = vars
+	return req
+}
@@ -0,0 +1,76 @@
+package engine
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ironpark/tons/internal/tokens"
+)
+
+// Router is implemented by engines that pass each request on to one of
+// several engines. Chunked routes a long text once, so all of its chunks go
+// to the same engine.
+type Router interface {
+	Route(req Request) Engine
+}
+
+// SizeRouter sends short texts to a fast engine, e.g. a small local model,
+// and long ones to an engine with a large context, by estimated tokens
+type SizeRouter struct {
+	Short     Engine
+	Long      Engine
+	Threshold int // estimated tokens from which a text is long
+}
+
+// NewSizeRouter routes texts of at least threshold tokens to long and
+// others to short
+func NewSizeRouter(short, long Engine, threshold int) *SizeRouter {
+	return &SizeRouter{Short: short, Long: long, Threshold: threshold}
+}
+
+// Route returns the engine for req
+func (r *SizeRouter) Route(req Request) Engine {
+	if tokens.Estimate(req.Text) >= r.Threshold {
+		return r.Long
+	}
+	return r.Short
+}
+
+// Name names both engines
+func (r *SizeRouter) Name() string {
+	return "smart(" + r.Short.Name() + ", " + r.Long.Name() + ")"
+}
+
+// Translate translates with the engine for the text
+func (r *SizeRouter) Translate(ctx context.Context, req Request) (Response, error) {
+	return r.Route(req).Translate(ctx, req)
+}
+
+// TranslateStream streams from the engine for the text
+func (r *SizeRouter) TranslateStream(ctx context.Context, req Request) (<-chan Response, error) {
+	return r.Route(req).TranslateStream(ctx, req)
+}
+
+// Available reports whether either engine can translate. Texts routed to
+// an unavailable engine fail like they would without routing.
+func (r *SizeRouter) Available() bool {
+	return r.Short.Available() || r.Long.Available()
+}
+
+// Health reports the health of the short engine, or of the long engine if
+// only that one has a problem
+func (r *SizeRouter) Health(ctx context.Context) Health {
+	short := CheckHealth(ctx, r.Short)
+	if short.Error != "" {
+		return short
+	}
+	if long := CheckHealth(ctx, r.Long); long.Error != "" {
+		return long
+	}
+	return short
+}
+
+// Close closes both engines
+func (r *SizeRouter) Close() error {
+	return errors.Join(r.Short.Close(), r.Long.Close())
+}
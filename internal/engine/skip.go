@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"github.com/ironpark/tons/internal/langdetect"
+	"github.com/ironpark/tons/internal/placeholder"
+)
+
+const (
+	// skipMinConfidence is the detection confidence required to skip a translation
+	skipMinConfidence = 0.8
+	// skipMinLetters avoids skipping short, ambiguous inputs
+	skipMinLetters = 12
+)
+
+// SkipUnneeded wraps an engine and returns the input unchanged when it is already
+// in the target language or contains nothing translatable (numbers, code, emoji)
+type SkipUnneeded struct {
+	Engine
+}
+
+// NewSkipUnneeded wraps e with the no-translation-needed short circuit
+func NewSkipUnneeded(e Engine) *SkipUnneeded {
+	return &SkipUnneeded{Engine: e}
+}
+
+// NeedsTranslation reports whether text has to be sent to an engine to reach targetLang
+func NeedsTranslation(text, targetLang string) bool {
+	masked, _ := placeholder.Protect(text)
+	letters := 0
+	for _, r := range masked {
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	if letters == 0 {
+		return false
+	}
+	if letters < skipMinLetters {
+		return true
+	}
+
+	target := langdetect.Code(targetLang)
+	if target == langdetect.Unknown {
+		return true
+	}
+	result := langdetect.Detect(masked)
+	return result.Lang != target || result.Confidence < skipMinConfidence
+}
+
+// Translate returns the original text when no translation is needed
+func (s *SkipUnneeded) Translate(ctx context.Context, req Request) (Response, error) {
+	if strings.TrimSpace(req.Text) != "" && !NeedsTranslation(req.Text, req.TargetLang) {
+		return Response{Text: req.Text, Done: true, Skipped: true}, nil
+	}
+	return s.Engine.Translate(ctx, req)
+}
+
+// TranslateStream streams the original text when no translation is needed
+func (s *SkipUnneeded) TranslateStream(ctx context.Context, req Request) (<-chan Response, error) {
+	if strings.TrimSpace(req.Text) == "" || NeedsTranslation(req.Text, req.TargetLang) {
+		return s.Engine.TranslateStream(ctx, req)
+	}
+
+	ch := make(chan Response, 2)
+	ch <- Response{Text: req.Text, Skipped: true}
+	ch <- Response{Done: true, Skipped: true}
+	close(ch)
+	return ch, nil
+}
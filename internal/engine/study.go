@@ -0,0 +1,42 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+)
+
+// StudyNote is learning material for a translated text
+type StudyNote struct {
+	Romanization       string `json:"romanization"`       // pronunciation of the source text in Latin script
+	Example            string `json:"example"`            // another sentence using the text, in the source language
+	ExampleTranslation string `json:"exampleTranslation"` // the example in the target language
+}
+
+// studyPrompt asks the engine for study material as JSON
+const studyPrompt = `A language learner is studying this {{.SourceLang}} text and its {{.TargetLang}} translation.
+
+Text:
+{{.Text}}
+
+Give the romanization of the text (empty if it is already written in Latin script), and one short,
+natural example sentence in {{.SourceLang}} that uses it, with its {{.TargetLang}} translation.
+
+Respond with JSON only, in this exact form:
+{"romanization": "...", "example": "...", "exampleTranslation": "..."}`
+
+// Study returns the romanization of req.Text and an example sentence using it
+func Study(ctx context.Context, e Engine, req Request) (StudyNote, error) {
+	studyReq := req
+	studyReq.Prompt = studyPrompt
+	studyReq.SystemPrompt = "You are a patient language teacher."
+
+	res, err := e.Translate(ctx, studyReq)
+	if err != nil {
+		return StudyNote{}, fmt.Errorf("study note failed: %w", err)
+	}
+	var note StudyNote
+	if err := extractJSON(res.Text, &note); err != nil {
+		return StudyNote{}, fmt.Errorf("study note failed: %w", err)
+	}
+	return note, nil
+}
@@ -0,0 +1,171 @@
+package engine
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// GlossaryTerm is a fixed translation for a term
+type GlossaryTerm struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+// PromptData is the data prompt templates are executed with, e.g.
+//
+//	{{if .SourceLang}}from {{.SourceLang}} {{end}}to {{.TargetLang}}
+//	{{range .Glossary}}{{.Source}} => {{.Target}}
+//	{{end}}{{.Vars.audience}}
+type PromptData struct {
+	Text       string
+	SourceLang string
+	TargetLang string
+	Formality  string
+	Tone       string
+	Context    string
+	Examples   string // rendered few-shot examples
+	Glossary   []GlossaryTerm
+	Vars       map[string]string // custom variables
+}
+
+// legacyVars maps the old {{name}} placeholders to template fields
+var legacyVars = map[string]string{
+	"text":        "Text",
+	"source_lang": "SourceLang",
+	"target_lang": "TargetLang",
+	"formality":   "Formality",
+	"tone":        "Tone",
+	"context":     "Context",
+	"examples":    "Examples",
+}
+
+var legacyVar = regexp.MustCompile(`\{\{\s*(text|source_lang|target_lang|formality|tone|context|examples)\s*\}\}`)
+
+// parsePrompt parses a prompt template, accepting legacy {{text}} style placeholders
+func parsePrompt(tmpl string) (*template.Template, error) {
+	tmpl = legacyVar.ReplaceAllStringFunc(tmpl, func(m string) string {
+		name := legacyVar.FindStringSubmatch(m)[1]
+		return "{{." + legacyVars[name] + "}}"
+	})
+	return template.New("prompt").Option("missingkey=zero").Parse(tmpl)
+}
+
+// ValidatePrompt parses a prompt template and returns the variables it uses
+// that are neither PromptData fields nor in vars
+func ValidatePrompt(tmpl string, vars map[string]string) ([]string, error) {
+	t, err := parsePrompt(tmpl)
+	if err != nil {
+		return nil, fmt.Errorf("invalid prompt template: %w", err)
+	}
+
+	fields := map[string]bool{}
+	dataType := reflect.TypeOf(PromptData{})
+	for i := range dataType.NumField() {
+		fields[dataType.Field(i).Name] = true
+	}
+
+	seen := map[string]bool{}
+	for _, ref := range references(t) {
+		switch {
+		case !fields[ref[0]]:
+			seen[ref[0]] = true
+		case ref[0] == "Vars" && len(ref) > 1:
+			if _, ok := vars[ref[1]]; !ok {
+				seen["Vars."+ref[1]] = true
+			}
+		}
+	}
+
+	undefined := make([]string, 0, len(seen))
+	for name := range seen {
+		undefined = append(undefined, name)
+	}
+	sort.Strings(undefined)
+	return undefined, nil
+}
+
+// referencesField reports whether the template uses the given top-level field
+func referencesField(t *template.Template, name string) bool {
+	for _, ref := range references(t) {
+		if ref[0] == name {
+			return true
+		}
+	}
+	return false
+}
+
+// references returns the field chains the template reads from the top-level data.
+// Fields inside range and with blocks are relative to another value and only
+// count when accessed through $.
+func references(t *template.Template) [][]string {
+	var refs [][]string
+	var walk func(node parse.Node, root bool)
+	walk = func(node parse.Node, root bool) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child, root)
+			}
+		case *parse.ActionNode:
+			walk(n.Pipe, root)
+		case *parse.IfNode:
+			walk(n.Pipe, root)
+			walk(n.List, root)
+			walk(n.ElseList, root)
+		case *parse.RangeNode:
+			walk(n.Pipe, root)
+			walk(n.List, false)
+			walk(n.ElseList, root)
+		case *parse.WithNode:
+			walk(n.Pipe, root)
+			walk(n.List, false)
+			walk(n.ElseList, root)
+		case *parse.TemplateNode:
+			walk(n.Pipe, root)
+		case *parse.PipeNode:
+			if n == nil {
+				return
+			}
+			for _, cmd := range n.Cmds {
+				walk(cmd, root)
+			}
+		case *parse.CommandNode:
+			for _, arg := range n.Args {
+				walk(arg, root)
+			}
+		case *parse.ChainNode:
+			walk(n.Node, root)
+		case *parse.FieldNode:
+			if root {
+				refs = append(refs, n.Ident)
+			}
+		case *parse.VariableNode:
+			if n.Ident[0] == "$" && len(n.Ident) > 1 {
+				refs = append(refs, n.Ident[1:])
+			}
+		}
+	}
+	walk(t.Root, true)
+	return refs
+}
+
+// renderGlossary formats glossary terms as an instruction
+func renderGlossary(terms []GlossaryTerm) string {
+	if len(terms) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("Use these translations for the following terms:")
+	for _, term := range terms {
+		b.WriteString("\n- " + term.Source + " => " + term.Target)
+	}
+	return b.String()
+}
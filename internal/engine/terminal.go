@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -25,27 +26,36 @@ const (
 
 // TerminalConfig holds configuration for terminal-based engines
 type TerminalConfig struct {
-	Command string        // CLI command name (e.g., "claude", "gemini")
-	Args    []string      // Base arguments before prompt
-	Timeout time.Duration // Timeout for translation operations
+	Command     string        // CLI command name (e.g., "claude", "gemini")
+	Args        []string      // Base arguments before prompt
+	Startup     time.Duration // Time to the first output, 0 to count it in Timeout and MaxDuration
+	Timeout     time.Duration // Time without output before giving up
+	MaxDuration time.Duration // Overall limit of a translation, 0 for none
+	MaxChars    int           // Longest text accepted, 0 for no limit
 }
 
+// defaultTerminalMaxDuration is the overall limit of a terminal translation
+const defaultTerminalMaxDuration = 10 * time.Minute
+
 // predefinedEngines contains default configurations for known terminal engines
 var predefinedEngines = map[TerminalEngineType]TerminalConfig{
 	TerminalClaudeCode: {
-		Command: "claude",
-		Args:    []string{"--model", "haiku", "--tools", "", "--output-format", "stream-json", "--verbose", "--include-partial-messages", "-p"},
-		Timeout: 60 * time.Second,
+		Command:     "claude",
+		Args:        []string{"--model", "haiku", "--tools", "", "--output-format", "stream-json", "--verbose", "--include-partial-messages", "-p"},
+		Timeout:     60 * time.Second,
+		MaxDuration: defaultTerminalMaxDuration,
 	},
 	TerminalGeminiCLI: {
-		Command: "gemini",
-		Args:    []string{"-p"},
-		Timeout: 60 * time.Second,
+		Command:     "gemini",
+		Args:        []string{"-p"},
+		Timeout:     60 * time.Second,
+		MaxDuration: defaultTerminalMaxDuration,
 	},
 	TerminalCodex: {
-		Command: "codex",
-		Args:    []string{"-p"},
-		Timeout: 60 * time.Second,
+		Command:     "codex",
+		Args:        []string{"-p"},
+		Timeout:     60 * time.Second,
+		MaxDuration: defaultTerminalMaxDuration,
 	},
 }
 
@@ -53,18 +63,41 @@ var predefinedEngines = map[TerminalEngineType]TerminalConfig{
 type TerminalEngine struct {
 	name   string
 	config TerminalConfig
+	log    *slog.Logger
 }
 
 // TerminalEngineOption is a functional option for TerminalEngine
 type TerminalEngineOption func(*TerminalEngine)
 
-// WithTerminalTimeout sets the timeout for terminal operations
+// WithTerminalTimeout sets how long the command may go without output
 func WithTerminalTimeout(timeout time.Duration) TerminalEngineOption {
 	return func(e *TerminalEngine) {
 		e.config.Timeout = timeout
 	}
 }
 
+// WithTerminalStartupTimeout sets the time allowed for starting the command
+// up to its first output, after which the other timeouts start
+func WithTerminalStartupTimeout(startup time.Duration) TerminalEngineOption {
+	return func(e *TerminalEngine) {
+		e.config.Startup = startup
+	}
+}
+
+// WithTerminalMaxDuration sets the overall limit of a translation
+func WithTerminalMaxDuration(limit time.Duration) TerminalEngineOption {
+	return func(e *TerminalEngine) {
+		e.config.MaxDuration = limit
+	}
+}
+
+// WithTerminalMaxChars sets the longest text accepted
+func WithTerminalMaxChars(limit int) TerminalEngineOption {
+	return func(e *TerminalEngine) {
+		e.config.MaxChars = limit
+	}
+}
+
 // WithTerminalArgs sets additional arguments for the terminal command
 func WithTerminalArgs(args []string) TerminalEngineOption {
 	return func(e *TerminalEngine) {
@@ -72,6 +105,22 @@ func WithTerminalArgs(args []string) TerminalEngineOption {
 	}
 }
 
+// WithTerminalExtraArgs adds arguments in front of the base arguments
+func WithTerminalExtraArgs(args []string) TerminalEngineOption {
+	return func(e *TerminalEngine) {
+		e.config.Args = append(append([]string(nil), args...), e.config.Args...)
+	}
+}
+
+// WithTerminalLogger sets the logger of the engine
+func WithTerminalLogger(logger *slog.Logger) TerminalEngineOption {
+	return func(e *TerminalEngine) {
+		if logger != nil {
+			e.log = logger
+		}
+	}
+}
+
 // WithTerminalCommand overrides the command to execute
 func WithTerminalCommand(command string) TerminalEngineOption {
 	return func(e *TerminalEngine) {
@@ -85,15 +134,17 @@ func NewTerminalEngine(engineType TerminalEngineType, opts ...TerminalEngineOpti
 	if !ok {
 		// Default fallback configuration
 		cfg = TerminalConfig{
-			Command: string(engineType),
-			Args:    []string{"-p"},
-			Timeout: 60 * time.Second,
+			Command:     string(engineType),
+			Args:        []string{"-p"},
+			Timeout:     60 * time.Second,
+			MaxDuration: defaultTerminalMaxDuration,
 		}
 	}
 
 	e := &TerminalEngine{
 		name:   string(engineType),
 		config: cfg,
+		log:    slog.Default(),
 	}
 
 	for _, opt := range opts {
@@ -108,10 +159,12 @@ func NewCustomTerminalEngine(name, command string, args []string, opts ...Termin
 	e := &TerminalEngine{
 		name: name,
 		config: TerminalConfig{
-			Command: command,
-			Args:    args,
-			Timeout: 60 * time.Second,
+			Command:     command,
+			Args:        args,
+			Timeout:     60 * time.Second,
+			MaxDuration: defaultTerminalMaxDuration,
 		},
+		log: slog.Default(),
 	}
 
 	for _, opt := range opts {
@@ -157,23 +210,39 @@ func (e *TerminalEngine) Translate(ctx context.Context, req Request) (Response,
 		return Response{Text: "", Done: true}, nil
 	}
 
-	prompt := BuildPrompt(req.Prompt, req.Text, req.SourceLang, req.TargetLang)
+	prompt, err := req.RenderPrompt()
+	if err != nil {
+		return Response{}, err
+	}
 
-	ctx, cancel := context.WithTimeout(ctx, e.config.Timeout)
-	defer cancel()
+	t := withTimeouts(ctx, e.config.Startup, e.config.Timeout, e.config.MaxDuration)
+	defer t.stop()
 
 	args := e.buildArgs(prompt, req.SystemPrompt)
-	cmd := exec.CommandContext(ctx, e.config.Command, args...)
-	slog.Info("Translate", "cmd", cmd)
-	output, err := cmd.Output()
-	if err != nil {
-		if ctx.Err() == context.DeadlineExceeded {
-			return Response{}, fmt.Errorf("translation timed out")
+	cmd := command(t.ctx, e.config.Command, args...)
+	e.log.Debug("running terminal agent", "engine", e.name, "command", e.config.Command, "prompt", prompt)
+	var output strings.Builder
+	cmd.Stdout = touchWriter{&output, t}
+	if err := cmd.Run(); err != nil {
+		if t.timedOut() {
+			text := strings.TrimSpace(output.String())
+			return Response{Text: text, Done: true, Partial: text != ""}, errors.New(t.timeoutMessage())
 		}
 		return Response{}, fmt.Errorf("terminal agent error: %w", err)
 	}
 
-	return Response{Text: strings.TrimSpace(string(output)), Done: true}, nil
+	return Response{Text: strings.TrimSpace(output.String()), Done: true}, nil
+}
+
+// touchWriter restarts the idle timeout on every write
+type touchWriter struct {
+	w io.Writer
+	t *generationTimeouts
+}
+
+func (w touchWriter) Write(p []byte) (int, error) {
+	w.t.touch()
+	return w.w.Write(p)
 }
 
 // claudeCodeEvent represents the JSON structure from Claude Code stream output
@@ -202,15 +271,18 @@ func (e *TerminalEngine) TranslateStream(ctx context.Context, req Request) (<-ch
 			return
 		}
 
-		prompt := BuildPrompt(req.Prompt, req.Text, req.SourceLang, req.TargetLang)
-		slog.Info("TranslateStream", "prompt", prompt)
+		prompt, err := req.RenderPrompt()
+		if err != nil {
+			ch <- ErrorResponse(err.Error())
+			return
+		}
 
-		ctx, cancel := context.WithTimeout(ctx, e.config.Timeout)
-		defer cancel()
+		t := withTimeouts(ctx, e.config.Startup, e.config.Timeout, e.config.MaxDuration)
+		defer t.stop()
 
 		args := e.buildArgs(prompt, req.SystemPrompt)
-		cmd := exec.CommandContext(ctx, e.config.Command, args...)
-		slog.Info("TranslateStream", "cmd", cmd)
+		cmd := command(t.ctx, e.config.Command, args...)
+		e.log.Debug("running terminal agent", "engine", e.name, "command", e.config.Command, "prompt", prompt, "stream", true)
 
 		stdout, err := cmd.StdoutPipe()
 		cmd.Stderr = os.Stderr
@@ -228,9 +300,9 @@ func (e *TerminalEngine) TranslateStream(ctx context.Context, req Request) (<-ch
 		isClaudeCode := e.name == string(TerminalClaudeCode)
 
 		if isClaudeCode {
-			e.streamClaudeCodeOutput(ctx, cmd, stdout, ch)
+			e.streamClaudeCodeOutput(t, cmd, stdout, ch)
 		} else {
-			e.streamRawOutput(ctx, cmd, stdout, ch)
+			e.streamRawOutput(t, cmd, stdout, ch)
 		}
 		cmd.Wait()
 
@@ -240,7 +312,7 @@ func (e *TerminalEngine) TranslateStream(ctx context.Context, req Request) (<-ch
 }
 
 // streamClaudeCodeOutput handles JSON streaming output from Claude Code CLI
-func (e *TerminalEngine) streamClaudeCodeOutput(ctx context.Context, cmd *exec.Cmd, stdout io.ReadCloser, ch chan<- Response) {
+func (e *TerminalEngine) streamClaudeCodeOutput(t *generationTimeouts, cmd *exec.Cmd, stdout io.ReadCloser, ch chan<- Response) {
 	// lineResult holds the result of reading a line
 	type lineResult struct {
 		line string
@@ -254,28 +326,30 @@ func (e *TerminalEngine) streamClaudeCodeOutput(ctx context.Context, cmd *exec.C
 		// // Increase buffer size for potentially large JSON lines
 		// scanner.Buffer(make([]byte, 64*1024), 1024*1024)
 		for scanner.Scan() {
-			text := scanner.Text()
-			slog.Info("streamClaudeCodeOutput", "LINE", text)
-			lineCh <- lineResult{line: text}
+			lineCh <- lineResult{line: scanner.Text()}
 		}
 		if err := scanner.Err(); err != nil {
 			lineCh <- lineResult{err: err}
 		}
 	}()
-	text := ""
+	streamed := false
 	for {
 		select {
-		case <-ctx.Done():
+		case <-t.ctx.Done():
 			gracefulShutdown(cmd.Process)
-			ch <- ErrorResponse("translation timed out")
+			ch <- stoppedResponse(t, streamed)
 			return
-		case result := <-lineCh:
-			// slog.Info("DD", "LINE", result.line)
-			// if !ok {
-			// 	cmd.Wait()
-			// 	ch <- Response{Done: true}
-			// 	return
-			// }
+		case result, ok := <-lineCh:
+			if !ok {
+				// The agent exited without a result event
+				if err := cmd.Wait(); err != nil {
+					ch <- ErrorResponsef("terminal agent error: %v", err)
+					return
+				}
+				ch <- Response{Done: true}
+				return
+			}
+			t.touch()
 			if result.err != nil {
 				ch <- ErrorResponsef("read error: %v", result.err)
 				cmd.Wait()
@@ -286,6 +360,7 @@ func (e *TerminalEngine) streamClaudeCodeOutput(ctx context.Context, cmd *exec.C
 			var event claudeCodeEvent
 			if err := json.Unmarshal([]byte(result.line), &event); err != nil {
 				// Skip non-JSON lines
+				e.log.Debug("skipping non-JSON agent output", "engine", e.name, "line", result.line)
 				continue
 			}
 
@@ -294,13 +369,16 @@ func (e *TerminalEngine) streamClaudeCodeOutput(ctx context.Context, cmd *exec.C
 				// Extract text from content_block_delta events
 				if event.Event != nil && event.Event.Type == "content_block_delta" && event.Event.Delta != nil {
 					if event.Event.Delta.Type == "text_delta" && event.Event.Delta.Text != "" {
-						text += event.Event.Delta.Text
-						ch <- Response{Text: text, Done: false}
+						streamed = true
+						ch <- Response{Text: event.Event.Delta.Text, Done: false}
 					}
 				}
 			case "result":
-				// Final result - we already streamed the content, just mark as done
+				// Final result - the content was streamed unless partial messages are off
 				cmd.Wait()
+				if !streamed && event.Result != "" {
+					ch <- Response{Text: event.Result, Done: false}
+				}
 				ch <- Response{Done: true}
 				return
 			}
@@ -309,7 +387,7 @@ func (e *TerminalEngine) streamClaudeCodeOutput(ctx context.Context, cmd *exec.C
 }
 
 // streamRawOutput handles raw byte streaming for non-Claude Code engines
-func (e *TerminalEngine) streamRawOutput(ctx context.Context, cmd *exec.Cmd, stdout io.ReadCloser, ch chan<- Response) {
+func (e *TerminalEngine) streamRawOutput(t *generationTimeouts, cmd *exec.Cmd, stdout io.ReadCloser, ch chan<- Response) {
 	// readResult holds the result of a read operation
 	type readResult struct {
 		data []byte
@@ -336,15 +414,21 @@ func (e *TerminalEngine) streamRawOutput(ctx context.Context, cmd *exec.Cmd, std
 		}
 	}()
 
+	pending := "" // an incomplete character at the end of the last read
+	streamed := false
 	for {
 		select {
-		case <-ctx.Done():
+		case <-t.ctx.Done():
 			gracefulShutdown(cmd.Process)
-			ch <- ErrorResponse("translation timed out")
+			ch <- stoppedResponse(t, streamed)
 			return
 		case result, ok := <-readCh:
+			t.touch()
 			if !ok {
 				cmd.Wait()
+				if pending != "" {
+					ch <- Response{Text: pending, Done: false}
+				}
 				ch <- Response{Done: true}
 				return
 			}
@@ -353,9 +437,31 @@ func (e *TerminalEngine) streamRawOutput(ctx context.Context, cmd *exec.Cmd, std
 				cmd.Wait()
 				return
 			}
-			ch <- Response{Text: string(result.data), Done: false}
+			var text string
+			text, pending = completeRunes(pending + string(result.data))
+			if text != "" {
+				streamed = true
+				ch <- Response{Text: text, Done: false}
+			}
+		}
+	}
+}
+
+// terminateDelay is how long a terminal agent may take to exit after SIGTERM
+const terminateDelay = 3 * time.Second
+
+// command is exec.CommandContext, but a cancelled command is asked to exit
+// with SIGTERM and only killed if it is still running after terminateDelay
+func command(ctx context.Context, name string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Cancel = func() error {
+		if err := cmd.Process.Signal(syscall.SIGTERM); err != nil {
+			return cmd.Process.Kill() // no SIGTERM on Windows
 		}
+		return nil
 	}
+	cmd.WaitDelay = terminateDelay
+	return cmd
 }
 
 // gracefulShutdown attempts to terminate a process gracefully before force killing
@@ -367,7 +473,7 @@ func gracefulShutdown(proc *os.Process) {
 	// First, try SIGTERM for graceful shutdown
 	proc.Signal(syscall.SIGTERM)
 
-	// Wait up to 3 seconds for graceful termination
+	// Wait for graceful termination
 	done := make(chan struct{})
 	go func() {
 		proc.Wait()
@@ -378,7 +484,7 @@ func gracefulShutdown(proc *os.Process) {
 	case <-done:
 		// Process terminated gracefully
 		return
-	case <-time.After(3 * time.Second):
+	case <-time.After(terminateDelay):
 		// Force kill if still running
 		proc.Kill()
 		proc.Wait()
@@ -0,0 +1,63 @@
+package engine_test
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/ironpark/tons/internal/engine"
+	"github.com/ironpark/tons/pkg/enginetest"
+	"github.com/ironpark/tons/pkg/tons"
+)
+
+// fakeAgentEnv makes the test binary act as a terminal agent, see TestFakeAgent
+const fakeAgentEnv = "TONS_FAKE_AGENT"
+
+// TestFakeAgent is not a test: run by the terminal engine with fakeAgentEnv
+// set, the test binary prints a translation word by word like an agent CLI
+func TestFakeAgent(t *testing.T) {
+	if os.Getenv(fakeAgentEnv) == "" {
+		t.Skip("only run as the fake agent")
+	}
+	for _, word := range []string{"Das ", "Wetter ", "ist ", "heute ", "schön.\n"} {
+		time.Sleep(5 * time.Millisecond)
+		fmt.Print(word)
+	}
+	os.Exit(0)
+}
+
+func TestTerminalEngineConformance(t *testing.T) {
+	t.Setenv(fakeAgentEnv, "1")
+	enginetest.RunConformance(t, func(t *testing.T) tons.Engine {
+		// The prompt is appended as the last argument, after the flag terminator
+		return engine.NewCustomTerminalEngine("fake", os.Args[0], []string{"-test.run=^TestFakeAgent$", "--"})
+	})
+}
+
+func TestClaudeCodeExitWithoutResult(t *testing.T) {
+	t.Setenv(fakeAgentEnv, "1")
+	// The fake agent prints plain text, so no result event ever arrives
+	e := engine.NewCustomTerminalEngine(string(engine.TerminalClaudeCode), os.Args[0], []string{"-test.run=^TestFakeAgent$", "--"},
+		engine.WithTerminalTimeout(time.Minute))
+	ch, err := e.TranslateStream(t.Context(), enginetest.DefaultOptions().Request)
+	if err != nil {
+		t.Fatalf("TranslateStream: %v", err)
+	}
+	deadline := time.After(10 * time.Second)
+	var last tons.Response
+	for {
+		select {
+		case res, ok := <-ch:
+			if !ok {
+				if !last.Done {
+					t.Errorf("the last response %+v is not Done", last)
+				}
+				return
+			}
+			last = res
+		case <-deadline:
+			t.Fatal("the stream did not end after the agent exited")
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Causes of a cancelled generation, see withTimeouts
+var (
+	errStartupTimeout = errors.New("no output within the startup timeout")
+	errIdleTimeout    = errors.New("no output within the idle timeout")
+	errTotalTimeout   = errors.New("overall time limit reached")
+)
+
+// generationTimeouts limits a generation by the time to its first output,
+// by inactivity and by total duration
+type generationTimeouts struct {
+	ctx                  context.Context
+	cancel               context.CancelCauseFunc
+	startup, idle, total time.Duration
+
+	mu       sync.Mutex
+	started  bool        // output arrived, or no startup limit
+	timer    *time.Timer // the startup limit until started, then the idle limit
+	deadline *time.Timer
+}
+
+// withTimeouts returns a context that is cancelled when no output arrives for
+// idle or when total has passed. With a startup limit, connecting, starting
+// a process or loading a model until the first output get startup instead,
+// and idle and total count from the first output, so a slow start doesn't
+// leave no time for generating. Zero disables a limit; without a startup
+// limit, idle and total count from the start. Call touch on every piece of
+// output and stop when done.
+func withTimeouts(parent context.Context, startup, idle, total time.Duration) *generationTimeouts {
+	ctx, cancel := context.WithCancelCause(parent)
+	t := &generationTimeouts{ctx: ctx, cancel: cancel, startup: startup, idle: idle, total: total}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if startup > 0 {
+		t.timer = time.AfterFunc(startup, func() { cancel(errStartupTimeout) })
+	} else {
+		t.start()
+	}
+	return t
+}
+
+// start starts the idle and total limits. Must be called with t.mu held.
+func (t *generationTimeouts) start() {
+	t.started = true
+	t.timer = nil
+	if t.idle > 0 {
+		t.timer = time.AfterFunc(t.idle, func() { t.cancel(errIdleTimeout) })
+	}
+	if t.total > 0 {
+		t.deadline = time.AfterFunc(t.total, func() { t.cancel(errTotalTimeout) })
+	}
+}
+
+// touch restarts the idle timeout after output arrived, or ends the startup
+func (t *generationTimeouts) touch() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.started {
+		t.timer.Stop()
+		t.start()
+		return
+	}
+	if t.timer != nil {
+		t.timer.Reset(t.idle)
+	}
+}
+
+// stop releases the timers and the context
+func (t *generationTimeouts) stop() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+	if t.deadline != nil {
+		t.deadline.Stop()
+	}
+	t.cancel(nil)
+}
+
+// timedOut reports whether the generation was stopped by one of the limits
+// rather than cancelled by the caller
+func (t *generationTimeouts) timedOut() bool {
+	cause := context.Cause(t.ctx)
+	return errors.Is(cause, errStartupTimeout) || errors.Is(cause, errIdleTimeout) || errors.Is(cause, errTotalTimeout)
+}
+
+// timeoutMessage describes which limit stopped the generation
+func (t *generationTimeouts) timeoutMessage() string {
+	switch cause := context.Cause(t.ctx); {
+	case errors.Is(cause, errStartupTimeout):
+		return fmt.Sprintf("translation timed out: the engine did not start answering within %s", t.startup)
+	case errors.Is(cause, errIdleTimeout):
+		return fmt.Sprintf("translation timed out: no output for %s", t.idle)
+	}
+	return "translation timed out: " + errTotalTimeout.Error()
+}
+
+// stoppedResponse is the error response of a generation whose context
+// ended, marked partial if some text was streamed before
+func stoppedResponse(t *generationTimeouts, streamed bool) Response {
+	res := ErrorResponse("translation cancelled")
+	if t.timedOut() {
+		res.Error = t.timeoutMessage()
+	}
+	res.Partial = streamed
+	return res
+}
@@ -0,0 +1,54 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/ollama/ollama/api"
+)
+
+// Warmer is implemented by engines that can prepare for the first request,
+// so it isn't much slower than the ones after it
+type Warmer interface {
+	Warmup(ctx context.Context) error
+}
+
+// Warmup prepares e for the first request if it is a Warmer
+func Warmup(ctx context.Context, e Engine) error {
+	if warmer, ok := e.(Warmer); ok {
+		return warmer.Warmup(ctx)
+	}
+	return nil
+}
+
+// Warmup loads the model into memory
+func (e *Yzma) Warmup(ctx context.Context) error {
+	return e.Initialize()
+}
+
+// Warmup has the server load the model by sending an empty prompt, kept
+// loaded for the configured keep_alive
+func (e *Ollama) Warmup(ctx context.Context) error {
+	if err := e.client.Generate(ctx, e.buildGenerateRequest(""), func(api.GenerateResponse) error { return nil }); err != nil {
+		return fmt.Errorf("ollama error: %w", err)
+	}
+	return nil
+}
+
+// Warmup runs the command once, asking for its version. The agents start a
+// new session for every translation, but a first run loads the runtime and
+// its modules from disk, which later runs find in the file cache.
+func (e *TerminalEngine) Warmup(ctx context.Context) error {
+	if out, err := exec.CommandContext(ctx, e.config.Command, "--version").CombinedOutput(); err != nil {
+		return fmt.Errorf("%s --version: %w: %s", e.config.Command, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Warmup prepares both engines
+func (r *SizeRouter) Warmup(ctx context.Context) error {
+	return errors.Join(Warmup(ctx, r.Short), Warmup(ctx, r.Long))
+}
@@ -2,10 +2,13 @@ package engine
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/hybridgroup/yzma/pkg/llama"
 )
@@ -19,11 +22,16 @@ type Yzma struct {
 	ModelPath   string
 	Sampling    SamplingConfig
 	ContextSize int
+	Startup     time.Duration // time to the first token, 0 to count it in Timeout and MaxDuration
+	Timeout     time.Duration // time without a new token before giving up, 0 for none
+	MaxDuration time.Duration // overall limit of a translation, 0 for none
+	MaxChars    int           // longest text accepted, 0 for no limit
 	model       llama.Model
 	vocab       llama.Vocab
 	mu          sync.Mutex
 	initialized bool
 	inUse       chan struct{} // semaphore for inference concurrency control
+	log         *slog.Logger
 }
 
 // YzmaOption is a functional option for configuring Yzma
@@ -43,6 +51,39 @@ func WithYzmaContextSize(size int) YzmaOption {
 	}
 }
 
+// WithYzmaTimeouts sets how long generation may go without a new token and
+// its overall limit
+func WithYzmaTimeouts(idle, total time.Duration) YzmaOption {
+	return func(y *Yzma) {
+		y.Timeout = idle
+		y.MaxDuration = total
+	}
+}
+
+// WithYzmaStartupTimeout sets the time allowed for processing the prompt
+// up to the first token, after which the other timeouts start
+func WithYzmaStartupTimeout(startup time.Duration) YzmaOption {
+	return func(y *Yzma) {
+		y.Startup = startup
+	}
+}
+
+// WithYzmaMaxChars sets the longest text accepted
+func WithYzmaMaxChars(limit int) YzmaOption {
+	return func(y *Yzma) {
+		y.MaxChars = limit
+	}
+}
+
+// WithYzmaLogger sets the logger of the engine
+func WithYzmaLogger(logger *slog.Logger) YzmaOption {
+	return func(y *Yzma) {
+		if logger != nil {
+			y.log = logger
+		}
+	}
+}
+
 // NewYzma creates a new Yzma engine with the given model path and options
 func NewYzma(modelPath string, opts ...YzmaOption) *Yzma {
 	y := &Yzma{
@@ -50,6 +91,7 @@ func NewYzma(modelPath string, opts ...YzmaOption) *Yzma {
 		Sampling:    DefaultSamplingConfig(),
 		ContextSize: defaultNCtx,
 		inUse:       make(chan struct{}, 1),
+		log:         slog.Default(),
 	}
 	for _, opt := range opts {
 		opt(y)
@@ -79,11 +121,13 @@ func (e *Yzma) Initialize() error {
 
 	llama.Init()
 
+	start := time.Now()
 	params := llama.ModelDefaultParams()
 	model, err := llama.ModelLoadFromFile(e.ModelPath, params)
 	if err != nil {
 		return err
 	}
+	e.log.Info("model loaded", "path", e.ModelPath, "took", time.Since(start))
 
 	e.model = model
 	e.vocab = llama.ModelGetVocab(model)
@@ -91,13 +135,17 @@ func (e *Yzma) Initialize() error {
 	return nil
 }
 
-// Close releases model resources
+// Close releases model resources, waiting for a running inference to finish
 func (e *Yzma) Close() error {
+	e.inUse <- struct{}{}
+	defer func() { <-e.inUse }()
+
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
 	if e.model != 0 {
 		llama.ModelFree(e.model)
+		e.log.Info("model unloaded", "path", e.ModelPath)
 		e.model = 0
 		e.vocab = 0
 		e.initialized = false
@@ -105,6 +153,21 @@ func (e *Yzma) Close() error {
 	return nil
 }
 
+// CountTokens counts the tokens of text with the model's tokenizer, loading
+// the model if needed
+func (e *Yzma) CountTokens(ctx context.Context, text string) (int, error) {
+	if err := e.Initialize(); err != nil {
+		return 0, fmt.Errorf("yzma error: %w", err)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if !e.initialized {
+		return 0, errors.New("yzma error: model was closed")
+	}
+	return len(llama.Tokenize(e.vocab, text, true, false)), nil
+}
+
 // acquireModel acquires exclusive access to the model for inference.
 // Returns a release function that must be called when done.
 func (e *Yzma) acquireModel(ctx context.Context) (release func(), err error) {
@@ -135,6 +198,12 @@ func (e *Yzma) generateTokens(ctx context.Context, prompt string, cb generationC
 
 	// Create sampler chain using config
 	sampler := llama.SamplerChainInit(llama.SamplerChainDefaultParams())
+	if e.Sampling.RepeatPenalty > 0 && e.Sampling.RepeatPenalty != 1 {
+		llama.SamplerChainAdd(sampler, llama.SamplerInitPenalties(64, e.Sampling.RepeatPenalty, 0, 0))
+	}
+	if e.Sampling.TopK > 0 {
+		llama.SamplerChainAdd(sampler, llama.SamplerInitTopK(int32(e.Sampling.TopK)))
+	}
 	llama.SamplerChainAdd(sampler, llama.SamplerInitTempExt(e.Sampling.Temperature, 0, 1))
 	llama.SamplerChainAdd(sampler, llama.SamplerInitTopP(e.Sampling.TopP, 1))
 	llama.SamplerChainAdd(sampler, llama.SamplerInitDist(0))
@@ -194,7 +263,10 @@ func (e *Yzma) Translate(ctx context.Context, req Request) (Response, error) {
 		return Response{}, fmt.Errorf("yzma error: %w", err)
 	}
 
-	prompt := BuildPrompt(req.Prompt, req.Text, req.SourceLang, req.TargetLang)
+	prompt, err := req.RenderPrompt()
+	if err != nil {
+		return Response{}, err
+	}
 
 	release, err := e.acquireModel(ctx)
 	if err != nil {
@@ -202,17 +274,24 @@ func (e *Yzma) Translate(ctx context.Context, req Request) (Response, error) {
 	}
 	defer release()
 
+	t := withTimeouts(ctx, e.Startup, e.Timeout, e.MaxDuration)
+	defer t.stop()
+
 	var result strings.Builder
 
-	err = e.generateTokens(ctx, prompt, func(piece string) bool {
+	err = e.generateTokens(t.ctx, prompt, func(piece string) bool {
+		t.touch()
 		result.WriteString(piece)
 		return true
 	})
 
 	if err != nil {
+		if t.timedOut() {
+			err = errors.New(t.timeoutMessage())
+		}
 		// If we have partial results, return them along with the error
 		if result.Len() > 0 {
-			return Response{Text: result.String(), Done: true}, fmt.Errorf("yzma error: %w", err)
+			return Response{Text: result.String(), Done: true, Partial: true}, fmt.Errorf("yzma error: %w", err)
 		}
 		return Response{}, fmt.Errorf("yzma error: %w", err)
 	}
@@ -236,7 +315,11 @@ func (e *Yzma) TranslateStream(ctx context.Context, req Request) (<-chan Respons
 			return
 		}
 
-		prompt := BuildPrompt(req.Prompt, req.Text, req.SourceLang, req.TargetLang)
+		prompt, err := req.RenderPrompt()
+		if err != nil {
+			ch <- ErrorResponse(err.Error())
+			return
+		}
 
 		release, err := e.acquireModel(ctx)
 		if err != nil {
@@ -245,20 +328,38 @@ func (e *Yzma) TranslateStream(ctx context.Context, req Request) (<-chan Respons
 		}
 		defer release()
 
-		err = e.generateTokens(ctx, prompt, func(piece string) bool {
+		t := withTimeouts(ctx, e.Startup, e.Timeout, e.MaxDuration)
+		defer t.stop()
+
+		pending := "" // a character split across tokens
+		streamed := false
+		err = e.generateTokens(t.ctx, prompt, func(piece string) bool {
+			t.touch()
+			piece, pending = completeRunes(pending + piece)
+			if piece == "" {
+				return true
+			}
 			select {
 			case ch <- Response{Text: piece, Done: false}:
+				streamed = true
 				return true
-			case <-ctx.Done():
+			case <-t.ctx.Done():
 				return false
 			}
 		})
 
+		if t.ctx.Err() != nil {
+			ch <- stoppedResponse(t, streamed)
+			return
+		}
 		if err != nil {
 			ch <- ErrorResponsef("yzma error: %v", err)
 			return
 		}
 
+		if pending != "" {
+			ch <- Response{Text: pending, Done: false}
+		}
 		ch <- Response{Done: true}
 	}()
 
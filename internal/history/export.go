@@ -0,0 +1,149 @@
+package history
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/ironpark/tons/internal/engine"
+)
+
+// Export formats
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+)
+
+// csvHeader are the columns of exported CSV files
+var csvHeader = []string{"id", "createdAt", "sourceLang", "targetLang", "engine", "favorite", "adequacy", "fluency", "text", "translation"}
+
+// Export returns the entries, oldest first, as JSON or CSV
+func (s *Store) Export(format string, favoritesOnly bool) ([]byte, error) {
+	s.mu.RLock()
+	entries := make([]Entry, 0, len(s.entries))
+	for _, e := range s.entries {
+		if !favoritesOnly || e.Favorite {
+			entries = append(entries, e)
+		}
+	}
+	s.mu.RUnlock()
+
+	switch format {
+	case FormatJSON, "":
+		return json.MarshalIndent(entries, "", "  ")
+	case FormatCSV:
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		w.Write(csvHeader)
+		for _, e := range entries {
+			adequacy, fluency := "", ""
+			if e.Quality != nil {
+				adequacy, fluency = strconv.Itoa(e.Quality.Adequacy), strconv.Itoa(e.Quality.Fluency)
+			}
+			w.Write([]string{
+				e.ID, e.CreatedAt.Format(time.RFC3339), e.SourceLang, e.TargetLang, e.Engine,
+				strconv.FormatBool(e.Favorite), adequacy, fluency, e.Text, e.Translation,
+			})
+		}
+		w.Flush()
+		return buf.Bytes(), w.Error()
+	default:
+		return nil, fmt.Errorf("unsupported history format %q", format)
+	}
+}
+
+// Import adds entries exported as JSON or CSV. Entries already in the history
+// are skipped. Returns the number of added entries.
+func (s *Store) Import(data []byte, format string) (int, error) {
+	var entries []Entry
+	var err error
+	switch format {
+	case FormatJSON, "":
+		err = json.Unmarshal(data, &entries)
+	case FormatCSV:
+		entries, err = parseCSV(data)
+	default:
+		return 0, fmt.Errorf("unsupported history format %q", format)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid history file: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	known := make(map[string]bool, len(s.entries))
+	for _, e := range s.entries {
+		known[e.ID] = true
+	}
+	added := 0
+	for _, e := range entries {
+		if e.ID != "" && known[e.ID] {
+			continue
+		}
+		if e.ID == "" {
+			e.ID = newID()
+		}
+		if e.CreatedAt.IsZero() {
+			e.CreatedAt = time.Now()
+		}
+		known[e.ID] = true
+		s.entries = append(s.entries, e)
+		added++
+	}
+	if added == 0 {
+		return 0, nil
+	}
+	slices.SortStableFunc(s.entries, func(a, b Entry) int { return a.CreatedAt.Compare(b.CreatedAt) })
+	s.trim()
+	return added, s.save()
+}
+
+// parseCSV reads entries from CSV with a header row naming the columns
+func parseCSV(data []byte) ([]Entry, error) {
+	records, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	columns := make(map[string]int, len(records[0]))
+	for i, name := range records[0] {
+		columns[name] = i
+	}
+	if _, ok := columns["text"]; !ok {
+		return nil, fmt.Errorf("missing text column")
+	}
+
+	entries := make([]Entry, 0, len(records)-1)
+	for _, record := range records[1:] {
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return record[i]
+			}
+			return ""
+		}
+		e := Entry{
+			ID:          field("id"),
+			SourceLang:  field("sourceLang"),
+			TargetLang:  field("targetLang"),
+			Engine:      field("engine"),
+			Text:        field("text"),
+			Translation: field("translation"),
+		}
+		e.CreatedAt, _ = time.Parse(time.RFC3339, field("createdAt"))
+		e.Favorite, _ = strconv.ParseBool(field("favorite"))
+		adequacy, err1 := strconv.Atoi(field("adequacy"))
+		fluency, err2 := strconv.Atoi(field("fluency"))
+		if err1 == nil && err2 == nil {
+			e.Quality = &engine.Quality{Adequacy: adequacy, Fluency: fluency}
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
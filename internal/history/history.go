@@ -0,0 +1,204 @@
+// Package history persists completed translations
+package history
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/ironpark/tons/internal/engine"
+)
+
+// defaultMaxEntries bounds the history file size
+const defaultMaxEntries = 1000
+
+// ErrNotFound is returned when an entry does not exist
+var ErrNotFound = errors.New("history entry not found")
+
+// Entry is a completed translation
+type Entry struct {
+	ID          string          `json:"id"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	SourceLang  string          `json:"sourceLang"`
+	TargetLang  string          `json:"targetLang"`
+	Text        string          `json:"text"`
+	Translation string          `json:"translation"`
+	Engine      string          `json:"engine"`
+	Quality     *engine.Quality `json:"quality,omitempty"`
+	Confidence  *float64        `json:"confidence,omitempty"` // 0 to 1, see engine.Response
+	PromptHash  string          `json:"promptHash,omitempty"` // identifies the prompt settings the translation was made with
+	Favorite    bool            `json:"favorite,omitempty"`
+}
+
+// Store keeps the most recent entries in memory and on disk (newest last)
+type Store struct {
+	mu         sync.RWMutex
+	path       string
+	entries    []Entry
+	maxEntries int
+}
+
+// Open loads the history file at path, starting empty if it doesn't exist
+func Open(path string) (*Store, error) {
+	s := &Store{
+		path:       path,
+		maxEntries: defaultMaxEntries,
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.entries); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Add stores a new entry, assigning its ID and timestamp
+func (s *Store) Add(e Entry) (Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e.ID = newID()
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	s.entries = append(s.entries, e)
+	s.trim()
+	return e, s.save()
+}
+
+// List returns up to limit entries, newest first (limit <= 0 returns all)
+func (s *Store) List(limit int) []Entry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	n := len(s.entries)
+	if limit > 0 && limit < n {
+		n = limit
+	}
+	list := make([]Entry, 0, n)
+	for i := len(s.entries) - 1; i >= 0 && len(list) < n; i-- {
+		list = append(list, s.entries[i])
+	}
+	return list
+}
+
+// Recent returns the newest entry made since the given time that translated
+// text between the same languages with the same prompt settings
+func (s *Store) Recent(text, sourceLang, targetLang, promptHash string, since time.Time) (Entry, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for i := len(s.entries) - 1; i >= 0; i-- {
+		e := s.entries[i]
+		if e.CreatedAt.Before(since) {
+			break
+		}
+		if e.Text == text && e.SourceLang == sourceLang && e.TargetLang == targetLang && e.PromptHash == promptHash {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Get returns the entry with the given ID
+func (s *Store) Get(id string) (Entry, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, e := range s.entries {
+		if e.ID == id {
+			return e, true
+		}
+	}
+	return Entry{}, false
+}
+
+// Update applies fn to the entry with the given ID and saves
+func (s *Store) Update(id string, fn func(*Entry)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.entries {
+		if s.entries[i].ID == id {
+			fn(&s.entries[i])
+			s.entries[i].ID = id
+			return s.save()
+		}
+	}
+	return ErrNotFound
+}
+
+// Delete removes the entry with the given ID
+func (s *Store) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, e := range s.entries {
+		if e.ID == id {
+			s.entries = append(s.entries[:i], s.entries[i+1:]...)
+			return s.save()
+		}
+	}
+	return ErrNotFound
+}
+
+// Clear removes all entries
+func (s *Store) Clear() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries = nil
+	return s.save()
+}
+
+// SetFavorite marks or unmarks an entry as a favorite
+func (s *Store) SetFavorite(id string, favorite bool) error {
+	return s.Update(id, func(e *Entry) { e.Favorite = favorite })
+}
+
+// trim drops the oldest entries beyond maxEntries, keeping favorites; callers must hold mu
+func (s *Store) trim() {
+	excess := len(s.entries) - s.maxEntries
+	if excess <= 0 {
+		return
+	}
+	kept := s.entries[:0]
+	for _, e := range s.entries {
+		if excess > 0 && !e.Favorite {
+			excess--
+			continue
+		}
+		kept = append(kept, e)
+	}
+	s.entries = kept
+}
+
+// save writes all entries to disk; callers must hold mu
+func (s *Store) save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(s.entries)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0644)
+}
+
+// newID returns a random hex identifier
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,225 @@
+// Package htmldoc splits an HTML fragment into translatable sentences and
+// verbatim markup, and renders it back.
+//
+// Inline elements (<b>, <a>, <span>, ...) stay inside their sentence as
+// placeholder tokens so engines see whole sentences; block elements end a
+// sentence. Tags and attributes are always reproduced byte for byte.
+package htmldoc
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/ironpark/tons/internal/placeholder"
+	"golang.org/x/net/html"
+)
+
+// inlineTags are elements that do not break a sentence
+var inlineTags = map[string]bool{
+	"a": true, "abbr": true, "b": true, "bdi": true, "bdo": true, "br": true,
+	"cite": true, "data": true, "dfn": true, "em": true, "font": true, "i": true,
+	"img": true, "label": true, "mark": true, "q": true, "s": true, "small": true,
+	"span": true, "strong": true, "sub": true, "sup": true, "time": true, "u": true,
+	"wbr": true,
+}
+
+// atomicTags are inline elements whose whole content is kept verbatim
+var atomicTags = map[string]bool{
+	"code": true, "kbd": true, "samp": true, "var": true,
+}
+
+// rawTags are elements whose content is never translated
+var rawTags = map[string]bool{
+	"script": true, "style": true, "pre": true, "textarea": true,
+	"svg": true, "math": true, "template": true, "noscript": true,
+}
+
+// piece is either verbatim markup or a translatable sentence
+type piece struct {
+	text      string
+	translate bool
+	mapping   placeholder.Mapping
+}
+
+// Document is a parsed HTML fragment
+type Document struct {
+	pieces []piece
+}
+
+// Parse tokenizes src into verbatim markup and translatable sentences
+func Parse(src string) *Document {
+	d := &Document{}
+	z := html.NewTokenizer(strings.NewReader(src))
+
+	var seg segment
+	skipDepth := 0   // nesting inside a raw element
+	skipTag := ""    // raw element being skipped
+	atomicDepth := 0 // nesting inside an atomic element
+	atomicTag := ""  // atomic element being masked
+	var atomic bytes.Buffer
+
+	for {
+		tt := z.Next()
+		if tt == html.ErrorToken {
+			break
+		}
+		raw := string(z.Raw())
+		name, _ := z.TagName()
+		tag := string(name)
+
+		switch {
+		case skipDepth > 0:
+			d.raw(raw)
+			if tag == skipTag {
+				switch tt {
+				case html.StartTagToken:
+					skipDepth++
+				case html.EndTagToken:
+					skipDepth--
+				}
+			}
+			continue
+
+		case atomicDepth > 0:
+			atomic.WriteString(raw)
+			if tag == atomicTag {
+				switch tt {
+				case html.StartTagToken:
+					atomicDepth++
+				case html.EndTagToken:
+					atomicDepth--
+				}
+			}
+			if atomicDepth == 0 {
+				seg.mask(atomic.String())
+				atomic.Reset()
+			}
+			continue
+		}
+
+		switch tt {
+		case html.TextToken:
+			seg.text(raw)
+		case html.CommentToken:
+			seg.mask(raw)
+		case html.StartTagToken, html.EndTagToken, html.SelfClosingTagToken:
+			switch {
+			case rawTags[tag] && tt == html.StartTagToken:
+				d.flush(&seg)
+				d.raw(raw)
+				skipDepth, skipTag = 1, tag
+			case atomicTags[tag] && tt == html.StartTagToken:
+				atomic.WriteString(raw)
+				atomicDepth, atomicTag = 1, tag
+			case inlineTags[tag] || atomicTags[tag]:
+				seg.mask(raw)
+			default:
+				d.flush(&seg)
+				d.raw(raw)
+			}
+		default:
+			d.flush(&seg)
+			d.raw(raw)
+		}
+	}
+	if atomic.Len() > 0 {
+		seg.mask(atomic.String())
+	}
+	d.flush(&seg)
+	return d
+}
+
+// Texts returns the masked, unescaped text of every translatable sentence, in order
+func (d *Document) Texts() []string {
+	var texts []string
+	for _, p := range d.pieces {
+		if p.translate {
+			texts = append(texts, p.text)
+		}
+	}
+	return texts
+}
+
+// Render rebuilds the HTML, substituting translated texts in Texts order.
+// Translations are escaped before markup placeholders are restored.
+func (d *Document) Render(translated []string) string {
+	var sb strings.Builder
+	n := 0
+	for _, p := range d.pieces {
+		if !p.translate {
+			sb.WriteString(p.text)
+			continue
+		}
+		text := p.text
+		if n < len(translated) {
+			text = translated[n]
+		}
+		n++
+		sb.WriteString(p.mapping.Restore(escape(text)))
+	}
+	return sb.String()
+}
+
+// raw appends verbatim markup
+func (d *Document) raw(s string) {
+	if s != "" {
+		d.pieces = append(d.pieces, piece{text: s})
+	}
+}
+
+// flush ends the current sentence
+func (d *Document) flush(seg *segment) {
+	defer seg.reset()
+
+	text := seg.buf.String()
+	if !seg.hasText {
+		// Only whitespace and markup: emit verbatim
+		d.raw(seg.mapping.Restore(escape(text)))
+		return
+	}
+
+	// Keep surrounding whitespace outside the translatable text
+	trimmed := strings.TrimSpace(text)
+	start := strings.Index(text, trimmed)
+	d.raw(escape(text[:start]))
+	d.pieces = append(d.pieces, piece{text: trimmed, translate: true, mapping: seg.mapping})
+	d.raw(escape(text[start+len(trimmed):]))
+}
+
+// segment accumulates a sentence: unescaped text with inline markup masked
+type segment struct {
+	buf     strings.Builder
+	mapping placeholder.Mapping
+	hasText bool
+}
+
+// text appends raw (escaped) character data
+func (s *segment) text(raw string) {
+	text := html.UnescapeString(raw)
+	if strings.TrimSpace(text) != "" {
+		s.hasText = true
+	}
+	s.buf.WriteString(text)
+}
+
+// mask appends markup as a placeholder token
+func (s *segment) mask(raw string) {
+	var token string
+	token, s.mapping = s.mapping.Add(raw)
+	s.buf.WriteString(token)
+}
+
+// reset clears the segment
+func (s *segment) reset() {
+	s.buf.Reset()
+	s.mapping = nil
+	s.hasText = false
+}
+
+// textEscaper escapes the characters that are significant in HTML text
+var textEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+// escape escapes translated text for HTML
+func escape(s string) string {
+	return textEscaper.Replace(s)
+}
@@ -0,0 +1,60 @@
+// Package instance lets command line invocations pass commands to the
+// running app over a local socket, e.g. from a system keyboard shortcut
+// bound to "tons selection"
+package instance
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrNotRunning is returned by Send when the app isn't running
+var ErrNotRunning = errors.New("tons is not running")
+
+// Listen accepts commands on the socket at path until ctx is cancelled,
+// calling handle for each in its own goroutine
+func Listen(ctx context.Context, path string, handle func(command string)) error {
+	os.Remove(path) // left over from a crash
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		l.Close()
+		os.Remove(path)
+	}()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+				line, err := bufio.NewReader(conn).ReadString('\n')
+				if command := strings.TrimSpace(line); command != "" && (err == nil || line != "") {
+					handle(command)
+				}
+			}()
+		}
+	}()
+	return nil
+}
+
+// Send passes a command to the app listening on the socket at path
+func Send(path, command string) error {
+	conn, err := net.DialTimeout("unix", path, 2*time.Second)
+	if err != nil {
+		return ErrNotRunning
+	}
+	defer conn.Close()
+	_, err = conn.Write([]byte(command + "\n"))
+	return err
+}
@@ -0,0 +1,190 @@
+// Package jobs persists batch translation jobs so interrupted jobs can resume
+package jobs
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ErrNotFound is returned when a job does not exist
+var ErrNotFound = errors.New("job not found")
+
+// Status is the state of a job or of one of its files
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusRunning   Status = "running"
+	StatusDone      Status = "done"
+	StatusFailed    Status = "failed"
+	StatusCancelled Status = "cancelled"
+)
+
+// File is a file of a job
+type File struct {
+	Path   string `json:"path"`
+	Output string `json:"output,omitempty"`
+	Status Status `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Job translates the matching files of a folder
+type Job struct {
+	ID         string    `json:"id"`
+	CreatedAt  time.Time `json:"createdAt"`
+	Folder     string    `json:"folder"`
+	Pattern    string    `json:"pattern"`
+	Format     string    `json:"format"`
+	SourceLang string    `json:"sourceLang"`
+	TargetLang string    `json:"targetLang"`
+	Status     Status    `json:"status"`
+	Files      []File    `json:"files"`
+}
+
+// Progress is a summary of a job's files
+type Progress struct {
+	JobID  string `json:"jobId"`
+	Status Status `json:"status"`
+	Total  int    `json:"total"`
+	Done   int    `json:"done"`
+	Failed int    `json:"failed"`
+}
+
+// Progress counts the finished files
+func (j Job) Progress() Progress {
+	p := Progress{JobID: j.ID, Status: j.Status, Total: len(j.Files)}
+	for _, f := range j.Files {
+		switch f.Status {
+		case StatusDone:
+			p.Done++
+		case StatusFailed:
+			p.Failed++
+		}
+	}
+	return p
+}
+
+// Finished reports whether the job will not run any further on its own
+func (j Job) Finished() bool {
+	return j.Status == StatusDone || j.Status == StatusFailed || j.Status == StatusCancelled
+}
+
+// Store keeps jobs in memory and on disk (oldest first)
+type Store struct {
+	mu   sync.RWMutex
+	path string
+	jobs []Job
+}
+
+// Open loads the jobs file at path, starting empty if it doesn't exist
+func Open(path string) (*Store, error) {
+	s := &Store{path: path}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.jobs); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Add stores a new job, assigning its ID and timestamp
+func (s *Store) Add(j Job) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j.ID = newID()
+	j.CreatedAt = time.Now()
+	s.jobs = append(s.jobs, j)
+	return clone(j), s.save()
+}
+
+// List returns all jobs, newest first
+func (s *Store) List() []Job {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := make([]Job, 0, len(s.jobs))
+	for i := len(s.jobs) - 1; i >= 0; i-- {
+		list = append(list, clone(s.jobs[i]))
+	}
+	return list
+}
+
+// Get returns the job with the given ID
+func (s *Store) Get(id string) (Job, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, j := range s.jobs {
+		if j.ID == id {
+			return clone(j), true
+		}
+	}
+	return Job{}, false
+}
+
+// Update applies fn to the job with the given ID, saves and returns the updated job
+func (s *Store) Update(id string, fn func(*Job)) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.jobs {
+		if s.jobs[i].ID == id {
+			fn(&s.jobs[i])
+			s.jobs[i].ID = id
+			return clone(s.jobs[i]), s.save()
+		}
+	}
+	return Job{}, ErrNotFound
+}
+
+// Delete removes the job with the given ID
+func (s *Store) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, j := range s.jobs {
+		if j.ID == id {
+			s.jobs = append(s.jobs[:i], s.jobs[i+1:]...)
+			return s.save()
+		}
+	}
+	return ErrNotFound
+}
+
+// save writes all jobs to disk; callers must hold mu
+func (s *Store) save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(s.jobs)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0644)
+}
+
+// clone copies a job so callers can't modify the stored files
+func clone(j Job) Job {
+	j.Files = append([]File(nil), j.Files...)
+	return j
+}
+
+// newID returns a random hex identifier
+func newID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,210 @@
+// Package langdetect implements a small dependency-free language detector.
+//
+// Non-Latin scripts are identified by Unicode ranges; Latin-script languages
+// are told apart by scoring common function words.
+package langdetect
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Auto is the pseudo source language that requests detection
+const Auto = "auto"
+
+// Unknown is returned when the language cannot be determined
+const Unknown = ""
+
+// Result holds a detected language code (ISO 639-1) and a confidence in [0, 1]
+type Result struct {
+	Lang       string  `json:"lang"`
+	Confidence float64 `json:"confidence"`
+}
+
+// names maps supported language codes to English names
+var names = map[string]string{
+	"en": "English",
+	"ko": "Korean",
+	"ja": "Japanese",
+	"zh": "Chinese",
+	"es": "Spanish",
+	"fr": "French",
+	"de": "German",
+	"pt": "Portuguese",
+	"it": "Italian",
+	"nl": "Dutch",
+	"ru": "Russian",
+	"ar": "Arabic",
+	"el": "Greek",
+	"he": "Hebrew",
+	"th": "Thai",
+	"hi": "Hindi",
+}
+
+// Name returns the English name of a language code, or the code itself if unknown
+func Name(code string) string {
+	if name, ok := names[code]; ok {
+		return name
+	}
+	return code
+}
+
+// nativeNames maps native language names to codes
+var nativeNames = map[string]string{
+	"한국어": "ko", "日本語": "ja", "中文": "zh", "español": "es", "français": "fr",
+	"deutsch": "de", "português": "pt", "italiano": "it", "nederlands": "nl",
+	"русский": "ru", "العربية": "ar",
+}
+
+// Code normalizes a language code or English/native name (e.g. "korean", "ko-KR", "한국어")
+// to its ISO 639-1 code. Returns Unknown if the language is not recognized.
+func Code(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if base, _, ok := strings.Cut(strings.ReplaceAll(lang, "_", "-"), "-"); ok && len(base) == 2 {
+		lang = base
+	}
+	if _, ok := names[lang]; ok {
+		return lang
+	}
+	for code, name := range names {
+		if strings.ToLower(name) == lang {
+			return code
+		}
+	}
+	if code, ok := nativeNames[lang]; ok {
+		return code
+	}
+	return Unknown
+}
+
+// scriptLangs maps Unicode scripts to the language they almost always indicate
+var scriptLangs = []struct {
+	table *unicode.RangeTable
+	lang  string
+}{
+	{unicode.Hangul, "ko"},
+	{unicode.Cyrillic, "ru"},
+	{unicode.Arabic, "ar"},
+	{unicode.Greek, "el"},
+	{unicode.Hebrew, "he"},
+	{unicode.Thai, "th"},
+	{unicode.Devanagari, "hi"},
+}
+
+// stopwords lists frequent function words of Latin-script languages
+var stopwords = map[string][]string{
+	"en": {"the", "and", "is", "are", "was", "of", "to", "in", "that", "it", "for", "with", "you", "this", "not", "have", "be", "on", "what", "i"},
+	"es": {"el", "la", "los", "las", "de", "que", "y", "en", "es", "un", "una", "por", "con", "para", "no", "se", "lo", "como", "pero", "del"},
+	"fr": {"le", "la", "les", "de", "des", "et", "est", "un", "une", "que", "qui", "dans", "pour", "pas", "ne", "je", "vous", "il", "au", "du"},
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ein", "eine", "zu", "den", "mit", "von", "ich", "sie", "es", "auf", "für", "dem", "wir", "auch"},
+	"pt": {"o", "a", "os", "as", "de", "que", "e", "do", "da", "em", "um", "uma", "para", "não", "com", "por", "se", "mais", "como", "é"},
+	"it": {"il", "la", "di", "che", "e", "è", "un", "una", "per", "non", "sono", "con", "del", "della", "gli", "le", "ma", "si", "anche", "questo"},
+	"nl": {"de", "het", "een", "en", "van", "is", "dat", "niet", "ik", "je", "op", "te", "zijn", "met", "voor", "er", "maar", "ook", "wat", "hij"},
+}
+
+// latinLangs fixes the scoring order so ties resolve deterministically
+var latinLangs = []string{"en", "es", "fr", "de", "pt", "it", "nl"}
+
+// stopwordSets is stopwords indexed for lookup
+var stopwordSets = func() map[string]map[string]bool {
+	sets := make(map[string]map[string]bool, len(stopwords))
+	for lang, words := range stopwords {
+		set := make(map[string]bool, len(words))
+		for _, w := range words {
+			set[w] = true
+		}
+		sets[lang] = set
+	}
+	return sets
+}()
+
+// Detect identifies the language of text
+func Detect(text string) Result {
+	var letters, latin, han, kana int
+	scriptCounts := make(map[string]int)
+
+	for _, r := range text {
+		if !unicode.IsLetter(r) {
+			continue
+		}
+		letters++
+		switch {
+		case unicode.Is(unicode.Latin, r):
+			latin++
+		case unicode.In(r, unicode.Hiragana, unicode.Katakana):
+			kana++
+		case unicode.Is(unicode.Han, r):
+			han++
+		default:
+			for _, s := range scriptLangs {
+				if unicode.Is(s.table, r) {
+					scriptCounts[s.lang]++
+					break
+				}
+			}
+		}
+	}
+
+	if letters == 0 {
+		return Result{Lang: Unknown}
+	}
+
+	// Any kana means Japanese, since Japanese text mixes kana with Han characters
+	if kana > 0 && kana+han >= letters/2 {
+		return Result{Lang: "ja", Confidence: ratio(kana+han, letters)}
+	}
+
+	best, bestCount := "zh", han
+	for lang, count := range scriptCounts {
+		if count > bestCount {
+			best, bestCount = lang, count
+		}
+	}
+	if bestCount > latin {
+		return Result{Lang: best, Confidence: ratio(bestCount, letters)}
+	}
+
+	lang, confidence := detectLatin(text)
+	return Result{Lang: lang, Confidence: confidence * ratio(latin, letters)}
+}
+
+// detectLatin scores Latin-script text against the stopword lists
+func detectLatin(text string) (string, float64) {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+	if len(words) == 0 {
+		return "en", 0
+	}
+
+	scores := make(map[string]int, len(stopwordSets))
+	for _, w := range words {
+		for lang, set := range stopwordSets {
+			if set[w] {
+				scores[lang]++
+			}
+		}
+	}
+
+	best, bestScore, total := "en", 0, 0
+	for _, lang := range latinLangs {
+		score := scores[lang]
+		total += score
+		if score > bestScore {
+			best, bestScore = lang, score
+		}
+	}
+	if bestScore == 0 {
+		// No function words at all (e.g. a single name); English is the safest guess
+		return "en", 0.1
+	}
+	return best, ratio(bestScore, total)
+}
+
+// ratio returns n/d as a float, or 0 when d is 0
+func ratio(n, d int) float64 {
+	if d == 0 {
+		return 0
+	}
+	return float64(n) / float64(d)
+}
@@ -0,0 +1,67 @@
+// Package logging builds the app's log handler: a level that can change
+// while running, redaction of translated content and rotating log files.
+//
+// Content is redacted by attribute key: any string logged under one of the
+// content keys (text, prompt, translation, ...) is replaced with its length,
+// so log calls only need to pick the right key.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// contentKeys are the attribute keys that carry text the user translates
+var contentKeys = map[string]bool{
+	"text":        true,
+	"prompt":      true,
+	"translation": true,
+	"source":      true,
+	"line":        true,
+	"output":      true,
+	"content":     true,
+}
+
+// IsContentKey reports whether values logged under key are redacted
+func IsContentKey(key string) bool {
+	return contentKeys[strings.ToLower(key)]
+}
+
+// Redact returns a placeholder telling the length of text without its content
+func Redact(text string) string {
+	return fmt.Sprintf("[redacted, %d chars]", utf8.RuneCountInString(text))
+}
+
+// ParseLevel returns the level named "debug", "info", "warn" or "error",
+// falling back to info
+func ParseLevel(name string) slog.Level {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return slog.LevelInfo
+	}
+	return level
+}
+
+// Options control a handler; both can be changed while it is in use
+type Options struct {
+	Level  *slog.LevelVar
+	Redact *atomic.Bool // replace content attributes with their length
+}
+
+// NewHandler returns a text handler writing to w that drops records below
+// the level and redacts content attributes while redaction is on
+func NewHandler(w io.Writer, opts Options) slog.Handler {
+	return slog.NewTextHandler(w, &slog.HandlerOptions{
+		Level: opts.Level,
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if opts.Redact != nil && opts.Redact.Load() && a.Value.Kind() == slog.KindString && IsContentKey(a.Key) {
+				a.Value = slog.StringValue(Redact(a.Value.String()))
+			}
+			return a
+		},
+	})
+}
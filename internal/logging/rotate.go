@@ -0,0 +1,112 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// RotatingFile is a log file that is renamed to path.1 once it reaches its
+// size limit, shifting older files up to path.<maxFiles> and dropping the rest
+type RotatingFile struct {
+	mu       sync.Mutex
+	path     string
+	maxSize  int64
+	maxFiles int
+	file     *os.File
+	size     int64
+}
+
+// OpenRotating opens or creates the log file at path, appending to it
+func OpenRotating(path string, maxSize int64, maxFiles int) (*RotatingFile, error) {
+	r := &RotatingFile{path: path, maxSize: maxSize, maxFiles: maxFiles}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Path returns the path of the current log file
+func (r *RotatingFile) Path() string {
+	return r.path
+}
+
+// SetLimits changes the size at which the file is rotated and how many
+// rotated files are kept
+func (r *RotatingFile) SetLimits(maxSize int64, maxFiles int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.maxSize, r.maxFiles = maxSize, maxFiles
+}
+
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return 0, os.ErrClosed
+	}
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(p)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Close closes the current log file
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+func (r *RotatingFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.file, r.size = f, info.Size()
+	return nil
+}
+
+// rotate shifts the rotated files up by one and starts a new file
+func (r *RotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	r.file = nil
+	os.Remove(r.rotated(r.maxFiles))
+	for i := r.maxFiles - 1; i >= 1; i-- {
+		os.Rename(r.rotated(i), r.rotated(i+1))
+	}
+	if r.maxFiles > 0 {
+		os.Rename(r.path, r.rotated(1))
+	} else {
+		os.Remove(r.path)
+	}
+	return r.open()
+}
+
+// rotated returns the path of the nth rotated file
+func (r *RotatingFile) rotated(n int) string {
+	return fmt.Sprintf("%s.%d", r.path, n)
+}
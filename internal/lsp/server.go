@@ -0,0 +1,290 @@
+// Package lsp is a minimal language server offering translation code actions,
+// so editors with a generic LSP client can use tons without a custom plugin
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/ironpark/tons/internal/sourcecode"
+)
+
+// Commands offered as code actions
+const (
+	CommandTranslateSelection = "tons.translateSelection"
+	CommandTranslateComment   = "tons.translateComment"
+)
+
+// Translator translates text into targetLang, detecting the source language
+type Translator func(ctx context.Context, text, targetLang string) (string, error)
+
+// languageIDs maps LSP language identifiers to source code languages
+var languageIDs = map[string]sourcecode.Language{
+	"go":              sourcecode.Go,
+	"javascript":      sourcecode.JavaScript,
+	"javascriptreact": sourcecode.JavaScript,
+	"typescript":      sourcecode.JavaScript,
+	"typescriptreact": sourcecode.JavaScript,
+	"python":          sourcecode.Python,
+}
+
+// document is an open text document
+type document struct {
+	text       string
+	languageID string
+}
+
+// Server answers LSP requests over one connection
+type Server struct {
+	conn       *conn
+	translate  Translator
+	targetLang string
+
+	mu     sync.Mutex
+	docs   map[string]*document
+	nextID int
+	wg     sync.WaitGroup // running commands
+}
+
+// NewServer creates a server reading requests from r and writing to w.
+// targetLang is used unless the client sets initializationOptions.targetLang.
+func NewServer(r io.Reader, w io.Writer, translate Translator, targetLang string) *Server {
+	return &Server{
+		conn:       newConn(r, w),
+		translate:  translate,
+		targetLang: targetLang,
+		docs:       make(map[string]*document),
+	}
+}
+
+// Run serves requests until the client sends "exit" or closes the input
+func (s *Server) Run() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		cancel()
+		s.wg.Wait()
+	}()
+
+	for {
+		msg, err := s.conn.read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if msg.Method == "" {
+			continue // a response to one of our requests, or unparseable
+		}
+		if msg.Method == "exit" {
+			return nil
+		}
+		result, rerr := s.handle(ctx, msg)
+		if msg.ID != nil && result != replyLater {
+			s.conn.reply(msg.ID, result, rerr)
+		}
+	}
+}
+
+// replyLater is returned by handle for requests answered from another goroutine
+var replyLater = new(struct{})
+
+// handle runs one request or notification
+func (s *Server) handle(ctx context.Context, msg *message) (any, *responseError) {
+	switch msg.Method {
+	case "initialize":
+		var params initializeParams
+		if err := json.Unmarshal(msg.Params, &params); err == nil && params.InitializationOptions.TargetLang != "" {
+			s.targetLang = params.InitializationOptions.TargetLang
+		}
+		return map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync":   1, // full documents
+				"codeActionProvider": true,
+				"executeCommandProvider": map[string]any{
+					"commands": []string{CommandTranslateSelection, CommandTranslateComment},
+				},
+			},
+			"serverInfo": map[string]string{"name": "tons"},
+		}, nil
+	case "shutdown":
+		return nil, nil
+	case "textDocument/didOpen":
+		var params didOpenParams
+		if json.Unmarshal(msg.Params, &params) == nil {
+			s.mu.Lock()
+			s.docs[params.TextDocument.URI] = &document{text: params.TextDocument.Text, languageID: params.TextDocument.LanguageID}
+			s.mu.Unlock()
+		}
+		return nil, nil
+	case "textDocument/didChange":
+		var params didChangeParams
+		if json.Unmarshal(msg.Params, &params) == nil {
+			s.mu.Lock()
+			if doc, ok := s.docs[params.TextDocument.URI]; ok {
+				for _, change := range params.ContentChanges {
+					if change.Range == nil {
+						doc.text = change.Text
+					} else {
+						start := offset(doc.text, change.Range.Start)
+						end := max(start, offset(doc.text, change.Range.End))
+						doc.text = doc.text[:start] + change.Text + doc.text[end:]
+					}
+				}
+			}
+			s.mu.Unlock()
+		}
+		return nil, nil
+	case "textDocument/didClose":
+		var params didCloseParams
+		if json.Unmarshal(msg.Params, &params) == nil {
+			s.mu.Lock()
+			delete(s.docs, params.TextDocument.URI)
+			s.mu.Unlock()
+		}
+		return nil, nil
+	case "textDocument/codeAction":
+		var params codeActionParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil {
+			return nil, &responseError{Code: codeInvalidParams, Message: err.Error()}
+		}
+		return s.codeActions(params), nil
+	case "workspace/executeCommand":
+		var params executeCommandParams
+		if err := json.Unmarshal(msg.Params, &params); err != nil || len(params.Arguments) != 1 {
+			return nil, &responseError{Code: codeInvalidParams, Message: "expected one {uri, range} argument"}
+		}
+		// Translations take a while; keep reading requests meanwhile
+		s.wg.Go(func() {
+			s.conn.reply(msg.ID, nil, s.execute(ctx, params.Command, params.Arguments[0]))
+		})
+		return replyLater, nil
+	}
+	if msg.ID != nil {
+		return nil, &responseError{Code: codeMethodNotFound, Message: "unsupported method " + msg.Method}
+	}
+	return nil, nil // ignored notification
+}
+
+// codeActions offers translating the selection, and the comment under the
+// cursor in supported source files
+func (s *Server) codeActions(params codeActionParams) []CodeAction {
+	s.mu.Lock()
+	doc, ok := s.docs[params.TextDocument.URI]
+	var text, languageID string
+	if ok {
+		text, languageID = doc.text, doc.languageID
+	}
+	s.mu.Unlock()
+	if !ok {
+		return []CodeAction{}
+	}
+
+	arg := commandParam{URI: params.TextDocument.URI, Range: params.Range}
+	actions := []CodeAction{}
+	if params.Range.Start != params.Range.End {
+		actions = append(actions, CodeAction{
+			Title:   "Translate selection to " + s.targetLang,
+			Kind:    "refactor.rewrite",
+			Command: &Command{Title: "Translate selection", Command: CommandTranslateSelection, Arguments: []any{arg}},
+		})
+	}
+	if file := parseComments(params.TextDocument.URI, languageID, text); file != nil && file.At(offset(text, params.Range.Start)) >= 0 {
+		actions = append(actions, CodeAction{
+			Title:   "Translate comment to " + s.targetLang,
+			Kind:    "refactor.rewrite",
+			Command: &Command{Title: "Translate comment", Command: CommandTranslateComment, Arguments: []any{arg}},
+		})
+	}
+	return actions
+}
+
+// execute runs a translate command and asks the client to apply the result
+func (s *Server) execute(ctx context.Context, command string, arg commandParam) *responseError {
+	s.mu.Lock()
+	doc, ok := s.docs[arg.URI]
+	var text, languageID string
+	if ok {
+		text, languageID = doc.text, doc.languageID
+	}
+	s.mu.Unlock()
+	if !ok {
+		return &responseError{Code: codeInvalidParams, Message: "document is not open: " + arg.URI}
+	}
+
+	var start, end int
+	var replacement string
+	switch command {
+	case CommandTranslateSelection:
+		start, end = offset(text, arg.Range.Start), offset(text, arg.Range.End)
+		if start >= end {
+			return &responseError{Code: codeInvalidParams, Message: "nothing is selected"}
+		}
+		translated, err := s.translate(ctx, text[start:end], s.targetLang)
+		if err != nil {
+			return &responseError{Code: codeRequestFailed, Message: err.Error()}
+		}
+		replacement = translated
+	case CommandTranslateComment:
+		file := parseComments(arg.URI, languageID, text)
+		i := -1
+		if file != nil {
+			i = file.At(offset(text, arg.Range.Start))
+		}
+		if i < 0 {
+			return &responseError{Code: codeInvalidParams, Message: "no comment at the cursor"}
+		}
+		translated, err := s.translate(ctx, file.Texts()[i], s.targetLang)
+		if err != nil {
+			return &responseError{Code: codeRequestFailed, Message: err.Error()}
+		}
+		start, end, replacement = file.Replace(i, translated)
+	default:
+		return &responseError{Code: codeMethodNotFound, Message: "unknown command " + command}
+	}
+
+	edit := WorkspaceEdit{Changes: map[string][]TextEdit{
+		arg.URI: {{
+			Range:   Range{Start: position(text, start), End: position(text, end)},
+			NewText: replacement,
+		}},
+	}}
+	if err := s.request("workspace/applyEdit", map[string]any{"label": "Translate", "edit": edit}); err != nil {
+		return &responseError{Code: codeInternalError, Message: err.Error()}
+	}
+	return nil
+}
+
+// request sends a request to the client without waiting for its response
+func (s *Server) request(method string, params any) error {
+	data, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.nextID++
+	id := json.RawMessage(fmt.Sprintf(`"tons-%d"`, s.nextID))
+	s.mu.Unlock()
+	return s.conn.write(&message{ID: &id, Method: method, Params: data})
+}
+
+// parseComments parses the comments of a supported source file, or returns nil
+func parseComments(uri, languageID, text string) *sourcecode.File {
+	lang, ok := languageIDs[languageID]
+	if !ok {
+		if lang, ok = sourcecode.LanguageFor(path.Ext(strings.TrimSuffix(uri, "/"))); !ok {
+			return nil
+		}
+	}
+	file, err := sourcecode.Parse(lang, []byte(text), sourcecode.Options{Comments: true})
+	if err != nil {
+		return nil
+	}
+	return file
+}
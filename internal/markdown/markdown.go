@@ -0,0 +1,276 @@
+// Package markdown splits a Markdown document into translatable text blocks
+// and structural parts that must be kept verbatim, and renders it back.
+//
+// It is a line-based reader covering the constructs found in READMEs and docs:
+// front matter, fenced and indented code, headings, lists, block quotes,
+// tables, HTML blocks and link reference definitions.
+package markdown
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/ironpark/tons/internal/placeholder"
+)
+
+// Block is a piece of the document. Rendering concatenates Prefix, Text and
+// Suffix of all blocks; only blocks with Translate set have their Text replaced.
+type Block struct {
+	Prefix    string
+	Text      string
+	Suffix    string
+	Translate bool
+
+	mapping placeholder.Mapping // inline fragments masked in Text
+}
+
+// Document is a parsed Markdown document
+type Document struct {
+	Blocks []Block
+}
+
+var (
+	fenceOpen     = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+	heading       = regexp.MustCompile(`^( {0,3}#{1,6}[ \t]+)(.*?)([ \t]+#+)?[ \t]*$`)
+	listItem      = regexp.MustCompile(`^([ \t]*(?:[-*+]|\d{1,9}[.)])[ \t]+(?:\[[ xX]\][ \t]+)?)(.*)$`)
+	blockquote    = regexp.MustCompile(`^((?: {0,3}>[ \t]?)+)(.*)$`)
+	thematicBreak = regexp.MustCompile(`^ {0,3}(?:(?:\*[ \t]*){3,}|(?:-[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	tableDelim    = regexp.MustCompile(`^[ \t]*\|?(?:[ \t]*:?-+:?[ \t]*\|)+[ \t]*:?-*:?[ \t]*\|?[ \t]*$`)
+	linkRefDef    = regexp.MustCompile(`^ {0,3}\[[^\]]+\]:[ \t]*\S+`)
+	htmlBlock     = regexp.MustCompile(`^ {0,3}<(?:[a-zA-Z][a-zA-Z0-9-]*|/[a-zA-Z]|!--)`)
+
+	// inline matches fragments inside translatable text that must not change
+	inline = regexp.MustCompile(strings.Join([]string{
+		"`[^`\n]+`",                    // code spans
+		`\]\([^)\s]*(?:\s+"[^"]*")?\)`, // link and image targets
+		`\]\[[^\]]*\]`,                 // reference link labels
+		`<(?:https?|mailto):[^>\s]+>`,  // autolinks
+		`</?[a-zA-Z][^>\n]*>`,          // inline HTML tags
+	}, "|"))
+)
+
+// Parse splits src into blocks
+func Parse(src string) *Document {
+	d := &Document{}
+	lines := splitLines(src)
+
+	i := 0
+	// Front matter must be the very first line
+	if len(lines) > 0 && (trimEOL(lines[0]) == "---" || trimEOL(lines[0]) == "+++") {
+		delim := trimEOL(lines[0])
+		for j := 1; j < len(lines); j++ {
+			if trimEOL(lines[j]) == delim {
+				d.raw(strings.Join(lines[:j+1], ""))
+				i = j + 1
+				break
+			}
+		}
+	}
+
+	prevBlank := true
+	for i < len(lines) {
+		line := lines[i]
+		content := trimEOL(line)
+		eol := line[len(content):]
+
+		switch {
+		case strings.TrimSpace(content) == "":
+			d.raw(line)
+			prevBlank = true
+			i++
+			continue
+
+		case fenceOpen.MatchString(content):
+			marker := fenceOpen.FindStringSubmatch(content)[1]
+			j := i + 1
+			for j < len(lines) && !isFenceClose(trimEOL(lines[j]), marker) {
+				j++
+			}
+			if j < len(lines) {
+				j++ // include closing fence
+			}
+			d.raw(strings.Join(lines[i:j], ""))
+			i = j
+
+		case prevBlank && (strings.HasPrefix(content, "    ") || strings.HasPrefix(content, "\t")):
+			j := i
+			for j < len(lines) && (strings.TrimSpace(lines[j]) == "" || strings.HasPrefix(lines[j], "    ") || strings.HasPrefix(lines[j], "\t")) {
+				j++
+			}
+			d.raw(strings.Join(lines[i:j], ""))
+			i = j
+
+		case htmlBlock.MatchString(content):
+			j := i
+			for j < len(lines) && strings.TrimSpace(lines[j]) != "" {
+				j++
+			}
+			d.raw(strings.Join(lines[i:j], ""))
+			i = j
+
+		case thematicBreak.MatchString(content), linkRefDef.MatchString(content), tableDelim.MatchString(content):
+			d.raw(line)
+			i++
+
+		case heading.MatchString(content):
+			m := heading.FindStringSubmatch(content)
+			d.text(m[1], m[2], m[3]+eol)
+			i++
+
+		case strings.HasPrefix(strings.TrimSpace(content), "|"):
+			d.tableRow(content, eol)
+			i++
+
+		case listItem.MatchString(content):
+			m := listItem.FindStringSubmatch(content)
+			d.text(m[1], m[2], eol)
+			i++
+
+		case blockquote.MatchString(content):
+			m := blockquote.FindStringSubmatch(content)
+			d.text(m[1], m[2], eol)
+			i++
+
+		default:
+			// Paragraph: consume lines until a blank line or another construct
+			j := i + 1
+			for j < len(lines) && isParagraphContinuation(trimEOL(lines[j])) {
+				j++
+			}
+			para := strings.Join(lines[i:j], "")
+			body := strings.TrimRight(para, "\r\n")
+			indent := body[:len(body)-len(strings.TrimLeft(body, " \t"))]
+			d.text(indent, body[len(indent):], para[len(body):])
+			i = j
+		}
+		prevBlank = false
+	}
+	return d
+}
+
+// Texts returns the masked text of every translatable block, in order
+func (d *Document) Texts() []string {
+	var texts []string
+	for _, b := range d.Blocks {
+		if b.Translate {
+			texts = append(texts, b.Text)
+		}
+	}
+	return texts
+}
+
+// Render rebuilds the document, substituting translated texts in Texts order.
+// Missing translations keep the original text.
+func (d *Document) Render(translated []string) string {
+	var sb strings.Builder
+	n := 0
+	for _, b := range d.Blocks {
+		sb.WriteString(b.Prefix)
+		text := b.Text
+		if b.Translate {
+			if n < len(translated) {
+				text = translated[n]
+			}
+			n++
+		}
+		sb.WriteString(b.mapping.Restore(text))
+		sb.WriteString(b.Suffix)
+	}
+	return sb.String()
+}
+
+// raw appends verbatim content
+func (d *Document) raw(s string) {
+	if s == "" {
+		return
+	}
+	d.Blocks = append(d.Blocks, Block{Text: s})
+}
+
+// text appends a translatable block, keeping blank text verbatim
+func (d *Document) text(prefix, text, suffix string) {
+	if strings.TrimSpace(text) == "" {
+		d.raw(prefix + text + suffix)
+		return
+	}
+	masked, mapping := placeholder.ProtectPattern(text, inline)
+	d.Blocks = append(d.Blocks, Block{
+		Prefix:    prefix,
+		Text:      masked,
+		Suffix:    suffix,
+		Translate: true,
+		mapping:   mapping,
+	})
+}
+
+// tableRow appends each cell of a table row as its own block
+func (d *Document) tableRow(content, eol string) {
+	body := strings.TrimLeft(content, " \t")
+	lead := content[:len(content)-len(body)] + "|"
+
+	cells := splitCells(body[1:])
+	tail := eol
+	if last := cells[len(cells)-1]; len(cells) > 1 && strings.TrimSpace(last) == "" {
+		// The row ends with a pipe
+		tail = "|" + last + eol
+		cells = cells[:len(cells)-1]
+	}
+
+	for i, cell := range cells {
+		trimmed := strings.TrimSpace(cell)
+		start := strings.Index(cell, trimmed)
+		suffix := cell[start+len(trimmed):]
+		if i == len(cells)-1 {
+			suffix += tail
+		}
+		d.text(lead+cell[:start], trimmed, suffix)
+		lead = "|"
+	}
+}
+
+// splitCells splits a table row on unescaped pipes
+func splitCells(row string) []string {
+	var cells []string
+	start := 0
+	for i := 0; i < len(row); i++ {
+		switch row[i] {
+		case '\\':
+			i++
+		case '|':
+			cells = append(cells, row[start:i])
+			start = i + 1
+		}
+	}
+	return append(cells, row[start:])
+}
+
+// isParagraphContinuation reports whether line continues the current paragraph
+func isParagraphContinuation(line string) bool {
+	return strings.TrimSpace(line) != "" &&
+		!fenceOpen.MatchString(line) &&
+		!heading.MatchString(line) &&
+		!listItem.MatchString(line) &&
+		!blockquote.MatchString(line) &&
+		!thematicBreak.MatchString(line) &&
+		!htmlBlock.MatchString(line) &&
+		!strings.HasPrefix(strings.TrimSpace(line), "|")
+}
+
+// isFenceClose reports whether line closes a fence opened with marker
+func isFenceClose(line, marker string) bool {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		return false
+	}
+	return strings.HasPrefix(trimmed, marker) && strings.Trim(trimmed, string(marker[0])+" \t") == ""
+}
+
+// splitLines splits s into lines, keeping line endings
+func splitLines(s string) []string {
+	return strings.SplitAfter(s, "\n")
+}
+
+// trimEOL removes a trailing line ending
+func trimEOL(line string) string {
+	return strings.TrimRight(line, "\r\n")
+}
@@ -0,0 +1,196 @@
+package metrics
+
+import (
+	"maps"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ironpark/tons/internal/engine"
+)
+
+// EngineStats holds aggregated generation statistics for one engine/model
+type EngineStats struct {
+	Engine           string         `json:"engine"`
+	Requests         int            `json:"requests"`
+	PromptTokens     int            `json:"promptTokens"`
+	CompletionTokens int            `json:"completionTokens"`
+	EvalDuration     time.Duration  `json:"evalDuration"`
+	LoadDuration     time.Duration  `json:"loadDuration"`
+	TokensPerSecond  float64        `json:"tokensPerSecond"` // averaged over all recorded requests
+	AverageLatency   time.Duration  `json:"averageLatency"`  // time from request to complete translation
+	Failures         int            `json:"failures"`
+	Errors           map[string]int `json:"errors,omitempty"` // failures by ErrorClass
+	LastError        string         `json:"lastError,omitempty"`
+	LastErrorAt      time.Time      `json:"lastErrorAt,omitzero"`
+
+	latencyTotal   time.Duration
+	latencyCount   int
+	latencyBuckets []int // translations per latencyBounds bucket, cumulative like Prometheus
+}
+
+// latencyBounds are the upper bounds in seconds of the latency histogram
+var latencyBounds = []float64{0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120}
+
+// Recorder aggregates engine usage in memory
+type Recorder struct {
+	mu          sync.Mutex
+	stats       map[string]*EngineStats
+	cacheHits   int
+	cacheMisses int
+}
+
+// NewRecorder creates an empty Recorder
+func NewRecorder() *Recorder {
+	return &Recorder{
+		stats: make(map[string]*EngineStats),
+	}
+}
+
+// engine returns the statistics of an engine, creating them on first use.
+// Must be called with r.mu held.
+func (r *Recorder) engine(engineName string) *EngineStats {
+	s, ok := r.stats[engineName]
+	if !ok {
+		s = &EngineStats{Engine: engineName}
+		r.stats[engineName] = s
+	}
+	return s
+}
+
+// Record adds the usage of one completed translation
+func (r *Recorder) Record(engineName string, usage engine.Usage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := r.engine(engineName)
+	s.Requests++
+	s.PromptTokens += usage.PromptTokens
+	s.CompletionTokens += usage.CompletionTokens
+	s.EvalDuration += usage.EvalDuration
+	s.LoadDuration += usage.LoadDuration
+	if s.EvalDuration > 0 {
+		s.TokensPerSecond = float64(s.CompletionTokens) / s.EvalDuration.Seconds()
+	}
+}
+
+// RecordLatency adds the time one translation took from request to result
+func (r *Recorder) RecordLatency(engineName string, latency time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := r.engine(engineName)
+	s.latencyTotal += latency
+	s.latencyCount++
+	s.AverageLatency = s.latencyTotal / time.Duration(s.latencyCount)
+	if s.latencyBuckets == nil {
+		s.latencyBuckets = make([]int, len(latencyBounds))
+	}
+	for i, bound := range latencyBounds {
+		if latency.Seconds() <= bound {
+			s.latencyBuckets[i]++
+		}
+	}
+}
+
+// RecordCache counts a lookup in the translation cache
+func (r *Recorder) RecordCache(hit bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if hit {
+		r.cacheHits++
+	} else {
+		r.cacheMisses++
+	}
+}
+
+// Cache returns the translation cache hits and misses
+func (r *Recorder) Cache() (hits, misses int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.cacheHits, r.cacheMisses
+}
+
+// RecordError remembers a failed translation
+func (r *Recorder) RecordError(engineName, message string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := r.engine(engineName)
+	s.Failures++
+	if s.Errors == nil {
+		s.Errors = make(map[string]int)
+	}
+	s.Errors[ErrorClass(message)]++
+	s.LastError = message
+	s.LastErrorAt = time.Now()
+}
+
+// Engine returns the statistics of one engine
+func (r *Recorder) Engine(engineName string) (EngineStats, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.stats[engineName]
+	if !ok {
+		return EngineStats{Engine: engineName}, false
+	}
+	return s.copy(), true
+}
+
+// Stats returns a copy of all aggregated statistics sorted by engine name
+func (r *Recorder) Stats() []EngineStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stats := make([]EngineStats, 0, len(r.stats))
+	for _, s := range r.stats {
+		stats = append(stats, s.copy())
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Engine < stats[j].Engine
+	})
+	return stats
+}
+
+// copy returns a copy of the statistics that shares no map
+func (s *EngineStats) copy() EngineStats {
+	c := *s
+	c.Errors = maps.Clone(s.Errors)
+	c.latencyBuckets = slices.Clone(s.latencyBuckets)
+	return c
+}
+
+// ErrorClass sorts an error message into a coarse class such as "timeout" or
+// "unavailable", for statistics that must not carry the message itself
+func ErrorClass(message string) string {
+	m := strings.ToLower(message)
+	switch {
+	case strings.Contains(m, "deadline exceeded") || strings.Contains(m, "timeout") || strings.Contains(m, "timed out"):
+		return "timeout"
+	case strings.Contains(m, "canceled") || strings.Contains(m, "cancelled"):
+		return "cancelled"
+	case strings.Contains(m, "connection refused") || strings.Contains(m, "no such host") ||
+		strings.Contains(m, "not found") || strings.Contains(m, "not available") || strings.Contains(m, "not pulled"):
+		return "unavailable"
+	case strings.Contains(m, "unauthorized") || strings.Contains(m, "forbidden") || strings.Contains(m, "401") || strings.Contains(m, "403"):
+		return "auth"
+	case strings.Contains(m, "context") && (strings.Contains(m, "length") || strings.Contains(m, "size")) || strings.Contains(m, "too long"):
+		return "input-too-long"
+	default:
+		return "other"
+	}
+}
+
+// Reset clears all recorded statistics
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.stats = make(map[string]*EngineStats)
+	r.cacheHits, r.cacheMisses = 0, 0
+}
@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// PrometheusContentType is the content type of WritePrometheus output
+const PrometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// WritePrometheus writes the statistics in the Prometheus text exposition
+// format: translations, errors by class, latency, tokens per engine and the
+// translation cache lookups
+func (r *Recorder) WritePrometheus(w io.Writer) error {
+	stats := r.Stats()
+	hits, misses := r.Cache()
+	b := bufio.NewWriter(w)
+
+	metric := func(name, kind, help string) {
+		fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	metric("tons_translations_total", "counter", "Completed translations.")
+	for _, s := range stats {
+		fmt.Fprintf(b, "tons_translations_total{engine=%s} %d\n", label(s.Engine), s.latencyCount)
+	}
+
+	metric("tons_translation_errors_total", "counter", "Failed translations by error class.")
+	for _, s := range stats {
+		for _, class := range slices.Sorted(maps.Keys(s.Errors)) {
+			fmt.Fprintf(b, "tons_translation_errors_total{engine=%s,class=%s} %d\n", label(s.Engine), label(class), s.Errors[class])
+		}
+	}
+
+	metric("tons_translation_duration_seconds", "histogram", "Time from request to complete translation.")
+	for _, s := range stats {
+		for i, bound := range latencyBounds {
+			n := 0
+			if s.latencyBuckets != nil {
+				n = s.latencyBuckets[i]
+			}
+			fmt.Fprintf(b, "tons_translation_duration_seconds_bucket{engine=%s,le=%s} %d\n",
+				label(s.Engine), label(strconv.FormatFloat(bound, 'g', -1, 64)), n)
+		}
+		fmt.Fprintf(b, "tons_translation_duration_seconds_bucket{engine=%s,le=\"+Inf\"} %d\n", label(s.Engine), s.latencyCount)
+		fmt.Fprintf(b, "tons_translation_duration_seconds_sum{engine=%s} %g\n", label(s.Engine), s.latencyTotal.Seconds())
+		fmt.Fprintf(b, "tons_translation_duration_seconds_count{engine=%s} %d\n", label(s.Engine), s.latencyCount)
+	}
+
+	metric("tons_prompt_tokens_total", "counter", "Prompt tokens sent to engines.")
+	for _, s := range stats {
+		fmt.Fprintf(b, "tons_prompt_tokens_total{engine=%s} %d\n", label(s.Engine), s.PromptTokens)
+	}
+	metric("tons_completion_tokens_total", "counter", "Tokens generated by engines.")
+	for _, s := range stats {
+		fmt.Fprintf(b, "tons_completion_tokens_total{engine=%s} %d\n", label(s.Engine), s.CompletionTokens)
+	}
+
+	metric("tons_cache_hits_total", "counter", "Translations served from the translation cache.")
+	fmt.Fprintf(b, "tons_cache_hits_total %d\n", hits)
+	metric("tons_cache_misses_total", "counter", "Translation cache lookups that found nothing.")
+	fmt.Fprintf(b, "tons_cache_misses_total %d\n", misses)
+
+	return b.Flush()
+}
+
+// labelEscaper escapes label values as the exposition format requires
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// label quotes a label value
+func label(value string) string {
+	return `"` + labelEscaper.Replace(value) + `"`
+}
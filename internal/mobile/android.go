@@ -0,0 +1,295 @@
+// Package mobile reads and writes mobile app string resources: Android
+// strings.xml, Apple .strings and Xcode .xcstrings string catalogs.
+//
+// Each parsed file exposes its translatable values as plain text (escaping
+// removed, inline markup masked with placeholder tokens) and renders a
+// localized file from their translations.
+package mobile
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ironpark/tons/internal/placeholder"
+)
+
+// AndroidStrings is a parsed Android strings.xml resource file
+type AndroidStrings struct {
+	data     []byte
+	values   []androidValue
+	removals []span // elements not to copy into a localized file
+}
+
+// androidValue is the inner content of a <string> or an <item> of an array or plurals
+type androidValue struct {
+	Name    string // resource name; array items add their index, plural items their quantity
+	content span
+	text    string
+	mapping placeholder.Mapping
+}
+
+// span is a byte range in the original file
+type span struct {
+	start, end int
+}
+
+// ParseAndroid reads an Android strings.xml file
+func ParseAndroid(data []byte) (*AndroidStrings, error) {
+	doc := &AndroidStrings{data: data}
+	dec := xml.NewDecoder(bytes.NewReader(data))
+
+	sawResources := false
+	parentName := ""
+	itemIndex := 0
+	for {
+		start := int(dec.InputOffset())
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("strings.xml: %w", err)
+		}
+		end := int(dec.InputOffset())
+
+		t, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch t.Name.Local {
+		case "resources":
+			sawResources = true
+		case "string", "string-array", "plurals":
+			name := xmlAttr(t, "name")
+			if xmlAttr(t, "translatable") == "false" {
+				elemEnd, err := skipElement(dec)
+				if err != nil {
+					return nil, err
+				}
+				doc.removals = append(doc.removals, lineSpan(data, start, elemEnd))
+				continue
+			}
+			if t.Name.Local != "string" {
+				parentName = name
+				itemIndex = 0
+				continue
+			}
+			inner, err := readInner(dec, data, end)
+			if err != nil {
+				return nil, err
+			}
+			doc.addValue(name, span{end, end + len(inner)})
+		case "item":
+			name := parentName
+			if q := xmlAttr(t, "quantity"); q != "" {
+				name += ":" + q
+			} else {
+				name += "[" + strconv.Itoa(itemIndex) + "]"
+				itemIndex++
+			}
+			inner, err := readInner(dec, data, end)
+			if err != nil {
+				return nil, err
+			}
+			doc.addValue(name, span{end, end + len(inner)})
+		}
+	}
+	if !sawResources {
+		return nil, errors.New("strings.xml: missing <resources> element")
+	}
+	return doc, nil
+}
+
+// addValue records a translatable value, skipping resource references like @string/other
+func (a *AndroidStrings) addValue(name string, content span) {
+	raw := string(a.data[content.start:content.end])
+	if strings.HasPrefix(strings.TrimSpace(raw), "@") || strings.TrimSpace(raw) == "" {
+		return
+	}
+	masked, mapping := maskTags(raw)
+	a.values = append(a.values, androidValue{
+		Name:    name,
+		content: content,
+		text:    unescapeAndroid(masked),
+		mapping: mapping,
+	})
+}
+
+// Texts returns the translatable values as plain text
+func (a *AndroidStrings) Texts() []string {
+	texts := make([]string, len(a.values))
+	for i, v := range a.values {
+		texts[i] = v.text
+	}
+	return texts
+}
+
+// Names returns the resource names of the values, in the order of Texts
+func (a *AndroidStrings) Names() []string {
+	names := make([]string, len(a.values))
+	for i, v := range a.values {
+		names[i] = v.Name
+	}
+	return names
+}
+
+// Render returns a localized strings.xml with the translated values.
+// Untranslatable strings are left out, as they belong to the default resources only.
+func (a *AndroidStrings) Render(translated []string) ([]byte, error) {
+	if len(translated) != len(a.values) {
+		return nil, fmt.Errorf("strings.xml: got %d translations for %d values", len(translated), len(a.values))
+	}
+	edits := make([]edit, 0, len(a.values)+len(a.removals))
+	for i, v := range a.values {
+		edits = append(edits, edit{v.content, v.mapping.Restore(escapeAndroid(translated[i]))})
+	}
+	for _, r := range a.removals {
+		edits = append(edits, edit{r, ""})
+	}
+	return applyEdits(a.data, edits), nil
+}
+
+// unescapeAndroid decodes XML entities and Android string escapes
+func unescapeAndroid(s string) string {
+	s = html.UnescapeString(s)
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		s = s[1 : len(s)-1] // quoted values keep their whitespace
+	} else {
+		s = strings.Join(strings.Fields(s), " ")
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i == len(s)-1 {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'u':
+			if i+4 < len(s) {
+				if r, err := strconv.ParseUint(s[i+1:i+5], 16, 32); err == nil {
+					b.WriteRune(rune(r))
+					i += 4
+					continue
+				}
+			}
+			b.WriteString(`\u`)
+		default:
+			b.WriteByte(s[i]) // \' \" \\ \@ \?
+		}
+	}
+	return b.String()
+}
+
+// escapeAndroid encodes text for an Android string resource
+func escapeAndroid(s string) string {
+	s = escapeXML(s)
+	s = strings.NewReplacer(`\`, `\\`, `'`, `\'`, `"`, `\"`, "\n", `\n`, "\t", `\t`).Replace(s)
+	if strings.TrimSpace(s) != s || strings.Contains(s, "  ") {
+		return `"` + s + `"` // unquoted values have their whitespace collapsed
+	}
+	if strings.HasPrefix(s, "@") || strings.HasPrefix(s, "?") {
+		s = `\` + s
+	}
+	return s
+}
+
+var markupTag = regexp.MustCompile(`(?s)<!\[CDATA\[.*?\]\]>|<[^>]+>`)
+
+// maskTags replaces inline markup (<b>, <xliff:g>, ...) with placeholder tokens
+func maskTags(s string) (string, placeholder.Mapping) {
+	return placeholder.ProtectPattern(s, markupTag)
+}
+
+// escapeXML escapes the characters XML text cannot contain
+func escapeXML(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
+
+// xmlAttr returns the value of the named attribute
+func xmlAttr(t xml.StartElement, name string) string {
+	for _, a := range t.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// readInner consumes the current element and returns its raw inner XML
+func readInner(dec *xml.Decoder, data []byte, contentStart int) (string, error) {
+	depth := 1
+	for {
+		before := int(dec.InputOffset())
+		tok, err := dec.Token()
+		if err != nil {
+			return "", fmt.Errorf("strings.xml: %w", err)
+		}
+		switch tok.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+			if depth == 0 {
+				return string(data[contentStart:max(before, contentStart)]), nil
+			}
+		}
+	}
+}
+
+// skipElement consumes the current element and returns the offset after it
+func skipElement(dec *xml.Decoder) (int, error) {
+	if err := dec.Skip(); err != nil {
+		return 0, fmt.Errorf("strings.xml: %w", err)
+	}
+	return int(dec.InputOffset()), nil
+}
+
+// lineSpan widens an element span to its whole line when it stands alone on it
+func lineSpan(data []byte, start, end int) span {
+	lineStart := bytes.LastIndexByte(data[:start], '\n') + 1
+	if len(bytes.TrimSpace(data[lineStart:start])) != 0 {
+		return span{start, end}
+	}
+	lineEnd := end
+	if i := bytes.IndexByte(data[end:], '\n'); i >= 0 && len(bytes.TrimSpace(data[end:end+i])) == 0 {
+		lineEnd = end + i + 1
+	}
+	return span{lineStart, lineEnd}
+}
+
+// edit replaces a byte range of the original file
+type edit struct {
+	span
+	text string
+}
+
+// applyEdits splices non-overlapping edits into data
+func applyEdits(data []byte, edits []edit) []byte {
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+	var out bytes.Buffer
+	pos := 0
+	for _, e := range edits {
+		if e.start < pos {
+			continue
+		}
+		out.Write(data[pos:e.start])
+		out.WriteString(e.text)
+		pos = e.end
+	}
+	out.Write(data[pos:])
+	return out.Bytes()
+}
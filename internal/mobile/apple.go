@@ -0,0 +1,243 @@
+package mobile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// AppleStrings is a parsed Apple .strings file ("key" = "value"; pairs with comments)
+type AppleStrings struct {
+	data    []byte
+	entries []appleEntry
+}
+
+// appleEntry is a key/value pair; value spans the quoted value including quotes
+type appleEntry struct {
+	Key   string
+	Value string
+	value span
+}
+
+// ParseAppleStrings reads a .strings file. UTF-16 files are converted to UTF-8.
+func ParseAppleStrings(data []byte) (*AppleStrings, error) {
+	data, err := decodeUTF16(data)
+	if err != nil {
+		return nil, err
+	}
+	doc := &AppleStrings{data: data}
+	s := stringsScanner{data: data}
+	for {
+		s.skipSpace()
+		if s.pos >= len(data) {
+			return doc, nil
+		}
+		key, _, err := s.token()
+		if err != nil {
+			return nil, err
+		}
+		s.skipSpace()
+		if !s.consume('=') {
+			return nil, s.errorf("expected '=' after %q", key)
+		}
+		s.skipSpace()
+		value, valueSpan, err := s.token()
+		if err != nil {
+			return nil, err
+		}
+		s.skipSpace()
+		if !s.consume(';') {
+			return nil, s.errorf("expected ';' after value of %q", key)
+		}
+		doc.entries = append(doc.entries, appleEntry{Key: key, Value: value, value: valueSpan})
+	}
+}
+
+// Texts returns the values to translate
+func (a *AppleStrings) Texts() []string {
+	texts := make([]string, len(a.entries))
+	for i, e := range a.entries {
+		texts[i] = e.Value
+	}
+	return texts
+}
+
+// Keys returns the keys of the values, in the order of Texts
+func (a *AppleStrings) Keys() []string {
+	keys := make([]string, len(a.entries))
+	for i, e := range a.entries {
+		keys[i] = e.Key
+	}
+	return keys
+}
+
+// Render returns the file with translated values, keeping keys and comments
+func (a *AppleStrings) Render(translated []string) ([]byte, error) {
+	if len(translated) != len(a.entries) {
+		return nil, fmt.Errorf(".strings: got %d translations for %d values", len(translated), len(a.entries))
+	}
+	edits := make([]edit, len(a.entries))
+	for i, e := range a.entries {
+		edits[i] = edit{e.value, quoteApple(translated[i])}
+	}
+	return applyEdits(a.data, edits), nil
+}
+
+// stringsScanner tokenizes the old-style property list syntax of .strings files
+type stringsScanner struct {
+	data []byte
+	pos  int
+}
+
+// skipSpace skips whitespace and comments
+func (s *stringsScanner) skipSpace() {
+	for s.pos < len(s.data) {
+		rest := s.data[s.pos:]
+		switch {
+		case bytes.HasPrefix(rest, []byte("/*")):
+			end := bytes.Index(rest[2:], []byte("*/"))
+			if end < 0 {
+				s.pos = len(s.data)
+				return
+			}
+			s.pos += end + 4
+		case bytes.HasPrefix(rest, []byte("//")):
+			end := bytes.IndexByte(rest, '\n')
+			if end < 0 {
+				s.pos = len(s.data)
+				return
+			}
+			s.pos += end + 1
+		case rest[0] == ' ' || rest[0] == '\t' || rest[0] == '\n' || rest[0] == '\r':
+			s.pos++
+		default:
+			return
+		}
+	}
+}
+
+// consume skips c if it is next
+func (s *stringsScanner) consume(c byte) bool {
+	if s.pos < len(s.data) && s.data[s.pos] == c {
+		s.pos++
+		return true
+	}
+	return false
+}
+
+// token reads a quoted string or a bare word
+func (s *stringsScanner) token() (string, span, error) {
+	start := s.pos
+	if s.pos >= len(s.data) {
+		return "", span{}, s.errorf("unexpected end of file")
+	}
+	if s.data[s.pos] != '"' {
+		for s.pos < len(s.data) && isBareChar(s.data[s.pos]) {
+			s.pos++
+		}
+		if s.pos == start {
+			return "", span{}, s.errorf("unexpected %q", s.data[s.pos])
+		}
+		return string(s.data[start:s.pos]), span{start, s.pos}, nil
+	}
+
+	var b strings.Builder
+	s.pos++
+	for s.pos < len(s.data) {
+		c := s.data[s.pos]
+		switch c {
+		case '"':
+			s.pos++
+			return b.String(), span{start, s.pos}, nil
+		case '\\':
+			s.pos++
+			if s.pos >= len(s.data) {
+				return "", span{}, s.errorf("unterminated string")
+			}
+			s.unescape(&b)
+		default:
+			b.WriteByte(c)
+			s.pos++
+		}
+	}
+	return "", span{}, s.errorf("unterminated string")
+}
+
+// unescape decodes the escape sequence at pos (after the backslash)
+func (s *stringsScanner) unescape(b *strings.Builder) {
+	c := s.data[s.pos]
+	s.pos++
+	switch c {
+	case 'n':
+		b.WriteByte('\n')
+	case 't':
+		b.WriteByte('\t')
+	case 'r':
+		b.WriteByte('\r')
+	case 'U', 'u':
+		if s.pos+4 <= len(s.data) {
+			if r, err := strconv.ParseUint(string(s.data[s.pos:s.pos+4]), 16, 32); err == nil {
+				s.pos += 4
+				if utf16.IsSurrogate(rune(r)) && s.pos+6 <= len(s.data) && s.data[s.pos] == '\\' {
+					if lo, err := strconv.ParseUint(string(s.data[s.pos+2:s.pos+6]), 16, 32); err == nil {
+						b.WriteRune(utf16.DecodeRune(rune(r), rune(lo)))
+						s.pos += 6
+						return
+					}
+				}
+				b.WriteRune(rune(r))
+				return
+			}
+		}
+		b.WriteByte(c)
+	default:
+		b.WriteByte(c) // \" \\ \'
+	}
+}
+
+func (s *stringsScanner) errorf(format string, args ...any) error {
+	line := bytes.Count(s.data[:min(s.pos, len(s.data))], []byte("\n")) + 1
+	return fmt.Errorf(".strings line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// isBareChar reports whether c may appear in an unquoted key
+func isBareChar(c byte) bool {
+	return c == '_' || c == '.' || c == '-' || c == '$' || c == '/' || c == ':' ||
+		('0' <= c && c <= '9') || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+// quoteApple quotes a value for a .strings file
+func quoteApple(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, "\r", `\r`).Replace(s) + `"`
+}
+
+// decodeUTF16 converts UTF-16 data with a byte order mark to UTF-8
+func decodeUTF16(data []byte) ([]byte, error) {
+	var order binary.ByteOrder
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}):
+		order = binary.LittleEndian
+	case bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		order = binary.BigEndian
+	default:
+		return bytes.TrimPrefix(data, []byte{0xEF, 0xBB, 0xBF}), nil
+	}
+	data = data[2:]
+	if len(data)%2 != 0 {
+		return nil, errors.New(".strings: truncated UTF-16 file")
+	}
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2*i:])
+	}
+	var out []byte
+	for _, r := range utf16.Decode(units) {
+		out = utf8.AppendRune(out, r)
+	}
+	return out, nil
+}
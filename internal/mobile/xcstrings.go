@@ -0,0 +1,153 @@
+package mobile
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// String unit states in a string catalog
+const (
+	StateTranslated  = "translated"
+	StateNeedsReview = "needs_review"
+)
+
+// StringCatalog is a parsed Xcode string catalog (.xcstrings). Fields the
+// catalog carries but tons does not use are kept as they are.
+type StringCatalog struct {
+	SourceLang string
+	Units      []*CatalogUnit
+	root       map[string]any
+	targetLang string
+}
+
+// CatalogUnit is a source string unit of a catalog; plural and device
+// variations are units of their own
+type CatalogUnit struct {
+	ID         string // string key, followed by the variation path
+	Text       string
+	Translated bool // the string has a localization in the target language
+	key        string
+	path       []string // path of the unit below the string's localization
+}
+
+// ParseStringCatalog reads a .xcstrings file for translation into targetLang
+func ParseStringCatalog(data []byte, targetLang string) (*StringCatalog, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var root map[string]any
+	if err := dec.Decode(&root); err != nil {
+		return nil, fmt.Errorf(".xcstrings: %w", err)
+	}
+	c := &StringCatalog{root: root, targetLang: targetLang}
+	c.SourceLang, _ = root["sourceLanguage"].(string)
+	if c.SourceLang == "" {
+		return nil, fmt.Errorf(".xcstrings: missing sourceLanguage")
+	}
+
+	strs, _ := root["strings"].(map[string]any)
+	keys := make([]string, 0, len(strs))
+	for key := range strs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		entry, _ := strs[key].(map[string]any)
+		if entry == nil || entry["shouldTranslate"] == false || key == "" {
+			continue
+		}
+		locs, _ := entry["localizations"].(map[string]any)
+		_, translated := locs[targetLang]
+		source, _ := locs[c.SourceLang].(map[string]any)
+		if source == nil {
+			// Strings extracted from code use the key as source text
+			c.Units = append(c.Units, &CatalogUnit{ID: key, Text: key, Translated: translated, key: key, path: []string{"stringUnit"}})
+			continue
+		}
+		c.collect(key, source, nil, translated)
+	}
+	return c, nil
+}
+
+// collect adds the string units below a source localization, including plural and device variations
+func (c *StringCatalog) collect(key string, node map[string]any, path []string, translated bool) {
+	if unit, ok := node["stringUnit"].(map[string]any); ok {
+		if value, _ := unit["value"].(string); strings.TrimSpace(value) != "" {
+			c.Units = append(c.Units, &CatalogUnit{
+				ID:         strings.Join(append([]string{key}, path...), "/"),
+				Text:       value,
+				Translated: translated,
+				key:        key,
+				path:       append(append([]string(nil), path...), "stringUnit"),
+			})
+		}
+	}
+	if variations, ok := node["variations"].(map[string]any); ok {
+		for _, kind := range sortedKeys(variations) {
+			cases, _ := variations[kind].(map[string]any)
+			for _, name := range sortedKeys(cases) {
+				if child, ok := cases[name].(map[string]any); ok {
+					c.collect(key, child, append(append([]string(nil), path...), "variations", kind, name), translated)
+				}
+			}
+		}
+	}
+	if subs, ok := node["substitutions"].(map[string]any); ok {
+		for _, name := range sortedKeys(subs) {
+			if child, ok := subs[name].(map[string]any); ok {
+				c.collect(key, child, append(append([]string(nil), path...), "substitutions", name), translated)
+			}
+		}
+	}
+}
+
+// SetTranslation sets the target language value of a unit with the given state
+func (c *StringCatalog) SetTranslation(u *CatalogUnit, value, state string) {
+	strs, _ := c.root["strings"].(map[string]any)
+	entry := strs[u.key].(map[string]any)
+	node := child(entry, "localizations")
+	node = child(node, c.targetLang)
+	for _, name := range u.path {
+		node = child(node, name)
+	}
+	node["state"] = state
+	node["value"] = value
+}
+
+// Bytes returns the catalog in Xcode's formatting
+func (c *StringCatalog) Bytes() ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(c.root); err != nil {
+		return nil, err
+	}
+	return xcodeKey.ReplaceAll(buf.Bytes(), []byte("$1 : ")), nil
+}
+
+// xcodeKey matches object keys, which Xcode writes as "key" : value
+var xcodeKey = regexp.MustCompile(`(?m)^(\s*"(?:[^"\\]|\\.)*"): `)
+
+// child returns the object under name, creating it when missing
+func child(node map[string]any, name string) map[string]any {
+	next, ok := node[name].(map[string]any)
+	if !ok {
+		next = map[string]any{}
+		node[name] = next
+	}
+	return next
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
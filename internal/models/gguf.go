@@ -0,0 +1,224 @@
+// Package models manages the GGUF model files used by the internal engine
+package models
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// ErrNotGGUF is returned for files without a GGUF header
+var ErrNotGGUF = errors.New("not a GGUF file")
+
+// maxStringLen guards against corrupt headers claiming huge strings
+const maxStringLen = 1 << 24
+
+// GGUF value types
+const (
+	typeUint8 uint32 = iota
+	typeInt8
+	typeUint16
+	typeInt16
+	typeUint32
+	typeInt32
+	typeFloat32
+	typeBool
+	typeString
+	typeArray
+	typeUint64
+	typeInt64
+	typeFloat64
+)
+
+// Info is the metadata of a GGUF model
+type Info struct {
+	Version         uint32         `json:"version"` // GGUF format version
+	TensorCount     uint64         `json:"tensorCount"`
+	Architecture    string         `json:"architecture"` // e.g. "llama", "gemma3"
+	Name            string         `json:"name,omitempty"`
+	SizeLabel       string         `json:"sizeLabel,omitempty"` // e.g. "8B"
+	Quantization    string         `json:"quantization"`        // e.g. "Q4_K_M"
+	ContextLength   int            `json:"contextLength,omitempty"`
+	EmbeddingLength int            `json:"embeddingLength,omitempty"`
+	BlockCount      int            `json:"blockCount,omitempty"`
+	Metadata        map[string]any `json:"metadata"` // all scalar keys; arrays are reported by length
+}
+
+// fileTypes names the general.file_type values written by llama.cpp
+var fileTypes = map[uint64]string{
+	0: "F32", 1: "F16", 2: "Q4_0", 3: "Q4_1", 7: "Q8_0", 8: "Q5_0", 9: "Q5_1",
+	10: "Q2_K", 11: "Q3_K_S", 12: "Q3_K_M", 13: "Q3_K_L", 14: "Q4_K_S", 15: "Q4_K_M",
+	16: "Q5_K_S", 17: "Q5_K_M", 18: "Q6_K", 19: "IQ2_XXS", 20: "IQ2_XS", 21: "Q2_K_S",
+	22: "IQ3_XS", 23: "IQ3_XXS", 24: "IQ1_S", 25: "IQ4_NL", 26: "IQ3_S", 27: "IQ3_M",
+	28: "IQ2_S", 29: "IQ2_M", 30: "IQ4_XS", 31: "IQ1_M", 32: "BF16", 36: "TQ1_0", 37: "TQ2_0",
+}
+
+// Inspect reads the metadata of a GGUF file without loading its tensors
+func Inspect(path string) (Info, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Info{}, err
+	}
+	defer f.Close()
+
+	info, err := readHeader(bufio.NewReader(f))
+	if err != nil {
+		return Info{}, fmt.Errorf("%s: %w", path, err)
+	}
+	return info, nil
+}
+
+// readHeader parses the GGUF header and key/value metadata
+func readHeader(r io.Reader) (Info, error) {
+	d := &decoder{r: r}
+	var magic [4]byte
+	if _, err := io.ReadFull(r, magic[:]); err != nil || string(magic[:]) != "GGUF" {
+		return Info{}, ErrNotGGUF
+	}
+	info := Info{Version: d.uint32(), Metadata: map[string]any{}}
+	if d.err == nil && info.Version < 2 {
+		return Info{}, fmt.Errorf("GGUF version %d is not supported", info.Version)
+	}
+	info.TensorCount = d.uint64()
+	count := d.uint64()
+	for i := uint64(0); i < count && d.err == nil; i++ {
+		key := d.string()
+		info.Metadata[key] = d.value(d.uint32())
+	}
+	if d.err != nil {
+		return Info{}, fmt.Errorf("reading GGUF metadata: %w", d.err)
+	}
+
+	info.Architecture, _ = info.Metadata["general.architecture"].(string)
+	info.Name, _ = info.Metadata["general.name"].(string)
+	info.SizeLabel, _ = info.Metadata["general.size_label"].(string)
+	if fileType, ok := toUint(info.Metadata["general.file_type"]); ok {
+		info.Quantization = fileTypes[fileType]
+		if info.Quantization == "" {
+			info.Quantization = fmt.Sprintf("type %d", fileType)
+		}
+	}
+	arch := info.Architecture + "."
+	if n, ok := toUint(info.Metadata[arch+"context_length"]); ok {
+		info.ContextLength = int(n)
+	}
+	if n, ok := toUint(info.Metadata[arch+"embedding_length"]); ok {
+		info.EmbeddingLength = int(n)
+	}
+	if n, ok := toUint(info.Metadata[arch+"block_count"]); ok {
+		info.BlockCount = int(n)
+	}
+	return info, nil
+}
+
+// toUint converts an unsigned metadata value
+func toUint(v any) (uint64, bool) {
+	switch n := v.(type) {
+	case uint8:
+		return uint64(n), true
+	case uint16:
+		return uint64(n), true
+	case uint32:
+		return uint64(n), true
+	case uint64:
+		return n, true
+	case int32:
+		return uint64(max(n, 0)), true
+	case int64:
+		return uint64(max(n, 0)), true
+	}
+	return 0, false
+}
+
+// ArrayInfo stands in for array values, which can hold whole vocabularies
+type ArrayInfo struct {
+	Type   string `json:"type"`
+	Length uint64 `json:"length"`
+}
+
+// decoder reads little-endian GGUF values, keeping the first error
+type decoder struct {
+	r   io.Reader
+	err error
+	buf [8]byte
+}
+
+func (d *decoder) read(n int) []byte {
+	if d.err != nil {
+		return make([]byte, n)
+	}
+	_, d.err = io.ReadFull(d.r, d.buf[:n])
+	return d.buf[:n]
+}
+
+func (d *decoder) uint32() uint32 { return binary.LittleEndian.Uint32(d.read(4)) }
+func (d *decoder) uint64() uint64 { return binary.LittleEndian.Uint64(d.read(8)) }
+
+func (d *decoder) string() string {
+	n := d.uint64()
+	if d.err != nil {
+		return ""
+	}
+	if n > maxStringLen {
+		d.err = fmt.Errorf("string of %d bytes", n)
+		return ""
+	}
+	b := make([]byte, n)
+	_, d.err = io.ReadFull(d.r, b)
+	return string(b)
+}
+
+// value reads a value of type t. Arrays are skipped and summarized.
+func (d *decoder) value(t uint32) any {
+	switch t {
+	case typeUint8:
+		return d.read(1)[0]
+	case typeInt8:
+		return int8(d.read(1)[0])
+	case typeUint16:
+		return binary.LittleEndian.Uint16(d.read(2))
+	case typeInt16:
+		return int16(binary.LittleEndian.Uint16(d.read(2)))
+	case typeUint32:
+		return d.uint32()
+	case typeInt32:
+		return int32(d.uint32())
+	case typeFloat32:
+		return math.Float32frombits(d.uint32())
+	case typeBool:
+		return d.read(1)[0] != 0
+	case typeString:
+		return d.string()
+	case typeUint64:
+		return d.uint64()
+	case typeInt64:
+		return int64(d.uint64())
+	case typeFloat64:
+		return math.Float64frombits(d.uint64())
+	case typeArray:
+		elem := d.uint32()
+		n := d.uint64()
+		for i := uint64(0); i < n && d.err == nil; i++ {
+			d.value(elem)
+		}
+		return ArrayInfo{Type: typeName(elem), Length: n}
+	default:
+		if d.err == nil {
+			d.err = fmt.Errorf("unknown value type %d", t)
+		}
+		return nil
+	}
+}
+
+// typeName names a GGUF value type
+func typeName(t uint32) string {
+	names := []string{"uint8", "int8", "uint16", "int16", "uint32", "int32", "float32", "bool", "string", "array", "uint64", "int64", "float64"}
+	if int(t) < len(names) {
+		return names[t]
+	}
+	return "unknown"
+}
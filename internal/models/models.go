@@ -0,0 +1,84 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when no model file has the given name
+var ErrNotFound = errors.New("model not found")
+
+// Model is a GGUF file in the models directory
+type Model struct {
+	Name       string    `json:"name"` // file name
+	Path       string    `json:"path"`
+	Size       int64     `json:"size"`
+	ModifiedAt time.Time `json:"modifiedAt"`
+	Info       *Info     `json:"info,omitempty"`  // nil if the header could not be read
+	Error      string    `json:"error,omitempty"` // why the header could not be read
+}
+
+// List returns the GGUF models in dir sorted by name. A missing directory has no models.
+func List(dir string) ([]Model, error) {
+	entries, err := os.ReadDir(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var list []Model
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.EqualFold(filepath.Ext(entry.Name()), ".gguf") {
+			continue
+		}
+		fi, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		m := Model{
+			Name:       entry.Name(),
+			Path:       filepath.Join(dir, entry.Name()),
+			Size:       fi.Size(),
+			ModifiedAt: fi.ModTime(),
+		}
+		if info, err := Inspect(m.Path); err != nil {
+			m.Error = err.Error()
+		} else {
+			m.Info = &info
+		}
+		list = append(list, m)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list, nil
+}
+
+// Path returns the path of the named model in dir, rejecting names that
+// would leave the directory
+func Path(dir, name string) (string, error) {
+	if name == "" || name != filepath.Base(name) || name == "." || name == ".." {
+		return "", fmt.Errorf("invalid model name %q", name)
+	}
+	return filepath.Join(dir, name), nil
+}
+
+// Delete removes the named model from dir
+func Delete(dir, name string) error {
+	path, err := Path(dir, name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%w: %s", ErrNotFound, name)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package models
+
+import "errors"
+
+// FreeSpace is not supported on this system
+func FreeSpace(dir string) (uint64, error) {
+	return 0, errors.ErrUnsupported
+}
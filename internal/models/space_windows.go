@@ -0,0 +1,16 @@
+package models
+
+import "golang.org/x/sys/windows"
+
+// FreeSpace returns the bytes available to the user on the disk holding dir
+func FreeSpace(dir string) (uint64, error) {
+	path, err := windows.UTF16PtrFromString(dir)
+	if err != nil {
+		return 0, err
+	}
+	var free, total, totalFree uint64
+	if err := windows.GetDiskFreeSpaceEx(path, &free, &total, &totalFree); err != nil {
+		return 0, err
+	}
+	return free, nil
+}
@@ -0,0 +1,158 @@
+// Package ocr extracts text from images so it can be fed into translation.
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ErrUnavailable is returned when no OCR backend is installed
+var ErrUnavailable = errors.New("ocr: no recognizer available")
+
+// Recognizer extracts text from an encoded image (PNG, JPEG, ...)
+type Recognizer interface {
+	Name() string
+	// Recognize returns the text found in image. langs are ISO 639-1 codes
+	// hinting which languages to expect; empty means the backend default.
+	Recognize(ctx context.Context, image []byte, langs []string) (string, error)
+	Available() bool
+}
+
+// Tesseract runs the tesseract command line tool
+type Tesseract struct {
+	Command string
+	Timeout time.Duration
+}
+
+// TesseractOption is a functional option for Tesseract
+type TesseractOption func(*Tesseract)
+
+// WithTesseractCommand overrides the tesseract executable
+func WithTesseractCommand(command string) TesseractOption {
+	return func(t *Tesseract) {
+		if command != "" {
+			t.Command = command
+		}
+	}
+}
+
+// WithTesseractTimeout sets the recognition timeout
+func WithTesseractTimeout(timeout time.Duration) TesseractOption {
+	return func(t *Tesseract) {
+		t.Timeout = timeout
+	}
+}
+
+// NewTesseract creates a Tesseract recognizer
+func NewTesseract(opts ...TesseractOption) *Tesseract {
+	t := &Tesseract{
+		Command: "tesseract",
+		Timeout: 30 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+// Name returns the recognizer name
+func (t *Tesseract) Name() string {
+	return "tesseract"
+}
+
+// Available reports whether the tesseract command is installed
+func (t *Tesseract) Available() bool {
+	_, err := exec.LookPath(t.Command)
+	return err == nil
+}
+
+// Recognize pipes image into tesseract and returns the recognized text
+func (t *Tesseract) Recognize(ctx context.Context, image []byte, langs []string) (string, error) {
+	if !t.Available() {
+		return "", ErrUnavailable
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, t.Timeout)
+	defer cancel()
+
+	args := []string{"stdin", "stdout"}
+	if codes := tesseractLangs(langs); codes != "" {
+		args = append(args, "-l", codes)
+	}
+	cmd := exec.CommandContext(ctx, t.Command, args...)
+	cmd.Stdin = bytes.NewReader(image)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("ocr timed out")
+		}
+		return "", fmt.Errorf("tesseract error: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return Clean(string(out)), nil
+}
+
+// tesseractCodes maps ISO 639-1 codes to tesseract traineddata names
+var tesseractCodes = map[string]string{
+	"en": "eng", "ko": "kor", "ja": "jpn", "zh": "chi_sim", "es": "spa", "fr": "fra",
+	"de": "deu", "pt": "por", "it": "ita", "nl": "nld", "ru": "rus", "ar": "ara",
+	"th": "tha", "hi": "hin", "he": "heb", "el": "ell", "vi": "vie",
+}
+
+// tesseractLangs converts language hints to tesseract's "eng+kor" form
+func tesseractLangs(langs []string) string {
+	var codes []string
+	for _, lang := range langs {
+		code, ok := tesseractCodes[lang]
+		if !ok {
+			code = lang // already a tesseract name, e.g. "chi_tra"
+		}
+		if code != "" {
+			codes = append(codes, code)
+		}
+	}
+	return strings.Join(codes, "+")
+}
+
+// Clean joins lines wrapped by the OCR layout and drops empty noise lines
+func Clean(text string) string {
+	text = strings.ReplaceAll(text, "\f", "")
+	var paragraphs []string
+	for _, para := range strings.Split(text, "\n\n") {
+		var lines []string
+		for _, line := range strings.Split(para, "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				lines = append(lines, line)
+			}
+		}
+		if len(lines) > 0 {
+			paragraphs = append(paragraphs, strings.Join(lines, "\n"))
+		}
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
+
+// DecodeImage decodes base64 image data as sent by the frontend,
+// with or without a "data:image/png;base64," prefix
+func DecodeImage(data string) ([]byte, error) {
+	if strings.HasPrefix(data, "data:") {
+		_, payload, ok := strings.Cut(data, ",")
+		if !ok {
+			return nil, errors.New("ocr: malformed data URL")
+		}
+		data = payload
+	}
+	image, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("ocr: invalid image data: %w", err)
+	}
+	return image, nil
+}
@@ -0,0 +1,196 @@
+package pipeline
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/ironpark/tons/internal/engine"
+)
+
+// Boilerplate processor names
+const (
+	StripBoilerplate = "strip-boilerplate"
+	StrictOutput     = "strict-output"
+)
+
+func init() {
+	Register(StripBoilerplate, func() Processor { return stripBoilerplate{} })
+	Register(StrictOutput, func() Processor { return strictOutput{} })
+}
+
+// strictInstruction is prepended to the prompt by the strict-output processor
+const strictInstruction = "Respond with the translation only. Do not add greetings, explanations, notes, or quotation marks around the text."
+
+// maxPreamble is how much output is buffered while looking for a preamble line
+const maxPreamble = 200
+
+var (
+	// preambles match wrapper phrases models put before the translation
+	preambles = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)^\s*(?:(?:sure|certainly|of course|okay|ok|absolutely)[!,.]?\s*)?(?:here(?: is|'s| are)|below is)\b[^\n:]{0,80}\btranslat[^\n:]{0,40}:[ \t]*\n*`),
+		regexp.MustCompile(`(?i)^\s*(?:sure|certainly|of course|absolutely)[!.][ \t]*\n+`),
+		regexp.MustCompile(`(?i)^\s*(?:[a-z]+ )?translation(?: \([^)\n]*\))?:[ \t]*\n*`),
+		regexp.MustCompile(`^\s*(?:다음은[^\n:]{0,40}번역[^\n:]{0,20}[:：]|번역(?:문|본|\s*결과)?\s*[:：])[ \t]*\n*`),
+		regexp.MustCompile(`^\s*(?:翻訳(?:文|結果)?|译文|翻译(?:结果)?)\s*[:：][ \t]*\n*`),
+	}
+
+	// noteMarkers start a trailing paragraph that is commentary, not translation
+	noteMarkers = []string{
+		"note:", "notes:", "note that", "(note", "translator's note", "translator’s note",
+		"explanation:", "i hope this helps", "let me know", "참고:", "(참고", "※", "注:", "注：", "注意:",
+	}
+
+	// quotePairs are quote marks models wrap a whole translation in
+	quotePairs = map[rune]rune{'"': '"', '“': '”', '«': '»', '「': '」', '『': '』', '\'': '\''}
+)
+
+// stripBoilerplate removes wrapper phrases, trailing notes and enclosing quotes
+// that chatty models add around a translation
+type stripBoilerplate struct{}
+
+func (stripBoilerplate) Name() string { return StripBoilerplate }
+
+func (stripBoilerplate) Process(req *engine.Request) Post {
+	return &boilerplatePost{source: strings.TrimSpace(req.Text)}
+}
+
+// boilerplatePost streams output while holding back text that may turn out to be boilerplate
+type boilerplatePost struct {
+	source  string
+	started bool // preamble check is done
+	leading bool // enclosing quote check is done
+	quoted  rune // closing quote to strip at the end, buffering everything until then
+	pending string
+}
+
+// Write implements Post
+func (b *boilerplatePost) Write(delta string) string {
+	b.pending += delta
+	if !b.started {
+		if !strings.Contains(b.pending, "\n") && len(b.pending) < maxPreamble {
+			return ""
+		}
+		b.start()
+	}
+	if !b.leading {
+		b.checkQuote()
+	}
+	if !b.leading || b.quoted != 0 {
+		return ""
+	}
+
+	// Hold back the last paragraph while it may still become a trailing note
+	cut := len(strings.TrimRight(b.pending, "\n"))
+	if i := strings.LastIndex(b.pending, "\n\n"); i >= 0 && b.mayBeNote(b.pending[i:]) {
+		cut = i
+	}
+	out := b.pending[:cut]
+	b.pending = b.pending[cut:]
+	return out
+}
+
+// Flush implements Post
+func (b *boilerplatePost) Flush() string {
+	for !b.started {
+		b.start()
+	}
+	if !b.leading {
+		b.checkQuote()
+	}
+	text := b.pending
+	b.pending = ""
+
+	if i := strings.LastIndex(text, "\n\n"); i >= 0 && b.isNote(text[i:]) {
+		text = text[:i]
+	}
+	if b.quoted != 0 {
+		trimmed := strings.TrimRight(text, " \t\n")
+		if r, size := utf8.DecodeLastRuneInString(trimmed); r == b.quoted && len(trimmed) > size {
+			return trimmed[:len(trimmed)-size]
+		}
+		return string(openingQuote(b.quoted)) + text
+	}
+	return text
+}
+
+// start strips a preamble from the buffered head. A preamble alone on its
+// line ("Sure!") keeps the check open for the next line.
+func (b *boilerplatePost) start() {
+	b.started = true
+	for _, re := range preambles {
+		if re.MatchString(b.source) {
+			continue
+		}
+		if loc := re.FindStringIndex(b.pending); loc != nil {
+			b.pending = b.pending[loc[1]:]
+			b.started = strings.TrimSpace(b.pending) != ""
+			break
+		}
+	}
+}
+
+// checkQuote drops leading blank lines and an opening quote the source does not have
+func (b *boilerplatePost) checkQuote() {
+	b.pending = strings.TrimLeft(b.pending, " \t\n")
+	if b.pending == "" {
+		return
+	}
+	b.leading = true
+	r, size := utf8.DecodeRuneInString(b.pending)
+	closing, ok := quotePairs[r]
+	if !ok || strings.HasPrefix(b.source, string(r)) {
+		return
+	}
+	b.quoted = closing
+	b.pending = b.pending[size:]
+}
+
+// mayBeNote reports whether a trailing paragraph is, or could still grow into, a note
+func (b *boilerplatePost) mayBeNote(tail string) bool {
+	para := strings.ToLower(strings.TrimLeft(tail, " \t\n"))
+	if para == "" {
+		return true
+	}
+	for _, marker := range noteMarkers {
+		if strings.HasPrefix(para, marker) || strings.HasPrefix(marker, para) {
+			return true
+		}
+	}
+	return false
+}
+
+// isNote reports whether a trailing paragraph is a note the source did not contain
+func (b *boilerplatePost) isNote(tail string) bool {
+	para := strings.ToLower(strings.TrimSpace(tail))
+	if para == "" {
+		return false
+	}
+	source := strings.ToLower(b.source)
+	for _, marker := range noteMarkers {
+		if strings.HasPrefix(para, marker) && !strings.Contains(source, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// openingQuote returns the opening quote for a closing quote
+func openingQuote(closing rune) rune {
+	for open, c := range quotePairs {
+		if c == closing {
+			return open
+		}
+	}
+	return closing
+}
+
+// strictOutput asks the model to answer with the bare translation
+type strictOutput struct{}
+
+func (strictOutput) Name() string { return StrictOutput }
+
+func (strictOutput) Process(req *engine.Request) Post {
+	req.Prompt = strictInstruction + "\n\n" + req.Prompt
+	return nil
+}
@@ -0,0 +1,93 @@
+package pipeline
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/ironpark/tons/internal/engine"
+)
+
+// BeforeHook rewrites a request before the processors run, e.g. to apply
+// company terminology. An error fails the translation.
+type BeforeHook func(ctx context.Context, req *engine.Request) error
+
+// AfterHook rewrites a complete translation after the processors restored
+// it; req is the request as the before hooks left it. An error fails the
+// translation.
+type AfterHook func(ctx context.Context, req engine.Request, translation string) (string, error)
+
+var (
+	hooksMu     sync.RWMutex
+	hookID      int
+	beforeHooks []hook[BeforeHook]
+	afterHooks  []hook[AfterHook]
+)
+
+// hook is a registered hook, identified for removal
+type hook[F any] struct {
+	id int
+	fn F
+}
+
+// OnBeforeTranslate registers a hook run on every request, in registration
+// order, and returns a function that removes it
+func OnBeforeTranslate(fn BeforeHook) (remove func()) {
+	return register(&beforeHooks, fn)
+}
+
+// OnAfterTranslate registers a hook run on every completed translation, in
+// registration order, and returns a function that removes it. While after
+// hooks are registered, streamed translations arrive as one delta at the
+// end, as the hooks need the complete text.
+func OnAfterTranslate(fn AfterHook) (remove func()) {
+	return register(&afterHooks, fn)
+}
+
+func register[F any](hooks *[]hook[F], fn F) func() {
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+
+	hookID++
+	id := hookID
+	*hooks = append(*hooks, hook[F]{id: id, fn: fn})
+	return func() {
+		hooksMu.Lock()
+		defer hooksMu.Unlock()
+
+		*hooks = slices.DeleteFunc(*hooks, func(h hook[F]) bool { return h.id == id })
+	}
+}
+
+// registered returns the functions of the registered hooks
+func registered[F any](hooks *[]hook[F]) []F {
+	hooksMu.RLock()
+	defer hooksMu.RUnlock()
+
+	fns := make([]F, len(*hooks))
+	for i, h := range *hooks {
+		fns[i] = h.fn
+	}
+	return fns
+}
+
+// runBefore runs the before hooks on req
+func runBefore(ctx context.Context, req *engine.Request) error {
+	for _, fn := range registered(&beforeHooks) {
+		if err := fn(ctx, req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runAfter runs the after hooks on a translation
+func runAfter(ctx context.Context, hooks []AfterHook, req engine.Request, translation string) (string, error) {
+	for _, fn := range hooks {
+		var err error
+		if translation, err = fn(ctx, req, translation); err != nil {
+			return "", err
+		}
+	}
+	return translation, nil
+}
@@ -0,0 +1,81 @@
+package pipeline
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/ironpark/tons/internal/engine"
+	"github.com/ironpark/tons/internal/placeholder"
+)
+
+// ScrubPII is the name of the processor that masks personal data
+const ScrubPII = "scrub-pii"
+
+func init() {
+	Register(ScrubPII, func() Processor { return scrubPII{} })
+}
+
+// pii matches personal data, identification numbers before phone numbers as
+// those would match them too
+var pii = regexp.MustCompile(strings.Join([]string{
+	`⟦\d+⟧`,                            // tokens of an earlier masking pass
+	`\b[\w.+-]+@[\w-]+(?:\.[\w-]+)+\b`, // email addresses
+	`\b\d{6}-[1-8]\d{6}\b`,             // Korean resident registration numbers
+	`\b\d{3}-\d{2}-\d{4}\b`,            // US social security numbers
+	`\b\d{4}[ -]?\d{6}[ -]?\d{5}\b`,    // American Express card numbers
+	`\b(?:\d{4}[ -]?){3}\d{4}\b`,       // other card numbers
+	`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){3,7}(?: ?[A-Z0-9]{1,3})?\b`,          // IBANs
+	`\+\d{1,3}[ .-]?(?:\(\d{1,4}\)[ .-]?)?\d{1,4}(?:[ .-]?\d{2,4}){1,4}\b`, // international phone numbers
+	`(?:\(\d{2,4}\)[ .-]?|\b\d{2,4}[ .-])\d{3,4}[ .-]\d{4}\b`,              // local phone numbers
+}, "|"))
+
+// scrubPII masks email addresses, phone numbers and identification numbers
+// so they never reach the engine, and restores them in the output. Every
+// text sent along with the request is masked, not only the text to translate:
+// the context, examples, glossary and template variables, e.g. the
+// translation a quality check rates.
+type scrubPII struct{}
+
+func (scrubPII) Name() string { return ScrubPII }
+
+func (scrubPII) Process(req *engine.Request) Post {
+	var mapping placeholder.Mapping
+	mask := func(text string) string {
+		return pii.ReplaceAllStringFunc(text, func(s string) string {
+			var token string
+			token, mapping = mapping.Add(s)
+			return token
+		})
+	}
+
+	req.Text = mask(req.Text)
+	req.Context = mask(req.Context)
+	// The slices and map are shared with the caller, so they are copied
+	examples := make([]engine.Example, len(req.Examples))
+	for i, ex := range req.Examples {
+		ex.Source, ex.Target = mask(ex.Source), mask(ex.Target)
+		examples[i] = ex
+	}
+	glossary := make([]engine.GlossaryTerm, len(req.Glossary))
+	for i, term := range req.Glossary {
+		glossary[i] = engine.GlossaryTerm{Source: mask(term.Source), Target: mask(term.Target)}
+	}
+	var vars map[string]string
+	if req.Variables != nil {
+		vars = make(map[string]string, len(req.Variables))
+		for name, value := range req.Variables {
+			vars[name] = mask(value)
+		}
+	}
+	if len(mapping) == 0 {
+		return nil
+	}
+	if req.Examples != nil {
+		req.Examples = examples
+	}
+	if req.Glossary != nil {
+		req.Glossary = glossary
+	}
+	req.Variables = vars
+	return mapping.NewStreamRestorer()
+}
@@ -0,0 +1,220 @@
+// Package pipeline applies ordered text processors around an engine call.
+//
+// Each Processor may rewrite the request before it reaches the engine and
+// return a Post stage that transforms the translated output. Post stages run
+// in reverse order, so a processor that masks text restores it last-in,
+// first-out relative to the others.
+//
+// Code embedding the packages can add its own steps without registering a
+// configurable processor: hooks registered with OnBeforeTranslate and
+// OnAfterTranslate run around every pipeline. Programs outside this module
+// register them through the public package pkg/tons.
+package pipeline
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ironpark/tons/internal/engine"
+)
+
+// Post transforms translated output. Write receives streamed deltas and may
+// hold back text it cannot process yet; Flush returns whatever is left.
+type Post interface {
+	Write(delta string) string
+	Flush() string
+}
+
+// Processor rewrites a request before translation
+type Processor interface {
+	Name() string
+	// Process modifies req and returns the post stage for its output, or nil
+	Process(req *engine.Request) Post
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = map[string]func() Processor{}
+)
+
+// Register makes a processor available by name for configuration
+func Register(name string, factory func() Processor) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	registry[name] = factory
+}
+
+// Lookup returns a new instance of the named processor
+func Lookup(name string) (Processor, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	factory, ok := registry[name]
+	if !ok {
+		return nil, false
+	}
+	return factory(), true
+}
+
+// Names returns all registered processor names, sorted
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Engine wraps an engine with a processor pipeline
+type Engine struct {
+	engine.Engine
+	Processors []Processor
+}
+
+// New wraps e with the given processors, applied in order
+func New(e engine.Engine, processors ...Processor) *Engine {
+	return &Engine{Engine: e, Processors: processors}
+}
+
+// prepare runs all processors on req and returns the combined post stage
+func (p *Engine) prepare(req *engine.Request) Post {
+	var posts []Post
+	for _, proc := range p.Processors {
+		if post := proc.Process(req); post != nil {
+			posts = append(posts, post)
+		}
+	}
+	return chain(posts)
+}
+
+// Translate runs the before hooks and processors on the request, translates,
+// and post-processes the result before the after hooks
+func (p *Engine) Translate(ctx context.Context, req engine.Request) (engine.Response, error) {
+	if err := runBefore(ctx, &req); err != nil {
+		return engine.Response{}, err
+	}
+	hooked := req
+	post := p.prepare(&req)
+	res, err := p.Engine.Translate(ctx, req)
+	res.Text = post.Write(res.Text) + post.Flush()
+	if err == nil && res.Error == "" {
+		if res.Text, err = runAfter(ctx, registered(&afterHooks), hooked, res.Text); err != nil {
+			return engine.Response{}, err
+		}
+	}
+	return res, err
+}
+
+// TranslateStream runs the before hooks and processors on the request and
+// post-processes every streamed delta. With after hooks registered the
+// output is held back until the end; a failed translation passes on what
+// arrived without running them.
+func (p *Engine) TranslateStream(ctx context.Context, req engine.Request) (<-chan engine.Response, error) {
+	if err := runBefore(ctx, &req); err != nil {
+		return nil, err
+	}
+	hooked := req
+	after := registered(&afterHooks)
+	post := p.prepare(&req)
+	inner, err := p.Engine.TranslateStream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan engine.Response)
+	go func() {
+		defer close(ch)
+		var held strings.Builder
+		for res := range inner {
+			res.Text = post.Write(res.Text)
+			if res.Done || res.Error != "" {
+				res.Text += post.Flush()
+			}
+			if len(after) > 0 {
+				held.WriteString(res.Text)
+				res.Text = ""
+				switch {
+				case res.Error != "":
+					res.Text = held.String()
+				case res.Done:
+					text, err := runAfter(ctx, after, hooked, held.String())
+					if err != nil {
+						res = engine.ErrorResponse(err.Error())
+					}
+					res.Text = text
+				}
+			}
+			if res.Text == "" && !res.Done && res.Error == "" && res.Progress == nil {
+				continue
+			}
+			ch <- res
+		}
+	}()
+	return ch, nil
+}
+
+// chained runs post stages in reverse order
+type chained []Post
+
+// chain combines post stages so the last processor's stage runs first
+func chain(posts []Post) Post {
+	return chained(posts)
+}
+
+// Write implements Post
+func (c chained) Write(delta string) string {
+	for i := len(c) - 1; i >= 0; i-- {
+		delta = c[i].Write(delta)
+	}
+	return delta
+}
+
+// Flush implements Post, feeding each stage's remainder through the later stages
+func (c chained) Flush() string {
+	var out string
+	for i := len(c) - 1; i >= 0; i-- {
+		out = c[i].Write(out) + c[i].Flush()
+	}
+	return out
+}
+
+// Func adapts a whole-text transformation to a Post stage.
+// Output is processed line by line, so streaming is delayed until each newline.
+func Func(fn func(string) string) Post {
+	return &lineBuffered{fn: fn}
+}
+
+// lineBuffered applies fn to complete lines
+type lineBuffered struct {
+	fn      func(string) string
+	pending string
+}
+
+// Write implements Post
+func (l *lineBuffered) Write(delta string) string {
+	text := l.pending + delta
+	i := strings.LastIndexByte(text, '\n')
+	if i < 0 {
+		l.pending = text
+		return ""
+	}
+	l.pending = text[i+1:]
+	return l.fn(text[:i+1])
+}
+
+// Flush implements Post
+func (l *lineBuffered) Flush() string {
+	text := l.pending
+	l.pending = ""
+	if text == "" {
+		return ""
+	}
+	return l.fn(text)
+}
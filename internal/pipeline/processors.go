@@ -0,0 +1,85 @@
+package pipeline
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/ironpark/tons/internal/engine"
+	"github.com/ironpark/tons/internal/placeholder"
+)
+
+// Built-in processor names
+const (
+	NormalizeWhitespace = "normalize-whitespace"
+	StripSignature      = "strip-signature"
+	ProtectPlaceholders = "protect-placeholders"
+)
+
+func init() {
+	Register(NormalizeWhitespace, func() Processor { return normalizeWhitespace{} })
+	Register(StripSignature, func() Processor { return stripSignature{} })
+	Register(ProtectPlaceholders, func() Processor { return protectPlaceholders{} })
+}
+
+var (
+	trailingSpace = regexp.MustCompile(`[ \t]+\n`)
+	extraBlank    = regexp.MustCompile(`\n{3,}`)
+	// signatureDelim matches the conventional "-- " line and common sign-offs
+	signatureDelim = regexp.MustCompile(`(?m)^(?:-- ?|—+|_{3,})$`)
+)
+
+// normalizeWhitespace unifies line endings, trims trailing spaces and collapses blank lines
+type normalizeWhitespace struct{}
+
+func (normalizeWhitespace) Name() string { return NormalizeWhitespace }
+
+func (normalizeWhitespace) Process(req *engine.Request) Post {
+	text := strings.ReplaceAll(req.Text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\u00a0", " ")
+	text = trailingSpace.ReplaceAllString(text, "\n")
+	text = extraBlank.ReplaceAllString(text, "\n\n")
+	req.Text = strings.TrimSpace(text)
+	return nil
+}
+
+// stripSignature removes an email-style signature before translation and
+// appends it, untranslated, to the output
+type stripSignature struct{}
+
+func (stripSignature) Name() string { return StripSignature }
+
+func (stripSignature) Process(req *engine.Request) Post {
+	loc := signatureDelim.FindStringIndex(req.Text)
+	if loc == nil {
+		return nil
+	}
+	signature := req.Text[loc[0]:]
+	body := strings.TrimRight(req.Text[:loc[0]], " \t\n")
+	if body == "" {
+		return nil
+	}
+	req.Text = body
+	return &appendPost{suffix: "\n\n" + signature}
+}
+
+// appendPost appends a fixed suffix when the stream ends
+type appendPost struct {
+	suffix string
+}
+
+func (a *appendPost) Write(delta string) string { return delta }
+func (a *appendPost) Flush() string             { return a.suffix }
+
+// protectPlaceholders masks code, URLs and variables with tokens and restores them
+type protectPlaceholders struct{}
+
+func (protectPlaceholders) Name() string { return ProtectPlaceholders }
+
+func (protectPlaceholders) Process(req *engine.Request) Post {
+	masked, mapping := placeholder.Protect(req.Text)
+	if len(mapping) == 0 {
+		return nil
+	}
+	req.Text = masked
+	return mapping.NewStreamRestorer()
+}
@@ -0,0 +1,247 @@
+package pipeline
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ironpark/tons/internal/engine"
+	"github.com/ironpark/tons/internal/langdetect"
+)
+
+// LocalizePunctuation is the name of the processor that converts quotes,
+// dashes, numbers and dates to the target language's conventions
+const LocalizePunctuation = "localize-punctuation"
+
+func init() {
+	Register(LocalizePunctuation, func() Processor { return NewLocalizePunctuation(nil) })
+}
+
+// conventions are the typographic conventions of a language. Empty fields
+// keep the text as the engine wrote it.
+type conventions struct {
+	open, close    string // double quotes
+	dash           string // replaces a spaced hyphen or an em dash between words
+	decimal, group string // number separators
+	date           func(year, month, day int) string
+}
+
+var (
+	dayMonthYear = func(sep string) func(int, int, int) string {
+		return func(y, m, d int) string { return fmt.Sprintf("%02d%s%02d%s%d", d, sep, m, sep, y) }
+	}
+	yearMonthDayCJK = func(y, m, d int) string { return fmt.Sprintf("%d年%d月%d日", y, m, d) }
+
+	// punctuation maps language codes to their conventions
+	punctuation = map[string]conventions{
+		"ja": {open: "「", close: "」", date: yearMonthDayCJK},
+		"zh": {open: "“", close: "”", date: yearMonthDayCJK},
+		"ko": {open: "“", close: "”", date: func(y, m, d int) string { return fmt.Sprintf("%d. %d. %d.", y, m, d) }},
+		"fr": {open: "«\u00a0", close: "\u00a0»", dash: " – ", decimal: ",", group: "\u202f", date: dayMonthYear("/")},
+		"de": {open: "„", close: "“", dash: " – ", decimal: ",", group: ".", date: dayMonthYear(".")},
+		"es": {open: "«", close: "»", decimal: ",", group: ".", date: dayMonthYear("/")},
+		"it": {open: "«", close: "»", dash: " – ", decimal: ",", group: ".", date: dayMonthYear("/")},
+		"pt": {open: "“", close: "”", decimal: ",", group: ".", date: dayMonthYear("/")},
+		"nl": {open: "“", close: "”", dash: " – ", decimal: ",", group: ".", date: dayMonthYear("-")},
+		"ru": {open: "«", close: "»", dash: " — ", decimal: ",", group: "\u00a0", date: dayMonthYear(".")},
+	}
+
+	// number matches digits joined by separators; the match is then checked
+	// to be an English-formatted number, not a version or an IP address
+	number      = regexp.MustCompile(`\d[\d.,]*\d`)
+	groupedNum  = regexp.MustCompile(`^\d{1,3}(?:,\d{3})+(?:\.\d+)?$`)
+	decimalNum  = regexp.MustCompile(`^\d+\.\d+$`)
+	ambiguousK  = regexp.MustCompile(`^\d{1,3}\.\d{3}$`) // a decimal or a localized thousand
+	isoDate     = regexp.MustCompile(`\b(\d{4})-(\d{2})-(\d{2})\b`)
+	spacedDash  = regexp.MustCompile(`(\S) +[-–—] +`)
+	closedDash  = regexp.MustCompile(`(\pL)—(\pL)`)
+	holdRunes   = "0123456789.,-–— \t"
+	openingRune = "([{-–—/"
+)
+
+// PunctuationLangs returns the language codes with known conventions, sorted
+func PunctuationLangs() []string {
+	codes := make([]string, 0, len(punctuation))
+	for code := range punctuation {
+		codes = append(codes, code)
+	}
+	slices.Sort(codes)
+	return codes
+}
+
+// localizePunctuation converts the output's double quotes, dashes, numbers
+// and ISO dates for the languages it is enabled for
+type localizePunctuation struct {
+	langs []string
+}
+
+// NewLocalizePunctuation returns the processor enabled for the given
+// target languages, or for all languages with known conventions if none
+func NewLocalizePunctuation(langs []string) Processor {
+	codes := make([]string, 0, len(langs))
+	for _, lang := range langs {
+		codes = append(codes, langdetect.Code(lang))
+	}
+	return localizePunctuation{langs: codes}
+}
+
+func (localizePunctuation) Name() string { return LocalizePunctuation }
+
+func (p localizePunctuation) Process(req *engine.Request) Post {
+	code := langdetect.Code(req.TargetLang)
+	conv, ok := punctuation[code]
+	if !ok || (len(p.langs) > 0 && !slices.Contains(p.langs, code)) {
+		return nil
+	}
+	return &punctuationPost{conv: conv}
+}
+
+// punctuationPost localizes streamed output. Trailing digits, separators
+// and spaces are held back until the text after them shows whether they
+// form a number, a date or a dash.
+type punctuationPost struct {
+	conv    conventions
+	pending string
+	prev    rune // last rune written, for the context of the next text
+	quoted  bool // inside double quotes
+}
+
+func (p *punctuationPost) Write(delta string) string {
+	p.pending += delta
+	cut := len(p.pending)
+	for cut > 0 {
+		r, size := utf8.DecodeLastRuneInString(p.pending[:cut])
+		if !strings.ContainsRune(holdRunes, r) {
+			break
+		}
+		cut -= size
+	}
+	text := p.pending[:cut]
+	p.pending = p.pending[cut:]
+	return p.localize(text)
+}
+
+func (p *punctuationPost) Flush() string {
+	text := p.pending
+	p.pending = ""
+	return p.localize(text)
+}
+
+// localize converts text following p.prev
+func (p *punctuationPost) localize(text string) string {
+	if text == "" {
+		return ""
+	}
+	prev := p.prev
+	p.prev, _ = utf8.DecodeLastRuneInString(text)
+
+	if p.conv.date != nil {
+		text = replaceAfter(text, prev, isoDate, func(m []string) string {
+			y, _ := strconv.Atoi(m[1])
+			mo, _ := strconv.Atoi(m[2])
+			d, _ := strconv.Atoi(m[3])
+			if mo < 1 || mo > 12 || d < 1 || d > 31 {
+				return m[0]
+			}
+			return p.conv.date(y, mo, d)
+		})
+	}
+	if p.conv.decimal != "" {
+		text = replaceAfter(text, prev, number, func(m []string) string { return p.number(m[0]) })
+	}
+	if p.conv.dash != "" {
+		// The rune before the text decides whether a leading dash is spaced,
+		// as opposed to e.g. a list item
+		before := prev
+		if before == 0 {
+			before = '\n'
+		}
+		s := string(before) + text
+		s = spacedDash.ReplaceAllString(s, "${1}"+p.conv.dash)
+		s = closedDash.ReplaceAllString(s, "${1}"+p.conv.dash+"${2}")
+		text = s[utf8.RuneLen(before):]
+	}
+	if p.conv.open != "" {
+		text = p.quotes(text, prev)
+	}
+	return text
+}
+
+// quotes replaces straight and English curly double quotes. A quote after
+// a space or an opening bracket opens, otherwise quotes alternate.
+func (p *punctuationPost) quotes(text string, prev rune) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch r {
+		case '"', '“', '”':
+			if prev == 0 || unicode.IsSpace(prev) || strings.ContainsRune(openingRune, prev) {
+				p.quoted = false
+			}
+			if p.quoted {
+				b.WriteString(p.conv.close)
+			} else {
+				b.WriteString(p.conv.open)
+			}
+			p.quoted = !p.quoted
+		default:
+			b.WriteRune(r)
+		}
+		prev = r
+	}
+	return b.String()
+}
+
+// number rewrites an English-formatted number with the local separators,
+// leaving anything else as it is
+func (p *punctuationPost) number(s string) string {
+	switch {
+	case groupedNum.MatchString(s):
+	case decimalNum.MatchString(s) && !ambiguousK.MatchString(s):
+	default:
+		return s
+	}
+	whole, frac, hasFrac := strings.Cut(s, ".")
+	whole = strings.ReplaceAll(whole, ",", p.conv.group)
+	if hasFrac {
+		return whole + p.conv.decimal + frac
+	}
+	return whole
+}
+
+// replaceAfter replaces the matches of re in text that don't continue a
+// word, a number or a version, prev being the rune before text
+func replaceAfter(text string, prev rune, re *regexp.Regexp, repl func([]string) string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range re.FindAllStringSubmatchIndex(text, -1) {
+		before := prev
+		if loc[0] > 0 {
+			before, _ = utf8.DecodeLastRuneInString(text[:loc[0]])
+		}
+		if isNumberRune(before) {
+			continue
+		}
+		if after, _ := utf8.DecodeRuneInString(text[loc[1]:]); isNumberRune(after) && after != '.' && after != ',' {
+			continue
+		}
+		m := make([]string, len(loc)/2)
+		for i := range m {
+			m[i] = text[loc[2*i]:loc[2*i+1]]
+		}
+		b.WriteString(text[last:loc[0]])
+		b.WriteString(repl(m))
+		last = loc[1]
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// isNumberRune reports whether r continues a word or a number. Scripts
+// written without spaces, like Japanese, may run into numbers.
+func isNumberRune(r rune) bool {
+	return unicode.In(r, unicode.Latin, unicode.Cyrillic, unicode.Greek) || unicode.IsDigit(r) || r == '.' || r == ',' || r == '-' || r == '_'
+}
@@ -0,0 +1,129 @@
+// Package placeholder masks fragments that must survive translation verbatim
+// (inline code, URLs, emails, template variables, printf verbs) with stable tokens.
+package placeholder
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// protected matches fragments to mask, most specific first
+var protected = regexp.MustCompile(strings.Join([]string{
+	`⟦\d+⟧`,     // tokens of an earlier masking pass, so nested mappings restore cleanly
+	"`[^`\n]+`", // inline code spans
+	`\b(?:https?|ftp)://[^\s<>"'()]+[^\s<>"'().,;:!?]`, // URLs
+	`\b[\w.+-]+@[\w-]+(?:\.[\w-]+)+\b`,                 // email addresses
+	`\{\{[^{}\n]*\}\}`,                                 // {{mustache}} variables
+	`\$\{[^{}\n]*\}`,                                   // ${shell} variables
+	`\{[\w.:-]*\}`,                                     // {placeholders} and {0}
+	`%(?:\d+\$)?[-+#0]*\d*(?:\.\d+)?(?:ll?|hh?|[qjzL])?[sdifgexXobcqvTtp@]`, // printf-style verbs
+}, "|"))
+
+// token matches placeholder tokens in engine output, tolerating common mangling
+var token = regexp.MustCompile(`⟦\s*(\d+)\s*⟧|\[\[\s*(\d+)\s*\]\]`)
+
+// Mapping holds the original fragments by token index
+type Mapping []string
+
+// Protect replaces protected fragments with ⟦n⟧ tokens
+func Protect(text string) (string, Mapping) {
+	return ProtectPattern(text, protected)
+}
+
+// ProtectPattern replaces every match of re with ⟦n⟧ tokens
+func ProtectPattern(text string, re *regexp.Regexp) (string, Mapping) {
+	var m Mapping
+	masked := re.ReplaceAllStringFunc(text, func(s string) string {
+		m = append(m, s)
+		return tokenFor(len(m) - 1)
+	})
+	return masked, m
+}
+
+// Add appends a fragment and returns its token together with the extended mapping
+func (m Mapping) Add(fragment string) (string, Mapping) {
+	m = append(m, fragment)
+	return tokenFor(len(m) - 1), m
+}
+
+// Restore replaces tokens with the original fragments.
+// Unknown tokens are left untouched.
+func (m Mapping) Restore(text string) string {
+	if len(m) == 0 {
+		return text
+	}
+	return token.ReplaceAllStringFunc(text, func(s string) string {
+		sub := token.FindStringSubmatch(s)
+		idx := sub[1]
+		if idx == "" {
+			idx = sub[2]
+		}
+		n, err := strconv.Atoi(idx)
+		if err != nil || n >= len(m) {
+			return s
+		}
+		return m[n]
+	})
+}
+
+// Missing returns the fragments whose tokens do not appear in text
+func (m Mapping) Missing(text string) []string {
+	var missing []string
+	for i, original := range m {
+		if !strings.Contains(text, tokenFor(i)) && !strings.Contains(text, original) {
+			missing = append(missing, original)
+		}
+	}
+	return missing
+}
+
+// tokenFor returns the token for index n
+func tokenFor(n int) string {
+	return fmt.Sprintf("⟦%d⟧", n)
+}
+
+// StreamRestorer restores tokens in streamed deltas, holding back text
+// that could be the start of a token split across deltas
+type StreamRestorer struct {
+	m       Mapping
+	pending string
+}
+
+// NewStreamRestorer creates a StreamRestorer for m
+func (m Mapping) NewStreamRestorer() *StreamRestorer {
+	return &StreamRestorer{m: m}
+}
+
+// Write accepts a delta and returns the restored text that is safe to emit
+func (r *StreamRestorer) Write(delta string) string {
+	text := r.pending + delta
+	cut := len(text)
+	if i := strings.LastIndexAny(text, "⟦["); i >= 0 && !strings.ContainsAny(text[i:], "⟧]") {
+		cut = i
+	}
+	// "[[n]" is incomplete until the second bracket arrives
+	if i := strings.LastIndex(text, "[["); i >= 0 && i < cut && !strings.Contains(text[i:], "]]") {
+		cut = i
+	}
+	// Deltas may end in the middle of a multi-byte rune (e.g. the start of "⟦")
+	if r, size := utf8.DecodeLastRuneInString(text[:cut]); r == utf8.RuneError && size == 1 {
+		for cut > 0 && !utf8.RuneStart(text[cut-1]) {
+			cut--
+		}
+		if cut > 0 {
+			cut--
+		}
+	}
+	r.pending = text[cut:]
+	return r.m.Restore(text[:cut])
+}
+
+// Flush returns any held-back text
+func (r *StreamRestorer) Flush() string {
+	text := r.m.Restore(r.pending)
+	r.pending = ""
+	return text
+}
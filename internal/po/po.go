@@ -0,0 +1,308 @@
+// Package po reads and writes gettext PO/POT catalogs.
+//
+// Comments, references and obsolete entries are preserved as-is so a parsed
+// catalog writes back unchanged apart from the translations that were filled in.
+package po
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Entry is a single message in a catalog
+type Entry struct {
+	Comments    []string // raw comment lines (#, #., #:, #|, #~) except flags
+	Flags       []string // e.g. "fuzzy", "c-format"
+	Context     string
+	MsgID       string
+	MsgIDPlural string
+	MsgStr      []string // one string, or one per plural form
+}
+
+// IsHeader reports whether the entry is the catalog header
+func (e *Entry) IsHeader() bool {
+	return e.MsgID == "" && e.Context == "" && e.MsgStr != nil
+}
+
+// Translated reports whether every msgstr is filled in
+func (e *Entry) Translated() bool {
+	if len(e.MsgStr) == 0 {
+		return false
+	}
+	for _, s := range e.MsgStr {
+		if s == "" {
+			return false
+		}
+	}
+	return true
+}
+
+// HasFlag reports whether the entry has the given flag
+func (e *Entry) HasFlag(flag string) bool {
+	return slices.Contains(e.Flags, flag)
+}
+
+// AddFlag adds a flag if it is not already set. "fuzzy" goes first, as gettext tools write it.
+func (e *Entry) AddFlag(flag string) {
+	switch {
+	case e.HasFlag(flag):
+	case flag == "fuzzy":
+		e.Flags = append([]string{flag}, e.Flags...)
+	default:
+		e.Flags = append(e.Flags, flag)
+	}
+}
+
+// File is a parsed catalog
+type File struct {
+	Entries []*Entry
+}
+
+// Header returns the header entry, or nil
+func (f *File) Header() *Entry {
+	for _, e := range f.Entries {
+		if e.IsHeader() {
+			return e
+		}
+	}
+	return nil
+}
+
+var nplurals = regexp.MustCompile(`nplurals\s*=\s*(\d+)`)
+
+// NPlurals returns the number of plural forms declared in the header (default 2)
+func (f *File) NPlurals() int {
+	if h := f.Header(); h != nil && len(h.MsgStr) > 0 {
+		if m := nplurals.FindStringSubmatch(h.MsgStr[0]); m != nil {
+			if n, err := strconv.Atoi(m[1]); err == nil && n > 0 {
+				return n
+			}
+		}
+	}
+	return 2
+}
+
+// SetHeaderField sets a "Name: value" field in the header, creating the header if needed
+func (f *File) SetHeaderField(name, value string) {
+	h := f.Header()
+	if h == nil {
+		h = &Entry{MsgStr: []string{""}}
+		f.Entries = append([]*Entry{h}, f.Entries...)
+	}
+	if len(h.MsgStr) == 0 {
+		h.MsgStr = []string{""}
+	}
+
+	line := name + ": " + value + "\n"
+	lines := strings.SplitAfter(h.MsgStr[0], "\n")
+	for i, l := range lines {
+		if strings.HasPrefix(l, name+":") {
+			lines[i] = line
+			h.MsgStr[0] = strings.Join(lines, "")
+			return
+		}
+	}
+	if h.MsgStr[0] != "" && !strings.HasSuffix(h.MsgStr[0], "\n") {
+		h.MsgStr[0] += "\n"
+	}
+	h.MsgStr[0] += line
+}
+
+// Parse reads a PO or POT catalog
+func Parse(data []byte) (*File, error) {
+	f := &File{}
+	var cur *Entry
+	var target *string // string receiving continuation lines
+	lineNo := 0
+
+	flush := func() {
+		if cur != nil {
+			f.Entries = append(f.Entries, cur)
+		}
+		cur, target = nil, nil
+	}
+	entry := func() *Entry {
+		if cur == nil {
+			cur = &Entry{}
+		}
+		return cur
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "":
+			flush()
+		case strings.HasPrefix(line, "#,"):
+			// A comment after the msgstr starts a new entry
+			if cur != nil && cur.MsgStr != nil {
+				flush()
+			}
+			for _, flag := range strings.Split(line[2:], ",") {
+				if flag = strings.TrimSpace(flag); flag != "" {
+					entry().AddFlag(flag)
+				}
+			}
+		case strings.HasPrefix(line, "#"):
+			if cur != nil && cur.MsgStr != nil {
+				flush()
+			}
+			e := entry()
+			e.Comments = append(e.Comments, line)
+			target = nil
+		case strings.HasPrefix(line, `"`):
+			if target == nil {
+				return nil, fmt.Errorf("po: line %d: unexpected string", lineNo)
+			}
+			s, err := unquote(line)
+			if err != nil {
+				return nil, fmt.Errorf("po: line %d: %w", lineNo, err)
+			}
+			*target += s
+		default:
+			keyword, rest, _ := strings.Cut(line, " ")
+			s, err := unquote(strings.TrimSpace(rest))
+			if err != nil {
+				return nil, fmt.Errorf("po: line %d: %w", lineNo, err)
+			}
+			if cur != nil && cur.MsgStr != nil && (keyword == "msgctxt" || keyword == "msgid") {
+				flush()
+			}
+			e := entry()
+			switch {
+			case keyword == "msgctxt":
+				e.Context = s
+				target = &e.Context
+			case keyword == "msgid":
+				e.MsgID = s
+				target = &e.MsgID
+			case keyword == "msgid_plural":
+				e.MsgIDPlural = s
+				target = &e.MsgIDPlural
+			case keyword == "msgstr":
+				e.MsgStr = []string{s}
+				target = &e.MsgStr[0]
+			case strings.HasPrefix(keyword, "msgstr[") && strings.HasSuffix(keyword, "]"):
+				n, err := strconv.Atoi(keyword[len("msgstr[") : len(keyword)-1])
+				if err != nil || n < 0 || n > 32 {
+					return nil, fmt.Errorf("po: line %d: invalid plural index %q", lineNo, keyword)
+				}
+				for len(e.MsgStr) <= n {
+					e.MsgStr = append(e.MsgStr, "")
+				}
+				e.MsgStr[n] = s
+				target = &e.MsgStr[n]
+			default:
+				return nil, fmt.Errorf("po: line %d: unknown keyword %q", lineNo, keyword)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	flush()
+	return f, nil
+}
+
+// Bytes serializes the catalog in PO format
+func (f *File) Bytes() []byte {
+	var b bytes.Buffer
+	for i, e := range f.Entries {
+		if i > 0 {
+			b.WriteByte('\n')
+		}
+		for _, c := range e.Comments {
+			b.WriteString(c + "\n")
+		}
+		if len(e.Flags) > 0 {
+			b.WriteString("#, " + strings.Join(e.Flags, ", ") + "\n")
+		}
+		if e.Context != "" {
+			writeString(&b, "msgctxt", e.Context)
+		}
+		if e.MsgID == "" && e.MsgStr == nil {
+			continue // comment-only block, e.g. obsolete entries
+		}
+		writeString(&b, "msgid", e.MsgID)
+		if e.MsgIDPlural != "" {
+			writeString(&b, "msgid_plural", e.MsgIDPlural)
+			for n, s := range e.MsgStr {
+				writeString(&b, "msgstr["+strconv.Itoa(n)+"]", s)
+			}
+			continue
+		}
+		s := ""
+		if len(e.MsgStr) > 0 {
+			s = e.MsgStr[0]
+		}
+		writeString(&b, "msgstr", s)
+	}
+	return b.Bytes()
+}
+
+// writeString writes a keyword and a quoted string, splitting multi-line
+// strings after each newline the way gettext tools do
+func writeString(b *bytes.Buffer, keyword, s string) {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) <= 1 {
+		b.WriteString(keyword + " " + quote(s) + "\n")
+		return
+	}
+	b.WriteString(keyword + ` ""` + "\n")
+	for _, line := range lines {
+		b.WriteString(quote(line) + "\n")
+	}
+}
+
+// quote escapes s as a PO string literal
+func quote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, "\r", `\r`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// unquote decodes a PO string literal
+func unquote(s string) (string, error) {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return "", fmt.Errorf("malformed string %s", s)
+	}
+	s = s[1 : len(s)-1]
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '\\' || i == len(s)-1 {
+			b.WriteByte(c)
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		case 'a':
+			b.WriteByte('\a')
+		case 'b':
+			b.WriteByte('\b')
+		case 'f':
+			b.WriteByte('\f')
+		case 'v':
+			b.WriteByte('\v')
+		default:
+			b.WriteByte(s[i]) // \" \\ and unknown escapes
+		}
+	}
+	return b.String(), nil
+}
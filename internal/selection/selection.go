@@ -0,0 +1,197 @@
+// Package selection reads the text selected in the focused application and
+// the mouse cursor position, and pastes into it, using the platform's
+// accessibility tools
+package selection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrUnsupported is returned when selected text can't be read on this system
+	ErrUnsupported = errors.New("selection: not supported on this system")
+	// ErrEmpty is returned when no text is selected
+	ErrEmpty = errors.New("selection: no text selected")
+)
+
+// macSelection reads the selected text of the focused UI element through the
+// accessibility API. tons needs the Accessibility permission for this.
+const macSelection = `tell application "System Events"
+	set p to first application process whose frontmost is true
+	set f to value of attribute "AXFocusedUIElement" of p
+	return value of attribute "AXSelectedText" of f
+end tell`
+
+// windowsSelection reads the selected text of the focused element through UI Automation
+const windowsSelection = `Add-Type -AssemblyName UIAutomationClient,UIAutomationTypes
+$e = [System.Windows.Automation.AutomationElement]::FocusedElement
+$p = $null
+if ($e -and $e.TryGetCurrentPattern([System.Windows.Automation.TextPattern]::Pattern, [ref]$p)) {
+	[Console]::OutputEncoding = [Text.Encoding]::UTF8
+	($p.GetSelection() | ForEach-Object { $_.GetText(-1) }) -join "` + "`n" + `"
+}`
+
+// linuxSelection lists commands printing the primary selection, in order of preference
+var linuxSelection = [][]string{
+	{"xclip", "-o", "-selection", "primary"},
+	{"xsel", "-o", "-p"},
+}
+
+// Available reports whether selected text can be read on this system
+func Available() bool {
+	_, ok := command()
+	return ok
+}
+
+// command returns the command that prints the selected text
+func command() ([]string, bool) {
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{"osascript", "-e", macSelection}, true
+	case "windows":
+		return []string{"powershell", "-NoProfile", "-NonInteractive", "-Command", windowsSelection}, true
+	case "linux":
+		if os.Getenv("WAYLAND_DISPLAY") != "" {
+			if _, err := exec.LookPath("wl-paste"); err == nil {
+				return []string{"wl-paste", "--primary", "--no-newline"}, true
+			}
+		}
+		for _, c := range linuxSelection {
+			if _, err := exec.LookPath(c[0]); err == nil {
+				return c, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Text returns the text selected in the focused application
+func Text(ctx context.Context) (string, error) {
+	c, ok := command()
+	if !ok {
+		return "", ErrUnsupported
+	}
+	out, err := exec.CommandContext(ctx, c[0], c[1:]...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && len(out) == 0 && runtime.GOOS == "linux" {
+			return "", ErrEmpty // the clipboard tools fail when there is no selection
+		}
+		return "", fmt.Errorf("%s: %w", c[0], err)
+	}
+	text := strings.TrimSpace(string(out))
+	if text == "" || text == "missing value" { // AppleScript's nil
+		return "", ErrEmpty
+	}
+	return text, nil
+}
+
+// linuxPaste lists commands pressing Ctrl+V in the focused window, in order
+// of preference; wtype only works on Wayland and xdotool only on X11
+var linuxPaste = map[bool][][]string{
+	true:  {{"wtype", "-M", "ctrl", "v", "-m", "ctrl"}},
+	false: {{"xdotool", "key", "--clearmodifiers", "ctrl+v"}},
+}
+
+// pasteCommand returns the command pressing the paste shortcut
+func pasteCommand() ([]string, bool) {
+	switch runtime.GOOS {
+	case "darwin":
+		return []string{"osascript", "-e", `tell application "System Events" to keystroke "v" using command down`}, true
+	case "windows":
+		return []string{"powershell", "-NoProfile", "-NonInteractive", "-Command",
+			`Add-Type -AssemblyName System.Windows.Forms; [System.Windows.Forms.SendKeys]::SendWait("^v")`}, true
+	case "linux":
+		for _, c := range linuxPaste[os.Getenv("WAYLAND_DISPLAY") != ""] {
+			if _, err := exec.LookPath(c[0]); err == nil {
+				return c, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// PasteAvailable reports whether Paste works on this system
+func PasteAvailable() bool {
+	_, ok := pasteCommand()
+	return ok
+}
+
+// Paste presses the paste shortcut in the focused application, replacing
+// its selection with the clipboard. On macOS tons needs the Accessibility
+// permission for this.
+func Paste(ctx context.Context) error {
+	c, ok := pasteCommand()
+	if !ok {
+		return ErrUnsupported
+	}
+	if out, err := exec.CommandContext(ctx, c[0], c[1:]...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %w: %s", c[0], err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// macCursor prints the mouse position with the origin at the top left of the main screen
+const macCursor = `ObjC.import("AppKit");
+var p = $.NSEvent.mouseLocation;
+var h = $.NSScreen.mainScreen.frame.size.height;
+Math.round(p.x) + "," + Math.round(h - p.y)`
+
+// windowsCursor prints the mouse position
+const windowsCursor = `Add-Type -AssemblyName System.Windows.Forms
+$p = [System.Windows.Forms.Cursor]::Position
+"$($p.X),$($p.Y)"`
+
+// Cursor returns the mouse cursor position in screen coordinates. ok is false
+// where it can't be read, e.g. on Wayland.
+func Cursor(ctx context.Context) (x, y int, ok bool) {
+	var out []byte
+	var err error
+	switch runtime.GOOS {
+	case "darwin":
+		out, err = exec.CommandContext(ctx, "osascript", "-l", "JavaScript", "-e", macCursor).Output()
+	case "windows":
+		out, err = exec.CommandContext(ctx, "powershell", "-NoProfile", "-NonInteractive", "-Command", windowsCursor).Output()
+	case "linux":
+		if os.Getenv("WAYLAND_DISPLAY") != "" {
+			return 0, 0, false
+		}
+		out, err = exec.CommandContext(ctx, "xdotool", "getmouselocation", "--shell").Output()
+		if err == nil {
+			out = []byte(xdotoolPosition(string(out)))
+		}
+	default:
+		return 0, 0, false
+	}
+	if err != nil {
+		return 0, 0, false
+	}
+	xs, ys, found := strings.Cut(strings.TrimSpace(string(out)), ",")
+	if !found {
+		return 0, 0, false
+	}
+	x, errX := strconv.Atoi(strings.TrimSpace(xs))
+	y, errY := strconv.Atoi(strings.TrimSpace(ys))
+	return x, y, errX == nil && errY == nil
+}
+
+// xdotoolPosition turns xdotool's X=.. Y=.. lines into "x,y"
+func xdotoolPosition(out string) string {
+	var x, y string
+	for _, line := range strings.Split(out, "\n") {
+		if v, ok := strings.CutPrefix(line, "X="); ok {
+			x = v
+		}
+		if v, ok := strings.CutPrefix(line, "Y="); ok {
+			y = v
+		}
+	}
+	return x + "," + y
+}
@@ -0,0 +1,148 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+
+	"github.com/ironpark/tons/internal/anki"
+	"github.com/ironpark/tons/internal/config"
+	"github.com/ironpark/tons/internal/engine"
+	"github.com/ironpark/tons/internal/history"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// AnkiService turns history entries into flashcards
+type AnkiService struct {
+	cfg       *config.Config
+	history   *history.Store
+	translate *TranslateService
+}
+
+func NewAnkiService(cfg *config.Config, hist *history.Store, translate *TranslateService) *AnkiService {
+	return &AnkiService{
+		cfg:       cfg,
+		history:   hist,
+		translate: translate,
+	}
+}
+
+// AnkiAvailable reports whether Anki is running with AnkiConnect
+func (an *AnkiService) AnkiAvailable() bool {
+	return anki.NewClient(an.cfg.Snapshot().Anki.URL).Available(context.Background())
+}
+
+// SendToAnki adds the given history entries, or all favorites if ids is
+// empty, to the configured deck. Returns the number of added notes;
+// duplicates already in the deck are skipped.
+func (an *AnkiService) SendToAnki(ids []string) (int, error) {
+	settings := an.cfg.Snapshot().Anki
+	notes, err := an.notes(settings, ids)
+	if err != nil {
+		return 0, err
+	}
+	return anki.NewClient(settings.URL).AddNotes(context.Background(), settings.Deck, settings.NoteType, notes)
+}
+
+// ExportAnkiTSV returns the given history entries, or all favorites if ids is
+// empty, as a TSV file for Anki's importer
+func (an *AnkiService) ExportAnkiTSV(ids []string) (string, error) {
+	settings := an.cfg.Snapshot().Anki
+	notes, err := an.notes(settings, ids)
+	if err != nil {
+		return "", err
+	}
+	fields := make([]string, len(settings.Fields))
+	for i, f := range settings.Fields {
+		fields[i] = f.Name
+	}
+	return string(anki.TSV(fields, notes)), nil
+}
+
+// notes builds a note per entry with the configured fields
+func (an *AnkiService) notes(settings config.AnkiConfig, ids []string) ([]anki.Note, error) {
+	if len(settings.Fields) == 0 {
+		return nil, errors.New("no Anki note fields are configured")
+	}
+	entries, err := an.entries(ids)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, errors.New("no history entries to export")
+	}
+
+	study := slices.ContainsFunc(settings.Fields, func(f config.AnkiField) bool {
+		return f.Content == config.AnkiRomanization || f.Content == config.AnkiExample || f.Content == config.AnkiExampleTranslation
+	})
+	notes := make([]anki.Note, len(entries))
+	for i, e := range entries {
+		var note engine.StudyNote
+		if study {
+			if note, err = an.translate.studyNote(e.SourceLang, e.TargetLang, e.Text); err != nil {
+				slog.Warn("failed to create study note", "entry", e.ID, "error", err)
+			}
+		}
+		fields := make(map[string]string, len(settings.Fields))
+		for _, f := range settings.Fields {
+			fields[f.Name] = fieldContent(f.Content, e, note)
+		}
+		notes[i] = anki.Note{Fields: fields, Tags: settings.Tags}
+	}
+	return notes, nil
+}
+
+// entries returns the history entries with the given IDs, or all favorites if ids is empty
+func (an *AnkiService) entries(ids []string) ([]history.Entry, error) {
+	if len(ids) == 0 {
+		var favorites []history.Entry
+		for _, e := range an.history.List(0) {
+			if e.Favorite {
+				favorites = append(favorites, e)
+			}
+		}
+		return favorites, nil
+	}
+	entries := make([]history.Entry, 0, len(ids))
+	for _, id := range ids {
+		e, ok := an.history.Get(id)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", history.ErrNotFound, id)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// fieldContent returns the content of a note field
+func fieldContent(content string, e history.Entry, note engine.StudyNote) string {
+	switch content {
+	case config.AnkiSource:
+		return e.Text
+	case config.AnkiTranslation:
+		return e.Translation
+	case config.AnkiRomanization:
+		return note.Romanization
+	case config.AnkiExample:
+		return note.Example
+	case config.AnkiExampleTranslation:
+		return note.ExampleTranslation
+	case config.AnkiSourceLang:
+		return e.SourceLang
+	case config.AnkiTargetLang:
+		return e.TargetLang
+	default:
+		return ""
+	}
+}
+
+// ServiceStartup is called when the service starts
+func (an *AnkiService) ServiceStartup(ctx context.Context, options application.ServiceOptions) error {
+	return nil
+}
+
+func (an *AnkiService) ServiceShutdown() error {
+	return nil
+}
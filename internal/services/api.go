@@ -0,0 +1,394 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ironpark/tons/internal/config"
+	"github.com/ironpark/tons/internal/engine"
+	"github.com/ironpark/tons/internal/metrics"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// maxAPIBody bounds the size of API request bodies
+const maxAPIBody = 1 << 20
+
+// APITranslateRequest is the body of POST /translate and POST /translate/stream
+type APITranslateRequest struct {
+	Text        string             `json:"text"`
+	SourceLang  string             `json:"sourceLang"` // empty or "auto" to detect
+	TargetLang  string             `json:"targetLang"`
+	Format      engine.Format      `json:"format,omitempty"`
+	Formality   engine.Formality   `json:"formality,omitempty"`
+	Tone        string             `json:"tone,omitempty"`
+	Context     string             `json:"context,omitempty"`
+	Constraints engine.Constraints `json:"constraints,omitzero"` // output length and line limits
+	Fresh       bool               `json:"fresh,omitempty"`      // translate again even if the history has a recent identical translation
+
+	// To refine a translation instead: Previous is a translation of Text and
+	// the last of Instructions the follow-up to apply to it, after the
+	// earlier ones that made it
+	Previous     string   `json:"previous,omitempty"`
+	Instructions []string `json:"instructions,omitempty"`
+}
+
+// APITranslateResponse is the body returned by POST /translate
+type APITranslateResponse struct {
+	Text         string        `json:"text"`
+	Engine       string        `json:"engine"`
+	DetectedLang string        `json:"detectedLang,omitempty"`
+	Skipped      bool          `json:"skipped,omitempty"` // already in the target language
+	Usage        *engine.Usage `json:"usage,omitempty"`
+	Confidence   *float64      `json:"confidence,omitempty"`  // 0 to 1, when known
+	Violations   []string      `json:"violations,omitempty"`  // constraints the translation breaks
+	FromHistory  bool          `json:"fromHistory,omitempty"` // repeated from a recent identical translation
+}
+
+// APIServerStatus describes the local HTTP API server
+type APIServerStatus struct {
+	Running bool   `json:"running"`
+	Address string `json:"address,omitempty"` // e.g. "http://127.0.0.1:47821"
+	Error   string `json:"error,omitempty"`   // why the server is not running
+}
+
+// APIService serves translations over a local HTTP API so that scripts,
+// editors and other apps can use tons as a translation backend
+type APIService struct {
+	cfg       *config.Config
+	translate *TranslateService
+	status    *StatusService
+
+	mu      sync.Mutex
+	server  *http.Server
+	address string
+	err     error
+}
+
+func NewAPIService(cfg *config.Config, translate *TranslateService, status *StatusService) *APIService {
+	return &APIService{
+		cfg:       cfg,
+		translate: translate,
+		status:    status,
+	}
+}
+
+// GetAPIServerStatus returns whether the API server is running and where
+func (as *APIService) GetAPIServerStatus() APIServerStatus {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	status := APIServerStatus{Running: as.server != nil}
+	if as.server != nil {
+		status.Address = "http://" + as.address
+	}
+	if as.err != nil {
+		status.Error = as.err.Error()
+	}
+	return status
+}
+
+// RestartAPIServer applies the saved API settings, stopping the server or
+// starting it on the configured port
+func (as *APIService) RestartAPIServer() error {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	as.stop()
+	return as.start()
+}
+
+// RegenerateAPIToken replaces the API token, invalidating the old one, and returns the new token
+func (as *APIService) RegenerateAPIToken() (string, error) {
+	token, err := newAPIToken()
+	if err != nil {
+		return "", err
+	}
+	settings := as.cfg.Snapshot().API
+	settings.Token = token
+	as.cfg.SetAPI(settings)
+	if err := as.cfg.Save(); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// start listens on the configured port if the API is enabled. Must be called with as.mu held.
+func (as *APIService) start() error {
+	as.err = nil
+	settings := as.cfg.Snapshot().API
+	if !settings.Enabled {
+		return nil
+	}
+	if settings.Token == "" {
+		token, err := newAPIToken()
+		if err != nil {
+			as.err = err
+			return err
+		}
+		settings.Token = token
+		as.cfg.SetAPI(settings)
+		if err := as.cfg.Save(); err != nil {
+			slog.Warn("failed to save API token", "error", err)
+		}
+	}
+
+	address := net.JoinHostPort("127.0.0.1", strconv.Itoa(settings.Port))
+	l, err := net.Listen("tcp", address)
+	if err != nil {
+		as.err = fmt.Errorf("API server: %w", err)
+		return as.err
+	}
+	server := &http.Server{
+		Handler:           as.handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	go func() {
+		if err := server.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Warn("API server stopped", "error", err)
+		}
+	}()
+	as.server = server
+	as.address = address
+	slog.Info("API server listening", "address", address)
+	return nil
+}
+
+// stop shuts the server down, letting running requests finish for a few
+// seconds. Must be called with as.mu held.
+func (as *APIService) stop() {
+	if as.server == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := as.server.Shutdown(ctx); err != nil {
+		as.server.Close()
+	}
+	as.server = nil
+	as.address = ""
+}
+
+// handler routes API requests, checking the token first
+func (as *APIService) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /translate", as.handleTranslate)
+	mux.HandleFunc("POST /translate/stream", as.handleTranslateStream)
+	mux.HandleFunc("GET /engines", as.handleEngines)
+	mux.HandleFunc("GET /metrics", as.handleMetrics)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !as.authorized(r) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeAPIError(w, http.StatusUnauthorized, "missing or invalid token")
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// authorized reports whether r carries the configured token
+func (as *APIService) authorized(r *http.Request) bool {
+	token := as.cfg.Snapshot().API.Token
+	given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && token != "" && subtle.ConstantTimeCompare([]byte(given), []byte(token)) == 1
+}
+
+// handleTranslate serves POST /translate
+func (as *APIService) handleTranslate(w http.ResponseWriter, r *http.Request) {
+	req, ok := readAPITranslateRequest(w, r)
+	if !ok {
+		return
+	}
+	snapshot := as.cfg.Snapshot()
+	if entry, ok := as.translate.fromHistory(snapshot, as.translate.applyDefaults(snapshot, req)); ok {
+		writeAPIJSON(w, http.StatusOK, APITranslateResponse{
+			Text:        entry.Translation,
+			Engine:      entry.Engine,
+			Confidence:  entry.Confidence,
+			FromHistory: true,
+		})
+		return
+	}
+	res, engineName, err := as.translate.translateOnce(r.Context(), engine.PriorityInteractive, req)
+	var tooLarge *engine.TooLargeError
+	switch {
+	case errors.As(err, &tooLarge):
+		writeAPIError(w, http.StatusRequestEntityTooLarge, err.Error())
+		return
+	case err != nil:
+		writeAPIError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	writeAPIJSON(w, http.StatusOK, APITranslateResponse{
+		Text:         res.Text,
+		Engine:       engineName,
+		DetectedLang: res.DetectedLang,
+		Skipped:      res.Skipped,
+		Usage:        res.Usage,
+		Confidence:   res.Confidence,
+		Violations:   res.Violations,
+	})
+}
+
+// handleTranslateStream serves POST /translate/stream as Server-Sent Events:
+// "delta" events carry TranslateDelta, then one "done" (TranslateDone) or
+// "error" (TranslateError) event ends the stream. "queued" events
+// (TranslateQueued) may come first while the engine is busy, and a
+// "language" event (TranslateLanguageWarning) when the engine handles the
+// language pair poorly.
+func (as *APIService) handleTranslateStream(w http.ResponseWriter, r *http.Request) {
+	req, ok := readAPITranslateRequest(w, r)
+	if !ok {
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeAPIError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	// The queue reports positions from other goroutines, possibly after the
+	// stream has ended
+	var mu sync.Mutex
+	ended := false
+	as.translate.streamTo(r.Context(), req, func(event string, payload any) {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if ended {
+			return
+		}
+		fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+		flusher.Flush()
+	})
+	mu.Lock()
+	ended = true
+	mu.Unlock()
+}
+
+// readAPITranslateRequest decodes and checks the body of a translate request,
+// writing an error response if it is invalid
+func readAPITranslateRequest(w http.ResponseWriter, r *http.Request) (engine.Request, bool) {
+	var body APITranslateRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAPIBody)).Decode(&body); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return engine.Request{}, false
+	}
+	req, err := body.request()
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, err.Error())
+		return engine.Request{}, false
+	}
+	return req, true
+}
+
+// request checks the fields and converts them to an engine request
+func (body APITranslateRequest) request() (engine.Request, error) {
+	if strings.TrimSpace(body.Text) == "" {
+		return engine.Request{}, errors.New("text is required")
+	}
+	if body.TargetLang == "" {
+		return engine.Request{}, errors.New("targetLang is required")
+	}
+	if body.Constraints.MaxLength < 0 {
+		return engine.Request{}, errors.New("constraints.maxLength must not be negative")
+	}
+	if len(body.Instructions) > 0 && strings.TrimSpace(body.Previous) == "" {
+		return engine.Request{}, errors.New("previous is required with instructions")
+	}
+	req := engine.Request{
+		Text:        body.Text,
+		SourceLang:  body.SourceLang,
+		TargetLang:  body.TargetLang,
+		Format:      body.Format,
+		Formality:   body.Formality,
+		Tone:        body.Tone,
+		Context:     body.Context,
+		Constraints: body.Constraints,
+		Fresh:       body.Fresh,
+	}
+	if len(body.Instructions) > 0 {
+		req = engine.RefineRequest(req, body.Previous, body.Instructions)
+	}
+	return req, nil
+}
+
+// handleEngines serves GET /engines
+func (as *APIService) handleEngines(w http.ResponseWriter, r *http.Request) {
+	writeAPIJSON(w, http.StatusOK, as.status.GetEngineStatus())
+}
+
+// handleMetrics serves GET /metrics in the Prometheus text format, for
+// monitoring tons run as a shared translation server
+func (as *APIService) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", metrics.PrometheusContentType)
+	if err := as.translate.metrics.WritePrometheus(w); err != nil {
+		slog.Warn("failed to write metrics", "error", err)
+	}
+}
+
+// writeAPIJSON writes v as a JSON response
+func writeAPIJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeAPIError writes an error response as {"error": message}
+func writeAPIError(w http.ResponseWriter, status int, message string) {
+	writeAPIJSON(w, status, map[string]string{"error": message})
+}
+
+// newAPIToken returns a random token
+func newAPIToken() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// ServiceStartup starts the API server if it is enabled
+func (as *APIService) ServiceStartup(ctx context.Context, options application.ServiceOptions) error {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	if err := as.start(); err != nil {
+		slog.Warn("failed to start API server", "error", err)
+	}
+	application.Get().Event.On("config:changed", func(e *application.CustomEvent) {
+		if section, _ := e.Data.(string); section == "api" {
+			if err := as.RestartAPIServer(); err != nil {
+				slog.Warn("failed to restart API server", "error", err)
+			}
+		}
+	})
+	return nil
+}
+
+func (as *APIService) ServiceShutdown() error {
+	as.mu.Lock()
+	defer as.mu.Unlock()
+
+	as.stop()
+	return nil
+}
@@ -0,0 +1,129 @@
+package services
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/ironpark/tons/internal/cache"
+	"github.com/ironpark/tons/internal/config"
+	"github.com/ironpark/tons/internal/engine"
+	"github.com/ironpark/tons/internal/history"
+)
+
+// cacheKey returns the cache key of a request with defaults applied
+func cacheKey(req engine.Request) string {
+	parts := []any{req.Text, req.SourceLang, req.TargetLang, req.Prompt, req.SystemPrompt,
+		req.Format, req.Formality, req.Tone, req.Context, req.Examples, req.Glossary, req.Variables}
+	if req.Constraints != (engine.Constraints{}) {
+		// Only when set, so that earlier entries keep their keys
+		parts = append(parts, req.Constraints)
+	}
+	return cache.Key(parts...)
+}
+
+// promptHash identifies the settings of a request, with defaults applied,
+// that shape its prompt: everything but the text and the languages
+func promptHash(req engine.Request) string {
+	parts := []any{req.Prompt, req.SystemPrompt, req.Format, req.Formality, req.Tone,
+		req.Context, req.Examples, req.Glossary, req.Variables, req.Constraints}
+	return cache.Key(parts...)
+}
+
+// fromHistory returns a translation of req made recently enough to answer
+// it again, unless the request asks for a fresh one
+func (ts *TranslateService) fromHistory(snapshot *config.Config, req engine.Request) (history.Entry, bool) {
+	minutes := snapshot.Cache.HistoryMinutes
+	if ts.history == nil || minutes <= 0 || req.Fresh || req.Text == "" {
+		return history.Entry{}, false
+	}
+	since := time.Now().Add(-time.Duration(minutes) * time.Minute)
+	return ts.history.Recent(req.Text, req.SourceLang, req.TargetLang, promptHash(req), since)
+}
+
+// replayHistory sends a translation from the history as one "delta" and
+// the "done" event, both marked as from history
+func replayHistory(req engine.Request, entry history.Entry, send func(event string, payload any)) TranslateDone {
+	send("delta", TranslateDelta{
+		RequestID:   req.ID,
+		Engine:      entry.Engine,
+		Delta:       entry.Translation,
+		Text:        entry.Translation,
+		FromHistory: true,
+	})
+	done := TranslateDone{
+		RequestID:   req.ID,
+		Engine:      entry.Engine,
+		Text:        entry.Translation,
+		Quality:     entry.Quality,
+		Confidence:  entry.Confidence,
+		FromHistory: true,
+	}
+	send("done", done)
+	return done
+}
+
+// cached returns the cached translation of req if the settings allow
+// serving it: always with reuse, if the same engine made it, and with
+// offline mode while the engine is unavailable. available is only called
+// when there is a cached translation.
+func (ts *TranslateService) cached(snapshot *config.Config, req engine.Request, engineName string, available func() bool) (cache.Entry, bool) {
+	settings := snapshot.Cache
+	if ts.cache == nil || !settings.Enabled || req.Text == "" {
+		return cache.Entry{}, false
+	}
+	entry, ok := ts.cache.Get(cacheKey(req))
+	switch {
+	case !ok:
+		ts.metrics.RecordCache(false)
+		return cache.Entry{}, false
+	case settings.Reuse && entry.Engine == engineName:
+		ts.metrics.RecordCache(true)
+		return entry, true
+	case settings.Offline && (available == nil || !available()):
+		slog.Info("engine unavailable, serving cached translation", "engine", engineName)
+		ts.metrics.RecordCache(true)
+		return entry, true
+	}
+	return cache.Entry{}, false
+}
+
+// storeCache caches a completed translation
+func (ts *TranslateService) storeCache(snapshot *config.Config, req engine.Request, done TranslateDone) {
+	if ts.cache == nil || !snapshot.Cache.Enabled || done.Cached || done.Skipped || done.Text == "" {
+		return
+	}
+	ts.cache.Put(cacheKey(req), cache.Entry{
+		Translation:  done.Text,
+		Engine:       done.Engine,
+		DetectedLang: done.DetectedLang,
+	})
+}
+
+// replayCached sends a cached translation as one "delta" and the "done"
+// event, both marked as cached
+func replayCached(req engine.Request, entry cache.Entry, send func(event string, payload any)) TranslateDone {
+	send("delta", TranslateDelta{
+		RequestID: req.ID,
+		Engine:    entry.Engine,
+		Delta:     entry.Translation,
+		Text:      entry.Translation,
+		Cached:    true,
+	})
+	done := TranslateDone{
+		RequestID:    req.ID,
+		Engine:       entry.Engine,
+		Text:         entry.Translation,
+		DetectedLang: entry.DetectedLang,
+		Cached:       true,
+	}
+	send("done", done)
+	return done
+}
+
+// ClearTranslationCache removes all cached translations
+func (ts *TranslateService) ClearTranslationCache() error {
+	if ts.cache == nil {
+		return nil
+	}
+	return ts.cache.Clear()
+}
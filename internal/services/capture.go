@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"errors"
+
+	"github.com/ironpark/tons/internal/capture"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// overlayWindow is the name of the always-on-top window showing capture results
+const overlayWindow = "overlay"
+
+type CaptureService struct {
+	ocr *OCRService
+	app *application.App
+}
+
+func NewCaptureService(ocr *OCRService) *CaptureService {
+	return &CaptureService{
+		ocr: ocr,
+	}
+}
+
+// CaptureAvailable reports whether screen region capture is supported on this system
+func (cs *CaptureService) CaptureAvailable() bool {
+	return capture.Available()
+}
+
+// CaptureAndTranslate lets the user select a screen region, recognizes its text
+// and translates it into the overlay window. Cancelling the selection is not an error.
+func (cs *CaptureService) CaptureAndTranslate(sourceLang, targetLang string) error {
+	data, err := capture.Region(context.Background())
+	if errors.Is(err, capture.ErrCancelled) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	cs.showOverlay()
+	return cs.ocr.translateImage(data, sourceLang, targetLang)
+}
+
+// HideOverlay hides the capture result overlay
+func (cs *CaptureService) HideOverlay() {
+	if w, ok := cs.app.Window.GetByName(overlayWindow); ok {
+		w.Hide()
+	}
+}
+
+// showOverlay shows the overlay window, creating it on first use
+func (cs *CaptureService) showOverlay() {
+	if w, ok := cs.app.Window.GetByName(overlayWindow); ok {
+		w.Show()
+		w.Focus()
+		return
+	}
+	cs.app.Window.NewWithOptions(application.WebviewWindowOptions{
+		Name:             overlayWindow,
+		Title:            "Translation",
+		Width:            420,
+		Height:           240,
+		AlwaysOnTop:      true,
+		Frameless:        true,
+		BackgroundColour: application.NewRGB(27, 38, 54),
+		URL:              "/#/overlay",
+	})
+}
+
+// ServiceStartup is called when the service starts
+func (cs *CaptureService) ServiceStartup(ctx context.Context, options application.ServiceOptions) error {
+	cs.app = application.Get()
+	return nil
+}
+
+func (cs *CaptureService) ServiceShutdown() error {
+	return nil
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ironpark/tons/internal/config"
+	"github.com/ironpark/tons/internal/engine"
+	"github.com/ironpark/tons/internal/langdetect"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// ClipboardText is new text found on the clipboard
+type ClipboardText struct {
+	Text         string `json:"text"`
+	DetectedLang string `json:"detectedLang"`
+}
+
+// ClipboardTranslation is the translation of clipboard text
+type ClipboardTranslation struct {
+	Text        string `json:"text"`
+	Translation string `json:"translation"`
+	TargetLang  string `json:"targetLang"`
+}
+
+// ClipboardService watches the clipboard for new text while enabled in the
+// settings and translates it automatically or on request
+type ClipboardService struct {
+	cfg       *config.Config
+	translate *TranslateService
+	app       *application.App
+	cancel    context.CancelFunc
+
+	mu          sync.Mutex
+	primed      bool   // lastSeen holds the clipboard from before the monitor was enabled
+	lastSeen    string // last clipboard text checked
+	lastWritten string // last translation written back, so it isn't picked up again
+	pending     string // last text waiting to be translated
+	seq         int    // bumps with every new text so stale translations aren't written back
+}
+
+func NewClipboardService(cfg *config.Config, translate *TranslateService) *ClipboardService {
+	return &ClipboardService{
+		cfg:       cfg,
+		translate: translate,
+	}
+}
+
+// PendingClipboardText returns the last clipboard text found by the monitor
+func (cb *ClipboardService) PendingClipboardText() string {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	return cb.pending
+}
+
+// TranslateClipboard translates the last text found by the monitor, for when
+// auto-translate is off and the user taps to translate
+func (cb *ClipboardService) TranslateClipboard() (string, error) {
+	cb.mu.Lock()
+	text, seq := cb.pending, cb.seq
+	cb.mu.Unlock()
+	if text == "" {
+		return "", nil
+	}
+	return cb.translateText(text, seq, cb.cfg.Snapshot().Clipboard)
+}
+
+// TranslateClipboardNow translates the text on the clipboard, whether or not
+// the monitor is enabled
+func (cb *ClipboardService) TranslateClipboardNow() (string, error) {
+	text, ok := cb.app.Clipboard.Text()
+	text = strings.TrimSpace(text)
+	if !ok || text == "" {
+		return "", nil
+	}
+	cb.mu.Lock()
+	cb.lastSeen = text
+	cb.pending = text
+	cb.seq++
+	seq := cb.seq
+	cb.mu.Unlock()
+	return cb.translateText(text, seq, cb.cfg.Snapshot().Clipboard)
+}
+
+// watch checks the clipboard until ctx is cancelled
+func (cb *ClipboardService) watch(ctx context.Context) {
+	for {
+		interval := time.Duration(cb.cfg.Snapshot().Clipboard.IntervalMs) * time.Millisecond
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(max(interval, 100*time.Millisecond)):
+		}
+		cb.check()
+	}
+}
+
+// check looks for new clipboard text and reports it
+func (cb *ClipboardService) check() {
+	settings := cb.cfg.Snapshot().Clipboard
+	text, ok := cb.app.Clipboard.Text()
+
+	cb.mu.Lock()
+	if !settings.Enabled {
+		cb.primed = false
+		cb.mu.Unlock()
+		return
+	}
+	if !cb.primed {
+		// Text copied before the monitor was enabled isn't new
+		cb.primed = true
+		cb.lastSeen = text
+		cb.mu.Unlock()
+		return
+	}
+	if !ok || text == cb.lastSeen || text == cb.lastWritten {
+		cb.lastSeen = text
+		cb.mu.Unlock()
+		return
+	}
+	cb.lastSeen = text
+	cb.mu.Unlock()
+
+	trimmed := strings.TrimSpace(text)
+	length := utf8.RuneCountInString(trimmed)
+	if length < settings.MinLength || (settings.MaxLength > 0 && length > settings.MaxLength) {
+		return
+	}
+	detected := langdetect.Detect(trimmed)
+	if detected.Lang == langdetect.Code(settings.TargetLang) && detected.Confidence >= 0.8 {
+		return // already in the target language
+	}
+
+	cb.mu.Lock()
+	cb.pending = trimmed
+	cb.seq++
+	seq := cb.seq
+	cb.mu.Unlock()
+
+	cb.app.Event.Emit("clipboard:text", ClipboardText{Text: trimmed, DetectedLang: detected.Lang})
+	if settings.AutoTranslate {
+		go func() {
+			if _, err := cb.translateText(trimmed, seq, settings); err != nil {
+				slog.Warn("clipboard translation failed", "error", err)
+			}
+		}()
+	}
+}
+
+// translateText translates clipboard text, emits "clipboard:translation" and
+// writes the translation back when configured and the text is still the latest
+func (cb *ClipboardService) translateText(text string, seq int, settings config.ClipboardConfig) (string, error) {
+	translation, err := cb.translate.TranslateText(settings.SourceLang, settings.TargetLang, text, engine.FormatText)
+	if err != nil {
+		return "", err
+	}
+	cb.app.Event.Emit("clipboard:translation", ClipboardTranslation{
+		Text:        text,
+		Translation: translation,
+		TargetLang:  settings.TargetLang,
+	})
+
+	cb.mu.Lock()
+	writeBack := settings.WriteBack && seq == cb.seq
+	if writeBack {
+		cb.lastWritten = translation
+	}
+	cb.mu.Unlock()
+	if writeBack {
+		cb.app.Clipboard.SetText(translation)
+	}
+	return translation, nil
+}
+
+// setText puts a translation on the clipboard, where the monitor won't take
+// it for new text
+func (cb *ClipboardService) setText(translation string) {
+	cb.mu.Lock()
+	cb.lastWritten = translation
+	cb.mu.Unlock()
+	cb.app.Clipboard.SetText(translation)
+}
+
+// ServiceStartup is called when the service starts
+func (cb *ClipboardService) ServiceStartup(ctx context.Context, options application.ServiceOptions) error {
+	cb.app = application.Get()
+	watchCtx, cancel := context.WithCancel(context.Background())
+	cb.cancel = cancel
+	go cb.watch(watchCtx)
+	return nil
+}
+
+func (cb *ClipboardService) ServiceShutdown() error {
+	if cb.cancel != nil {
+		cb.cancel()
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/ironpark/tons/internal/sourcecode"
+)
+
+// Source code modes: what to translate in source files
+const (
+	CodeComments = "comments"
+	CodeStrings  = "strings"
+	CodeAll      = "all"
+)
+
+func init() {
+	for _, ext := range sourcecode.Extensions() {
+		documentFormats[ext] = translateSourceCode
+	}
+}
+
+// translateSourceCode translates the comments and/or string literals of a
+// source file, as set by the code mode, keeping the code itself unchanged
+func translateSourceCode(ctx context.Context, ts *TranslateService, path string, data []byte, sourceLang, targetLang string) ([]byte, error) {
+	lang, _ := sourcecode.LanguageFor(filepath.Ext(path))
+	mode := ts.cfg.Snapshot().Translation.CodeMode
+	file, err := sourcecode.Parse(lang, data, sourcecode.Options{
+		Comments: mode != CodeStrings,
+		Strings:  mode == CodeStrings || mode == CodeAll,
+	})
+	if err != nil {
+		return nil, err
+	}
+	translated, err := ts.translateSegments(ctx, sourceLang, targetLang, file.Texts())
+	if err != nil {
+		return nil, err
+	}
+	return file.Render(translated)
+}
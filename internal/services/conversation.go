@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/ironpark/tons/internal/conversation"
+	"github.com/ironpark/tons/internal/engine"
+	"github.com/ironpark/tons/internal/langdetect"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// ConversationTurn is the payload of "conversation:turn" events, sent when
+// a turn of a conversation is translated
+type ConversationTurn struct {
+	ConversationID string            `json:"conversationId"`
+	Turn           conversation.Turn `json:"turn"`
+}
+
+// ConversationService interprets a chat between two people writing in
+// different languages: each message is translated into the other party's
+// language with the recent dialogue as context. Conversations are kept in
+// memory by ID.
+type ConversationService struct {
+	translate     *TranslateService
+	conversations *conversation.Manager
+	app           *application.App
+}
+
+func NewConversationService(translate *TranslateService) *ConversationService {
+	return &ConversationService{
+		translate:     translate,
+		conversations: conversation.NewManager(),
+	}
+}
+
+// StartConversation begins a conversation between a party writing langA and
+// one writing langB
+func (cs *ConversationService) StartConversation(langA, langB string) (conversation.Conversation, error) {
+	return cs.conversations.Start(strings.TrimSpace(langA), strings.TrimSpace(langB))
+}
+
+// GetConversation returns a conversation with its turns
+func (cs *ConversationService) GetConversation(id string) (conversation.Conversation, error) {
+	return cs.conversations.Get(id)
+}
+
+// ListConversations returns the open conversations, most recently used first
+func (cs *ConversationService) ListConversations() []conversation.Conversation {
+	return cs.conversations.List()
+}
+
+// EndConversation forgets a conversation
+func (cs *ConversationService) EndConversation(id string) {
+	cs.conversations.End(id)
+}
+
+// TranslateTurn translates a message of a party into the other party's
+// language and adds it to the conversation. An empty party is recognized
+// by the message's language, or else taken to be the one whose turn it is.
+func (cs *ConversationService) TranslateTurn(id string, party conversation.Party, text string) (conversation.Turn, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return conversation.Turn{}, errors.New("text is empty")
+	}
+	conv, err := cs.conversations.Get(id)
+	if err != nil {
+		return conversation.Turn{}, err
+	}
+	switch party {
+	case conversation.PartyA, conversation.PartyB:
+	case "":
+		party = speaker(conv, text)
+	default:
+		return conversation.Turn{}, errors.New(`party must be "a" or "b"`)
+	}
+
+	res, engineName, err := cs.translate.translateOnce(context.Background(), engine.PriorityInteractive, engine.Request{
+		Text:       text,
+		SourceLang: conv.Lang(party),
+		TargetLang: conv.Lang(party.Other()),
+		Context:    conv.Context(),
+	})
+	if err != nil {
+		return conversation.Turn{}, err
+	}
+	turn := conversation.Turn{
+		Party:       party,
+		Text:        text,
+		Translation: res.Text,
+		Engine:      engineName,
+	}
+	if conv, err = cs.conversations.AddTurn(id, turn); err != nil {
+		// Ended while translating
+		return conversation.Turn{}, err
+	}
+	turn = conv.Turns[len(conv.Turns)-1]
+	if cs.app != nil {
+		cs.app.Event.Emit("conversation:turn", ConversationTurn{ConversationID: id, Turn: turn})
+	}
+	return turn, nil
+}
+
+// speaker guesses the party that wrote text from its language, falling
+// back to the party whose turn it is
+func speaker(conv conversation.Conversation, text string) conversation.Party {
+	detected := langdetect.Detect(text)
+	if detected.Lang != langdetect.Unknown {
+		for _, p := range []conversation.Party{conversation.PartyA, conversation.PartyB} {
+			if langdetect.Code(conv.Lang(p)) == detected.Lang {
+				return p
+			}
+		}
+	}
+	return conv.Next()
+}
+
+// ServiceStartup is called when the service starts
+func (cs *ConversationService) ServiceStartup(ctx context.Context, options application.ServiceOptions) error {
+	cs.app = application.Get()
+	return nil
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/ironpark/tons/internal/config"
+	"github.com/ironpark/tons/internal/models"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// Results of a diagnostic check
+const (
+	CheckOK   = "ok"
+	CheckWarn = "warn"
+	CheckFail = "fail"
+)
+
+// minModelSpace is the free disk space below which downloading a model is
+// likely to fail
+const minModelSpace = 4 << 30
+
+// DiagnosticCheck is the result of one diagnostic check
+type DiagnosticCheck struct {
+	Name   string `json:"name"`
+	Status string `json:"status"` // CheckOK, CheckWarn or CheckFail
+	Detail string `json:"detail"`
+}
+
+// DiagnosticReport is what Diagnose found. Credentials and the home
+// directory are redacted, so it can be attached to bug reports.
+type DiagnosticReport struct {
+	Created   time.Time         `json:"created"`
+	System    string            `json:"system"` // OS, architecture and Go version
+	ConfigDir string            `json:"configDir"`
+	Checks    []DiagnosticCheck `json:"checks"`
+	Settings  string            `json:"settings"` // exported settings without credentials
+}
+
+// Failed reports whether a check failed
+func (r DiagnosticReport) Failed() bool {
+	for _, check := range r.Checks {
+		if check.Status == CheckFail {
+			return true
+		}
+	}
+	return false
+}
+
+// String formats the report as plain text
+func (r DiagnosticReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "tons diagnostics, %s\n", r.Created.Format(time.RFC3339))
+	fmt.Fprintf(&b, "system:     %s\n", r.System)
+	fmt.Fprintf(&b, "config dir: %s\n\n", r.ConfigDir)
+	for _, check := range r.Checks {
+		fmt.Fprintf(&b, "[%-4s] %s", check.Status, check.Name)
+		if check.Detail != "" {
+			fmt.Fprintf(&b, ": %s", strings.ReplaceAll(check.Detail, "\n", "\n         "))
+		}
+		b.WriteByte('\n')
+	}
+	fmt.Fprintf(&b, "\nsettings:\n%s\n", r.Settings)
+	return b.String()
+}
+
+// DoctorService checks the settings, engines and model files for problems
+type DoctorService struct {
+	cfg    *config.Config
+	status *StatusService
+}
+
+func NewDoctorService(cfg *config.Config, status *StatusService) *DoctorService {
+	return &DoctorService{
+		cfg:    cfg,
+		status: status,
+	}
+}
+
+// Diagnose checks the settings, the health of every engine (including CLI
+// versions and Ollama reachability), the model file and the free disk space
+// for models
+func (dr *DoctorService) Diagnose() DiagnosticReport {
+	snapshot := dr.cfg.Snapshot()
+	report := DiagnosticReport{
+		Created:   time.Now(),
+		System:    fmt.Sprintf("%s/%s, %s", runtime.GOOS, runtime.GOARCH, runtime.Version()),
+		ConfigDir: config.Dir(),
+	}
+	add := func(name, status, detail string) {
+		report.Checks = append(report.Checks, DiagnosticCheck{Name: name, Status: status, Detail: detail})
+	}
+
+	if backup := dr.cfg.RestoredFrom(); backup != "" {
+		add("config file", CheckWarn, "was corrupt, restored from "+backup)
+	} else {
+		add("config file", CheckOK, "")
+	}
+	if errs := validateSettings(snapshot); len(errs) > 0 {
+		lines := make([]string, len(errs))
+		for i, fe := range errs {
+			lines[i] = fe.Field + ": " + fe.Message
+		}
+		add("settings", CheckFail, strings.Join(lines, "\n"))
+	} else {
+		add("settings", CheckOK, "")
+	}
+	if names := dr.cfg.EnvOverrides(); len(names) > 0 {
+		add("environment", CheckOK, "overridden by "+strings.Join(names, ", "))
+	}
+
+	for _, status := range dr.status.GetEngineStatus() {
+		name := "engine " + status.Name
+		if status.Active {
+			name += " (active)"
+		}
+		h := status.Health
+		switch {
+		case h.Error == "":
+			add(name, CheckOK, h.Version)
+		case status.Active:
+			add(name, CheckFail, h.Error)
+		default:
+			add(name, CheckWarn, h.Error)
+		}
+	}
+
+	dr.checkModels(snapshot.Engine, add)
+
+	settings, err := dr.cfg.Export(config.ExportOptions{IncludePaths: true})
+	if err != nil {
+		report.Settings = err.Error()
+	} else {
+		report.Settings = string(settings)
+	}
+	return dr.redact(report)
+}
+
+// checkModels checks the internal engine's model file and the free space in
+// the models directory
+func (dr *DoctorService) checkModels(cfg config.EngineConfig, add func(name, status, detail string)) {
+	failed := CheckWarn
+	if cfg.Uses(config.EngineInternal) {
+		failed = CheckFail
+	}
+	if path := cfg.Internal.ModelPath; path == "" {
+		add("model file", failed, "not set")
+	} else if info, err := models.Inspect(path); err != nil {
+		add("model file", failed, err.Error())
+	} else {
+		add("model file", CheckOK, fmt.Sprintf("%s %s %s", info.Architecture, info.SizeLabel, info.Quantization))
+	}
+
+	dir := cfg.Internal.ModelsDirectory()
+	list, err := models.List(dir)
+	if err != nil {
+		add("models directory", CheckWarn, err.Error())
+	} else {
+		add("models directory", CheckOK, fmt.Sprintf("%s, %d models", dir, len(list)))
+	}
+
+	// The directory is created on the first download; measure its disk
+	for !exists(dir) && filepath.Dir(dir) != dir {
+		dir = filepath.Dir(dir)
+	}
+	free, err := models.FreeSpace(dir)
+	switch {
+	case errors.Is(err, errors.ErrUnsupported):
+	case err != nil:
+		add("disk space", CheckWarn, err.Error())
+	case free < minModelSpace:
+		add("disk space", CheckWarn, fmt.Sprintf("%.1f GB free for models", float64(free)/(1<<30)))
+	default:
+		add("disk space", CheckOK, fmt.Sprintf("%.1f GB free for models", float64(free)/(1<<30)))
+	}
+}
+
+// redact replaces credentials in the report and the home directory in paths
+func (dr *DoctorService) redact(report DiagnosticReport) DiagnosticReport {
+	var pairs []string
+	for _, secret := range dr.cfg.Secrets() {
+		if len(secret) >= 4 { // replacing a short user name would garble the report
+			pairs = append(pairs, secret, "[redacted]")
+		}
+	}
+	if home, err := os.UserHomeDir(); err == nil && len(home) > 1 {
+		pairs = append(pairs, home, "~")
+	}
+	r := strings.NewReplacer(pairs...)
+	report.ConfigDir = r.Replace(report.ConfigDir)
+	report.Settings = r.Replace(report.Settings)
+	for i := range report.Checks {
+		report.Checks[i].Detail = r.Replace(report.Checks[i].Detail)
+	}
+	return report
+}
+
+// SaveDiagnosticReport runs Diagnose and writes the report to a text file in
+// the config directory, returning its path
+func (dr *DoctorService) SaveDiagnosticReport() (string, error) {
+	report := dr.Diagnose()
+	path := filepath.Join(config.Dir(), "diagnostics-"+report.Created.Format("20060102-150405")+".txt")
+	if err := os.WriteFile(path, []byte(report.String()), 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// exists reports whether path exists
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// ServiceStartup is called when the service starts
+func (dr *DoctorService) ServiceStartup(ctx context.Context, options application.ServiceOptions) error {
+	return nil
+}
+
+func (dr *DoctorService) ServiceShutdown() error {
+	return nil
+}
@@ -0,0 +1,21 @@
+package services
+
+import (
+	"context"
+
+	"github.com/ironpark/tons/internal/docx"
+)
+
+// translateDOCX translates the paragraphs of a Word document, keeping the
+// formatting of its runs
+func translateDOCX(ctx context.Context, ts *TranslateService, path string, data []byte, sourceLang, targetLang string) ([]byte, error) {
+	doc, err := docx.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	translated, err := ts.translateSegments(ctx, sourceLang, targetLang, doc.Texts())
+	if err != nil {
+		return nil, err
+	}
+	return doc.Render(translated)
+}
@@ -0,0 +1,295 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ironpark/tons/internal/config"
+	"github.com/ironpark/tons/internal/engine"
+	"github.com/ironpark/tons/internal/pipeline"
+)
+
+// processors resolves the configured text processors, skipping unknown names.
+// Personal data is masked first when it would leave the machine. Punctuation
+// is localized last so that its output stage runs first, while code and
+// URLs are still masked.
+func processors(snapshot *config.Config) []pipeline.Processor {
+	cfg := snapshot.Translation
+	var procs []pipeline.Processor
+	if cfg.ScrubPII && remoteEngine(snapshot.Engine) && !slices.Contains(cfg.Processors, pipeline.ScrubPII) {
+		proc, _ := pipeline.Lookup(pipeline.ScrubPII)
+		procs = append(procs, proc)
+	}
+	for _, name := range cfg.Processors {
+		proc, ok := pipeline.Lookup(name)
+		if !ok {
+			slog.Warn("unknown text processor", "name", name)
+			continue
+		}
+		procs = append(procs, proc)
+	}
+	if cfg.ProtectPlaceholders {
+		proc, _ := pipeline.Lookup(pipeline.ProtectPlaceholders)
+		procs = append(procs, proc)
+	}
+	if len(cfg.LocalizePunctuation) > 0 && !slices.Contains(cfg.Processors, pipeline.LocalizePunctuation) {
+		procs = append(procs, pipeline.NewLocalizePunctuation(cfg.LocalizePunctuation))
+	}
+	return procs
+}
+
+// scrubsPII reports whether the processors mask personal data, because
+// the settings ask for it or it is among the configured processors
+func scrubsPII(snapshot *config.Config) bool {
+	cfg := snapshot.Translation
+	return cfg.ScrubPII && remoteEngine(snapshot.Engine) || slices.Contains(cfg.Processors, pipeline.ScrubPII)
+}
+
+// scrubbed wraps e with personal data masking if the settings ask for it,
+// for engine calls that don't go through the configured processors
+func scrubbed(snapshot *config.Config, e engine.Engine) engine.Engine {
+	if !scrubsPII(snapshot) {
+		return e
+	}
+	scrub, _ := pipeline.Lookup(pipeline.ScrubPII)
+	return pipeline.New(e, scrub)
+}
+
+// remoteEngine reports whether the engine sends text off the machine: agents
+// call cloud models, and Ollama may run on another host
+func remoteEngine(cfg config.EngineConfig) bool {
+	switch cfg.Type {
+	case config.EngineTerminalAgent:
+		return true
+	case config.EngineSmart:
+		short, err := cfg.SmartEngine(false)
+		if err != nil {
+			return true
+		}
+		long, err := cfg.SmartEngine(true)
+		if err != nil {
+			return true
+		}
+		return remoteEngine(short) || remoteEngine(long)
+	case config.EngineOllama:
+		u, err := url.Parse(cfg.Ollama.Host)
+		if err != nil {
+			return true
+		}
+		if u.Hostname() == "localhost" {
+			return false
+		}
+		ip := net.ParseIP(u.Hostname())
+		return ip == nil || !ip.IsLoopback()
+	default:
+		return false
+	}
+}
+
+// proxyOptions converts network settings into engine proxy options
+func proxyOptions(network config.NetworkConfig) engine.ProxyOptions {
+	switch network.ProxyMode {
+	case config.ProxyNone:
+		return engine.ProxyOptions{Direct: true}
+	case config.ProxyManual:
+		return engine.ProxyOptions{URL: network.ProxyURL, NoProxy: network.NoProxy}
+	default:
+		return engine.ProxyOptions{}
+	}
+}
+
+// ollamaOptions converts Ollama and network settings into engine options
+func ollamaOptions(snapshot *config.Config) []engine.OllamaOption {
+	cfg := snapshot.Engine.Ollama
+	return []engine.OllamaOption{
+		engine.WithOllamaHost(cfg.Host),
+		engine.WithOllamaStartupTimeout(time.Duration(cfg.Startup) * time.Second),
+		engine.WithOllamaTimeout(time.Duration(cfg.Timeout) * time.Second),
+		engine.WithOllamaMaxDuration(time.Duration(cfg.MaxDuration) * time.Second),
+		engine.WithOllamaMaxChars(cfg.MaxInput),
+		engine.WithOllamaSampling(sampling(cfg.Sampling)),
+		engine.WithOllamaAuth(engine.HTTPAuth{
+			BearerToken: cfg.BearerToken,
+			Username:    cfg.Username,
+			Password:    cfg.Password,
+		}),
+		engine.WithOllamaTLS(engine.TLSOptions{
+			CACertFile:         cfg.CACertFile,
+			InsecureSkipVerify: cfg.InsecureSkipVerify,
+		}),
+		engine.WithOllamaProxy(proxyOptions(snapshot.Network)),
+		engine.WithOllamaOptions(cfg.Options),
+	}
+}
+
+// sampling converts sampling settings into engine sampling parameters
+func sampling(cfg config.SamplingConfig) engine.SamplingConfig {
+	return engine.SamplingConfig{
+		Temperature:   cfg.Temperature,
+		TopP:          cfg.TopP,
+		TopK:          cfg.TopK,
+		RepeatPenalty: cfg.RepeatPenalty,
+		MaxTokens:     cfg.MaxTokens,
+	}
+}
+
+// concurrency returns how many translations run at once across the UI,
+// clipboard, API and jobs: the engine's limit, capped by the global
+// translation.maxConcurrent setting
+func concurrency(snapshot *config.Config) int {
+	n := snapshot.Engine.Concurrency()
+	if limit := snapshot.Translation.MaxConcurrent; limit > 0 && limit < n {
+		return limit
+	}
+	return n
+}
+
+// terminalEngine creates the selected terminal agent
+func terminalEngine(cfg config.TerminalAgentConfig, logger *slog.Logger) engine.Engine {
+	var agent config.TerminalAgentOption
+	var engineType engine.TerminalEngineType
+	switch cfg.Selected {
+	case config.AgentGeminiCLI:
+		agent, engineType = cfg.GeminiCLI, engine.TerminalGeminiCLI
+	case config.AgentCodex:
+		agent, engineType = cfg.Codex, engine.TerminalCodex
+	default:
+		agent, engineType = cfg.ClaudeCode, engine.TerminalClaudeCode
+	}
+
+	opts := []engine.TerminalEngineOption{engine.WithTerminalLogger(logger), engine.WithTerminalMaxChars(agent.MaxInput)}
+	if agent.Executable != "" {
+		opts = append(opts, engine.WithTerminalCommand(agent.Executable))
+	}
+	if agent.Startup > 0 {
+		opts = append(opts, engine.WithTerminalStartupTimeout(time.Duration(agent.Startup)*time.Second))
+	}
+	if agent.Timeout > 0 {
+		opts = append(opts, engine.WithTerminalTimeout(time.Duration(agent.Timeout)*time.Second))
+	}
+	if agent.MaxDuration > 0 {
+		opts = append(opts, engine.WithTerminalMaxDuration(time.Duration(agent.MaxDuration)*time.Second))
+	}
+	if len(agent.Args) > 0 {
+		opts = append(opts, engine.WithTerminalExtraArgs(agent.Args))
+	}
+	return engine.NewTerminalEngine(engineType, opts...)
+}
+
+// buildEngine creates the configured engine without any wrappers, logging
+// to logger (the default logger if nil)
+func buildEngine(snapshot *config.Config, logger *slog.Logger) (engine.Engine, error) {
+	return buildEngineWith(snapshot, snapshot.Engine, logger)
+}
+
+// buildEngineWith creates the engine of cfg, which replaces the engine
+// settings of snapshot
+func buildEngineWith(snapshot *config.Config, cfg config.EngineConfig, logger *slog.Logger) (engine.Engine, error) {
+	switch cfg.Type {
+	case config.EngineTerminalAgent:
+		return terminalEngine(cfg.TerminalAgent, logger), nil
+	case config.EngineOllama:
+		if cfg.Ollama.Model == "" {
+			return nil, errors.New("no Ollama model is configured")
+		}
+		return engine.NewOllama(cfg.Ollama.Model, append(ollamaOptions(snapshot), engine.WithOllamaLogger(logger))...), nil
+	case config.EngineInternal:
+		if cfg.Internal.ModelPath == "" {
+			return nil, errors.New("no model file is configured for the internal engine")
+		}
+		opts := []engine.YzmaOption{
+			engine.WithYzmaSampling(sampling(cfg.Internal.Sampling)),
+			engine.WithYzmaTimeouts(time.Duration(cfg.Internal.Timeout)*time.Second, time.Duration(cfg.Internal.MaxDuration)*time.Second),
+			engine.WithYzmaStartupTimeout(time.Duration(cfg.Internal.Startup) * time.Second),
+			engine.WithYzmaMaxChars(cfg.Internal.MaxInput),
+			engine.WithYzmaLogger(logger),
+		}
+		if cfg.Internal.ContextSize > 0 {
+			opts = append(opts, engine.WithYzmaContextSize(cfg.Internal.ContextSize))
+		}
+		return engine.NewYzma(cfg.Internal.ModelPath, opts...), nil
+	case config.EngineSmart:
+		shortCfg, err := cfg.SmartEngine(false)
+		if err != nil {
+			return nil, err
+		}
+		longCfg, err := cfg.SmartEngine(true)
+		if err != nil {
+			return nil, err
+		}
+		short, err := buildEngineWith(snapshot, shortCfg, logger)
+		if err != nil {
+			return nil, fmt.Errorf("smart engine for short texts: %w", err)
+		}
+		long, err := buildEngineWith(snapshot, longCfg, logger)
+		if err != nil {
+			short.Close()
+			return nil, fmt.Errorf("smart engine for long texts: %w", err)
+		}
+		return engine.NewSizeRouter(short, long, cfg.Smart.Threshold), nil
+	default:
+		return nil, fmt.Errorf("engine %q is not supported", cfg.Type)
+	}
+}
+
+// engineFactory builds the configured engine and reuses it until the settings it
+// was built from change, so e.g. a loaded local model stays in memory
+type engineFactory struct {
+	logger *slog.Logger // passed to the engines, the default logger if nil
+	mu     sync.Mutex
+	key    string // engine and network settings the cached engine was built from
+	engine engine.Engine
+}
+
+// get returns the engine for the configuration, rebuilding it if the settings changed
+func (f *engineFactory) get(snapshot *config.Config) (engine.Engine, error) {
+	key, err := json.Marshal(struct {
+		Engine  config.EngineConfig
+		Network config.NetworkConfig
+	}{snapshot.Engine, snapshot.Network})
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.engine != nil && f.key == string(key) {
+		return f.engine, nil
+	}
+	e, err := buildEngine(snapshot, f.logger)
+	if err != nil {
+		return nil, err
+	}
+	if old := f.engine; old != nil {
+		// Requests may still be using the old engine; Close waits for them where needed
+		go func() {
+			if err := old.Close(); err != nil {
+				slog.Warn("failed to close engine", "engine", old.Name(), "error", err)
+			}
+		}()
+	}
+	slog.Info("engine created", "engine", e.Name())
+	f.key, f.engine = string(key), e
+	return e, nil
+}
+
+// close releases the cached engine
+func (f *engineFactory) close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.engine == nil {
+		return nil
+	}
+	err := f.engine.Close()
+	f.key, f.engine = "", nil
+	return err
+}
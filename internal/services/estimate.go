@@ -0,0 +1,50 @@
+package services
+
+import (
+	"context"
+
+	"github.com/ironpark/tons/internal/engine"
+	"github.com/ironpark/tons/internal/tokens"
+)
+
+// TokenEstimate is the size of a translation request before it is sent
+type TokenEstimate struct {
+	Engine   string `json:"engine"`
+	Tokens   int    `json:"tokens"`   // prompt tokens, including the template and system prompt
+	Exact    bool   `json:"exact"`    // counted with the model's tokenizer rather than estimated
+	Limit    int    `json:"limit"`    // source text tokens that fit in one request, 0 if unknown
+	Requests int    `json:"requests"` // requests the text is split into
+}
+
+// EstimateTokens returns the prompt size of a translation with the current
+// settings and how many requests it will take, so the UI can show it before
+// the text is sent. The internal engine counts with its tokenizer, loading
+// the model if needed; other engines are estimated.
+func (ts *TranslateService) EstimateTokens(req engine.Request) (TokenEstimate, error) {
+	snapshot := ts.cfg.Snapshot()
+	req = ts.applyDefaults(snapshot, req)
+	prompt, err := req.RenderPrompt()
+	if err != nil {
+		return TokenEstimate{}, err
+	}
+	e, err := ts.engines.get(snapshot)
+	if err != nil {
+		return TokenEstimate{}, err
+	}
+
+	ctx := context.Background()
+	estimate := TokenEstimate{Engine: e.Name(), Requests: 1}
+	estimate.Tokens, estimate.Exact = tokens.Count(ctx, e, prompt)
+	if req.SystemPrompt != "" {
+		n, _ := tokens.Count(ctx, e, req.SystemPrompt)
+		estimate.Tokens += n
+	}
+	if limiter, ok := e.(engine.TokenLimiter); ok {
+		estimate.Limit = limiter.MaxInputTokens(ctx)
+	}
+	if estimate.Limit > 0 {
+		text, _ := tokens.Count(ctx, e, req.Text)
+		estimate.Requests = max((text+estimate.Limit-1)/estimate.Limit, 1)
+	}
+	return estimate, nil
+}
@@ -0,0 +1,202 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/ironpark/tons/internal/engine"
+	"github.com/ironpark/tons/internal/langdetect"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// documentTranslator translates the contents of a file format. path
+// identifies the file's segments in the translation memory.
+type documentTranslator func(ctx context.Context, ts *TranslateService, path string, data []byte, sourceLang, targetLang string) ([]byte, error)
+
+// documentFormats maps file extensions to their translators
+var documentFormats = map[string]documentTranslator{
+	".docx":      translateDOCX,
+	".md":        translateMarkdown,
+	".markdown":  translateMarkdown,
+	".po":        translatePO,
+	".pot":       translatePO,
+	".xlf":       translateXLIFF,
+	".xliff":     translateXLIFF,
+	".xml":       translateAndroid,
+	".strings":   translateAppleStrings,
+	".xcstrings": translateStringCatalog,
+}
+
+// SupportedExtensions returns the file extensions that can be translated, sorted
+func SupportedExtensions() []string {
+	exts := make([]string, 0, len(documentFormats))
+	for ext := range documentFormats {
+		exts = append(exts, ext)
+	}
+	slices.Sort(exts)
+	return exts
+}
+
+type FileService struct {
+	translate *TranslateService
+	notify    *NotificationService
+	app       *application.App
+}
+
+func NewFileService(translate *TranslateService, notify *NotificationService) *FileService {
+	return &FileService{
+		translate: translate,
+		notify:    notify,
+	}
+}
+
+// PickMarkdownFile opens a file picker for Markdown files and returns the chosen path
+func (fs *FileService) PickMarkdownFile() (string, error) {
+	return fs.app.Dialog.OpenFile().
+		SetTitle("Select a Markdown file").
+		CanChooseFiles(true).
+		AddFilter("Markdown", "*.md;*.markdown").
+		PromptForSingleSelection()
+}
+
+// PickDocumentFile opens a file picker for any supported file format
+func (fs *FileService) PickDocumentFile() (string, error) {
+	patterns := make([]string, 0, len(documentFormats))
+	for _, ext := range SupportedExtensions() {
+		patterns = append(patterns, "*"+ext)
+	}
+	return fs.app.Dialog.OpenFile().
+		SetTitle("Select a file to translate").
+		CanChooseFiles(true).
+		AddFilter("Documents", strings.Join(patterns, ";")).
+		PromptForSingleSelection()
+}
+
+// TranslateMarkdownFile translates the prose of a Markdown file, keeping code,
+// links, tables and front matter intact, and writes it next to the original
+// (README.md becomes README.ko.md). Returns the output path.
+func (fs *FileService) TranslateMarkdownFile(path, sourceLang, targetLang string) (string, error) {
+	return fs.TranslateFile(path, sourceLang, targetLang)
+}
+
+// TranslateFile translates a file of any supported format and writes the
+// result next to the original. Returns the output path.
+func (fs *FileService) TranslateFile(path, sourceLang, targetLang string) (string, error) {
+	ctx := engine.WithProgress(context.Background(), func(p engine.Progress) {
+		fs.app.Event.Emit("file:progress", newFileTranslationProgress("", path, p))
+	})
+	outPath, err := translateFile(ctx, fs.translate, path, sourceLang, targetLang)
+	if err != nil {
+		fs.notify.notify("Translation failed", filepath.Base(path)+": "+err.Error())
+		return "", err
+	}
+	fs.notify.notify("Translation finished", filepath.Base(outPath))
+	return outPath, nil
+}
+
+// FileTranslationProgress is the payload of "file:progress" events, sent as
+// the chunks or segment batches of a file are translated
+type FileTranslationProgress struct {
+	JobID    string          `json:"jobId,omitempty"` // set for files of batch jobs
+	Path     string          `json:"path"`
+	Progress engine.Progress `json:"progress"`
+	Percent  float64         `json:"percent"`
+}
+
+func newFileTranslationProgress(jobID, path string, p engine.Progress) FileTranslationProgress {
+	return FileTranslationProgress{JobID: jobID, Path: path, Progress: p, Percent: p.Percent()}
+}
+
+// translateFile translates a file and writes it to its TranslatedPath.
+// Progress is reported to the callback of ctx, see engine.WithProgress.
+func translateFile(ctx context.Context, ts *TranslateService, path, sourceLang, targetLang string) (string, error) {
+	translated, err := TranslateDocument(ctx, ts, path, sourceLang, targetLang)
+	if err != nil {
+		return "", err
+	}
+	outPath := TranslatedPath(path, targetLang)
+	if err := os.MkdirAll(filepath.Dir(outPath), 0755); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(outPath, translated, 0644); err != nil {
+		return "", err
+	}
+	return outPath, nil
+}
+
+// TranslateDocument reads the file at path and returns its translation,
+// choosing the format by file extension
+func TranslateDocument(ctx context.Context, ts *TranslateService, path, sourceLang, targetLang string) ([]byte, error) {
+	translate, ok := documentFormats[strings.ToLower(filepath.Ext(path))]
+	if !ok {
+		return nil, fmt.Errorf("unsupported file type %q", filepath.Ext(path))
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	translated, err := translate(ctx, ts, path, data, sourceLang, targetLang)
+	if err != nil {
+		return nil, fmt.Errorf("translating %s: %w", filepath.Base(path), err)
+	}
+	return translated, nil
+}
+
+// translateMarkdown translates prose only, keeping Markdown structure
+func translateMarkdown(ctx context.Context, ts *TranslateService, path string, data []byte, sourceLang, targetLang string) ([]byte, error) {
+	translated, err := ts.translateText(ctx, engine.PriorityBackground, sourceLang, targetLang, string(data), engine.FormatMarkdown)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(translated, "\n") && strings.HasSuffix(string(data), "\n") {
+		translated += "\n"
+	}
+	return []byte(translated), nil
+}
+
+// TranslatedPath returns the output path for a translation of path, inserting
+// the target language before the extension. Templates become catalogs
+// (messages.pot becomes messages.ko.po), mobile resources go to the localized
+// resource directory (values/strings.xml becomes values-ko/strings.xml,
+// en.lproj/Localizable.strings becomes ko.lproj/Localizable.strings), and
+// string catalogs, which hold every language, are updated in place. Go
+// files get a leading underscore so they don't join the package.
+func TranslatedPath(path, targetLang string) string {
+	lang := langdetect.Code(targetLang)
+	if lang == langdetect.Unknown {
+		lang = strings.ToLower(strings.ReplaceAll(targetLang, " ", "-"))
+	}
+	ext := filepath.Ext(path)
+	dir := filepath.Dir(path)
+	switch {
+	case strings.EqualFold(ext, ".xcstrings"):
+		return path
+	case strings.EqualFold(ext, ".xml") && filepath.Base(dir) == "values":
+		return filepath.Join(filepath.Dir(dir), "values-"+lang, filepath.Base(path))
+	case strings.HasSuffix(filepath.Base(dir), ".lproj"):
+		return filepath.Join(filepath.Dir(dir), lang+".lproj", filepath.Base(path))
+	}
+	outExt := ext
+	if ext == ".go" {
+		// Keep the translated copy out of the package; the go tool ignores files starting with _
+		return filepath.Join(dir, "_"+strings.TrimSuffix(filepath.Base(path), ext)+"."+lang+ext)
+	}
+	if strings.EqualFold(ext, ".pot") {
+		outExt = ".po"
+	}
+	return strings.TrimSuffix(path, ext) + "." + lang + outExt
+}
+
+// ServiceStartup is called when the service starts
+func (fs *FileService) ServiceStartup(ctx context.Context, options application.ServiceOptions) error {
+	fs.app = application.Get()
+	return nil
+}
+
+func (fs *FileService) ServiceShutdown() error {
+	return nil
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/ironpark/tons/internal/bilingual"
+	"github.com/ironpark/tons/internal/history"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+type HistoryService struct {
+	store   *history.Store
+	started time.Time // entries since then are the current session's
+	app     *application.App
+}
+
+func NewHistoryService(store *history.Store) *HistoryService {
+	return &HistoryService{
+		store:   store,
+		started: time.Now(),
+	}
+}
+
+// GetHistory returns up to limit entries, newest first (limit <= 0 returns all)
+func (hs *HistoryService) GetHistory(limit int) []history.Entry {
+	return hs.store.List(limit)
+}
+
+func (hs *HistoryService) DeleteHistoryEntry(id string) error {
+	return hs.store.Delete(id)
+}
+
+func (hs *HistoryService) ClearHistory() error {
+	return hs.store.Clear()
+}
+
+// SetHistoryFavorite marks or unmarks an entry as a favorite
+func (hs *HistoryService) SetHistoryFavorite(id string, favorite bool) error {
+	return hs.store.SetFavorite(id, favorite)
+}
+
+// ExportHistory returns the history as "json" or "csv", optionally favorites only
+func (hs *HistoryService) ExportHistory(format string, favoritesOnly bool) (string, error) {
+	data, err := hs.store.Export(format, favoritesOnly)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// ImportHistory adds entries from an exported "json" or "csv" history.
+// Returns the number of added entries.
+func (hs *HistoryService) ImportHistory(data, format string) (int, error) {
+	return hs.store.Import([]byte(data), format)
+}
+
+// PickBilingualExportFile opens a save dialog for a bilingual export and
+// returns the chosen path; the extension selects the format
+func (hs *HistoryService) PickBilingualExportFile() (string, error) {
+	return hs.app.Dialog.SaveFile().
+		SetMessage("Export translations").
+		SetFilename("translations.docx").
+		AddFilter("Word document", "*.docx").
+		AddFilter("HTML", "*.html").
+		AddFilter("Markdown", "*.md").
+		PromptForSingleSelection()
+}
+
+// ExportBilingual writes the given history entries, or the translations of
+// the current session if ids is empty, with their original texts to path.
+// The format follows the extension (.md, .html or .docx) and the layout is
+// "side-by-side" or "interleaved".
+func (hs *HistoryService) ExportBilingual(path string, ids []string, layout string) error {
+	format := bilingual.FormatForPath(path)
+	if format == "" {
+		return fmt.Errorf("unsupported export file %q: use .md, .html or .docx", path)
+	}
+	entries, err := hs.bilingualEntries(ids)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return errors.New("no translations to export")
+	}
+	pairs := make([]bilingual.Pair, len(entries))
+	for i, e := range entries {
+		pairs[i] = bilingual.Pair{SourceLang: e.SourceLang, TargetLang: e.TargetLang, Text: e.Text, Translation: e.Translation}
+	}
+	data, err := bilingual.Render(format, layout, "Translations", pairs)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// bilingualEntries returns the entries with the given IDs in that order, or
+// the entries of the current session, oldest first
+func (hs *HistoryService) bilingualEntries(ids []string) ([]history.Entry, error) {
+	if len(ids) == 0 {
+		var entries []history.Entry
+		for _, e := range hs.store.List(0) {
+			if !e.CreatedAt.Before(hs.started) {
+				entries = append(entries, e)
+			}
+		}
+		slices.Reverse(entries)
+		return entries, nil
+	}
+	entries := make([]history.Entry, 0, len(ids))
+	for _, id := range ids {
+		e, ok := hs.store.Get(id)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", history.ErrNotFound, id)
+		}
+		entries = append(entries, e)
+	}
+	return entries, nil
+}
+
+// ServiceStartup is called when the service starts
+func (hs *HistoryService) ServiceStartup(ctx context.Context, options application.ServiceOptions) error {
+	hs.app = application.Get()
+	return nil
+}
+
+func (hs *HistoryService) ServiceShutdown() error {
+	return nil
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sync"
+
+	"github.com/ironpark/tons/internal/config"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// HotkeyService binds the hotkey chords from the settings to their actions:
+// translating the clipboard, capturing a screen region, toggling the mini
+// window and swapping the languages of the main pane. The chords work while
+// a tons window has focus; system-wide shortcuts can run "tons <command>".
+type HotkeyService struct {
+	cfg       *config.Config
+	clipboard *ClipboardService
+	capture   *CaptureService
+	window    *WindowService
+	translate *TranslateService
+	app       *application.App
+
+	mu    sync.Mutex
+	bound []config.HotkeyBinding // registered, with normalized chords
+}
+
+func NewHotkeyService(cfg *config.Config, clipboard *ClipboardService, capture *CaptureService, window *WindowService, translate *TranslateService) *HotkeyService {
+	return &HotkeyService{
+		cfg:       cfg,
+		clipboard: clipboard,
+		capture:   capture,
+		window:    window,
+		translate: translate,
+	}
+}
+
+// GetHotkeys returns the registered chords and their actions
+func (hk *HotkeyService) GetHotkeys() []config.HotkeyBinding {
+	hk.mu.Lock()
+	defer hk.mu.Unlock()
+	return slices.Clone(hk.bound)
+}
+
+// ReloadHotkeys replaces the registered chords with the current settings.
+// Invalid chords and chords used by an earlier action are skipped.
+func (hk *HotkeyService) ReloadHotkeys() {
+	hk.mu.Lock()
+	defer hk.mu.Unlock()
+
+	for _, b := range hk.bound {
+		hk.app.KeyBinding.Remove(b.Chord)
+	}
+	hk.bound = nil
+
+	used := make(map[string]bool)
+	for _, b := range hk.cfg.Snapshot().Hotkeys.Bindings() {
+		chord, err := config.NormalizeChord(b.Chord)
+		if err != nil || used[chord] {
+			slog.Warn("skipping hotkey", "action", b.Action, "chord", b.Chord, "error", err)
+			continue
+		}
+		used[chord] = true
+		action := b.Action
+		hk.app.KeyBinding.Add(chord, func(application.Window) {
+			// Capturing waits for the user; keep the UI thread free
+			go func() {
+				if err := hk.RunHotkeyAction(action); err != nil {
+					slog.Warn("hotkey action failed", "action", action, "error", err)
+				}
+			}()
+		})
+		hk.bound = append(hk.bound, config.HotkeyBinding{Action: action, Chord: chord})
+	}
+}
+
+// RunHotkeyAction runs the action of a hotkey
+func (hk *HotkeyService) RunHotkeyAction(action config.HotkeyAction) error {
+	switch action {
+	case config.HotkeyTranslateClipboard:
+		_, err := hk.clipboard.TranslateClipboardNow()
+		return err
+	case config.HotkeyCaptureScreen:
+		// Captured text goes into the overlay, in the languages of the selection popup
+		settings := hk.cfg.Snapshot().Selection
+		return hk.capture.CaptureAndTranslate(settings.SourceLang, settings.TargetLang)
+	case config.HotkeyToggleWindow:
+		hk.window.ToggleMiniWindow()
+		return nil
+	case config.HotkeySwapLanguages:
+		return hk.translate.SwapAndRetranslate(PaneMain)
+	default:
+		return fmt.Errorf("unknown hotkey action %q", action)
+	}
+}
+
+// ServiceStartup registers the hotkeys and reloads them when the hotkey
+// settings change
+func (hk *HotkeyService) ServiceStartup(ctx context.Context, options application.ServiceOptions) error {
+	hk.app = application.Get()
+	hk.ReloadHotkeys()
+	hk.app.Event.On("config:changed", func(e *application.CustomEvent) {
+		if section, _ := e.Data.(string); section == "hotkeys" {
+			hk.ReloadHotkeys()
+		}
+	})
+	return nil
+}
+
+func (hk *HotkeyService) ServiceShutdown() error {
+	return nil
+}
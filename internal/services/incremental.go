@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/ironpark/tons/internal/langdetect"
+	"github.com/ironpark/tons/internal/tm"
+)
+
+// segmentMemory remembers the translations of a file's identified segments
+// (catalog entries, resource keys, XLIFF units) in the translation memory.
+// A nil segmentMemory remembers nothing, so every segment is translated.
+type segmentMemory struct {
+	store *tm.Store
+	file  string
+	lang  string
+	reuse bool // false records translations without looking them up
+}
+
+// segmentMemory returns the memory for a file, or nil without a translation memory
+func (ts *TranslateService) segmentMemory(path, targetLang string) *segmentMemory {
+	if ts.memory == nil || path == "" {
+		return nil
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	lang := langdetect.Code(targetLang)
+	if lang == langdetect.Unknown {
+		lang = targetLang
+	}
+	return &segmentMemory{
+		store: ts.memory,
+		file:  path,
+		lang:  lang,
+		reuse: ts.cfg.Snapshot().Translation.Incremental,
+	}
+}
+
+// segment is an identified piece of a file
+type segment struct {
+	ID         string
+	Text       string
+	Translated bool // the file already has a translation
+}
+
+// translateChanged translates the segments that are new or whose source
+// changed since the last run. Existing translations of unchanged segments are
+// kept; untranslated segments the memory knows reuse the remembered
+// translation. Returns the translations to write by segment index.
+func (ts *TranslateService) translateChanged(ctx context.Context, mem *segmentMemory, sourceLang, targetLang string, segments []segment) (map[int]string, error) {
+	results, _, err := ts.translateChangedReview(ctx, mem, sourceLang, targetLang, segments)
+	return results, err
+}
+
+// translateChangedReview is translateChanged that also returns the indexes
+// of new translations rated below the translation.reviewBelow confidence,
+// for formats that can flag them for review
+func (ts *TranslateService) translateChangedReview(ctx context.Context, mem *segmentMemory, sourceLang, targetLang string, segments []segment) (results map[int]string, review map[int]bool, err error) {
+	results = make(map[int]string)
+	review = make(map[int]bool)
+	var pending []int
+	var texts []string
+	for i, s := range segments {
+		e, match := mem.lookup(s)
+		switch {
+		case match == tm.Changed:
+		case s.Translated:
+			continue
+		case match == tm.Unchanged:
+			results[i] = e.Translation
+			continue
+		}
+		pending = append(pending, i)
+		texts = append(texts, s.Text)
+	}
+	if len(texts) == 0 {
+		return results, review, nil
+	}
+
+	translated, confidence, err := ts.translateSegmentsConfidence(ctx, sourceLang, targetLang, texts)
+	if err != nil {
+		return nil, nil, err
+	}
+	threshold := ts.cfg.Snapshot().Translation.ReviewBelow
+	for n, i := range pending {
+		results[i] = translated[n]
+		if confidence[n] != nil && *confidence[n] < threshold {
+			review[i] = true
+		}
+		mem.put(segments[i], translated[n])
+	}
+	return results, review, mem.save()
+}
+
+// translateAll translates every segment of a file that is generated from its
+// source, reusing remembered translations of unchanged segments
+func (ts *TranslateService) translateAll(ctx context.Context, mem *segmentMemory, sourceLang, targetLang string, ids, texts []string) ([]string, error) {
+	segments := make([]segment, len(texts))
+	for i := range texts {
+		segments[i] = segment{ID: ids[i], Text: texts[i]}
+	}
+	results, err := ts.translateChanged(ctx, mem, sourceLang, targetLang, segments)
+	if err != nil {
+		return nil, err
+	}
+	translated := make([]string, len(texts))
+	for i := range translated {
+		translated[i] = results[i]
+	}
+	return translated, nil
+}
+
+func (m *segmentMemory) lookup(s segment) (tm.Entry, tm.Match) {
+	if m == nil || !m.reuse {
+		return tm.Entry{}, tm.Missing
+	}
+	return m.store.Lookup(m.file+"#"+s.ID, m.lang, s.Text)
+}
+
+func (m *segmentMemory) put(s segment, translation string) {
+	if m != nil {
+		m.store.Put(m.file+"#"+s.ID, m.lang, s.Text, translation)
+	}
+}
+
+func (m *segmentMemory) save() error {
+	if m == nil {
+		return nil
+	}
+	return m.store.Save()
+}
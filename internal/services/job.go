@@ -0,0 +1,372 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/ironpark/tons/internal/config"
+	"github.com/ironpark/tons/internal/engine"
+	"github.com/ironpark/tons/internal/jobs"
+	"github.com/ironpark/tons/internal/langdetect"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// FileProgress is emitted when a file of a job finishes
+type FileProgress struct {
+	JobID string    `json:"jobId"`
+	File  jobs.File `json:"file"`
+}
+
+// JobService translates folders of files in the background. Jobs are saved
+// after every file, so jobs interrupted by quitting resume on the next start.
+type JobService struct {
+	translate *TranslateService
+	store     *jobs.Store
+	notify    *NotificationService
+	app       *application.App
+
+	sem     chan struct{} // bounds the files translated at once across all jobs
+	mu      sync.Mutex
+	runs    map[string]*jobRun
+	closing bool
+	wg      sync.WaitGroup
+	// quit is cancelled when the app quits, stopping the files in progress;
+	// a cancelled job lets them finish
+	quit     context.Context
+	quitting context.CancelFunc
+}
+
+// jobRun is a run of a job, told apart from a later run of the same job
+type jobRun struct {
+	cancel context.CancelFunc
+}
+
+func NewJobService(cfg *config.Config, translate *TranslateService, store *jobs.Store, notify *NotificationService) *JobService {
+	quit, quitting := context.WithCancel(context.Background())
+	return &JobService{
+		translate: translate,
+		store:     store,
+		notify:    notify,
+		sem:       make(chan struct{}, max(1, cfg.Snapshot().Translation.BatchParallel)),
+		runs:      make(map[string]*jobRun),
+		quit:      quit,
+		quitting:  quitting,
+	}
+}
+
+// StartJob queues the files in folder matching pattern and starts translating
+// them. A pattern without a slash matches file names in any subfolder
+// ("*.md"), otherwise it matches paths relative to folder ("docs/*.md").
+// format optionally limits the files to one extension ("po" or ".po").
+func (js *JobService) StartJob(folder, pattern, format, sourceLang, targetLang string) (jobs.Job, error) {
+	paths, err := findFiles(folder, pattern, format, targetLang)
+	if err != nil {
+		return jobs.Job{}, err
+	}
+	if len(paths) == 0 {
+		return jobs.Job{}, fmt.Errorf("no files to translate in %s", folder)
+	}
+	files := make([]jobs.File, len(paths))
+	for i, p := range paths {
+		files[i] = jobs.File{Path: p, Status: jobs.StatusPending}
+	}
+	job, err := js.store.Add(jobs.Job{
+		Folder:     folder,
+		Pattern:    pattern,
+		Format:     format,
+		SourceLang: sourceLang,
+		TargetLang: targetLang,
+		Status:     jobs.StatusPending,
+		Files:      files,
+	})
+	if err != nil {
+		return jobs.Job{}, err
+	}
+	js.run(job.ID)
+	return job, nil
+}
+
+// ListJobs returns all jobs, newest first
+func (js *JobService) ListJobs() []jobs.Job {
+	return js.store.List()
+}
+
+// GetJob returns the job with the given ID
+func (js *JobService) GetJob(id string) (jobs.Job, error) {
+	job, ok := js.store.Get(id)
+	if !ok {
+		return jobs.Job{}, jobs.ErrNotFound
+	}
+	return job, nil
+}
+
+// CancelJob stops a job after the files in progress finish
+func (js *JobService) CancelJob(id string) error {
+	job, err := js.store.Update(id, func(j *jobs.Job) {
+		if !j.Finished() {
+			j.Status = jobs.StatusCancelled
+		}
+	})
+	if err != nil {
+		return err
+	}
+	js.stop(id)
+	js.emitProgress(job)
+	return nil
+}
+
+// ResumeJob restarts a cancelled or failed job, retrying the files that failed
+func (js *JobService) ResumeJob(id string) error {
+	js.mu.Lock()
+	_, running := js.runs[id]
+	js.mu.Unlock()
+	if running {
+		return nil
+	}
+	_, err := js.store.Update(id, func(j *jobs.Job) {
+		j.Status = jobs.StatusPending
+		for i := range j.Files {
+			if j.Files[i].Status == jobs.StatusFailed {
+				j.Files[i].Status = jobs.StatusPending
+				j.Files[i].Error = ""
+			}
+		}
+	})
+	if err != nil {
+		return err
+	}
+	js.run(id)
+	return nil
+}
+
+// DeleteJob cancels a job and forgets it. Translated files are kept.
+func (js *JobService) DeleteJob(id string) error {
+	js.stop(id)
+	return js.store.Delete(id)
+}
+
+// run translates the pending files of a job in the background
+func (js *JobService) run(id string) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	if js.closing {
+		return
+	}
+	if _, running := js.runs[id]; running {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &jobRun{cancel: cancel}
+	js.runs[id] = r
+	js.wg.Add(1)
+
+	go func() {
+		defer js.wg.Done()
+		defer js.finish(id, r)
+
+		job, err := js.store.Update(id, func(j *jobs.Job) { j.Status = jobs.StatusRunning })
+		if err != nil {
+			return
+		}
+		js.emitProgress(job)
+
+		var wg sync.WaitGroup
+		for i, file := range job.Files {
+			if file.Status == jobs.StatusDone {
+				continue
+			}
+			acquired := false
+			select {
+			case js.sem <- struct{}{}:
+				acquired = true
+			case <-ctx.Done():
+			}
+			if ctx.Err() != nil {
+				// Both cases may be ready at once; don't keep the slot
+				if acquired {
+					<-js.sem
+				}
+				break
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-js.sem }()
+				js.translateFile(job, i)
+			}()
+		}
+		wg.Wait()
+
+		job, err = js.store.Update(id, func(j *jobs.Job) {
+			// Cancelled jobs keep their status; jobs stopped by quitting stay running to resume
+			if ctx.Err() != nil {
+				return
+			}
+			j.Status = jobs.StatusDone
+			if j.Progress().Failed > 0 {
+				j.Status = jobs.StatusFailed
+			}
+		})
+		if err == nil {
+			js.emitProgress(job)
+			js.notifyFinished(job)
+		}
+	}()
+}
+
+// translateFile translates the i-th file of a job and records the result
+func (js *JobService) translateFile(job jobs.Job, i int) {
+	file := job.Files[i]
+	ctx := engine.WithProgress(js.quit, func(p engine.Progress) {
+		if js.app != nil {
+			js.app.Event.Emit("file:progress", newFileTranslationProgress(job.ID, file.Path, p))
+		}
+	})
+	outPath, err := translateFile(ctx, js.translate, file.Path, job.SourceLang, job.TargetLang)
+	switch {
+	case err != nil && js.quit.Err() != nil:
+		// Stopped by quitting; translated again on the next start
+		file.Status = jobs.StatusPending
+		file.Error = ""
+	case err != nil:
+		file.Status = jobs.StatusFailed
+		file.Error = err.Error()
+	default:
+		file.Status = jobs.StatusDone
+		file.Output = outPath
+		file.Error = ""
+	}
+
+	updated, err := js.store.Update(job.ID, func(j *jobs.Job) {
+		if i < len(j.Files) {
+			j.Files[i] = file
+		}
+	})
+	if err != nil {
+		return
+	}
+	if js.app != nil {
+		js.app.Event.Emit("job:file", FileProgress{JobID: job.ID, File: file})
+	}
+	js.emitProgress(updated)
+}
+
+// stop cancels a running job
+func (js *JobService) stop(id string) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	if r, ok := js.runs[id]; ok {
+		r.cancel()
+		delete(js.runs, id)
+	}
+}
+
+// finish ends run r of a job. A later run of the job, started while r
+// finished the files in progress, is left alone.
+func (js *JobService) finish(id string, r *jobRun) {
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	r.cancel()
+	if js.runs[id] == r {
+		delete(js.runs, id)
+	}
+}
+
+// notifyFinished notifies that a job finished, unless it was cancelled or stopped
+func (js *JobService) notifyFinished(job jobs.Job) {
+	p := job.Progress()
+	switch p.Status {
+	case jobs.StatusDone:
+		js.notify.notify("Translation finished", fmt.Sprintf("%d files in %s", p.Done, filepath.Base(job.Folder)))
+	case jobs.StatusFailed:
+		js.notify.notify("Translation failed", fmt.Sprintf("%d of %d files in %s failed", p.Failed, p.Total, filepath.Base(job.Folder)))
+	}
+}
+
+func (js *JobService) emitProgress(job jobs.Job) {
+	if js.app != nil {
+		js.app.Event.Emit("job:progress", job.Progress())
+	}
+}
+
+// findFiles returns the supported files under folder matching pattern and format,
+// leaving out earlier translations into targetLang
+func findFiles(folder, pattern, format, targetLang string) ([]string, error) {
+	if pattern == "" {
+		pattern = "*"
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	if format != "" && !strings.HasPrefix(format, ".") {
+		format = "." + format
+	}
+	lang := langdetect.Code(targetLang)
+
+	var files []string
+	err := filepath.WalkDir(folder, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if p != folder && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		ext := strings.ToLower(filepath.Ext(p))
+		if _, ok := documentFormats[ext]; !ok || (format != "" && !strings.EqualFold(ext, format)) {
+			return nil
+		}
+		if lang != langdetect.Unknown && strings.HasSuffix(strings.TrimSuffix(d.Name(), filepath.Ext(p)), "."+lang) {
+			return nil
+		}
+		name := d.Name()
+		if strings.Contains(pattern, "/") {
+			rel, err := filepath.Rel(folder, p)
+			if err != nil {
+				return err
+			}
+			name = filepath.ToSlash(rel)
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			files = append(files, p)
+		}
+		return nil
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("folder not found: %s", folder)
+	}
+	return files, err
+}
+
+// ServiceStartup is called when the service starts; it resumes interrupted jobs
+func (js *JobService) ServiceStartup(ctx context.Context, options application.ServiceOptions) error {
+	js.app = application.Get()
+	for _, job := range js.store.List() {
+		if !job.Finished() {
+			js.run(job.ID)
+		}
+	}
+	return nil
+}
+
+// ServiceShutdown stops running jobs and the files in progress, leaving
+// them to resume on the next start
+func (js *JobService) ServiceShutdown() error {
+	js.mu.Lock()
+	js.closing = true
+	for _, r := range js.runs {
+		r.cancel()
+	}
+	js.mu.Unlock()
+	js.quitting()
+	js.wg.Wait()
+	return nil
+}
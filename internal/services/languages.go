@@ -0,0 +1,57 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/ironpark/tons/internal/config"
+	"github.com/ironpark/tons/internal/engine"
+)
+
+// ErrUnsupportedLanguage is returned for language pairs the engine can't
+// translate while translation.blockUnsupported is set
+var ErrUnsupportedLanguage = errors.New("unsupported language")
+
+// languageModel returns the name the capability tables rate the configured
+// engine by: the agent, the Ollama model or the internal model's file name
+func languageModel(snapshot *config.Config) string {
+	return engineModel(snapshot.Engine)
+}
+
+// engineModel returns the name of the model of cfg's engine; the smart
+// engine is rated by its engine for short, interactive texts
+func engineModel(cfg config.EngineConfig) string {
+	switch cfg.Type {
+	case config.EngineSmart:
+		short, err := cfg.SmartEngine(false)
+		if err != nil {
+			return string(cfg.Type)
+		}
+		return engineModel(short)
+	case config.EngineTerminalAgent:
+		return string(cfg.TerminalAgent.Selected)
+	case config.EngineOllama:
+		return "ollama:" + cfg.Ollama.Model
+	case config.EngineInternal:
+		return filepath.Base(cfg.Internal.ModelPath)
+	default:
+		return string(cfg.Type)
+	}
+}
+
+// checkLanguages rates the language pair of req for the configured engine,
+// failing for unsupported pairs if the settings say so
+func checkLanguages(snapshot *config.Config, req engine.Request) (engine.LanguageSupport, error) {
+	support := engine.CheckLanguages(languageModel(snapshot), req.SourceLang, req.TargetLang)
+	if support.Support == engine.SupportUnsupported && snapshot.Translation.BlockUnsupported {
+		return support, fmt.Errorf("%w: %s", ErrUnsupportedLanguage, support.Message)
+	}
+	return support, nil
+}
+
+// CheckLanguageSupport rates a language pair for the configured engine, so
+// the UI can warn before the text is translated
+func (ts *TranslateService) CheckLanguageSupport(sourceLang, targetLang string) engine.LanguageSupport {
+	return engine.CheckLanguages(languageModel(ts.cfg.Snapshot()), sourceLang, targetLang)
+}
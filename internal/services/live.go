@@ -0,0 +1,106 @@
+package services
+
+import (
+	"strings"
+	"time"
+
+	"github.com/ironpark/tons/internal/engine"
+)
+
+// liveInput is the translate-as-you-type input of a pane
+type liveInput struct {
+	timer *time.Timer
+	seq   int            // counts inputs, so a timer that fired late sees it was replaced
+	req   engine.Request // latest input, translated when the timer fires
+	last  string         // normalized text of the last translation started
+}
+
+// TranslateLive translates text as it is typed: each call replaces the
+// pending input of the pane, and the translation starts once no new input
+// arrived for the configured delay. Input that differs from the last
+// translated text by less than the configured number of characters, or only
+// in whitespace, is not translated again; other input cancels a translation
+// still streaming for older text right away.
+func (ts *TranslateService) TranslateLive(req engine.Request) {
+	if req.Pane == "" {
+		req.Pane = PaneMain
+	}
+	settings := ts.cfg.Snapshot().Translation
+	delay := time.Duration(settings.LiveDelayMs) * time.Millisecond
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	in, ok := ts.live[req.Pane]
+	if !ok {
+		in = &liveInput{}
+		ts.live[req.Pane] = in
+	}
+	if in.timer != nil {
+		in.timer.Stop()
+	}
+	in.seq++
+	text := normalizeLive(req.Text)
+	if text == "" || liveChanged(in.last, text, settings.LiveMinChange) {
+		if r, ok := ts.running[req.Pane]; ok {
+			r.cancel()
+			delete(ts.running, req.Pane)
+		}
+	}
+	if text == "" {
+		in.last = ""
+		return
+	}
+	in.req = req
+	pane, seq := req.Pane, in.seq
+	in.timer = time.AfterFunc(delay, func() { ts.fireLive(pane, seq) })
+}
+
+// fireLive translates the pending input of a pane after the typing pause
+func (ts *TranslateService) fireLive(pane string, seq int) {
+	minChange := ts.cfg.Snapshot().Translation.LiveMinChange
+
+	ts.mu.Lock()
+	in := ts.live[pane]
+	if in.seq != seq {
+		ts.mu.Unlock()
+		return
+	}
+	req, text := in.req, normalizeLive(in.req.Text)
+	if !liveChanged(in.last, text, minChange) {
+		ts.mu.Unlock()
+		return
+	}
+	in.last = text
+	ts.mu.Unlock()
+
+	// translate reports failures as "translate:error" events
+	ts.TranslateRequest(req)
+}
+
+// normalizeLive collapses whitespace so edits that only add spaces or line
+// breaks compare equal
+func normalizeLive(text string) string {
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// liveChanged reports whether text changed enough since the last translated
+// text to be translated again
+func liveChanged(last, text string, minChange int) bool {
+	return text != last && (last == "" || changedRunes(last, text) >= minChange)
+}
+
+// changedRunes returns how many characters differ between a and b, ignoring
+// their common prefix and suffix
+func changedRunes(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prefix := 0
+	for prefix < len(ra) && prefix < len(rb) && ra[prefix] == rb[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(ra)-prefix && suffix < len(rb)-prefix && ra[len(ra)-1-suffix] == rb[len(rb)-1-suffix] {
+		suffix++
+	}
+	return max(len(ra), len(rb)) - prefix - suffix
+}
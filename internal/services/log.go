@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ironpark/tons/internal/config"
+	"github.com/ironpark/tons/internal/logging"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// LogService owns the app's logger. It becomes the default slog logger when
+// created, so services and the engines they build log through it: to stderr
+// and, if enabled, to a rotating tons.log in the config directory, at the
+// configured level and with translated content redacted.
+type LogService struct {
+	cfg    *config.Config
+	app    *application.App
+	level  slog.LevelVar
+	redact atomic.Bool
+
+	mu   sync.Mutex
+	file *logging.RotatingFile // nil while file logging is off
+}
+
+// NewLogService creates the log service and installs its logger as the
+// default, so it should be created before the other services
+func NewLogService(cfg *config.Config) *LogService {
+	ls := &LogService{cfg: cfg}
+	handler := logging.NewHandler(logOutput{ls}, logging.Options{Level: &ls.level, Redact: &ls.redact})
+	slog.SetDefault(slog.New(handler))
+	ls.ReloadLogging()
+	return ls
+}
+
+// logOutput writes log records to stderr and the log file
+type logOutput struct {
+	ls *LogService
+}
+
+func (o logOutput) Write(p []byte) (int, error) {
+	o.ls.mu.Lock()
+	defer o.ls.mu.Unlock()
+
+	if o.ls.file != nil {
+		// A full disk shouldn't stop logging to stderr
+		o.ls.file.Write(p)
+	}
+	return os.Stderr.Write(p)
+}
+
+// GetLogPath returns the path of the log file
+func (ls *LogService) GetLogPath() string {
+	return filepath.Join(config.Dir(), "tons.log")
+}
+
+// GetRecentLogs returns up to the last lines of the log file, e.g. to
+// attach to a bug report
+func (ls *LogService) GetRecentLogs(lines int) (string, error) {
+	data, err := os.ReadFile(ls.GetLogPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	all := strings.SplitAfter(string(data), "\n")
+	if all[len(all)-1] == "" {
+		all = all[:len(all)-1]
+	}
+	if lines > 0 && len(all) > lines {
+		all = all[len(all)-lines:]
+	}
+	return strings.Join(all, ""), nil
+}
+
+// ReloadLogging applies the log settings: level, redaction and the log file
+func (ls *LogService) ReloadLogging() {
+	settings := ls.cfg.Snapshot().Log
+	ls.level.Set(logging.ParseLevel(settings.Level))
+	ls.redact.Store(settings.RedactContent)
+
+	maxSize := int64(settings.MaxSizeMB) << 20
+	var err error
+	ls.mu.Lock()
+	switch {
+	case settings.File && ls.file == nil:
+		ls.file, err = logging.OpenRotating(ls.GetLogPath(), maxSize, settings.MaxFiles)
+	case settings.File:
+		ls.file.SetLimits(maxSize, settings.MaxFiles)
+	case ls.file != nil:
+		err = ls.file.Close()
+		ls.file = nil
+	}
+	ls.mu.Unlock()
+
+	// Logged after unlocking, as writing a record takes the lock
+	if err != nil {
+		slog.Warn("failed to set up the log file", "error", err)
+	}
+}
+
+// ServiceStartup reloads the log settings when they change
+func (ls *LogService) ServiceStartup(ctx context.Context, options application.ServiceOptions) error {
+	ls.app = application.Get()
+	ls.app.Event.On("config:changed", func(e *application.CustomEvent) {
+		if section, _ := e.Data.(string); section == "log" {
+			ls.ReloadLogging()
+		}
+	})
+	return nil
+}
+
+// ServiceShutdown closes the log file; later records only go to stderr
+func (ls *LogService) ServiceShutdown() error {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	if ls.file == nil {
+		return nil
+	}
+	err := ls.file.Close()
+	ls.file = nil
+	if err != nil {
+		return fmt.Errorf("failed to close log file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package services
+
+import (
+	"context"
+
+	"github.com/ironpark/tons/internal/metrics"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+type MetricsService struct {
+	recorder *metrics.Recorder
+}
+
+func NewMetricsService(recorder *metrics.Recorder) *MetricsService {
+	return &MetricsService{
+		recorder: recorder,
+	}
+}
+
+// GetEngineStats returns aggregated token and speed statistics per engine
+func (ms *MetricsService) GetEngineStats() []metrics.EngineStats {
+	return ms.recorder.Stats()
+}
+
+// ResetEngineStats clears all recorded statistics
+func (ms *MetricsService) ResetEngineStats() {
+	ms.recorder.Reset()
+}
+
+// ServiceStartup is called when the service starts
+func (ms *MetricsService) ServiceStartup(ctx context.Context, options application.ServiceOptions) error {
+	return nil
+}
+
+func (ms *MetricsService) ServiceShutdown() error {
+	return nil
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+
+	"github.com/ironpark/tons/internal/langdetect"
+	"github.com/ironpark/tons/internal/mobile"
+)
+
+// translateAndroid translates an Android strings.xml into a localized
+// resource file for a values-<lang> directory
+func translateAndroid(ctx context.Context, ts *TranslateService, path string, data []byte, sourceLang, targetLang string) ([]byte, error) {
+	res, err := mobile.ParseAndroid(data)
+	if err != nil {
+		return nil, err
+	}
+	translated, err := ts.translateAll(ctx, ts.segmentMemory(path, targetLang), sourceLang, targetLang, res.Names(), res.Texts())
+	if err != nil {
+		return nil, err
+	}
+	return res.Render(translated)
+}
+
+// translateAppleStrings translates the values of an Apple .strings file
+func translateAppleStrings(ctx context.Context, ts *TranslateService, path string, data []byte, sourceLang, targetLang string) ([]byte, error) {
+	res, err := mobile.ParseAppleStrings(data)
+	if err != nil {
+		return nil, err
+	}
+	translated, err := ts.translateAll(ctx, ts.segmentMemory(path, targetLang), sourceLang, targetLang, res.Keys(), res.Texts())
+	if err != nil {
+		return nil, err
+	}
+	return res.Render(translated)
+}
+
+// translateStringCatalog adds target language localizations to an Xcode
+// string catalog, marking them for review when configured or translated
+// with low confidence
+func translateStringCatalog(ctx context.Context, ts *TranslateService, path string, data []byte, sourceLang, targetLang string) ([]byte, error) {
+	lang := langdetect.Code(targetLang)
+	if lang == langdetect.Unknown {
+		lang = targetLang
+	}
+	catalog, err := mobile.ParseStringCatalog(data, lang)
+	if err != nil {
+		return nil, err
+	}
+	if sourceLang == "" || sourceLang == langdetect.Auto {
+		sourceLang = catalog.SourceLang
+	}
+
+	segments := make([]segment, len(catalog.Units))
+	for i, u := range catalog.Units {
+		segments[i] = segment{ID: u.ID, Text: u.Text, Translated: u.Translated}
+	}
+	translated, review, err := ts.translateChangedReview(ctx, ts.segmentMemory(path, targetLang), sourceLang, targetLang, segments)
+	if err != nil {
+		return nil, err
+	}
+	if len(translated) == 0 {
+		return data, nil
+	}
+
+	markFuzzy := ts.cfg.Snapshot().Translation.MarkFuzzy
+	for i, text := range translated {
+		state := mobile.StateTranslated
+		if markFuzzy || review[i] {
+			state = mobile.StateNeedsReview
+		}
+		catalog.SetTranslation(catalog.Units[i], text, state)
+	}
+	return catalog.Bytes()
+}
@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/ironpark/tons/internal/config"
+	"github.com/ironpark/tons/internal/models"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// ErrModelInUse is returned when deleting the model the internal engine is set to
+var ErrModelInUse = errors.New("model is used by the internal engine")
+
+// ModelService manages the GGUF models in the models directory
+type ModelService struct {
+	cfg *config.Config
+}
+
+func NewModelService(cfg *config.Config) *ModelService {
+	return &ModelService{
+		cfg: cfg,
+	}
+}
+
+// ModelsDir returns the directory models are managed in
+func (mm *ModelService) ModelsDir() string {
+	return mm.cfg.Snapshot().Engine.Internal.ModelsDirectory()
+}
+
+// ListModels returns the GGUF models in the models directory with their metadata
+func (mm *ModelService) ListModels() ([]models.Model, error) {
+	return models.List(mm.ModelsDir())
+}
+
+// InspectModel reads the architecture, quantization and other metadata of a
+// GGUF file, either a model name in the models directory or a full path
+func (mm *ModelService) InspectModel(name string) (models.Info, error) {
+	path := name
+	if !filepath.IsAbs(name) {
+		var err error
+		if path, err = models.Path(mm.ModelsDir(), name); err != nil {
+			return models.Info{}, err
+		}
+	}
+	return models.Inspect(path)
+}
+
+// DeleteModel removes a model from the models directory. The model the
+// internal engine is set to can't be deleted.
+func (mm *ModelService) DeleteModel(name string) error {
+	internal := mm.cfg.Snapshot().Engine.Internal
+	dir := internal.ModelsDirectory()
+	path, err := models.Path(dir, name)
+	if err != nil {
+		return err
+	}
+	if internal.ModelPath != "" && filepath.Clean(internal.ModelPath) == path {
+		return fmt.Errorf("%w: %s", ErrModelInUse, name)
+	}
+	return models.Delete(dir, name)
+}
+
+// ServiceStartup is called when the service starts
+func (mm *ModelService) ServiceStartup(ctx context.Context, options application.ServiceOptions) error {
+	return nil
+}
+
+func (mm *ModelService) ServiceShutdown() error {
+	return nil
+}
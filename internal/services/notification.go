@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/ironpark/tons/internal/config"
+	"github.com/wailsapp/wails/v3/pkg/application"
+	"github.com/wailsapp/wails/v3/pkg/services/notifications"
+)
+
+// NotificationService shows OS notifications when file and batch
+// translations finish or fail while no tons window has focus, so long jobs
+// can run in the background
+type NotificationService struct {
+	cfg      *config.Config
+	notifier *notifications.NotificationService
+	app      *application.App
+}
+
+func NewNotificationService(cfg *config.Config, notifier *notifications.NotificationService) *NotificationService {
+	return &NotificationService{
+		cfg:      cfg,
+		notifier: notifier,
+	}
+}
+
+// notify shows a notification unless notifications are off or the user is
+// looking at tons anyway
+func (ns *NotificationService) notify(title, body string) {
+	if ns.app == nil || !ns.cfg.Snapshot().General.Notifications {
+		return
+	}
+	for _, w := range ns.app.Window.GetAll() {
+		if w.IsFocused() {
+			return
+		}
+	}
+	err := ns.notifier.SendNotification(notifications.NotificationOptions{
+		ID:    newRequestID(),
+		Title: title,
+		Body:  body,
+	})
+	if err != nil {
+		slog.Warn("failed to send notification", "error", err)
+	}
+}
+
+// ServiceStartup asks for permission to notify, which macOS requires
+func (ns *NotificationService) ServiceStartup(ctx context.Context, options application.ServiceOptions) error {
+	ns.app = application.Get()
+	if ns.cfg.Snapshot().General.Notifications {
+		go func() {
+			if _, err := ns.notifier.RequestNotificationAuthorization(); err != nil {
+				slog.Warn("failed to request notification permission", "error", err)
+			}
+		}()
+	}
+	return nil
+}
+
+func (ns *NotificationService) ServiceShutdown() error {
+	return nil
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/ironpark/tons/internal/config"
+	"github.com/ironpark/tons/internal/langdetect"
+	"github.com/ironpark/tons/internal/ocr"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+type OCRService struct {
+	cfg       *config.Config
+	translate *TranslateService
+	app       *application.App
+}
+
+func NewOCRService(cfg *config.Config, translate *TranslateService) *OCRService {
+	return &OCRService{
+		cfg:       cfg,
+		translate: translate,
+	}
+}
+
+// recognizer creates the configured OCR backend
+func (oc *OCRService) recognizer(snapshot *config.Config) ocr.Recognizer {
+	return ocr.NewTesseract(ocr.WithTesseractCommand(snapshot.OCR.Command))
+}
+
+// OCRAvailable reports whether an OCR backend is installed
+func (oc *OCRService) OCRAvailable() bool {
+	return oc.recognizer(oc.cfg.Snapshot()).Available()
+}
+
+// RecognizeImage extracts text from base64 image data (optionally a data URL)
+func (oc *OCRService) RecognizeImage(image string) (string, error) {
+	data, err := ocr.DecodeImage(image)
+	if err != nil {
+		return "", err
+	}
+	return oc.recognize(data, "")
+}
+
+// RecognizeFile extracts text from an image file
+func (oc *OCRService) RecognizeFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return oc.recognize(data, "")
+}
+
+// TranslateImage recognizes text in base64 image data and translates it,
+// emitting "ocr:text" with the recognized text followed by the usual translate events
+func (oc *OCRService) TranslateImage(image, sourceLang, targetLang string) error {
+	data, err := ocr.DecodeImage(image)
+	if err != nil {
+		return err
+	}
+	return oc.translateImage(data, sourceLang, targetLang)
+}
+
+// translateImage runs OCR on data and feeds the text into the translation pipeline
+func (oc *OCRService) translateImage(data []byte, sourceLang, targetLang string) error {
+	text, err := oc.recognize(data, sourceLang)
+	if err != nil {
+		return err
+	}
+	oc.app.Event.Emit("ocr:text", text)
+	if text == "" {
+		return nil
+	}
+	return oc.translate.Translate(sourceLang, targetLang, text)
+}
+
+// recognize runs OCR, hinting the source language when it is known
+func (oc *OCRService) recognize(data []byte, sourceLang string) (string, error) {
+	snapshot := oc.cfg.Snapshot()
+	langs := snapshot.OCR.Languages
+	if code := langdetect.Code(sourceLang); code != langdetect.Unknown && !slices.Contains(langs, code) {
+		langs = append([]string{code}, langs...)
+	}
+	text, err := oc.recognizer(snapshot).Recognize(context.Background(), data, langs)
+	if err != nil {
+		return "", fmt.Errorf("text recognition failed: %w", err)
+	}
+	return text, nil
+}
+
+// ServiceStartup is called when the service starts
+func (oc *OCRService) ServiceStartup(ctx context.Context, options application.ServiceOptions) error {
+	oc.app = application.Get()
+	return nil
+}
+
+func (oc *OCRService) ServiceShutdown() error {
+	return nil
+}
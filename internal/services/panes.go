@@ -0,0 +1,83 @@
+package services
+
+import (
+	"strings"
+
+	"github.com/ironpark/tons/internal/config"
+	"github.com/ironpark/tons/internal/engine"
+)
+
+// windowPanePrefix starts the panes of translator windows, followed by the window ID
+const windowPanePrefix = "window:"
+
+// paneSettings are the language pair and engine of a translator window's
+// pane, used when its requests don't set their own
+type paneSettings struct {
+	sourceLang, targetLang string
+	engine                 config.EngineType // empty for the configured engine
+}
+
+// windowPane returns the pane of a translator window
+func windowPane(id string) string {
+	return windowPanePrefix + id
+}
+
+// setPane sets the language pair and engine of a pane
+func (ts *TranslateService) setPane(pane string, settings paneSettings) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.panes[pane] = settings
+}
+
+// dropPane forgets a pane, cancelling its translation and pending live input
+func (ts *TranslateService) dropPane(pane string) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if r, ok := ts.running[pane]; ok {
+		r.cancel()
+		delete(ts.running, pane)
+	}
+	if in, ok := ts.live[pane]; ok && in.timer != nil {
+		in.timer.Stop()
+	}
+	delete(ts.live, pane)
+	delete(ts.last, pane)
+	delete(ts.panes, pane)
+}
+
+// forPane applies the settings of req's pane: its languages where req has
+// none, and its engine instead of the configured one
+func (ts *TranslateService) forPane(snapshot *config.Config, req engine.Request) (*config.Config, engine.Request) {
+	ts.mu.Lock()
+	settings, ok := ts.panes[req.Pane]
+	ts.mu.Unlock()
+	if !ok {
+		return snapshot, req
+	}
+	if req.SourceLang == "" {
+		req.SourceLang = settings.sourceLang
+	}
+	if req.TargetLang == "" {
+		req.TargetLang = settings.targetLang
+	}
+	if settings.engine != "" && settings.engine != snapshot.Engine.Type {
+		snapshot = snapshot.Snapshot()
+		snapshot.Engine.Type = settings.engine
+	}
+	return snapshot, req
+}
+
+// emitter returns a function emitting the events of a pane. Translator
+// windows get their own events, prefixed with their pane, e.g.
+// "window:<id>:translate:delta", so each window only sees its own stream.
+func (ts *TranslateService) emitter(pane string) func(event string, payload any) {
+	prefix := ""
+	if strings.HasPrefix(pane, windowPanePrefix) {
+		prefix = pane + ":"
+	}
+	return func(event string, payload any) {
+		ts.app.Event.Emit(prefix+event, payload)
+	}
+}
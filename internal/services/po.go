@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+
+	"github.com/ironpark/tons/internal/langdetect"
+	"github.com/ironpark/tons/internal/po"
+)
+
+// translatePO fills in untranslated entries of a PO/POT catalog. Plural forms
+// get the translated msgid_plural, and new translations are marked fuzzy when
+// configured, or when translated with low confidence, so translators review
+// them.
+func translatePO(ctx context.Context, ts *TranslateService, path string, data []byte, sourceLang, targetLang string) ([]byte, error) {
+	catalog, err := po.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	nplurals := catalog.NPlurals()
+
+	var entries []*po.Entry
+	var segments []segment
+	for _, e := range catalog.Entries {
+		if e.MsgID == "" {
+			continue
+		}
+		id := e.Context + "\x04" + e.MsgID
+		entries = append(entries, e)
+		segments = append(segments, segment{ID: id, Text: e.MsgID, Translated: e.Translated()})
+		if e.MsgIDPlural != "" {
+			segments = append(segments, segment{ID: id + "\x00plural", Text: e.MsgIDPlural, Translated: e.Translated()})
+		}
+	}
+
+	translated, review, err := ts.translateChangedReview(ctx, ts.segmentMemory(path, targetLang), sourceLang, targetLang, segments)
+	if err != nil {
+		return nil, err
+	}
+
+	markFuzzy := ts.cfg.Snapshot().Translation.MarkFuzzy
+	i := 0
+	for _, e := range entries {
+		singular, singularOK := translated[i]
+		fuzzy := markFuzzy || review[i]
+		i++
+		if e.MsgIDPlural == "" {
+			if singularOK {
+				e.MsgStr = []string{singular}
+				if fuzzy {
+					e.AddFlag("fuzzy")
+				}
+			}
+			continue
+		}
+
+		plural, pluralOK := translated[i]
+		fuzzy = fuzzy || review[i]
+		i++
+		if !singularOK && !pluralOK {
+			continue
+		}
+		// Only one form changed; keep the other translation
+		if !singularOK && len(e.MsgStr) > 0 {
+			singular = e.MsgStr[0]
+		}
+		if !pluralOK && len(e.MsgStr) > 0 {
+			plural = e.MsgStr[len(e.MsgStr)-1]
+		}
+		e.MsgStr = make([]string, nplurals)
+		for n := range e.MsgStr {
+			e.MsgStr[n] = plural
+		}
+		if nplurals > 1 {
+			e.MsgStr[0] = singular
+		}
+		if fuzzy {
+			e.AddFlag("fuzzy")
+		}
+	}
+
+	if code := langdetect.Code(targetLang); code != langdetect.Unknown {
+		catalog.SetHeaderField("Language", code)
+	}
+	return catalog.Bytes(), nil
+}
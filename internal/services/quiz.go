@@ -0,0 +1,103 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/ironpark/tons/internal/engine"
+	"github.com/ironpark/tons/internal/history"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+const (
+	// defaultQuizQuestions is how many recent translations a quiz asks about by default
+	defaultQuizQuestions = 5
+	// maxQuizQuestions bounds the questions of a quiz, to keep the request small
+	maxQuizQuestions = 20
+	// maxQuizTextChars skips translations too long to quiz on, such as documents
+	maxQuizTextChars = 500
+)
+
+// QuizService makes language-learning quizzes from the translation history
+type QuizService struct {
+	history   *history.Store
+	translate *TranslateService
+}
+
+func NewQuizService(hist *history.Store, translate *TranslateService) *QuizService {
+	return &QuizService{
+		history:   hist,
+		translate: translate,
+	}
+}
+
+// GenerateQuiz asks the engine for "cloze" or "multiple-choice" questions,
+// or a mix of both if kind is empty, with one question per history entry.
+// With no ids, the most recent short translations are used, up to count
+// (5 if count <= 0).
+func (qs *QuizService) GenerateQuiz(kind string, count int, ids []string) (engine.Quiz, error) {
+	entries, err := qs.entries(count, ids)
+	if err != nil {
+		return engine.Quiz{}, err
+	}
+	if len(entries) == 0 {
+		return engine.Quiz{}, errors.New("no translations in the history to make a quiz from")
+	}
+	items := make([]engine.QuizItem, len(entries))
+	for i, e := range entries {
+		items[i] = engine.QuizItem{
+			ID:          e.ID,
+			SourceLang:  e.SourceLang,
+			TargetLang:  e.TargetLang,
+			Text:        e.Text,
+			Translation: e.Translation,
+		}
+	}
+	return qs.translate.quiz(kind, items)
+}
+
+// entries returns the history entries with the given IDs, or the most
+// recent ones short enough to quiz on
+func (qs *QuizService) entries(count int, ids []string) ([]history.Entry, error) {
+	if len(ids) > 0 {
+		if len(ids) > maxQuizQuestions {
+			return nil, fmt.Errorf("a quiz can have at most %d questions", maxQuizQuestions)
+		}
+		entries := make([]history.Entry, 0, len(ids))
+		for _, id := range ids {
+			e, ok := qs.history.Get(id)
+			if !ok {
+				return nil, fmt.Errorf("%w: %s", history.ErrNotFound, id)
+			}
+			entries = append(entries, e)
+		}
+		return entries, nil
+	}
+
+	if count <= 0 {
+		count = defaultQuizQuestions
+	}
+	count = min(count, maxQuizQuestions)
+	var entries []history.Entry
+	for _, e := range qs.history.List(0) {
+		if e.Translation == "" || utf8.RuneCountInString(e.Text) > maxQuizTextChars {
+			continue
+		}
+		entries = append(entries, e)
+		if len(entries) == count {
+			break
+		}
+	}
+	return entries, nil
+}
+
+// ServiceStartup is called when the service starts
+func (qs *QuizService) ServiceStartup(ctx context.Context, options application.ServiceOptions) error {
+	return nil
+}
+
+func (qs *QuizService) ServiceShutdown() error {
+	return nil
+}
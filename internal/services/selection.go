@@ -0,0 +1,203 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ironpark/tons/internal/config"
+	"github.com/ironpark/tons/internal/engine"
+	"github.com/ironpark/tons/internal/instance"
+	"github.com/ironpark/tons/internal/selection"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// popupWindow is the name of the small window showing selection translations
+const popupWindow = "popup"
+
+// Commands accepted from "tons <command>"
+const (
+	CommandSelection = "selection" // translate the current selection
+	CommandSwap      = "swap"      // swap languages and translate the main pane's last output back
+	CommandReplace   = "replace"   // translate the selection or clipboard onto the clipboard, pasting it in place
+)
+
+// pasteDelay gives the clipboard time to take the translation before pasting
+const pasteDelay = 100 * time.Millisecond
+
+// SelectionReplaced is the payload of "selection:replaced" events, sent when
+// "tons replace" has put a translation on the clipboard
+type SelectionReplaced struct {
+	Text        string `json:"text"`
+	Translation string `json:"translation"`
+	Pasted      bool   `json:"pasted"` // pasted over the selection
+}
+
+// InstanceSocket returns the socket the running app accepts commands on
+func InstanceSocket() string {
+	return filepath.Join(config.Dir(), "tons.sock")
+}
+
+// SelectionService translates the text selected in other apps into a popup
+// next to the cursor. It is triggered from the app or by a system keyboard
+// shortcut running "tons selection".
+type SelectionService struct {
+	cfg       *config.Config
+	translate *TranslateService
+	clipboard *ClipboardService
+	app       *application.App
+	cancel    context.CancelFunc
+}
+
+func NewSelectionService(cfg *config.Config, translate *TranslateService, clipboard *ClipboardService) *SelectionService {
+	return &SelectionService{
+		cfg:       cfg,
+		translate: translate,
+		clipboard: clipboard,
+	}
+}
+
+// SelectionAvailable reports whether selected text can be read on this system
+func (sel *SelectionService) SelectionAvailable() bool {
+	return selection.Available()
+}
+
+// TranslateSelection reads the selected text, shows the popup next to the
+// cursor and streams the translation into it. Emits "selection:text" with the
+// selected text first.
+func (sel *SelectionService) TranslateSelection() error {
+	ctx := context.Background()
+	text, err := selection.Text(ctx)
+	if err != nil {
+		return err
+	}
+	settings := sel.cfg.Snapshot().Selection
+	x, y, ok := selection.Cursor(ctx)
+	sel.showPopup(settings, x, y, ok)
+	sel.app.Event.Emit("selection:text", text)
+	return sel.translate.TranslateRequest(engine.Request{
+		Text:       text,
+		SourceLang: settings.SourceLang,
+		TargetLang: settings.TargetLang,
+		Pane:       PanePopup,
+	})
+}
+
+// TranslateAndReplace translates the selected text, or the clipboard when
+// nothing is selected, and puts the translation on the clipboard. With
+// PasteInPlace set it is then pasted into the focused app, replacing the
+// selection, for translating in place in any app.
+func (sel *SelectionService) TranslateAndReplace() (string, error) {
+	ctx := context.Background()
+	text, err := selection.Text(ctx)
+	if errors.Is(err, selection.ErrEmpty) || errors.Is(err, selection.ErrUnsupported) {
+		clip, _ := sel.app.Clipboard.Text()
+		text, err = strings.TrimSpace(clip), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if text == "" {
+		return "", errors.New("no text selected or on the clipboard")
+	}
+
+	settings := sel.cfg.Snapshot().Selection
+	translation, err := sel.translate.TranslateText(settings.SourceLang, settings.TargetLang, text, engine.FormatText)
+	if err != nil {
+		return "", err
+	}
+	sel.clipboard.setText(translation)
+
+	pasted := false
+	if settings.PasteInPlace {
+		time.Sleep(pasteDelay)
+		if err := selection.Paste(ctx); err != nil {
+			slog.Warn("pasting the translation failed", "error", err)
+		} else {
+			pasted = true
+		}
+	}
+	sel.app.Event.Emit("selection:replaced", SelectionReplaced{Text: text, Translation: translation, Pasted: pasted})
+	return translation, nil
+}
+
+// PasteAvailable reports whether translations can be pasted in place on this system
+func (sel *SelectionService) PasteAvailable() bool {
+	return selection.PasteAvailable()
+}
+
+// HidePopup hides the selection popup
+func (sel *SelectionService) HidePopup() {
+	if w, ok := sel.app.Window.GetByName(popupWindow); ok {
+		w.Hide()
+	}
+}
+
+// showPopup shows the popup window below and right of the cursor, creating it on first use
+func (sel *SelectionService) showPopup(settings config.SelectionConfig, x, y int, atCursor bool) {
+	x, y = x+12, y+16
+	if w, ok := sel.app.Window.GetByName(popupWindow); ok {
+		w.SetSize(settings.PopupWidth, settings.PopupHeight)
+		if atCursor {
+			w.SetPosition(x, y)
+		}
+		w.Show()
+		w.Focus()
+		return
+	}
+	options := application.WebviewWindowOptions{
+		Name:             popupWindow,
+		Title:            "Translation",
+		Width:            settings.PopupWidth,
+		Height:           settings.PopupHeight,
+		AlwaysOnTop:      true,
+		Frameless:        true,
+		BackgroundColour: application.NewRGB(27, 38, 54),
+		URL:              "/#/popup",
+	}
+	if atCursor {
+		options.X, options.Y = x, y
+	}
+	sel.app.Window.NewWithOptions(options)
+}
+
+// handleCommand runs a command passed by "tons <command>"
+func (sel *SelectionService) handleCommand(command string) {
+	switch command {
+	case CommandSelection:
+		if err := sel.TranslateSelection(); err != nil {
+			slog.Warn("selection translation failed", "error", err)
+		}
+	case CommandSwap:
+		if err := sel.translate.SwapAndRetranslate(PaneMain); err != nil {
+			slog.Warn("swap and retranslate failed", "error", err)
+		}
+	case CommandReplace:
+		if _, err := sel.TranslateAndReplace(); err != nil {
+			slog.Warn("translate and replace failed", "error", err)
+		}
+	default:
+		slog.Warn("unknown command", "command", command)
+	}
+}
+
+// ServiceStartup is called when the service starts
+func (sel *SelectionService) ServiceStartup(ctx context.Context, options application.ServiceOptions) error {
+	sel.app = application.Get()
+	listenCtx, cancel := context.WithCancel(context.Background())
+	sel.cancel = cancel
+	if err := instance.Listen(listenCtx, InstanceSocket(), sel.handleCommand); err != nil {
+		slog.Warn("failed to listen for commands", "error", err)
+	}
+	return nil
+}
+
+func (sel *SelectionService) ServiceShutdown() error {
+	if sel.cancel != nil {
+		sel.cancel()
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/ironpark/tons/internal/config"
+	"github.com/ironpark/tons/internal/session"
+	"github.com/wailsapp/wails/v3/pkg/application"
+	"github.com/wailsapp/wails/v3/pkg/events"
+)
+
+// SessionService restores the language pair, engine, input text and main
+// window placement from the last run
+type SessionService struct {
+	cfg   *config.Config
+	store *session.Store
+	app   *application.App
+}
+
+func NewSessionService(cfg *config.Config, store *session.Store) *SessionService {
+	return &SessionService{
+		cfg:   cfg,
+		store: store,
+	}
+}
+
+// GetSessionState returns the state saved by the last run
+func (ses *SessionService) GetSessionState() session.State {
+	return ses.store.Get()
+}
+
+// SaveSessionState remembers the language pair and input text of the main window
+func (ses *SessionService) SaveSessionState(sourceLang, targetLang, inputText string) error {
+	return ses.store.Update(func(s *session.State) {
+		s.SourceLang = sourceLang
+		s.TargetLang = targetLang
+		s.InputText = inputText
+	})
+}
+
+// saveWindow remembers the engine and the main window's placement
+func (ses *SessionService) saveWindow(w application.Window) {
+	engineType := string(ses.cfg.Snapshot().Engine.Type)
+	x, y := w.Position()
+	width, height := w.Size()
+	err := ses.store.Update(func(s *session.State) {
+		s.Engine = engineType
+		s.Window = &session.Geometry{X: x, Y: y, Width: width, Height: height}
+	})
+	if err != nil {
+		slog.Warn("failed to save session state", "error", err)
+	}
+}
+
+// ServiceStartup switches back to the engine of the last run and saves the
+// main window's placement when it closes
+func (ses *SessionService) ServiceStartup(ctx context.Context, options application.ServiceOptions) error {
+	ses.app = application.Get()
+
+	if last := config.EngineType(ses.store.Get().Engine); last != "" && last != ses.cfg.Snapshot().Engine.Type {
+		ses.cfg.SetEngineType(last)
+	}
+	if w, ok := ses.app.Window.GetByName(mainWindow); ok {
+		w.OnWindowEvent(events.Common.WindowClosing, func(*application.WindowEvent) {
+			ses.saveWindow(w)
+		})
+	}
+	return nil
+}
+
+func (ses *SessionService) ServiceShutdown() error {
+	return nil
+}
@@ -2,14 +2,24 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
 
 	"github.com/ironpark/tons/internal/config"
+	"github.com/ironpark/tons/internal/engine"
 	"github.com/wailsapp/wails/v3/pkg/application"
 )
 
+// configWatchInterval is how often config.json is checked for outside edits
+const configWatchInterval = 2 * time.Second
+
 type SettingService struct {
-	cfg *config.Config
-	app *application.App
+	cfg    *config.Config
+	app    *application.App
+	cancel context.CancelFunc
 }
 
 func NewSettingService(cfg *config.Config) (*SettingService, error) {
@@ -22,28 +32,318 @@ func (ss *SettingService) GetCurrentConfig() *config.Config {
 	return ss.cfg.Snapshot()
 }
 
+// GetEnvOverrides returns the TONS_* environment variables overriding
+// settings in this run; changes to those settings are not saved
+func (ss *SettingService) GetEnvOverrides() []string {
+	return ss.cfg.EnvOverrides()
+}
+
 func (ss *SettingService) UpdateGeneralConfig(general config.GeneralConfig) error {
-	ss.cfg.SetGeneral(general)
-	return ss.cfg.Save()
+	return ss.update(func(c *config.Config) {
+		c.SetGeneral(general)
+	})
 }
 
+// UpdateEngineConfig saves the engine settings and emits "config:changed",
+// so the new engine is warmed up
 func (ss *SettingService) UpdateEngineConfig(engine config.EngineConfig) error {
-	ss.cfg.SetEngine(engine)
-	return ss.cfg.Save()
+	if err := ss.update(func(c *config.Config) {
+		c.SetEngine(engine)
+	}); err != nil {
+		return err
+	}
+	ss.emitChanged([]string{"engine"})
+	return nil
 }
 
 func (ss *SettingService) UpdatePromptConfig(prompt config.PromptConfig) error {
-	ss.cfg.SetPromptConfig(prompt)
+	return ss.update(func(c *config.Config) {
+		c.SetPromptConfig(prompt)
+	})
+}
+
+// ValidateSettings checks a configuration, e.g. one being edited in the
+// settings page, and returns every invalid setting
+func (ss *SettingService) ValidateSettings(candidate *config.Config) []config.FieldError {
+	return validateSettings(candidate)
+}
+
+// ValidatePromptTemplate checks a prompt template and returns the variables it
+// uses that are not defined, including custom variables missing from the config
+func (ss *SettingService) ValidatePromptTemplate(template string) ([]string, error) {
+	return engine.ValidatePrompt(template, ss.cfg.Snapshot().Prompt.Variables)
+}
+
+// SavePromptPreset creates or replaces a prompt preset
+func (ss *SettingService) SavePromptPreset(preset config.PromptPreset) error {
+	if err := ss.cfg.SavePreset(preset); err != nil {
+		return err
+	}
+	return ss.cfg.Save()
+}
+
+// DuplicatePromptPreset copies a preset under a new name
+func (ss *SettingService) DuplicatePromptPreset(name, newName string) error {
+	if err := ss.cfg.DuplicatePreset(name, newName); err != nil {
+		return err
+	}
 	return ss.cfg.Save()
 }
 
+func (ss *SettingService) DeletePromptPreset(name string) error {
+	if err := ss.cfg.DeletePreset(name); err != nil {
+		return err
+	}
+	return ss.cfg.Save()
+}
+
+// SetActivePromptPreset selects the preset used by default ("" for none)
+func (ss *SettingService) SetActivePromptPreset(name string) error {
+	if err := ss.cfg.SetActivePreset(name); err != nil {
+		return err
+	}
+	return ss.cfg.Save()
+}
+
+// ExportPromptPresets returns the named presets (all if none are given) as JSON
+func (ss *SettingService) ExportPromptPresets(names []string) (string, error) {
+	data, err := ss.cfg.ExportPresets(names...)
+	return string(data), err
+}
+
+// ImportPromptPresets adds presets from exported JSON and returns how many were imported
+func (ss *SettingService) ImportPromptPresets(data string) (int, error) {
+	n, err := ss.cfg.ImportPresets([]byte(data))
+	if err != nil {
+		return 0, err
+	}
+	return n, ss.cfg.Save()
+}
+
+func (ss *SettingService) UpdateTranslationConfig(translation config.TranslationConfig) error {
+	return ss.update(func(c *config.Config) {
+		c.SetTranslation(translation)
+	})
+}
+
+func (ss *SettingService) UpdateNetworkConfig(network config.NetworkConfig) error {
+	return ss.update(func(c *config.Config) {
+		c.SetNetwork(network)
+	})
+}
+
+func (ss *SettingService) UpdateOCRConfig(ocr config.OCRConfig) error {
+	return ss.update(func(c *config.Config) {
+		c.SetOCR(ocr)
+	})
+}
+
+func (ss *SettingService) UpdateSpeechConfig(speech config.SpeechConfig) error {
+	return ss.update(func(c *config.Config) {
+		c.SetSpeech(speech)
+	})
+}
+
+func (ss *SettingService) UpdateClipboardConfig(clipboard config.ClipboardConfig) error {
+	return ss.update(func(c *config.Config) {
+		c.SetClipboard(clipboard)
+	})
+}
+
+func (ss *SettingService) UpdateSelectionConfig(selection config.SelectionConfig) error {
+	return ss.update(func(c *config.Config) {
+		c.SetSelection(selection)
+	})
+}
+
+func (ss *SettingService) UpdateTrayConfig(tray config.TrayConfig) error {
+	return ss.update(func(c *config.Config) {
+		c.SetTray(tray)
+	})
+}
+
+func (ss *SettingService) UpdateAnkiConfig(anki config.AnkiConfig) error {
+	return ss.update(func(c *config.Config) {
+		c.SetAnki(anki)
+	})
+}
+
+// UpdateUsageConfig sets the usage budgets and when to warn about them
+func (ss *SettingService) UpdateUsageConfig(usage config.UsageConfig) error {
+	return ss.update(func(c *config.Config) {
+		c.SetUsage(usage)
+	})
+}
+
+// UpdateAPIConfig saves the local HTTP API settings; they take effect on
+// APIService.RestartAPIServer or the next start
+func (ss *SettingService) UpdateAPIConfig(api config.APIConfig) error {
+	return ss.update(func(c *config.Config) {
+		c.SetAPI(api)
+	})
+}
+
+// UpdateHotkeysConfig saves the hotkey chords; they take effect on
+// HotkeyService.ReloadHotkeys or the next start
+func (ss *SettingService) UpdateHotkeysConfig(hotkeys config.HotkeysConfig) error {
+	return ss.update(func(c *config.Config) {
+		c.SetHotkeys(hotkeys)
+	})
+}
+
+// UpdateTelemetryConfig saves the anonymous usage report settings
+func (ss *SettingService) UpdateTelemetryConfig(telemetry config.TelemetryConfig) error {
+	return ss.update(func(c *config.Config) {
+		c.SetTelemetry(telemetry)
+	})
+}
+
+// UpdateLogConfig saves the log settings; they take effect on
+// LogService.ReloadLogging or the next start
+func (ss *SettingService) UpdateLogConfig(log config.LogConfig) error {
+	return ss.update(func(c *config.Config) {
+		c.SetLog(log)
+	})
+}
+
+// UpdateCacheConfig saves the translation cache settings
+func (ss *SettingService) UpdateCacheConfig(cache config.CacheConfig) error {
+	return ss.update(func(c *config.Config) {
+		c.SetCache(cache)
+	})
+}
+
+// GetConfigChanges returns the settings changes saved in this session,
+// newest first
+func (ss *SettingService) GetConfigChanges() []config.ConfigChange {
+	return ss.cfg.Changes()
+}
+
+// UndoLastConfigChange reverts the last saved settings change, e.g. a broken
+// prompt template, and emits the change events of the sections it restored.
+// Returns config.ErrNothingToUndo if there is no change left to undo.
+func (ss *SettingService) UndoLastConfigChange() ([]string, error) {
+	sections, err := ss.cfg.Undo()
+	if err != nil {
+		return nil, err
+	}
+	ss.emitChanged(sections)
+	return sections, nil
+}
+
+// ExportSettings returns the whole configuration as JSON, leaving out secrets
+// and machine-specific paths unless asked to include them
+func (ss *SettingService) ExportSettings(opts config.ExportOptions) (string, error) {
+	data, err := ss.cfg.Export(opts)
+	return string(data), err
+}
+
+// ImportSettings applies settings exported by ExportSettings. Settings the
+// file leaves out keep their current values.
+func (ss *SettingService) ImportSettings(data string) error {
+	before := ss.cfg.Snapshot().General
+	if err := ss.cfg.Import([]byte(data)); err != nil {
+		return err
+	}
+	if err := ss.cfg.Save(); err != nil {
+		return err
+	}
+	if general := ss.cfg.Snapshot().General; general != before {
+		ss.app.Event.Emit("config:general-changed", general)
+	}
+	return nil
+}
+
+// GetOllamaModelInfo returns metadata (context length, parameter size, quantization)
+// for the currently configured Ollama model
+func (ss *SettingService) GetOllamaModelInfo() (engine.OllamaModelInfo, error) {
+	snapshot := ss.cfg.Snapshot()
+	ollama := snapshot.Engine.Ollama
+	return engine.GetOllamaModelInfo(ollama.Host, ollama.Model, ollamaOptions(snapshot)...)
+}
+
+// update applies change to the configuration and saves it. The change is
+// rejected if it makes a setting invalid; problems the configuration already
+// had don't block unrelated changes. Emits "config:general-changed" when the
+// theme or language changed, so every window follows them.
+func (ss *SettingService) update(change func(c *config.Config)) error {
+	current := ss.cfg.Snapshot()
+	candidate := ss.cfg.Snapshot()
+	change(candidate)
+	if errs := validateSettings(candidate).Without(validateSettings(current)); len(errs) > 0 {
+		return errs
+	}
+	change(ss.cfg)
+	if err := ss.cfg.Save(); err != nil {
+		return err
+	}
+	if general := ss.cfg.Snapshot().General; general != current.General {
+		ss.app.Event.Emit("config:general-changed", general)
+	}
+	return nil
+}
+
+// emitChanged emits "config:changed" for each changed section, and
+// "config:general-changed" if the general settings are among them
+func (ss *SettingService) emitChanged(sections []string) {
+	for _, section := range sections {
+		ss.app.Event.Emit("config:changed", section)
+		if section == "general" {
+			ss.app.Event.Emit("config:general-changed", ss.cfg.Snapshot().General)
+		}
+	}
+}
+
+// validateSettings checks the settings and the prompt templates
+func validateSettings(snapshot *config.Config) config.ValidationError {
+	var errs config.ValidationError
+	errors.As(snapshot.Validate(), &errs)
+
+	prompt := snapshot.Prompt
+	checkTemplate := func(field, template string) {
+		undefined, err := engine.ValidatePrompt(template, prompt.Variables)
+		switch {
+		case err != nil:
+			errs = append(errs, config.FieldError{Field: field, Message: err.Error()})
+		case len(undefined) > 0:
+			errs = append(errs, config.FieldError{Field: field, Message: "undefined variables: " + strings.Join(undefined, ", ")})
+		}
+	}
+	checkTemplate("prompt.template", prompt.Template)
+	for i, t := range prompt.Templates {
+		checkTemplate(fmt.Sprintf("prompt.templates.%d.template", i), t.Template)
+	}
+	for i, preset := range prompt.Presets {
+		checkTemplate(fmt.Sprintf("prompt.presets.%d.template", i), preset.Template)
+	}
+	return errs
+}
+
+// reloaded applies a config.json edited outside the app, emitting
+// "config:changed" for each changed section
+func (ss *SettingService) reloaded(sections []string, err error) {
+	if err != nil {
+		slog.Warn("ignoring invalid settings file", "error", err)
+		ss.app.Event.Emit("config:invalid", err.Error())
+		return
+	}
+	slog.Info("settings file changed", "sections", sections)
+	ss.emitChanged(sections)
+}
+
 // ServiceStartup is called when the service starts
 func (ss *SettingService) ServiceStartup(ctx context.Context, options application.ServiceOptions) error {
 	// Store the application instance for later use
 	ss.app = application.Get()
+	watchCtx, cancel := context.WithCancel(context.Background())
+	ss.cancel = cancel
+	go ss.cfg.Watch(watchCtx, configWatchInterval, validateSettings, ss.reloaded)
 	return nil
 }
 
 func (u *SettingService) ServiceShutdown() error {
+	if u.cancel != nil {
+		u.cancel()
+	}
 	return nil
 }
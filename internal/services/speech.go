@@ -0,0 +1,80 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ironpark/tons/internal/config"
+	"github.com/ironpark/tons/internal/langdetect"
+	"github.com/ironpark/tons/internal/speech"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+type SpeechService struct {
+	cfg       *config.Config
+	translate *TranslateService
+	recorder  *speech.Recorder
+	app       *application.App
+}
+
+func NewSpeechService(cfg *config.Config, translate *TranslateService) *SpeechService {
+	return &SpeechService{
+		cfg:       cfg,
+		translate: translate,
+		recorder:  speech.NewRecorder(cfg.Snapshot().Speech.InputDevice),
+	}
+}
+
+// transcriber creates the configured speech-to-text backend
+func (ss *SpeechService) transcriber(snapshot *config.Config) speech.Transcriber {
+	return speech.NewWhisper(snapshot.Speech.WhisperCommand, snapshot.Speech.WhisperModel)
+}
+
+// SpeechInputAvailable reports whether both recording and transcription are installed
+func (ss *SpeechService) SpeechInputAvailable() bool {
+	return ss.recorder.Available() && ss.transcriber(ss.cfg.Snapshot()).Available()
+}
+
+// StartListening starts recording from the microphone
+func (ss *SpeechService) StartListening() error {
+	ss.recorder.Device = ss.cfg.Snapshot().Speech.InputDevice
+	if err := ss.recorder.Start(); err != nil {
+		return err
+	}
+	ss.app.Event.Emit("speech:listening", true)
+	return nil
+}
+
+// StopListening stops recording, transcribes the audio and translates it,
+// emitting "speech:text" with the transcript followed by the usual translate events
+func (ss *SpeechService) StopListening(sourceLang, targetLang string) error {
+	wav, err := ss.recorder.Stop()
+	ss.app.Event.Emit("speech:listening", false)
+	if err != nil {
+		return err
+	}
+
+	snapshot := ss.cfg.Snapshot()
+	text, err := ss.transcriber(snapshot).Transcribe(context.Background(), wav, langdetect.Code(sourceLang))
+	if err != nil {
+		return fmt.Errorf("transcription failed: %w", err)
+	}
+	ss.app.Event.Emit("speech:text", text)
+	if text == "" {
+		return nil
+	}
+	return ss.translate.Translate(sourceLang, targetLang, text)
+}
+
+// ServiceStartup is called when the service starts
+func (ss *SpeechService) ServiceStartup(ctx context.Context, options application.ServiceOptions) error {
+	ss.app = application.Get()
+	return nil
+}
+
+func (ss *SpeechService) ServiceShutdown() error {
+	if ss.recorder.Recording() {
+		ss.recorder.Stop()
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/ironpark/tons/internal/config"
+	"github.com/ironpark/tons/internal/engine"
+	"github.com/ironpark/tons/internal/metrics"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// healthTimeout bounds the health check of one engine
+const healthTimeout = 5 * time.Second
+
+// EngineStatus is the live state of one engine for the diagnostics panel
+type EngineStatus struct {
+	Type   config.EngineType        `json:"type"`
+	Agent  config.TerminalAgentType `json:"agent,omitempty"` // terminal agents only
+	Name   string                   `json:"name"`
+	Active bool                     `json:"active"` // the engine translations currently use
+	Health engine.Health            `json:"health"`
+	Stats  metrics.EngineStats      `json:"stats"` // usage, latency and last error in this session
+}
+
+type StatusService struct {
+	cfg       *config.Config
+	recorder  *metrics.Recorder
+	translate *TranslateService
+}
+
+func NewStatusService(cfg *config.Config, recorder *metrics.Recorder, translate *TranslateService) *StatusService {
+	return &StatusService{
+		cfg:       cfg,
+		recorder:  recorder,
+		translate: translate,
+	}
+}
+
+// GetEngineStatus checks every engine that can be configured and returns its
+// health together with the statistics recorded for it
+func (st *StatusService) GetEngineStatus() []EngineStatus {
+	active := st.cfg.Snapshot().Engine
+
+	statuses := []EngineStatus{
+		{Type: config.EngineInternal},
+		{Type: config.EngineOllama},
+		{Type: config.EngineTerminalAgent, Agent: config.AgentClaudeCode},
+		{Type: config.EngineTerminalAgent, Agent: config.AgentGeminiCLI},
+		{Type: config.EngineTerminalAgent, Agent: config.AgentCodex},
+	}
+	var wg sync.WaitGroup
+	for i := range statuses {
+		status := &statuses[i]
+		status.Active = status.Type == active.Type &&
+			(status.Type != config.EngineTerminalAgent || status.Agent == active.TerminalAgent.Selected)
+
+		engineCfg := st.cfg.Snapshot()
+		engineCfg.Engine.Type = status.Type
+		if status.Agent != "" {
+			engineCfg.Engine.TerminalAgent.Selected = status.Agent
+		}
+		wg.Go(func() {
+			st.check(status, engineCfg)
+		})
+	}
+	wg.Wait()
+	return statuses
+}
+
+// check fills in the health and statistics of one engine. The active engine
+// is the cached instance, so a loaded local model is reported as such.
+func (st *StatusService) check(status *EngineStatus, snapshot *config.Config) {
+	var e engine.Engine
+	var err error
+	if status.Active {
+		e, err = st.translate.engines.get(snapshot)
+	} else {
+		e, err = buildEngine(snapshot, st.translate.engines.logger)
+		if err == nil {
+			defer e.Close()
+		}
+	}
+	if err != nil {
+		status.Name = string(status.Type)
+		status.Health = engine.Health{Error: err.Error()}
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), healthTimeout)
+	defer cancel()
+
+	status.Name = e.Name()
+	status.Health = engine.CheckHealth(ctx, e)
+	status.Stats, _ = st.recorder.Engine(e.Name())
+}
+
+// ServiceStartup is called when the service starts
+func (st *StatusService) ServiceStartup(ctx context.Context, options application.ServiceOptions) error {
+	return nil
+}
+
+func (st *StatusService) ServiceShutdown() error {
+	return nil
+}
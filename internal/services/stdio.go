@@ -0,0 +1,123 @@
+package services
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/ironpark/tons/internal/engine"
+	"github.com/ironpark/tons/internal/lsp"
+)
+
+// StdioRequest is one line read by ServeStdio: a translation request, or with
+// Cancel set, the cancellation of the running request with the same ID
+type StdioRequest struct {
+	ID     string `json:"id"` // echoed in every response line; generated when empty
+	Cancel bool   `json:"cancel,omitempty"`
+	APITranslateRequest
+}
+
+// StdioResponse is one line written by ServeStdio. Event and Data are those
+// of the local API's stream: "queued", "delta", then "done" or "error".
+type StdioResponse struct {
+	ID    string `json:"id"`
+	Event string `json:"event"`
+	Data  any    `json:"data"`
+}
+
+// ServeStdio reads translation requests as JSON lines from r and writes their
+// streamed responses as JSON lines to w until r ends. Requests run
+// concurrently, so lines of different requests interleave; every request
+// ends with a "done" or "error" line.
+func ServeStdio(ts *TranslateService, r io.Reader, w io.Writer) error {
+	var (
+		mu      sync.Mutex // serializes writes to w and guards running
+		running = make(map[string]*runningRequest)
+		wg      sync.WaitGroup
+	)
+	enc := json.NewEncoder(w)
+	write := func(res StdioResponse) {
+		mu.Lock()
+		defer mu.Unlock()
+		enc.Encode(res)
+	}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxAPIBody)
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var line StdioRequest
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			write(StdioResponse{Event: "error", Data: TranslateError{Error: "invalid request: " + err.Error()}})
+			continue
+		}
+		if line.Cancel {
+			mu.Lock()
+			if run, ok := running[line.ID]; ok {
+				run.cancel()
+			}
+			mu.Unlock()
+			continue
+		}
+		if line.ID == "" {
+			line.ID = newRequestID()
+		}
+		req, err := line.request()
+		if err != nil {
+			write(StdioResponse{ID: line.ID, Event: "error", Data: TranslateError{RequestID: line.ID, Error: err.Error()}})
+			continue
+		}
+		req.ID = line.ID
+
+		ctx, cancel := context.WithCancel(context.Background())
+		run := &runningRequest{id: line.ID, cancel: cancel}
+		mu.Lock()
+		if previous, ok := running[line.ID]; ok {
+			previous.cancel() // the ID is reused: the new request replaces the old one
+		}
+		running[line.ID] = run
+		mu.Unlock()
+
+		wg.Go(func() {
+			defer func() {
+				mu.Lock()
+				defer mu.Unlock()
+				cancel()
+				if running[line.ID] == run {
+					delete(running, line.ID)
+				}
+			}()
+			ended := false
+			ts.streamTo(ctx, req, func(event string, payload any) {
+				mu.Lock()
+				defer mu.Unlock()
+				if ended {
+					return
+				}
+				enc.Encode(StdioResponse{ID: line.ID, Event: event, Data: payload})
+				ended = event == "done" || event == "error"
+			})
+		})
+	}
+	wg.Wait()
+	return scanner.Err()
+}
+
+// ServeLSP runs a minimal language server on r and w offering "translate
+// selection" and "translate comment" code actions. Unless the editor sets
+// initializationOptions.targetLang, it translates into the UI language.
+func ServeLSP(ts *TranslateService, r io.Reader, w io.Writer) error {
+	translate := func(ctx context.Context, text, targetLang string) (string, error) {
+		res, _, err := ts.translateOnce(ctx, engine.PriorityInteractive, engine.Request{
+			Text:       text,
+			SourceLang: "auto",
+			TargetLang: targetLang,
+		})
+		return res.Text, err
+	}
+	return lsp.NewServer(r, w, translate, uiLanguage(ts.cfg.Snapshot().General)).Run()
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/ironpark/tons/internal/engine"
+	"github.com/ironpark/tons/internal/textdiff"
+)
+
+// TranslateDelta is the payload of "translate:delta" events
+type TranslateDelta struct {
+	RequestID   string `json:"requestId"`
+	Engine      string `json:"engine"`
+	Delta       string `json:"delta"` // text added by this event
+	Text        string `json:"text"`  // translation so far
+	Cached      bool   `json:"cached,omitempty"`
+	FromHistory bool   `json:"fromHistory,omitempty"`
+}
+
+// TranslateDone is the payload of "translate:done" events, sent once per
+// request after its last delta
+type TranslateDone struct {
+	RequestID    string          `json:"requestId"`
+	Engine       string          `json:"engine"`
+	Text         string          `json:"text"` // complete translation
+	DetectedLang string          `json:"detectedLang,omitempty"`
+	Skipped      bool            `json:"skipped,omitempty"`
+	Usage        *engine.Usage   `json:"usage,omitempty"`
+	Quality      *engine.Quality `json:"quality,omitempty"`
+	Confidence   *float64        `json:"confidence,omitempty"`  // 0 to 1, where the engine or quality estimation tells
+	Cached       bool            `json:"cached,omitempty"`      // replayed from the translation cache
+	FromHistory  bool            `json:"fromHistory,omitempty"` // repeated from a recent identical translation in the history
+	Violations   []string        `json:"violations,omitempty"`  // output constraints of the request the translation breaks
+}
+
+// TranslateError is the payload of "translate:error" events. No
+// "translate:done" event follows for the request.
+type TranslateError struct {
+	RequestID string `json:"requestId"`
+	Engine    string `json:"engine"`
+	Error     string `json:"error"`
+	Text      string `json:"text"`      // partial translation received before the error
+	Cancelled bool   `json:"cancelled"` // superseded by a newer request in the same pane, or cancelled
+	Partial   bool   `json:"partial"`   // stopped by a timeout or cancellation; Text is usable but incomplete
+}
+
+// TranslateQueued is the payload of "translate:queued" events, sent while a
+// request waits for the engine
+type TranslateQueued struct {
+	RequestID string `json:"requestId"`
+	Position  int    `json:"position"` // 1-based place in the queue, 0 once the request starts
+}
+
+// TranslateProgress is the payload of "translate:progress" events, sent
+// while a long text is translated in chunks: once with the chunk count
+// before the first delta, then with each chunk
+type TranslateProgress struct {
+	RequestID string          `json:"requestId"`
+	Progress  engine.Progress `json:"progress"`
+	Percent   float64         `json:"percent"`
+}
+
+func newTranslateProgress(requestID string, p engine.Progress) TranslateProgress {
+	return TranslateProgress{RequestID: requestID, Progress: p, Percent: p.Percent()}
+}
+
+// TranslateLanguageWarning is the payload of "translate:language" events,
+// sent before the first delta when the engine handles the language pair
+// poorly
+type TranslateLanguageWarning struct {
+	RequestID string                 `json:"requestId"`
+	Support   engine.LanguageSupport `json:"support"`
+}
+
+// TranslateAnnotation is the payload of "translate:annotation" events, sent
+// after "translate:done" when reading annotations are enabled
+type TranslateAnnotation struct {
+	RequestID  string            `json:"requestId"`
+	Annotation engine.Annotation `json:"annotation"`
+}
+
+// TranslateSwapped is the payload of "translate:swapped" events, sent when a
+// pane's languages are swapped to translate its last output back
+type TranslateSwapped struct {
+	Pane       string `json:"pane"`
+	SourceLang string `json:"sourceLang"`
+	TargetLang string `json:"targetLang"`
+	Text       string `json:"text"` // new source text: the previous translation
+}
+
+// TranslateDiff is the payload of "translate:diff" events, sent after
+// "translate:done" when a pane's text is translated again into the same
+// language and the translation changed
+type TranslateDiff struct {
+	RequestID string        `json:"requestId"`
+	Pane      string        `json:"pane"`
+	Previous  string        `json:"previous"` // the pane's previous translation
+	Ops       []textdiff.Op `json:"ops"`      // word-level changes from Previous to the new translation
+}
+
+// newRequestID returns a random identifier for a streamed request
+func newRequestID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// addUsage sums usage reported in several responses, e.g. one per chunk
+func addUsage(total *engine.Usage, u engine.Usage) *engine.Usage {
+	if total == nil {
+		return &u
+	}
+	total.PromptTokens += u.PromptTokens
+	total.CompletionTokens += u.CompletionTokens
+	total.LoadDuration += u.LoadDuration
+	total.PromptEvalDuration += u.PromptEvalDuration
+	total.EvalDuration += u.EvalDuration
+	total.TotalDuration += u.TotalDuration
+	return total
+}
@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ironpark/tons/internal/config"
+	"github.com/ironpark/tons/internal/metrics"
+	"github.com/ironpark/tons/internal/telemetry"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// telemetryInterval is how often usage reports are sent
+const telemetryInterval = 6 * time.Hour
+
+// TelemetryPreview is the report that would be sent next
+type TelemetryPreview struct {
+	Report  telemetry.Report `json:"report"`
+	Enabled bool             `json:"enabled"` // whether it will be sent
+	Reason  string           `json:"reason"`  // why not, if it will not
+}
+
+// TelemetryService sends the opt-in anonymous usage reports. Nothing is sent
+// unless telemetry is enabled, an endpoint is set and DO_NOT_TRACK is unset.
+type TelemetryService struct {
+	cfg      *config.Config
+	recorder *metrics.Recorder
+	cancel   context.CancelFunc
+	done     chan struct{}
+
+	mu       sync.Mutex
+	since    time.Time
+	baseline []metrics.EngineStats // the statistics at the last sent report
+}
+
+func NewTelemetryService(cfg *config.Config, recorder *metrics.Recorder) *TelemetryService {
+	return &TelemetryService{
+		cfg:      cfg,
+		recorder: recorder,
+		since:    time.Now(),
+	}
+}
+
+// GetTelemetryPreview returns the report that would be sent next, so the
+// user can see exactly what leaves the machine
+func (tl *TelemetryService) GetTelemetryPreview() TelemetryPreview {
+	report, _ := tl.report()
+	preview := TelemetryPreview{Report: report, Reason: tl.disabledReason()}
+	preview.Enabled = preview.Reason == ""
+	if report.InstallID == "" {
+		preview.Report.InstallID = "(generated on the first report)"
+	}
+	return preview
+}
+
+// disabledReason returns why reports are not sent, or "" if they are
+func (tl *TelemetryService) disabledReason() string {
+	settings := tl.cfg.Snapshot().Telemetry
+	switch {
+	case !settings.Enabled:
+		return "telemetry is turned off"
+	case settings.Endpoint == "":
+		return "no endpoint is set"
+	case os.Getenv("DO_NOT_TRACK") != "" && os.Getenv("DO_NOT_TRACK") != "0":
+		return "DO_NOT_TRACK is set"
+	}
+	return ""
+}
+
+// report builds the report of the usage since the last sent one and returns
+// it with the statistics it was built from
+func (tl *TelemetryService) report() (telemetry.Report, []metrics.EngineStats) {
+	tl.mu.Lock()
+	defer tl.mu.Unlock()
+
+	snapshot := tl.cfg.Snapshot()
+	stats := tl.recorder.Stats()
+	return telemetry.New(snapshot.Telemetry.InstallID, string(snapshot.Engine.Type), tl.since, stats, tl.baseline), stats
+}
+
+// send sends the report of the usage since the last one, if telemetry is
+// enabled and anything was used
+func (tl *TelemetryService) send(ctx context.Context) error {
+	if tl.disabledReason() != "" {
+		return nil
+	}
+	settings := tl.cfg.Snapshot().Telemetry
+	if settings.InstallID == "" {
+		id, err := newAPIToken()
+		if err != nil {
+			return err
+		}
+		settings.InstallID = id[:32]
+		tl.cfg.SetTelemetry(settings)
+		if err := tl.cfg.Save(); err != nil {
+			return err
+		}
+	}
+
+	report, stats := tl.report()
+	if report.Empty() {
+		return nil
+	}
+	if err := telemetry.Send(ctx, settings.Endpoint, report); err != nil {
+		return err
+	}
+	tl.mu.Lock()
+	tl.since, tl.baseline = time.Now(), stats
+	tl.mu.Unlock()
+	return nil
+}
+
+// run sends a report every telemetryInterval until ctx is done
+func (tl *TelemetryService) run(ctx context.Context) {
+	defer close(tl.done)
+	ticker := time.NewTicker(telemetryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			sendCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+			if err := tl.send(sendCtx); err != nil {
+				slog.Warn("failed to send usage report", "error", err)
+			}
+			cancel()
+		}
+	}
+}
+
+// ServiceStartup starts sending reports in the background
+func (tl *TelemetryService) ServiceStartup(ctx context.Context, options application.ServiceOptions) error {
+	ctx, tl.cancel = context.WithCancel(context.Background())
+	tl.done = make(chan struct{})
+	go tl.run(ctx)
+	return nil
+}
+
+// ServiceShutdown sends the usage since the last report, giving up quickly
+// so quitting is not held up by the network
+func (tl *TelemetryService) ServiceShutdown() error {
+	if tl.cancel == nil {
+		return nil
+	}
+	tl.cancel()
+	<-tl.done
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := tl.send(ctx); err != nil {
+		slog.Warn("failed to send usage report", "error", err)
+	}
+	return nil
+}
@@ -2,57 +2,1047 @@ package services
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/ironpark/tons/internal/cache"
 	"github.com/ironpark/tons/internal/config"
 	"github.com/ironpark/tons/internal/engine"
+	"github.com/ironpark/tons/internal/history"
+	"github.com/ironpark/tons/internal/langdetect"
+	"github.com/ironpark/tons/internal/metrics"
+	"github.com/ironpark/tons/internal/pipeline"
+	"github.com/ironpark/tons/internal/textdiff"
+	"github.com/ironpark/tons/internal/tm"
+	"github.com/ironpark/tons/internal/usage"
 	"github.com/wailsapp/wails/v3/pkg/application"
 )
 
 type TranslateService struct {
-	cfg *config.Config
-	app *application.App
+	cfg     *config.Config
+	metrics *metrics.Recorder
+	history *history.Store
+	memory  *tm.Store
+	cache   *cache.Store
+	usage   *usageTracker
+	engines engineFactory
+	queue   *engine.Queue
+	app     *application.App
+	ctx     context.Context // cancelled on shutdown
+	stop    context.CancelFunc
+	wg      sync.WaitGroup // running translations, see work
+
+	mu             sync.Mutex
+	closing        bool
+	sessionContext string                     // context hint reused by requests that don't set one
+	running        map[string]runningRequest  // streaming request of each pane
+	last           map[string]lastTranslation // last completed translation of each pane
+	live           map[string]*liveInput      // pending translate-as-you-type input of each pane
+	panes          map[string]paneSettings    // language pair and engine of translator windows
+}
+
+// Panes that show streamed translations
+const (
+	PaneMain  = "main"
+	PanePopup = "popup"
+)
+
+// lastTranslation is a completed translation that can be swapped and translated back
+type lastTranslation struct {
+	snapshot     *config.Config
+	id           string         // request that made the translation, if the caller chose its ID
+	req          engine.Request // as requested, before defaults were applied
+	translation  string
+	detectedLang string
+	instructions []string // follow-up instructions the translation was refined with
+}
+
+// shutdownGrace is how long shutdown waits for cancelled translations to
+// wind down, longer than a terminal agent gets to exit after SIGTERM
+const shutdownGrace = 5 * time.Second
+
+// ErrShuttingDown is returned for translations requested during shutdown
+var ErrShuttingDown = errors.New("tons is shutting down")
+
+// runningRequest is a streaming translation that can be cancelled
+type runningRequest struct {
+	id     string
+	cancel context.CancelFunc
 }
 
-func NewTranslateService(cfg *config.Config) *TranslateService {
+func NewTranslateService(cfg *config.Config, recorder *metrics.Recorder, hist *history.Store, memory *tm.Store, usageStore *usage.Store, translations *cache.Store) *TranslateService {
+	ctx, stop := context.WithCancel(context.Background())
 	return &TranslateService{
-		cfg: cfg,
+		ctx:     ctx,
+		stop:    stop,
+		cfg:     cfg,
+		metrics: recorder,
+		history: hist,
+		memory:  memory,
+		cache:   translations,
+		usage:   newUsageTracker(usageStore),
+		engines: engineFactory{logger: slog.Default()},
+		running: make(map[string]runningRequest),
+		last:    make(map[string]lastTranslation),
+		live:    make(map[string]*liveInput),
+		panes:   make(map[string]paneSettings),
+		queue:   engine.NewQueue(concurrency(cfg.Snapshot())),
 	}
 }
 
 func (ts *TranslateService) Translate(sourceLang, targetLang, text string) error {
+	return ts.TranslateRequest(engine.Request{
+		Text:       text,
+		SourceLang: sourceLang,
+		TargetLang: targetLang,
+	})
+}
+
+// TranslateWithPreset translates using the named prompt preset instead of the active one
+func (ts *TranslateService) TranslateWithPreset(preset, sourceLang, targetLang, text string) error {
 	snapshot := ts.cfg.Snapshot()
-	engineCfg := snapshot.Engine
-	// for test
-	if config.EngineTerminalAgent == engineCfg.Type && engineCfg.TerminalAgent.Selected == config.AgentClaudeCode {
-		slog.Info("Try Translate using claude code")
-		cc := engine.NewClaudeCode()
-		resCh, err := cc.TranslateStream(context.Background(), engine.Request{
-			Prompt:       snapshot.Prompt.Template,
-			SystemPrompt: snapshot.Prompt.SystemPrompt,
-			Text:         text,
-			SourceLang:   sourceLang,
-			TargetLang:   targetLang,
+	if _, ok := snapshot.Prompt.Preset(preset); !ok {
+		return fmt.Errorf("%w: %s", config.ErrPresetNotFound, preset)
+	}
+	snapshot.Prompt.ActivePreset = preset
+	return ts.translate(snapshot, engine.Request{
+		Text:       text,
+		SourceLang: sourceLang,
+		TargetLang: targetLang,
+	})
+}
+
+// TranslateRequest translates with full request options (e.g. Format).
+// Prompt and SystemPrompt default to the configured templates when empty.
+func (ts *TranslateService) TranslateRequest(req engine.Request) error {
+	return ts.translate(ts.cfg.Snapshot(), req)
+}
+
+// translate streams a translation with the given configuration, emitting
+// "translate:delta" events followed by "translate:done" or "translate:error"
+func (ts *TranslateService) translate(snapshot *config.Config, req engine.Request) error {
+	if req.Pane == "" {
+		req.Pane = PaneMain
+	}
+	snapshot, req = ts.forPane(snapshot, req)
+	requested := req
+	req = ts.applyDefaults(snapshot, req)
+	if req.ID == "" {
+		req.ID = newRequestID()
+	}
+	emit := ts.emitter(req.Pane)
+	_, finish, err := ts.work(context.Background())
+	if err != nil {
+		emit("translate:error", TranslateError{RequestID: req.ID, Error: err.Error()})
+		return err
+	}
+	defer finish()
+
+	support, err := checkLanguages(snapshot, req)
+	if err != nil {
+		emit("translate:error", TranslateError{RequestID: req.ID, Error: err.Error()})
+		return err
+	}
+	if support.Support != engine.SupportGood {
+		emit("translate:language", TranslateLanguageWarning{RequestID: req.ID, Support: support})
+	}
+
+	replay := func(entry cache.Entry) {
+		done := replayCached(req, entry, func(event string, payload any) {
+			emit("translate:"+event, payload)
+		})
+		ts.emitDiff(req, done)
+		ts.remember(snapshot, requested, req.Pane, done)
+	}
+	if entry, ok := ts.fromHistory(snapshot, req); ok {
+		done := replayHistory(req, entry, func(event string, payload any) {
+			emit("translate:"+event, payload)
 		})
-		if err != nil {
-			return err
+		ts.emitDiff(req, done)
+		ts.remember(snapshot, requested, req.Pane, done)
+		return nil
+	}
+	e, err := ts.newEngine(snapshot, engine.PriorityInteractive, func(position int) {
+		emit("translate:queued", TranslateQueued{RequestID: req.ID, Position: position})
+	})
+	if err != nil {
+		if entry, ok := ts.cached(snapshot, req, "", nil); ok {
+			replay(entry)
+			return nil
+		}
+		emit("translate:error", TranslateError{RequestID: req.ID, Error: err.Error()})
+		return err
+	}
+	if entry, ok := ts.cached(snapshot, req, e.Name(), e.Available); ok {
+		replay(entry)
+		return nil
+	}
+	ctx := ts.begin(req.Pane, req.ID)
+	defer ts.end(req.Pane, req.ID)
+	start := time.Now()
+	resCh, err := e.TranslateStream(ctx, req)
+	if err != nil {
+		ts.metrics.RecordError(e.Name(), err.Error())
+		emit("translate:error", TranslateError{RequestID: req.ID, Engine: e.Name(), Error: err.Error()})
+		return err
+	}
+	done := TranslateDone{RequestID: req.ID, Engine: e.Name()}
+	var full engine.Accumulator
+	for res := range resCh {
+		if ctx.Err() != nil {
+			// Superseded: let the engine wind down without emitting its late chunks
+			for range resCh {
+			}
+			break
+		}
+		if res.DetectedLang != "" && done.DetectedLang == "" {
+			emit("translate:detected", res.DetectedLang)
+			done.DetectedLang = res.DetectedLang
+		}
+		if res.Text != "" {
+			emit("translate:delta", TranslateDelta{
+				RequestID: req.ID,
+				Engine:    e.Name(),
+				Delta:     res.Text,
+				Text:      full.Add(res),
+			})
+		}
+		if res.Usage != nil {
+			ts.metrics.Record(e.Name(), *res.Usage)
+			done.Usage = addUsage(done.Usage, *res.Usage)
+		}
+		if res.Progress != nil {
+			emit("translate:progress", newTranslateProgress(req.ID, *res.Progress))
+		}
+		if res.Quality != nil {
+			emit("translate:quality", *res.Quality)
+			done.Quality = res.Quality
+		}
+		if res.Error != "" {
+			ts.metrics.RecordError(e.Name(), res.Error)
+			if full.Len() == 0 && metrics.ErrorClass(res.Error) == "unavailable" {
+				if entry, ok := ts.cached(snapshot, req, e.Name(), nil); ok {
+					for range resCh {
+					}
+					replay(entry)
+					return nil
+				}
+			}
+			emit("translate:error", TranslateError{
+				RequestID: req.ID,
+				Engine:    e.Name(),
+				Error:     res.Error,
+				Text:      full.String(),
+				Partial:   res.Partial && full.Len() > 0,
+			})
+			for range resCh {
+			}
+			return nil
+		}
+		if res.Confidence != nil {
+			done.Confidence = res.Confidence
+		}
+		if res.Violations != nil {
+			done.Violations = res.Violations
+		}
+		if res.Skipped && res.Done {
+			emit("translate:skipped", req.TargetLang)
+			done.Skipped = true
+		}
+	}
+	if ctx.Err() != nil {
+		emit("translate:error", TranslateError{
+			RequestID: req.ID,
+			Engine:    e.Name(),
+			Error:     ctx.Err().Error(),
+			Text:      full.String(),
+			Cancelled: true,
+			Partial:   full.Len() > 0,
+		})
+		return nil
+	}
+	ts.metrics.RecordLatency(e.Name(), time.Since(start))
+	done.Text = full.String()
+	ts.recordHistory(req, done.Text, e.Name(), done.Quality, done.Confidence)
+	ts.recordUsage(snapshot, e.Name(), req.Text, done.Usage)
+	ts.storeCache(snapshot, req, done)
+	emit("translate:done", done)
+	ts.emitDiff(req, done)
+	ts.remember(snapshot, requested, req.Pane, done)
+
+	if snapshot.Translation.Annotate && !done.Skipped {
+		if engine.ReadingSystem(langdetect.Code(req.TargetLang)) != "" {
+			annotation, err := ts.annotate(snapshot, done.Text, req.TargetLang)
+			if err != nil {
+				slog.Warn("failed to annotate translation", "error", err)
+				return nil
+			}
+			emit("translate:annotation", TranslateAnnotation{RequestID: req.ID, Annotation: annotation})
+		}
+	}
+	return nil
+}
+
+// streamTo streams a translation to send instead of app events, e.g. for API
+// clients. send receives "language", "queued", "delta", "progress", "done" and "error"
+// with the payloads of the matching "translate:*" events; "done" or "error"
+// comes last.
+func (ts *TranslateService) streamTo(ctx context.Context, req engine.Request, send func(event string, payload any)) {
+	snapshot := ts.cfg.Snapshot()
+	req = ts.applyDefaults(snapshot, req)
+	if req.ID == "" {
+		req.ID = newRequestID()
+	}
+	ctx, finish, err := ts.work(ctx)
+	if err != nil {
+		send("error", TranslateError{RequestID: req.ID, Error: err.Error()})
+		return
+	}
+	defer finish()
+
+	support, err := checkLanguages(snapshot, req)
+	if err != nil {
+		send("error", TranslateError{RequestID: req.ID, Error: err.Error()})
+		return
+	}
+	if support.Support != engine.SupportGood {
+		send("language", TranslateLanguageWarning{RequestID: req.ID, Support: support})
+	}
+	if entry, ok := ts.fromHistory(snapshot, req); ok {
+		replayHistory(req, entry, send)
+		return
+	}
+
+	e, err := ts.newEngine(snapshot, engine.PriorityInteractive, func(position int) {
+		send("queued", TranslateQueued{RequestID: req.ID, Position: position})
+	})
+	if err != nil {
+		if entry, ok := ts.cached(snapshot, req, "", nil); ok {
+			replayCached(req, entry, send)
+			return
+		}
+		send("error", TranslateError{RequestID: req.ID, Error: err.Error()})
+		return
+	}
+	if entry, ok := ts.cached(snapshot, req, e.Name(), e.Available); ok {
+		replayCached(req, entry, send)
+		return
+	}
+	start := time.Now()
+	resCh, err := e.TranslateStream(ctx, req)
+	if err != nil {
+		ts.metrics.RecordError(e.Name(), err.Error())
+		send("error", TranslateError{RequestID: req.ID, Engine: e.Name(), Error: err.Error()})
+		return
+	}
+	done := TranslateDone{RequestID: req.ID, Engine: e.Name()}
+	var full engine.Accumulator
+	for res := range resCh {
+		if res.DetectedLang != "" && done.DetectedLang == "" {
+			done.DetectedLang = res.DetectedLang
+		}
+		if res.Text != "" {
+			send("delta", TranslateDelta{
+				RequestID: req.ID,
+				Engine:    e.Name(),
+				Delta:     res.Text,
+				Text:      full.Add(res),
+			})
+		}
+		if res.Usage != nil {
+			ts.metrics.Record(e.Name(), *res.Usage)
+			done.Usage = addUsage(done.Usage, *res.Usage)
 		}
-		for res := range resCh {
-			if res.Text != "" {
-				ts.app.Event.Emit("translate", res.Text)
+		if res.Progress != nil {
+			send("progress", newTranslateProgress(req.ID, *res.Progress))
+		}
+		if res.Quality != nil {
+			done.Quality = res.Quality
+		}
+		if res.Error != "" {
+			ts.metrics.RecordError(e.Name(), res.Error)
+			if full.Len() == 0 && metrics.ErrorClass(res.Error) == "unavailable" {
+				if entry, ok := ts.cached(snapshot, req, e.Name(), nil); ok {
+					for range resCh {
+					}
+					replayCached(req, entry, send)
+					return
+				}
 			}
+			send("error", TranslateError{
+				RequestID: req.ID,
+				Engine:    e.Name(),
+				Error:     res.Error,
+				Text:      full.String(),
+				Cancelled: ctx.Err() != nil,
+				Partial:   res.Partial && full.Len() > 0,
+			})
+			for range resCh {
+			}
+			return
+		}
+		if res.Confidence != nil {
+			done.Confidence = res.Confidence
 		}
+		if res.Violations != nil {
+			done.Violations = res.Violations
+		}
+		if res.Skipped && res.Done {
+			done.Skipped = true
+		}
+	}
+	if ctx.Err() != nil {
+		send("error", TranslateError{
+			RequestID: req.ID,
+			Engine:    e.Name(),
+			Error:     ctx.Err().Error(),
+			Text:      full.String(),
+			Cancelled: true,
+			Partial:   full.Len() > 0,
+		})
+		return
+	}
+	ts.metrics.RecordLatency(e.Name(), time.Since(start))
+	done.Text = full.String()
+	ts.recordUsage(snapshot, e.Name(), req.Text, done.Usage)
+	ts.storeCache(snapshot, req, done)
+	send("done", done)
+}
+
+// remember keeps a completed translation of a pane for SwapAndRetranslate
+func (ts *TranslateService) remember(snapshot *config.Config, req engine.Request, pane string, done TranslateDone) {
+	if done.Skipped || done.Text == "" {
+		return
+	}
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	id := req.ID
+	req.ID, req.Pane = "", pane
+	ts.last[pane] = lastTranslation{
+		snapshot:     snapshot,
+		id:           id,
+		req:          req,
+		translation:  done.Text,
+		detectedLang: done.DetectedLang,
+	}
+}
+
+// emitDiff emits "translate:diff" if the pane's previous translation went
+// into the same language and differs from done, so the UI can highlight
+// what an edit of the source text changed. Call it before remember.
+func (ts *TranslateService) emitDiff(req engine.Request, done TranslateDone) {
+	if done.Skipped || done.Text == "" {
+		return
+	}
+	ts.mu.Lock()
+	last, ok := ts.last[req.Pane]
+	ts.mu.Unlock()
+	if !ok || last.req.TargetLang != req.TargetLang || last.translation == done.Text {
+		return
+	}
+	ts.emitter(req.Pane)("translate:diff", TranslateDiff{
+		RequestID: req.ID,
+		Pane:      req.Pane,
+		Previous:  last.translation,
+		Ops:       textdiff.Words(last.translation, done.Text),
+	})
+}
+
+// DiffTranslations returns the word-level changes from one translation to
+// another, e.g. to compare two history entries
+func (ts *TranslateService) DiffTranslations(previous, current string) []textdiff.Op {
+	return textdiff.Words(previous, current)
+}
+
+// SwapAndRetranslate swaps the languages of the pane's last translation and
+// translates its output back with the same engine and settings. Emits
+// "translate:swapped" with the new language pair and text before streaming.
+func (ts *TranslateService) SwapAndRetranslate(pane string) error {
+	if pane == "" {
+		pane = PaneMain
+	}
+	ts.mu.Lock()
+	last, ok := ts.last[pane]
+	ts.mu.Unlock()
+	if !ok {
+		return errors.New("nothing to translate back")
+	}
+
+	req := last.req
+	req.Text = last.translation
+	req.SourceLang, req.TargetLang = last.req.TargetLang, last.req.SourceLang
+	if (req.TargetLang == "" || req.TargetLang == langdetect.Auto) && last.detectedLang != "" {
+		req.TargetLang = last.detectedLang
+	}
+	ts.emitter(pane)("translate:swapped", TranslateSwapped{
+		Pane:       pane,
+		SourceLang: req.SourceLang,
+		TargetLang: req.TargetLang,
+		Text:       req.Text,
+	})
+	return ts.translate(last.snapshot, req)
+}
+
+// RefineTranslation revises the pane's last translation following a
+// follow-up instruction, such as "more formal" or "keep 'dashboard'
+// untranslated", with the same engine and settings. The revision streams
+// like a translation; earlier instructions for the same text still apply.
+func (ts *TranslateService) RefineTranslation(pane, instruction string) error {
+	if pane == "" {
+		pane = PaneMain
+	}
+	instruction = strings.TrimSpace(instruction)
+	if instruction == "" {
+		return errors.New("instruction is empty")
+	}
+	ts.mu.Lock()
+	last, ok := ts.last[pane]
+	ts.mu.Unlock()
+	if !ok {
+		return errors.New("nothing to refine")
+	}
+
+	instructions := append(slices.Clip(last.instructions), instruction)
+	req := engine.RefineRequest(last.req, last.translation, instructions)
+	req.ID = newRequestID()
+	if err := ts.translate(last.snapshot, req); err != nil {
+		return err
+	}
+
+	// Keep the original request, so the next instruction refines the text
+	// rather than this revision request
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+	if cur, ok := ts.last[pane]; ok && cur.id == req.ID {
+		cur.req = last.req
+		cur.instructions = instructions
+		ts.last[pane] = cur
 	}
 	return nil
 }
 
-// ServiceStartup is called when the service starts
+// begin registers a streaming request for its pane, cancelling the one it supersedes
+func (ts *TranslateService) begin(pane, id string) context.Context {
+	ctx, cancel := context.WithCancel(ts.ctx)
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if prev, ok := ts.running[pane]; ok {
+		prev.cancel()
+	}
+	ts.running[pane] = runningRequest{id: id, cancel: cancel}
+	return ctx
+}
+
+// end unregisters a finished request unless it was already superseded
+func (ts *TranslateService) end(pane, id string) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if r, ok := ts.running[pane]; ok && r.id == id {
+		r.cancel()
+		delete(ts.running, pane)
+	}
+}
+
+// CancelTranslation stops the translation streaming into the pane, if any
+func (ts *TranslateService) CancelTranslation(pane string) {
+	if pane == "" {
+		pane = PaneMain
+	}
+
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if r, ok := ts.running[pane]; ok {
+		r.cancel()
+		delete(ts.running, pane)
+	}
+}
+
+// recordUsage counts the characters and tokens of a translation, emitting
+// "usage:warning" events for budgets it brings close to or over their limit
+func (ts *TranslateService) recordUsage(snapshot *config.Config, engineName, text string, tokens *engine.Usage) {
+	for _, warning := range ts.usage.record(snapshot.Usage, engineName, text, tokens) {
+		slog.Warn("usage budget", "engine", warning.Budget.Engine, "period", warning.Budget.Period,
+			"metric", warning.Metric, "used", warning.Used, "limit", warning.Limit)
+		if ts.app != nil {
+			ts.app.Event.Emit("usage:warning", warning)
+		}
+	}
+}
+
+// recordHistory stores a completed translation
+func (ts *TranslateService) recordHistory(req engine.Request, translation, engineName string, quality *engine.Quality, confidence *float64) {
+	if translation == "" {
+		return
+	}
+	_, err := ts.history.Add(history.Entry{
+		SourceLang:  req.SourceLang,
+		TargetLang:  req.TargetLang,
+		Text:        req.Text,
+		Translation: translation,
+		Engine:      engineName,
+		Quality:     quality,
+		Confidence:  confidence,
+		PromptHash:  promptHash(req),
+	})
+	if err != nil {
+		slog.Warn("failed to save history", "error", err)
+	}
+}
+
+// TranslateText translates text without streaming or events and returns the result.
+// Format selects structure-preserving translation, e.g. engine.FormatMarkdown.
+func (ts *TranslateService) TranslateText(sourceLang, targetLang, text string, format engine.Format) (string, error) {
+	return ts.translateText(context.Background(), engine.PriorityInteractive, sourceLang, targetLang, text, format)
+}
+
+// translateText is TranslateText with a context and queue priority
+func (ts *TranslateService) translateText(ctx context.Context, priority engine.Priority, sourceLang, targetLang, text string, format engine.Format) (string, error) {
+	res, _, err := ts.translateOnce(ctx, priority, engine.Request{
+		Text:       text,
+		SourceLang: sourceLang,
+		TargetLang: targetLang,
+		Format:     format,
+	})
+	if err != nil {
+		return "", err
+	}
+	return res.Text, nil
+}
+
+// translateOnce translates req without streaming or events, returning the
+// response and the name of the engine that produced it
+func (ts *TranslateService) translateOnce(ctx context.Context, priority engine.Priority, req engine.Request) (engine.Response, string, error) {
+	snapshot := ts.cfg.Snapshot()
+	req = ts.applyDefaults(snapshot, req)
+	ctx, finish, err := ts.work(ctx)
+	if err != nil {
+		return engine.Response{}, "", err
+	}
+	defer finish()
+
+	e, err := ts.newEngine(snapshot, priority, nil)
+	if err != nil {
+		return engine.Response{}, "", err
+	}
+	start := time.Now()
+	res, err := e.Translate(ctx, req)
+	if err == nil && res.Error != "" {
+		err = errors.New(res.Error)
+	}
+	if err != nil {
+		ts.metrics.RecordError(e.Name(), err.Error())
+		return engine.Response{}, e.Name(), err
+	}
+	ts.metrics.RecordLatency(e.Name(), time.Since(start))
+	if res.Usage != nil {
+		ts.metrics.Record(e.Name(), *res.Usage)
+	}
+	ts.recordUsage(snapshot, e.Name(), req.Text, res.Usage)
+	return res, e.Name(), nil
+}
+
+// TranslateSegments translates independent segments, e.g. the messages of a
+// localization file, returning one translation per segment. Segments wait
+// behind interactive requests for the engine.
+func (ts *TranslateService) TranslateSegments(sourceLang, targetLang string, segments []string) ([]string, error) {
+	return ts.translateSegments(context.Background(), sourceLang, targetLang, segments)
+}
+
+// translateSegments is TranslateSegments with a context
+func (ts *TranslateService) translateSegments(ctx context.Context, sourceLang, targetLang string, segments []string) ([]string, error) {
+	translated, _, err := ts.translateSegmentsConfidence(ctx, sourceLang, targetLang, segments)
+	return translated, err
+}
+
+// translateSegmentsConfidence is translateSegments that also returns the
+// confidence in each translation, nil where unknown
+func (ts *TranslateService) translateSegmentsConfidence(ctx context.Context, sourceLang, targetLang string, segments []string) ([]string, []*float64, error) {
+	snapshot := ts.cfg.Snapshot()
+	req := ts.applyDefaults(snapshot, engine.Request{
+		SourceLang: sourceLang,
+		TargetLang: targetLang,
+	})
+
+	ctx, finish, err := ts.work(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer finish()
+
+	e, err := ts.newEngine(snapshot, engine.PriorityBackground, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	translated, confidence, err := engine.TranslateSegmentsConfidence(ctx, e, req, segments)
+	if err != nil {
+		return nil, nil, err
+	}
+	ts.recordUsage(snapshot, e.Name(), strings.Join(segments, ""), nil)
+	return translated, confidence, nil
+}
+
+// TranslateMulti translates text into several target languages concurrently,
+// emitting "translate:multi" events tagged with the target language.
+// If targetLangs is empty the configured multi-target list is used.
+func (ts *TranslateService) TranslateMulti(sourceLang string, targetLangs []string, text string) error {
+	snapshot := ts.cfg.Snapshot()
+	if len(targetLangs) == 0 {
+		targetLangs = snapshot.Translation.MultiTargets
+	}
+	req := ts.applyDefaults(snapshot, engine.Request{
+		Text:       text,
+		SourceLang: sourceLang,
+	})
+
+	ctx, finish, err := ts.work(context.Background())
+	if err != nil {
+		return err
+	}
+	defer finish()
+
+	e, err := ts.newEngine(snapshot, engine.PriorityInteractive, nil)
+	if err != nil {
+		return err
+	}
+	for res := range engine.TranslateMulti(ctx, e, req, targetLangs, snapshot.Translation.MultiTargetParallel) {
+		ts.app.Event.Emit("translate:multi", res)
+		if res.Usage != nil {
+			ts.metrics.Record(e.Name(), *res.Usage)
+		}
+		if res.Done && res.Error == "" {
+			ts.recordUsage(snapshot, e.Name(), req.Text, res.Usage)
+		}
+	}
+	return nil
+}
+
+// applyDefaults fills unset request options from the active preset, then the configuration
+func (ts *TranslateService) applyDefaults(snapshot *config.Config, req engine.Request) engine.Request {
+	if preset, ok := snapshot.Prompt.Preset(snapshot.Prompt.ActivePreset); ok {
+		if req.Prompt == "" {
+			req.Prompt = preset.Template
+		}
+		if req.SystemPrompt == "" {
+			req.SystemPrompt = preset.SystemPrompt
+		}
+		if req.Formality == engine.FormalityDefault {
+			req.Formality = engine.Formality(preset.Formality)
+		}
+		if req.Tone == "" {
+			req.Tone = preset.Tone
+		}
+	}
+	if req.Prompt == "" {
+		req.Prompt = snapshot.Prompt.ResolveTemplate(snapshot.Engine.Type, req.SourceLang, req.TargetLang)
+	}
+	if req.SystemPrompt == "" {
+		req.SystemPrompt = snapshot.Prompt.SystemPrompt
+	}
+	if req.Formality == engine.FormalityDefault {
+		req.Formality = engine.Formality(snapshot.Translation.Formality)
+	}
+	if req.Tone == "" {
+		req.Tone = snapshot.Translation.Tone
+	}
+	if req.Context == "" {
+		req.Context = ts.GetSessionContext()
+	}
+	if req.Examples == nil {
+		for _, ex := range snapshot.Prompt.Examples {
+			req.Examples = append(req.Examples, engine.Example(ex))
+		}
+	}
+	if req.Glossary == nil {
+		for _, term := range snapshot.Prompt.Glossary {
+			req.Glossary = append(req.Glossary, engine.GlossaryTerm(term))
+		}
+	}
+	if req.Variables == nil {
+		req.Variables = snapshot.Prompt.Variables
+	}
+	return req
+}
+
+// Explain translates text and explains its grammar and nuance in the UI language
+func (ts *TranslateService) Explain(sourceLang, targetLang, text string) (engine.Explanation, error) {
+	snapshot := ts.cfg.Snapshot()
+	req := ts.applyDefaults(snapshot, engine.Request{
+		Text:       text,
+		SourceLang: sourceLang,
+		TargetLang: targetLang,
+	})
+
+	ctx, finish, err := ts.work(context.Background())
+	if err != nil {
+		return engine.Explanation{}, err
+	}
+	defer finish()
+
+	base, err := ts.engines.get(snapshot)
+	if err != nil {
+		return engine.Explanation{}, err
+	}
+	ts.queue.SetLimit(concurrency(snapshot))
+	e := engine.NewAutoDetect(scrubbed(snapshot, engine.NewQueued(base, ts.queue, engine.PriorityInteractive)))
+	ex, err := engine.Explain(ctx, e, req, uiLanguage(snapshot.General))
+	if err != nil {
+		return engine.Explanation{}, err
+	}
+	ts.recordHistory(req, ex.Translation, e.Name(), nil, nil)
+	return ex, nil
+}
+
+// Annotate adds furigana (Japanese), pinyin (Chinese) or romanization (Korean)
+// readings to text in the given language
+func (ts *TranslateService) Annotate(text, lang string) (engine.Annotation, error) {
+	return ts.annotate(ts.cfg.Snapshot(), text, lang)
+}
+
+// annotate asks the configured engine for the readings of text
+func (ts *TranslateService) annotate(snapshot *config.Config, text, lang string) (engine.Annotation, error) {
+	ctx, finish, err := ts.work(context.Background())
+	if err != nil {
+		return engine.Annotation{}, err
+	}
+	defer finish()
+
+	base, err := ts.engines.get(snapshot)
+	if err != nil {
+		return engine.Annotation{}, err
+	}
+	ts.queue.SetLimit(concurrency(snapshot))
+	e := scrubbed(snapshot, engine.NewQueued(base, ts.queue, engine.PriorityInteractive))
+	return engine.Annotate(ctx, e, text, langdetect.Code(lang))
+}
+
+// studyNote asks the engine for the romanization of text and an example sentence using it
+func (ts *TranslateService) studyNote(sourceLang, targetLang, text string) (engine.StudyNote, error) {
+	snapshot := ts.cfg.Snapshot()
+	ctx, finish, err := ts.work(context.Background())
+	if err != nil {
+		return engine.StudyNote{}, err
+	}
+	defer finish()
+
+	base, err := ts.engines.get(snapshot)
+	if err != nil {
+		return engine.StudyNote{}, err
+	}
+	ts.queue.SetLimit(concurrency(snapshot))
+	e := scrubbed(snapshot, engine.NewQueued(base, ts.queue, engine.PriorityBackground))
+	return engine.Study(ctx, e, engine.Request{
+		Text:       text,
+		SourceLang: sourceLang,
+		TargetLang: targetLang,
+	})
+}
+
+// quiz asks the engine for quiz questions on translated texts, explained in the UI language
+func (ts *TranslateService) quiz(kind string, items []engine.QuizItem) (engine.Quiz, error) {
+	snapshot := ts.cfg.Snapshot()
+	ctx, finish, err := ts.work(context.Background())
+	if err != nil {
+		return engine.Quiz{}, err
+	}
+	defer finish()
+
+	base, err := ts.engines.get(snapshot)
+	if err != nil {
+		return engine.Quiz{}, err
+	}
+	ts.queue.SetLimit(concurrency(snapshot))
+	e := scrubbed(snapshot, engine.NewQueued(base, ts.queue, engine.PriorityInteractive))
+	return engine.GenerateQuiz(ctx, e, kind, items, uiLanguage(snapshot.General))
+}
+
+// uiLanguage returns the name of the language the UI is shown in
+func uiLanguage(general config.GeneralConfig) string {
+	lang := general.Language
+	if lang == "" || lang == "system" {
+		lang = os.Getenv("LANG")
+	}
+	if code := langdetect.Code(lang); code != langdetect.Unknown {
+		return langdetect.Name(code)
+	}
+	return "English"
+}
+
+// newEngine builds the processing chain around the configured engine. Requests
+// wait in the engine queue with the given priority, reporting their position
+// to onPosition (optional).
+func (ts *TranslateService) newEngine(snapshot *config.Config, priority engine.Priority, onPosition func(int)) (engine.Engine, error) {
+	base, err := ts.engines.get(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	if router, ok := base.(*engine.SizeRouter); ok && priority == engine.PriorityBackground && snapshot.Engine.Smart.Documents {
+		base = router.Long
+	}
+	ts.queue.SetLimit(concurrency(snapshot))
+
+	queued := engine.NewQueued(engine.NewChunked(base, 1), ts.queue, priority)
+	queued.OnPosition = onPosition
+	var e engine.Engine = queued
+	e = engine.NewFormatAware(e)
+	e = engine.NewConstrained(pipeline.New(e, processors(snapshot)...))
+	if snapshot.Translation.QualityEstimation {
+		estimator := engine.NewQualityEstimator(e)
+		// The rating is JSON; text processors like punctuation localization
+		// would break it. Personal data is still masked, in the source and
+		// in the translation being rated.
+		estimator.Judge = scrubbed(snapshot, queued)
+		e = estimator
+	}
+	if snapshot.Translation.SkipSameLanguage {
+		e = engine.NewSkipUnneeded(e)
+	}
+	return engine.NewAutoDetect(e), nil
+}
+
+// SetSessionContext remembers a context hint (e.g. "video game dialogue") for
+// subsequent translations in this session
+func (ts *TranslateService) SetSessionContext(hint string) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	ts.sessionContext = hint
+}
+
+// GetSessionContext returns the remembered context hint
+func (ts *TranslateService) GetSessionContext() string {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	return ts.sessionContext
+}
+
+// warmupTimeout bounds loading the engine, e.g. a large local model
+const warmupTimeout = 5 * time.Minute
+
+// EngineWarmup is the payload of "engine:warmup" events, sent when the
+// engine is ready for the first translation or failed to get ready
+type EngineWarmup struct {
+	Engine     string `json:"engine"`
+	DurationMs int64  `json:"durationMs"`
+	Error      string `json:"error,omitempty"`
+}
+
+// WarmupEngine prepares the configured engine, e.g. loads its model, so the
+// first translation isn't much slower than the ones after it
+func (ts *TranslateService) WarmupEngine() error {
+	e, err := ts.engines.get(ts.cfg.Snapshot())
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), warmupTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err = engine.Warmup(ctx, e)
+	event := EngineWarmup{Engine: e.Name(), DurationMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		event.Error = err.Error()
+	} else {
+		slog.Info("engine warmed up", "engine", e.Name(), "took", time.Since(start))
+	}
+	if ts.app != nil {
+		ts.app.Event.Emit("engine:warmup", event)
+	}
+	return err
+}
+
+// warmup warms the engine up in the background if enabled in the settings
+func (ts *TranslateService) warmup() {
+	if !ts.cfg.Snapshot().Engine.Warmup {
+		return
+	}
+	go func() {
+		if err := ts.WarmupEngine(); err != nil {
+			slog.Warn("engine warmup failed", "error", err)
+		}
+	}()
+}
+
+// ServiceStartup is called when the service starts. The engine is warmed
+// up now and after each change of the engine settings.
 func (ts *TranslateService) ServiceStartup(ctx context.Context, options application.ServiceOptions) error {
 	// Store the application instance for later use
 	ts.app = application.Get()
+	ts.warmup()
+	ts.app.Event.On("config:changed", func(e *application.CustomEvent) {
+		if section, _ := e.Data.(string); section == "engine" {
+			ts.warmup()
+		}
+	})
 	return nil
 }
 
+// work registers a running translation so shutdown can wait for it. The
+// returned context ends with ctx or on shutdown; call finish when done.
+func (ts *TranslateService) work(ctx context.Context) (context.Context, func(), error) {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	if ts.closing {
+		return nil, nil, ErrShuttingDown
+	}
+	ts.wg.Add(1)
+	ctx, cancel := context.WithCancel(ctx)
+	stop := context.AfterFunc(ts.ctx, cancel)
+	return ctx, func() {
+		stop()
+		cancel()
+		ts.wg.Done()
+	}, nil
+}
+
+// ServiceShutdown drops pending as-you-type input, cancels running
+// translations (terminal agents get SIGTERM before they are killed) and
+// waits up to shutdownGrace for them to finish, then saves the translation
+// memory and cache and releases the engine, unloading a local model.
+// History and usage are written as they change.
 func (ts *TranslateService) ServiceShutdown() error {
-	return nil
+	ts.mu.Lock()
+	ts.closing = true
+	for _, in := range ts.live {
+		if in.timer != nil {
+			in.timer.Stop()
+		}
+	}
+	ts.mu.Unlock()
+	ts.stop()
+
+	drained := make(chan struct{})
+	go func() {
+		ts.wg.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-time.After(shutdownGrace):
+		slog.Warn("translations still running at shutdown")
+	}
+
+	if ts.memory != nil {
+		if err := ts.memory.Save(); err != nil {
+			slog.Warn("failed to save translation memory", "error", err)
+		}
+	}
+	if ts.cache != nil {
+		if err := ts.cache.Save(); err != nil {
+			slog.Warn("failed to save translation cache", "error", err)
+		}
+	}
+	return ts.engines.close()
 }
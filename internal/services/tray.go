@@ -0,0 +1,149 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/ironpark/tons/internal/config"
+	"github.com/ironpark/tons/internal/langdetect"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// mainWindow is the name of the main app window
+const mainWindow = "main"
+
+// TrayService shows a tray (menu bar) icon with quick actions: switching the
+// language pair and engine, pausing the clipboard monitor and translating the
+// clipboard
+type TrayService struct {
+	cfg       *config.Config
+	clipboard *ClipboardService
+	icon      []byte
+	app       *application.App
+	tray      *application.SystemTray
+}
+
+func NewTrayService(cfg *config.Config, clipboard *ClipboardService, icon []byte) *TrayService {
+	return &TrayService{
+		cfg:       cfg,
+		clipboard: clipboard,
+		icon:      icon,
+	}
+}
+
+// RefreshTray rebuilds the tray menu, e.g. after settings changed
+func (tr *TrayService) RefreshTray() {
+	if tr.tray != nil {
+		tr.tray.SetMenu(tr.menu())
+	}
+}
+
+// menu builds the tray menu from the current settings
+func (tr *TrayService) menu() *application.Menu {
+	snapshot := tr.cfg.Snapshot()
+	menu := tr.app.Menu.New()
+
+	menu.Add("Open tons").OnClick(func(*application.Context) {
+		if w, ok := tr.app.Window.GetByName(mainWindow); ok {
+			w.Show()
+			w.Focus()
+		}
+	})
+	menu.Add("Translate Clipboard Now").OnClick(func(*application.Context) {
+		if _, err := tr.clipboard.TranslateClipboardNow(); err != nil {
+			slog.Warn("clipboard translation failed", "error", err)
+		}
+	})
+	menu.AddCheckbox("Pause Clipboard Monitoring", !snapshot.Clipboard.Enabled).OnClick(func(*application.Context) {
+		tr.update("clipboard", func() {
+			tr.cfg.SetClipboardEnabled(!tr.cfg.Snapshot().Clipboard.Enabled)
+		})
+	})
+	menu.AddSeparator()
+
+	languages := menu.AddSubmenu("Languages")
+	for _, pair := range snapshot.Tray.LanguagePairs {
+		current := pair.Source == snapshot.Clipboard.SourceLang && pair.Target == snapshot.Clipboard.TargetLang
+		languages.AddRadio(pairLabel(pair), current).OnClick(func(*application.Context) {
+			tr.update("languages", func() { tr.cfg.SetLanguagePair(pair) })
+		})
+	}
+
+	engines := menu.AddSubmenu("Engine")
+	for _, e := range []struct {
+		label  string
+		engine config.EngineType
+		agent  config.TerminalAgentType
+	}{
+		{"Internal", config.EngineInternal, ""},
+		{"Ollama", config.EngineOllama, ""},
+		{"Claude Code", config.EngineTerminalAgent, config.AgentClaudeCode},
+		{"Gemini CLI", config.EngineTerminalAgent, config.AgentGeminiCLI},
+		{"Codex", config.EngineTerminalAgent, config.AgentCodex},
+		{"Smart", config.EngineSmart, ""},
+	} {
+		current := snapshot.Engine.Type == e.engine &&
+			(e.agent == "" || snapshot.Engine.TerminalAgent.Selected == e.agent)
+		engines.AddRadio(e.label, current).OnClick(func(*application.Context) {
+			tr.update("engine", func() {
+				tr.cfg.SetEngineType(e.engine)
+				if e.agent != "" {
+					tr.cfg.SetTerminalAgent(e.agent)
+				}
+			})
+		})
+	}
+
+	menu.AddSeparator()
+	menu.Add("Quit").OnClick(func(*application.Context) {
+		tr.app.Quit()
+	})
+	return menu
+}
+
+// update applies a settings change made from the tray, saves it, refreshes the
+// menu and emits "config:changed" with the changed section
+func (tr *TrayService) update(section string, apply func()) {
+	apply()
+	if err := tr.cfg.Save(); err != nil {
+		slog.Warn("failed to save settings", "error", err)
+	}
+	tr.RefreshTray()
+	tr.app.Event.Emit("config:changed", section)
+}
+
+// pairLabel returns a menu label like "Auto → English"
+func pairLabel(pair config.LanguagePair) string {
+	name := func(lang string) string {
+		if lang == langdetect.Auto {
+			return "Auto"
+		}
+		if code := langdetect.Code(lang); code != langdetect.Unknown {
+			return langdetect.Name(code)
+		}
+		return lang
+	}
+	return name(pair.Source) + " → " + name(pair.Target)
+}
+
+// ServiceStartup is called when the service starts
+func (tr *TrayService) ServiceStartup(ctx context.Context, options application.ServiceOptions) error {
+	tr.app = application.Get()
+	if !tr.cfg.Snapshot().Tray.Enabled {
+		return nil
+	}
+	tr.tray = tr.app.SystemTray.New()
+	tr.tray.SetTooltip("tons")
+	if len(tr.icon) > 0 {
+		tr.tray.SetIcon(tr.icon)
+	}
+	tr.tray.SetMenu(tr.menu())
+	return nil
+}
+
+func (tr *TrayService) ServiceShutdown() error {
+	if tr.tray != nil {
+		tr.tray.Destroy()
+	}
+	return nil
+}
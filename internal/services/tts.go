@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/ironpark/tons/internal/config"
+	"github.com/ironpark/tons/internal/langdetect"
+	"github.com/ironpark/tons/internal/speech"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+type TTSService struct {
+	cfg   *config.Config
+	synth *speech.Synthesizer
+	app   *application.App
+}
+
+func NewTTSService(cfg *config.Config) *TTSService {
+	return &TTSService{
+		cfg:   cfg,
+		synth: speech.NewSynthesizer(),
+	}
+}
+
+// TTSAvailable reports whether an OS speech synthesizer is installed
+func (ts *TTSService) TTSAvailable() bool {
+	return ts.synth.Available()
+}
+
+// ListVoices returns the voices installed in the OS speech synthesizer
+func (ts *TTSService) ListVoices() ([]speech.Voice, error) {
+	return ts.synth.Voices(context.Background())
+}
+
+// Speak reads text aloud with the voice configured for lang, falling back to
+// the first installed voice for that language. Emits "tts:speaking" when
+// speech starts and ends, and "tts:sentence" and "tts:word" with the
+// position of the text being spoken, to highlight it.
+func (ts *TTSService) Speak(text, lang string) error {
+	snapshot := ts.cfg.Snapshot()
+	code := langdetect.Code(lang)
+	if code == langdetect.Unknown {
+		code = langdetect.Detect(text).Lang
+	}
+
+	voice := snapshot.Speech.Voices[code]
+	if voice == "" && code != langdetect.Unknown {
+		if voices, err := ts.synth.Voices(context.Background()); err == nil {
+			voice = speech.VoiceFor(voices, code)
+		}
+	}
+
+	progress := func(b speech.Boundary) {
+		ts.app.Event.Emit("tts:"+b.Kind, b)
+	}
+	err := ts.synth.Speak(text, voice, snapshot.Speech.Rate, progress, func(err error) {
+		if err != nil {
+			slog.Warn("speech synthesis failed", "error", err)
+		}
+		// Interrupted by a newer Speak call that is still going
+		if !ts.synth.Speaking() {
+			ts.app.Event.Emit("tts:speaking", false)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	ts.app.Event.Emit("tts:speaking", true)
+	return nil
+}
+
+// StopSpeaking interrupts the current speech
+func (ts *TTSService) StopSpeaking() {
+	ts.synth.Stop()
+}
+
+// IsSpeaking reports whether text is being read aloud
+func (ts *TTSService) IsSpeaking() bool {
+	return ts.synth.Speaking()
+}
+
+// ServiceStartup is called when the service starts
+func (ts *TTSService) ServiceStartup(ctx context.Context, options application.ServiceOptions) error {
+	ts.app = application.Get()
+	return nil
+}
+
+func (ts *TTSService) ServiceShutdown() error {
+	ts.synth.Stop()
+	return nil
+}
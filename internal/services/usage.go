@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/ironpark/tons/internal/config"
+	"github.com/ironpark/tons/internal/engine"
+	"github.com/ironpark/tons/internal/usage"
+	"github.com/wailsapp/wails/v3/pkg/application"
+)
+
+// UsageWarning is the payload of "usage:warning" events, sent once per
+// budget and period when usage reaches the warning level and again when it
+// exceeds the budget
+type UsageWarning struct {
+	Budget   config.Budget `json:"budget"`
+	Metric   string        `json:"metric"` // "characters" or "tokens"
+	Used     int           `json:"used"`
+	Limit    int           `json:"limit"`
+	Percent  int           `json:"percent"`
+	Exceeded bool          `json:"exceeded"`
+}
+
+// BudgetStatus is the current usage against a budget
+type BudgetStatus struct {
+	Budget config.Budget `json:"budget"`
+	Used   usage.Counts  `json:"used"`
+}
+
+// UsageSummary is the usage of today and this month
+type UsageSummary struct {
+	Today     map[string]usage.Counts `json:"today"` // per engine
+	ThisMonth map[string]usage.Counts `json:"thisMonth"`
+	Budgets   []BudgetStatus          `json:"budgets"`
+}
+
+// usageTracker records usage and warns when budgets are approached
+type usageTracker struct {
+	store *usage.Store
+
+	mu     sync.Mutex
+	warned map[string]bool // warnings already sent, by budget, period and level
+}
+
+func newUsageTracker(store *usage.Store) *usageTracker {
+	return &usageTracker{
+		store:  store,
+		warned: make(map[string]bool),
+	}
+}
+
+// record adds the usage of one translation and returns the budget warnings it triggers
+func (u *usageTracker) record(settings config.UsageConfig, engineName, text string, tokens *engine.Usage) []UsageWarning {
+	if u == nil || u.store == nil {
+		return nil
+	}
+	counts := usage.Counts{Requests: 1, Characters: utf8.RuneCountInString(text)}
+	if tokens != nil {
+		counts.PromptTokens = tokens.PromptTokens
+		counts.CompletionTokens = tokens.CompletionTokens
+	}
+	now := time.Now()
+	if err := u.store.Add(engineName, now, counts); err != nil {
+		slog.Warn("failed to save usage", "error", err)
+	}
+	return u.check(settings, engineName, now)
+}
+
+// check returns warnings for budgets of engineName that crossed a level and were not warned about yet
+func (u *usageTracker) check(settings config.UsageConfig, engineName string, now time.Time) []UsageWarning {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	var warnings []UsageWarning
+	for _, b := range settings.Budgets {
+		if b.Engine != "" && b.Engine != engineName {
+			continue
+		}
+		used := u.store.Total(b.Engine, b.Period, now)
+		period := now.Format("2006-01-02")
+		if b.Period == usage.PeriodMonth {
+			period = now.Format("2006-01")
+		}
+		for _, m := range []struct {
+			name        string
+			used, limit int
+		}{
+			{"characters", used.Characters, b.Characters},
+			{"tokens", used.Tokens(), b.Tokens},
+		} {
+			if m.limit <= 0 {
+				continue
+			}
+			percent := m.used * 100 / m.limit
+			exceeded := m.used >= m.limit
+			if !exceeded && percent < settings.WarnPercent {
+				continue
+			}
+			key := fmt.Sprintf("%s|%s|%s|%s|%d|%t", b.Engine, b.Period, period, m.name, m.limit, exceeded)
+			if u.warned[key] {
+				continue
+			}
+			u.warned[key] = true
+			warnings = append(warnings, UsageWarning{
+				Budget:   b,
+				Metric:   m.name,
+				Used:     m.used,
+				Limit:    m.limit,
+				Percent:  percent,
+				Exceeded: exceeded,
+			})
+		}
+	}
+	return warnings
+}
+
+// UsageService reports character and token usage per engine
+type UsageService struct {
+	cfg   *config.Config
+	store *usage.Store
+}
+
+func NewUsageService(cfg *config.Config, store *usage.Store) *UsageService {
+	return &UsageService{
+		cfg:   cfg,
+		store: store,
+	}
+}
+
+// GetUsageSummary returns today's and this month's usage per engine and the
+// usage counted against each budget
+func (us *UsageService) GetUsageSummary() UsageSummary {
+	now := time.Now()
+	summary := UsageSummary{
+		Today:     us.store.Engines(usage.PeriodDay, now),
+		ThisMonth: us.store.Engines(usage.PeriodMonth, now),
+	}
+	for _, b := range us.cfg.Snapshot().Usage.Budgets {
+		summary.Budgets = append(summary.Budgets, BudgetStatus{
+			Budget: b,
+			Used:   us.store.Total(b.Engine, b.Period, now),
+		})
+	}
+	return summary
+}
+
+// GetDailyUsage returns the usage per engine of the last days, oldest first
+func (us *UsageService) GetDailyUsage(days int) []usage.Day {
+	return us.store.Days(max(days, 1), time.Now())
+}
+
+// ServiceStartup is called when the service starts
+func (us *UsageService) ServiceStartup(ctx context.Context, options application.ServiceOptions) error {
+	return nil
+}
+
+func (us *UsageService) ServiceShutdown() error {
+	return nil
+}
@@ -0,0 +1,240 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/ironpark/tons/internal/config"
+	"github.com/wailsapp/wails/v3/pkg/application"
+	"github.com/wailsapp/wails/v3/pkg/events"
+)
+
+const (
+	// miniWindow is the name of the compact always-on-top translator window
+	miniWindow = "mini"
+	// translatorWindowPrefix starts the names of translator windows, followed by their ID
+	translatorWindowPrefix = "translator-"
+)
+
+// TranslatorWindow is an extra translator window with its own language
+// pair and engine. Its translations stream into its own pane, Pane, whose
+// events are prefixed with the pane, e.g. "window:<id>:translate:delta".
+type TranslatorWindow struct {
+	ID         string            `json:"id"`
+	Pane       string            `json:"pane"`
+	SourceLang string            `json:"sourceLang"`
+	TargetLang string            `json:"targetLang"`
+	Engine     config.EngineType `json:"engine,omitempty"` // empty for the configured engine
+}
+
+// WindowService manages the mini translator window: a compact window that
+// stays on top while reading or watching something else. Text pasted or
+// dropped into it is translated like in the main window, so it receives the
+// same streamed "translate:delta" events.
+//
+// It also opens translator windows side by side, e.g. to compare two
+// engines or translate into two languages at once. Each translates
+// independently of the others and of the main window.
+type WindowService struct {
+	cfg       *config.Config
+	translate *TranslateService
+	app       *application.App
+
+	mu          sync.Mutex
+	translators map[string]TranslatorWindow
+}
+
+func NewWindowService(cfg *config.Config, translate *TranslateService) *WindowService {
+	return &WindowService{
+		cfg:         cfg,
+		translate:   translate,
+		translators: make(map[string]TranslatorWindow),
+	}
+}
+
+// ShowMiniWindow shows the mini window where it was last placed, creating it on first use
+func (ws *WindowService) ShowMiniWindow() {
+	if w, ok := ws.app.Window.GetByName(miniWindow); ok {
+		w.Show()
+		w.Focus()
+		return
+	}
+	settings := ws.cfg.Snapshot().MiniWindow
+	options := application.WebviewWindowOptions{
+		Name:             miniWindow,
+		Title:            "tons",
+		Width:            settings.Width,
+		Height:           settings.Height,
+		MinWidth:         240,
+		MinHeight:        120,
+		AlwaysOnTop:      settings.AlwaysOnTop,
+		Frameless:        true,
+		BackgroundColour: application.NewRGB(27, 38, 54),
+		URL:              "/#/mini",
+	}
+	if settings.Placed {
+		options.InitialPosition = application.WindowXY
+		options.X, options.Y = settings.X, settings.Y
+	}
+	ws.app.Window.NewWithOptions(options)
+}
+
+// HideMiniWindow hides the mini window, remembering its placement
+func (ws *WindowService) HideMiniWindow() {
+	if w, ok := ws.app.Window.GetByName(miniWindow); ok {
+		ws.savePlacement(w)
+		w.Hide()
+	}
+}
+
+// ToggleMiniWindow shows the mini window if it is hidden and hides it otherwise.
+// Returns whether it is now visible.
+func (ws *WindowService) ToggleMiniWindow() bool {
+	if w, ok := ws.app.Window.GetByName(miniWindow); ok && w.IsVisible() {
+		ws.HideMiniWindow()
+		return false
+	}
+	ws.ShowMiniWindow()
+	return true
+}
+
+// SetMiniWindowOnTop sets whether the mini window stays above other windows
+func (ws *WindowService) SetMiniWindowOnTop(onTop bool) error {
+	settings := ws.cfg.Snapshot().MiniWindow
+	settings.AlwaysOnTop = onTop
+	ws.cfg.SetMiniWindow(settings)
+	if w, ok := ws.app.Window.GetByName(miniWindow); ok {
+		w.SetAlwaysOnTop(onTop)
+	}
+	return ws.cfg.Save()
+}
+
+// savePlacement stores the window's position and size in the settings
+func (ws *WindowService) savePlacement(w application.Window) {
+	settings := ws.cfg.Snapshot().MiniWindow
+	settings.X, settings.Y = w.Position()
+	settings.Width, settings.Height = w.Size()
+	settings.Placed = true
+	ws.cfg.SetMiniWindow(settings)
+	if err := ws.cfg.Save(); err != nil {
+		slog.Warn("failed to save mini window placement", "error", err)
+	}
+}
+
+// OpenTranslatorWindow opens a translator window for a language pair. An
+// empty engine uses the configured one.
+func (ws *WindowService) OpenTranslatorWindow(sourceLang, targetLang string, engineType config.EngineType) (TranslatorWindow, error) {
+	tw := TranslatorWindow{ID: newRequestID()}
+	tw.Pane = windowPane(tw.ID)
+	if err := ws.setTranslator(tw, sourceLang, targetLang, engineType); err != nil {
+		return TranslatorWindow{}, err
+	}
+
+	w := ws.app.Window.NewWithOptions(application.WebviewWindowOptions{
+		Name:             translatorWindowPrefix + tw.ID,
+		Title:            "tons",
+		Width:            480,
+		Height:           560,
+		MinWidth:         320,
+		MinHeight:        240,
+		BackgroundColour: application.NewRGB(27, 38, 54),
+		URL:              "/#/translator/" + tw.ID,
+	})
+	w.OnWindowEvent(events.Common.WindowClosing, func(*application.WindowEvent) {
+		ws.forget(tw.ID)
+	})
+	return tw, nil
+}
+
+// UpdateTranslatorWindow changes the language pair and engine of a translator window
+func (ws *WindowService) UpdateTranslatorWindow(id, sourceLang, targetLang string, engineType config.EngineType) (TranslatorWindow, error) {
+	tw, err := ws.GetTranslatorWindow(id)
+	if err != nil {
+		return TranslatorWindow{}, err
+	}
+	if err := ws.setTranslator(tw, sourceLang, targetLang, engineType); err != nil {
+		return TranslatorWindow{}, err
+	}
+	return ws.GetTranslatorWindow(id)
+}
+
+// GetTranslatorWindow returns an open translator window, e.g. for the
+// window to load its settings
+func (ws *WindowService) GetTranslatorWindow(id string) (TranslatorWindow, error) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	tw, ok := ws.translators[id]
+	if !ok {
+		return TranslatorWindow{}, errors.New("translator window not found")
+	}
+	return tw, nil
+}
+
+// ListTranslatorWindows returns the open translator windows, by ID
+func (ws *WindowService) ListTranslatorWindows() []TranslatorWindow {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	return slices.SortedFunc(maps.Values(ws.translators), func(a, b TranslatorWindow) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+}
+
+// CloseTranslatorWindow closes a translator window, cancelling its translation
+func (ws *WindowService) CloseTranslatorWindow(id string) {
+	if w, ok := ws.app.Window.GetByName(translatorWindowPrefix + id); ok {
+		// The closing event forgets the window
+		w.Close()
+		return
+	}
+	ws.forget(id)
+}
+
+// setTranslator validates and stores the settings of a translator window
+func (ws *WindowService) setTranslator(tw TranslatorWindow, sourceLang, targetLang string, engineType config.EngineType) error {
+	tw.SourceLang = strings.TrimSpace(sourceLang)
+	tw.TargetLang = strings.TrimSpace(targetLang)
+	tw.Engine = engineType
+	switch tw.Engine {
+	case "", config.EngineInternal, config.EngineTerminalAgent, config.EngineOllama, config.EngineSmart:
+	default:
+		return errors.New("unknown engine: " + string(tw.Engine))
+	}
+
+	ws.mu.Lock()
+	ws.translators[tw.ID] = tw
+	ws.mu.Unlock()
+	ws.translate.setPane(tw.Pane, paneSettings{sourceLang: tw.SourceLang, targetLang: tw.TargetLang, engine: tw.Engine})
+	return nil
+}
+
+// forget drops a closed translator window and its pane
+func (ws *WindowService) forget(id string) {
+	ws.mu.Lock()
+	delete(ws.translators, id)
+	ws.mu.Unlock()
+	ws.translate.dropPane(windowPane(id))
+}
+
+// ServiceStartup is called when the service starts
+func (ws *WindowService) ServiceStartup(ctx context.Context, options application.ServiceOptions) error {
+	ws.app = application.Get()
+	return nil
+}
+
+// ServiceShutdown remembers where the mini window was
+func (ws *WindowService) ServiceShutdown() error {
+	if ws.app == nil {
+		return nil
+	}
+	if w, ok := ws.app.Window.GetByName(miniWindow); ok && w.IsVisible() {
+		ws.savePlacement(w)
+	}
+	return nil
+}
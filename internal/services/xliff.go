@@ -0,0 +1,55 @@
+package services
+
+import (
+	"context"
+	"strings"
+
+	"github.com/ironpark/tons/internal/langdetect"
+	"github.com/ironpark/tons/internal/placeholder"
+	"github.com/ironpark/tons/internal/xliff"
+)
+
+// translateXLIFF fills in the targets of untranslated XLIFF 1.2/2.0 units,
+// keeping inline markup, and marks them for review when configured or
+// translated with low confidence. XLIFF 2.0 has no review state.
+func translateXLIFF(ctx context.Context, ts *TranslateService, path string, data []byte, sourceLang, targetLang string) ([]byte, error) {
+	doc, err := xliff.Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if (sourceLang == "" || sourceLang == langdetect.Auto) && doc.SourceLang != "" {
+		sourceLang = doc.SourceLang
+	}
+
+	mappings := make([]placeholder.Mapping, len(doc.Units))
+	segments := make([]segment, len(doc.Units))
+	for i, u := range doc.Units {
+		text, mapping := u.SourceText()
+		mappings[i] = mapping
+		segments[i] = segment{ID: u.ID, Text: text, Translated: !u.NeedsTranslation()}
+	}
+
+	translated, review, err := ts.translateChangedReview(ctx, ts.segmentMemory(path, targetLang), sourceLang, targetLang, segments)
+	if err != nil {
+		return nil, err
+	}
+	if len(translated) == 0 {
+		return data, nil
+	}
+
+	markFuzzy := ts.cfg.Snapshot().Translation.MarkFuzzy
+	for i, text := range translated {
+		state := xliff.StateTranslated20
+		if !strings.HasPrefix(doc.Version, "2") {
+			state = xliff.StateTranslated12
+			if markFuzzy || review[i] {
+				state = xliff.StateNeedsReview12
+			}
+		}
+		doc.SetTarget(doc.Units[i], xliff.UnmaskInline(text, mappings[i]), state)
+	}
+	if code := langdetect.Code(targetLang); code != langdetect.Unknown {
+		doc.SetTargetLang(code)
+	}
+	return doc.Bytes(), nil
+}
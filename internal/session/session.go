@@ -0,0 +1,98 @@
+// Package session remembers where the user left off, separately from the settings
+package session
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// maxInputText bounds the remembered input so the state file stays small
+const maxInputText = 64 << 10
+
+// Geometry is a window's position and size
+type Geometry struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// State is what the app restores on the next start
+type State struct {
+	SourceLang string    `json:"sourceLang"`
+	TargetLang string    `json:"targetLang"`
+	Engine     string    `json:"engine,omitempty"`
+	InputText  string    `json:"inputText"`
+	Window     *Geometry `json:"window,omitempty"` // main window, nil until it was closed once
+}
+
+// Store keeps the state in memory and on disk
+type Store struct {
+	mu    sync.Mutex
+	path  string
+	state State
+}
+
+// Open loads the state file at path, starting empty if it doesn't exist
+func Open(path string) (*Store, error) {
+	s := &Store{path: path}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.state); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Get returns the current state
+func (s *Store) Get() State {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state := s.state
+	if state.Window != nil {
+		window := *state.Window
+		state.Window = &window
+	}
+	return state
+}
+
+// Update changes the state and saves it
+func (s *Store) Update(fn func(*State)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fn(&s.state)
+	if len(s.state.InputText) > maxInputText {
+		s.state.InputText = truncate(s.state.InputText, maxInputText)
+	}
+	return s.save()
+}
+
+// save writes the state to disk. Must be called with s.mu held.
+func (s *Store) save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0644)
+}
+
+// truncate cuts s to at most n bytes without splitting a UTF-8 sequence
+func truncate(s string, n int) string {
+	for n > 0 && n < len(s) && s[n]&0xC0 == 0x80 {
+		n--
+	}
+	return s[:n]
+}
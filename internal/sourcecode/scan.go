@@ -0,0 +1,297 @@
+package sourcecode
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// tokenKind is the kind of a scanned token
+type tokenKind int
+
+const (
+	lineComment tokenKind = iota
+	blockComment
+	stringLit
+	docString
+)
+
+// token is a comment or string literal
+type token struct {
+	kind       tokenKind
+	start, end int
+	ownLine    bool // only whitespace precedes it on its line
+}
+
+// scanner finds the comments and string literals of a file. It knows just
+// enough of each language to skip over everything else.
+type scanner struct {
+	lang Language
+	data []byte
+	pos  int
+	last byte // last significant byte outside comments and strings, for JS regex literals
+}
+
+func (s *scanner) scan() ([]token, error) {
+	var tokens []token
+	for s.pos < len(s.data) {
+		c := s.data[s.pos]
+		start := s.pos
+		switch {
+		case s.lang != Python && s.hasPrefix("//"), s.lang == Python && c == '#':
+			s.skipLine()
+			tokens = append(tokens, token{kind: lineComment, start: start, end: s.pos, ownLine: s.ownLine(start)})
+		case s.lang != Python && s.hasPrefix("/*"):
+			end := bytes.Index(s.data[s.pos+2:], []byte("*/"))
+			if end < 0 {
+				return nil, s.errorf(start, "unterminated comment")
+			}
+			s.pos += end + 4
+			tokens = append(tokens, token{kind: blockComment, start: start, end: s.pos, ownLine: s.ownLine(start)})
+		case s.lang == JavaScript && c == '/' && s.regexAllowed():
+			if err := s.skipRegex(); err != nil {
+				return nil, err
+			}
+			s.last = '/'
+		case c == '"' || c == '\'' || c == '`' || (s.lang == Python && s.stringPrefix() > 0):
+			if s.lang == Go && c == '\'' {
+				if err := s.skipQuoted('\''); err != nil {
+					return nil, err
+				}
+				s.last = '\''
+				continue
+			}
+			kind := stringLit
+			if s.lang == Python && s.isDocString(start) {
+				kind = docString
+			}
+			if err := s.skipString(); err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: kind, start: start, end: s.pos, ownLine: s.ownLine(start)})
+			s.last = '"'
+		default:
+			if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+				s.last = c
+			}
+			s.pos++
+		}
+	}
+	return tokens, nil
+}
+
+// skipString skips a string literal at pos
+func (s *scanner) skipString() error {
+	start := s.pos
+	prefix := 0
+	if s.lang == Python {
+		prefix = s.stringPrefix()
+	}
+	s.pos += prefix
+	quote := s.data[s.pos]
+	raw := s.lang == Go && quote == '`' || bytes.ContainsAny(bytes.ToLower(s.data[start:start+prefix]), "r")
+
+	if s.lang == Python && (s.hasPrefix(`"""`) || s.hasPrefix(`'''`)) {
+		delim := s.data[s.pos : s.pos+3]
+		s.pos += 3
+		for s.pos < len(s.data) {
+			if s.data[s.pos] == '\\' {
+				s.pos += 2
+				continue
+			}
+			if s.hasPrefix(string(delim)) {
+				s.pos += 3
+				return nil
+			}
+			s.pos++
+		}
+		return s.errorf(start, "unterminated string")
+	}
+	if raw {
+		end := bytes.IndexByte(s.data[s.pos+1:], quote)
+		if end < 0 {
+			return s.errorf(start, "unterminated string")
+		}
+		s.pos += end + 2
+		return nil
+	}
+	if s.lang == JavaScript && quote == '`' {
+		return s.skipTemplate()
+	}
+	return s.skipQuoted(quote)
+}
+
+// skipQuoted skips a single-line quoted literal with backslash escapes
+func (s *scanner) skipQuoted(quote byte) error {
+	start := s.pos
+	s.pos++
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case '\\':
+			s.pos += 2
+			continue
+		case quote:
+			s.pos++
+			return nil
+		case '\n':
+			return s.errorf(start, "unterminated string")
+		}
+		s.pos++
+	}
+	return s.errorf(start, "unterminated string")
+}
+
+// skipTemplate skips a JavaScript template literal, including nested ${} expressions
+func (s *scanner) skipTemplate() error {
+	start := s.pos
+	s.pos++
+	for s.pos < len(s.data) {
+		switch {
+		case s.data[s.pos] == '\\':
+			s.pos += 2
+			continue
+		case s.data[s.pos] == '`':
+			s.pos++
+			return nil
+		case s.hasPrefix("${"):
+			depth := 0
+			for s.pos < len(s.data) {
+				switch s.data[s.pos] {
+				case '{':
+					depth++
+				case '}':
+					depth--
+				case '`':
+					if err := s.skipTemplate(); err != nil {
+						return err
+					}
+					continue
+				}
+				s.pos++
+				if depth == 0 {
+					break
+				}
+			}
+			continue
+		}
+		s.pos++
+	}
+	return s.errorf(start, "unterminated template literal")
+}
+
+// regexAllowed reports whether a / starts a regular expression literal rather than a division
+func (s *scanner) regexAllowed() bool {
+	if isIdentByte(s.last) {
+		end := bytes.LastIndexFunc(s.data[:s.pos], func(r rune) bool { return r != ' ' && r != '\t' && r != '\n' }) + 1
+		start := end
+		for start > 0 && isIdentByte(s.data[start-1]) {
+			start--
+		}
+		switch string(s.data[start:end]) {
+		case "return", "typeof", "case", "do", "else", "in", "of", "void", "yield", "await":
+			return true
+		}
+		return false
+	}
+	return s.last == 0 || strings.IndexByte("(,=:[!&|?{};+-*%<>~^", s.last) >= 0
+}
+
+// skipRegex skips a JavaScript regular expression literal and its flags
+func (s *scanner) skipRegex() error {
+	start := s.pos
+	s.pos++
+	inClass := false
+	for s.pos < len(s.data) {
+		switch c := s.data[s.pos]; {
+		case c == '\\':
+			s.pos++
+		case c == '[':
+			inClass = true
+		case c == ']':
+			inClass = false
+		case c == '/' && !inClass:
+			s.pos++
+			for s.pos < len(s.data) && isIdentByte(s.data[s.pos]) {
+				s.pos++
+			}
+			return nil
+		case c == '\n':
+			return s.errorf(start, "unterminated regular expression")
+		}
+		s.pos++
+	}
+	return s.errorf(start, "unterminated regular expression")
+}
+
+// stringPrefix returns the length of a Python string prefix (r, b, f, u, rb, ...) at pos,
+// or 0 if no prefixed string starts there
+func (s *scanner) stringPrefix() int {
+	if s.pos > 0 && isIdentByte(s.data[s.pos-1]) {
+		return 0
+	}
+	for n := 1; n <= 2 && s.pos+n < len(s.data); n++ {
+		prefix := strings.ToLower(string(s.data[s.pos : s.pos+n]))
+		if strings.Trim(prefix, "rbfu") != "" {
+			return 0
+		}
+		if c := s.data[s.pos+n]; c == '"' || c == '\'' {
+			return n
+		}
+	}
+	return 0
+}
+
+// isDocString reports whether the Python string at start is a statement of
+// its own in a triple-quoted form, as docstrings are
+func (s *scanner) isDocString(start int) bool {
+	rest := s.data[start+s.stringPrefix():]
+	if !bytes.HasPrefix(rest, []byte(`"""`)) && !bytes.HasPrefix(rest, []byte(`'''`)) {
+		return false
+	}
+	return s.ownLine(start) && (s.last == 0 || s.last == ':' || s.last == '"' || s.last == ')' || isIdentByte(s.last))
+}
+
+// continues reports whether line comment b directly follows line comment a
+// on the next line with the same indentation
+func (s *scanner) continues(a, b token) bool {
+	if b.kind != lineComment || !b.ownLine {
+		return false
+	}
+	between := s.data[a.end:b.start]
+	return bytes.Count(between, []byte("\n")) == 1 && len(bytes.TrimSpace(between)) == 0 &&
+		s.indent(a.start) == s.indent(b.start)
+}
+
+// ownLine reports whether only whitespace precedes pos on its line
+func (s *scanner) ownLine(pos int) bool {
+	lineStart := bytes.LastIndexByte(s.data[:pos], '\n') + 1
+	return len(bytes.TrimSpace(s.data[lineStart:pos])) == 0
+}
+
+// indent returns the whitespace that starts the line of pos
+func (s *scanner) indent(pos int) string {
+	lineStart := bytes.LastIndexByte(s.data[:pos], '\n') + 1
+	line := s.data[lineStart:pos]
+	return string(line[:len(line)-len(bytes.TrimLeft(line, " \t"))])
+}
+
+func (s *scanner) hasPrefix(prefix string) bool {
+	return bytes.HasPrefix(s.data[s.pos:], []byte(prefix))
+}
+
+func (s *scanner) skipLine() {
+	if end := bytes.IndexByte(s.data[s.pos:], '\n'); end >= 0 {
+		s.pos += end
+	} else {
+		s.pos = len(s.data)
+	}
+}
+
+func (s *scanner) errorf(pos int, msg string) error {
+	line := bytes.Count(s.data[:min(pos, len(s.data))], []byte("\n")) + 1
+	return fmt.Errorf("line %d: %s", line, msg)
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' || ('0' <= c && c <= '9') || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || c >= 0x80
+}
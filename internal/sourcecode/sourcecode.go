@@ -0,0 +1,400 @@
+// Package sourcecode finds the comments and string literals of Go,
+// JavaScript/TypeScript and Python files so they can be translated while the
+// code around them is kept byte for byte.
+package sourcecode
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/ironpark/tons/internal/placeholder"
+)
+
+// Language is a supported programming language
+type Language string
+
+const (
+	Go         Language = "go"
+	JavaScript Language = "javascript"
+	Python     Language = "python"
+)
+
+// languages maps file extensions to languages
+var languages = map[string]Language{
+	".go":  Go,
+	".js":  JavaScript,
+	".mjs": JavaScript,
+	".cjs": JavaScript,
+	".jsx": JavaScript,
+	".ts":  JavaScript,
+	".tsx": JavaScript,
+	".py":  Python,
+}
+
+// LanguageFor returns the language of a file extension
+func LanguageFor(ext string) (Language, bool) {
+	lang, ok := languages[strings.ToLower(ext)]
+	return lang, ok
+}
+
+// Extensions returns the file extensions of the supported languages
+func Extensions() []string {
+	exts := make([]string, 0, len(languages))
+	for ext := range languages {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	return exts
+}
+
+// Options selects what is translated
+type Options struct {
+	Comments bool // comments and Python docstrings
+	Strings  bool // string literals that read like prose
+}
+
+// File is a parsed source file
+type File struct {
+	data   []byte
+	pieces []*piece
+}
+
+// piece is a translatable comment or string
+type piece struct {
+	span
+	text    string
+	mapping placeholder.Mapping
+	render  func(translated string) string
+}
+
+// span is a byte range of the file
+type span struct {
+	start, end int
+}
+
+// Parse finds the translatable comments and strings of a source file
+func Parse(lang Language, data []byte, opts Options) (*File, error) {
+	s := &scanner{lang: lang, data: data}
+	tokens, err := s.scan()
+	if err != nil {
+		return nil, err
+	}
+
+	f := &File{data: data}
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		var p *piece
+		switch {
+		case t.kind == lineComment && opts.Comments:
+			// Consecutive line comments on their own lines form one paragraph
+			group := []token{t}
+			for t.ownLine && i+1 < len(tokens) && s.continues(tokens[i], tokens[i+1]) {
+				i++
+				group = append(group, tokens[i])
+			}
+			p = s.lineComments(group)
+		case t.kind == blockComment && opts.Comments:
+			p = s.blockComment(t)
+		case t.kind == docString && opts.Comments:
+			p = s.docString(t)
+		case t.kind == stringLit && opts.Strings:
+			p = s.stringLiteral(t)
+		}
+		if p != nil && strings.TrimSpace(p.text) != "" {
+			f.pieces = append(f.pieces, p)
+		}
+	}
+	return f, nil
+}
+
+// Texts returns the text of the translatable pieces
+func (f *File) Texts() []string {
+	texts := make([]string, len(f.pieces))
+	for i, p := range f.pieces {
+		texts[i] = p.text
+	}
+	return texts
+}
+
+// Render returns the file with the pieces replaced by their translations
+func (f *File) Render(translated []string) ([]byte, error) {
+	if len(translated) != len(f.pieces) {
+		return nil, fmt.Errorf("source file: got %d translations for %d pieces", len(translated), len(f.pieces))
+	}
+	var out bytes.Buffer
+	pos := 0
+	for i, p := range f.pieces {
+		out.Write(f.data[pos:p.start])
+		out.WriteString(p.render(p.mapping.Restore(translated[i])))
+		pos = p.end
+	}
+	out.Write(f.data[pos:])
+	return out.Bytes(), nil
+}
+
+// At returns the index of the piece spanning byte offset, or -1 if there is none
+func (f *File) At(offset int) int {
+	for i, p := range f.pieces {
+		if p.start <= offset && offset <= p.end {
+			return i
+		}
+	}
+	return -1
+}
+
+// Replace returns the byte range of piece i and the text that replaces it
+// with its translation, for editing the file in place
+func (f *File) Replace(i int, translated string) (start, end int, text string) {
+	p := f.pieces[i]
+	return p.start, p.end, p.render(p.mapping.Restore(translated))
+}
+
+// directive matches comments that tools read and must not change
+var directive = regexp.MustCompile(`^(?:go:|nolint|\+build|lint:|export |extern |line |#!|!|\s*-\*-|\s*(?:type|noqa|pylint|pragma|fmt|isort|mypy)\s*:|\s*(?:eslint|prettier|istanbul|jshint|global |@ts-|@flow|@jsx|c8 |v8 |webpackChunkName|TODO$))`)
+
+// lineComments turns a group of line comments into one piece
+func (s *scanner) lineComments(group []token) *piece {
+	marker := "//"
+	if s.lang == Python {
+		marker = "#"
+	}
+	first := group[0]
+	if first.start == 0 && strings.HasPrefix(string(s.data[first.start:first.end]), "#!") {
+		return nil
+	}
+
+	lines := make([]string, len(group))
+	space := ""
+	for i, t := range group {
+		body := string(s.data[t.start+len(marker) : t.end])
+		if directive.MatchString(body) {
+			return nil
+		}
+		if i == 0 && strings.HasPrefix(body, " ") {
+			space = " "
+		}
+		lines[i] = strings.TrimSpace(body)
+	}
+	indent := s.indent(first.start)
+	text, mapping := placeholder.Protect(strings.Join(lines, "\n"))
+	return &piece{
+		span:    span{first.start, group[len(group)-1].end},
+		text:    text,
+		mapping: mapping,
+		render: func(translated string) string {
+			var b strings.Builder
+			for i, line := range strings.Split(strings.TrimSpace(translated), "\n") {
+				if i > 0 {
+					b.WriteString("\n" + indent)
+				}
+				line = strings.TrimSpace(line)
+				b.WriteString(marker)
+				if line != "" {
+					b.WriteString(space + line)
+				}
+			}
+			return b.String()
+		},
+	}
+}
+
+// starLeader matches the " * " that starts the lines of doc block comments
+var starLeader = regexp.MustCompile(`^[ \t]*\*(?:[ \t]|$)`)
+
+// blockComment turns a /* */ comment into a piece, keeping its padding and * leaders
+func (s *scanner) blockComment(t token) *piece {
+	raw := string(s.data[t.start:t.end])
+	open := "/*"
+	if strings.HasPrefix(raw, "/**") {
+		open = "/**"
+	}
+	inner := strings.TrimSuffix(strings.TrimPrefix(raw, open), "*/")
+	if directive.MatchString(inner) || strings.HasPrefix(strings.TrimSpace(inner), "#__PURE__") || strings.HasPrefix(inner, "!") {
+		return nil
+	}
+
+	lines := strings.Split(inner, "\n")
+	leader := ""
+	if len(lines) > 1 {
+		leader = s.indent(t.start) + " * "
+		for _, line := range lines[1:] {
+			if strings.TrimSpace(line) != "" && !starLeader.MatchString(line) {
+				leader = ""
+				break
+			}
+		}
+	}
+	if leader != "" {
+		for i := 1; i < len(lines); i++ {
+			lines[i] = starLeader.ReplaceAllString(lines[i], "")
+		}
+	}
+	body := strings.Join(lines, "\n")
+	head, tail := padding(body)
+	text, mapping := placeholder.Protect(strings.TrimSpace(body))
+	indent := s.indent(t.start) + "   "
+	return &piece{
+		span:    span{t.start, t.end},
+		text:    text,
+		mapping: mapping,
+		render: func(translated string) string {
+			lines := strings.Split(strings.TrimSpace(translated), "\n")
+			for i := 1; i < len(lines); i++ {
+				switch {
+				case leader != "":
+					lines[i] = strings.TrimRight(leader+strings.TrimSpace(lines[i]), " ")
+				case strings.TrimSpace(lines[i]) != "":
+					lines[i] = indent + strings.TrimSpace(lines[i])
+				}
+			}
+			// Starred comments start their first line with a leader and end with a bare " */"
+			head, tail := head, tail
+			if leader != "" && strings.Contains(head, "\n") {
+				head = strings.TrimRight(head, " \t") + leader
+			}
+			if leader != "" && strings.Contains(tail, "\n") {
+				tail = strings.TrimRight(tail, " \t") + s.indent(t.start) + " "
+			}
+			return open + head + strings.Join(lines, "\n") + tail + "*/"
+		},
+	}
+}
+
+// docString turns a Python docstring into a piece, keeping its indentation
+func (s *scanner) docString(t token) *piece {
+	prefixLen, quote := stringQuote(s.data[t.start:t.end])
+	body := string(s.data[t.start+prefixLen+len(quote) : t.end-len(quote)])
+	head, tail := padding(body)
+	indent := s.indent(t.start)
+	text, mapping := placeholder.Protect(dedent(strings.TrimSpace(body)))
+	return &piece{
+		span:    span{t.start, t.end},
+		text:    text,
+		mapping: mapping,
+		render: func(translated string) string {
+			lines := strings.Split(strings.TrimSpace(translated), "\n")
+			for i := 1; i < len(lines); i++ {
+				if strings.TrimSpace(lines[i]) != "" {
+					lines[i] = indent + strings.TrimRight(lines[i], " \t")
+				} else {
+					lines[i] = ""
+				}
+			}
+			translated = strings.Join(lines, "\n")
+			translated = strings.ReplaceAll(translated, quote, `\`+quote[:1]+quote[1:])
+			return string(s.data[t.start:t.start+prefixLen]) + quote + head + translated + tail + quote
+		},
+	}
+}
+
+// escapeSeq matches escape sequences and interpolations in string literals
+var escapeSeq = regexp.MustCompile(`\\(?:x[0-9a-fA-F]{2}|u\{[0-9a-fA-F]+\}|u[0-9a-fA-F]{4}|U[0-9a-fA-F]{8}|N\{[^}]*\}|[0-7]{1,3}|.)|\$\{[^}]*\}|\{[^{}\s]*\}`)
+
+// stringLiteral turns a string literal that reads like prose into a piece
+func (s *scanner) stringLiteral(t token) *piece {
+	raw := s.data[t.start:t.end]
+	prefixLen, quote := stringQuote(raw)
+	prefix := strings.ToLower(string(raw[:prefixLen]))
+	if strings.Contains(prefix, "b") {
+		return nil // Python bytes
+	}
+	body := string(raw[prefixLen+len(quote) : len(raw)-len(quote)])
+	if !isProse(body) {
+		return nil
+	}
+
+	rawString := quote == "`" && s.lang == Go || strings.Contains(prefix, "r")
+	multiline := len(quote) == 3 || quote == "`"
+	// Escapes are masked first so translations can be escaped before they are restored
+	var escapes placeholder.Mapping
+	if !rawString {
+		body, escapes = placeholder.ProtectPattern(body, escapeSeq)
+	}
+	head, tail := padding(body)
+	text, mapping := placeholder.Protect(strings.TrimSpace(body))
+	return &piece{
+		span:    span{t.start + prefixLen + len(quote), t.end - len(quote)},
+		text:    text,
+		mapping: mapping,
+		render: func(translated string) string {
+			translated = strings.TrimSpace(translated)
+			switch {
+			case rawString && quote == "`":
+				translated = strings.ReplaceAll(translated, "`", "'")
+			case rawString:
+				other := map[string]string{`"`: "'", "'": `"`}[quote[:1]]
+				translated = strings.ReplaceAll(translated, quote[:1], other)
+			default:
+				translated = strings.ReplaceAll(translated, `\`, `\\`)
+				translated = strings.ReplaceAll(translated, quote[:1], `\`+quote[:1])
+				if !multiline {
+					translated = strings.ReplaceAll(translated, "\n", `\n`)
+				}
+			}
+			return escapes.Restore(head + translated + tail)
+		},
+	}
+}
+
+// isProse reports whether a string literal looks like text for people rather
+// than an identifier, path, format or code
+func isProse(body string) bool {
+	words := strings.Fields(body)
+	if len(words) < 2 || strings.Contains(body, `:"`) {
+		return false // single words are usually keys; `json:"x"` is a struct tag
+	}
+	letters := 0
+	for _, r := range body {
+		if unicode.IsLetter(r) {
+			letters++
+		}
+	}
+	return letters*2 >= len([]rune(body))
+}
+
+// stringQuote returns the length of a string literal's prefix (r, f, b, ...) and its quote
+func stringQuote(lit []byte) (int, string) {
+	i := 0
+	for i < len(lit) && lit[i] != '"' && lit[i] != '\'' && lit[i] != '`' {
+		i++
+	}
+	if i+3 <= len(lit) && (bytes.HasPrefix(lit[i:], []byte(`"""`)) || bytes.HasPrefix(lit[i:], []byte(`'''`))) && len(lit)-i >= 6 {
+		return i, string(lit[i : i+3])
+	}
+	return i, string(lit[i : i+1])
+}
+
+// padding returns the leading and trailing whitespace of s
+func padding(s string) (string, string) {
+	trimmed := strings.TrimSpace(s)
+	if trimmed == "" {
+		return s, ""
+	}
+	start := strings.Index(s, trimmed)
+	return s[:start], s[start+len(trimmed):]
+}
+
+// dedent removes the indentation shared by all lines after the first
+func dedent(s string) string {
+	lines := strings.Split(s, "\n")
+	prefix := ""
+	for i, line := range lines[1:] {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		ws := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		if i == 0 || prefix == "" || !strings.HasPrefix(ws, prefix) {
+			if prefix == "" || strings.HasPrefix(prefix, ws) {
+				prefix = ws
+			}
+		}
+	}
+	for i := 1; i < len(lines); i++ {
+		lines[i] = strings.TrimPrefix(lines[i], prefix)
+	}
+	return strings.Join(lines, "\n")
+}
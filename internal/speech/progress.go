@@ -0,0 +1,201 @@
+package speech
+
+import (
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Kinds of boundaries reached while speaking
+const (
+	BoundaryWord     = "word"
+	BoundarySentence = "sentence"
+)
+
+// Boundary is the word or sentence being spoken. Offset and Length count
+// UTF-16 code units, like the indexes of JavaScript strings, so the text
+// can be highlighted as it is read.
+type Boundary struct {
+	Kind   string `json:"kind"`
+	Offset int    `json:"offset"`
+	Length int    `json:"length"`
+}
+
+// textSpan is a word or sentence of the spoken text, in UTF-16 code units
+type textSpan struct {
+	offset, length int
+	weight         float64 // speaking time relative to an average word
+}
+
+const (
+	// avgWordRunes is the length of an average word, to estimate how long longer words take
+	avgWordRunes = 5.0
+	// sentencePause is added after each sentence when estimating progress
+	sentencePause = 300 * time.Millisecond
+)
+
+// sentenceEnd are the runes that end a sentence
+const sentenceEnd = ".!?。！？…"
+
+// progressTracker turns the words reached into word and sentence boundaries
+type progressTracker struct {
+	sentences []textSpan
+	current   int // sentence spoken last, -1 before the first
+	report    func(Boundary)
+}
+
+func newProgressTracker(text string, report func(Boundary)) *progressTracker {
+	_, sentences := spans(text)
+	return &progressTracker{sentences: sentences, current: -1, report: report}
+}
+
+// word reports the word at offset, preceded by its sentence if it starts a new one
+func (t *progressTracker) word(offset, length int) {
+	for i := max(t.current, 0); i < len(t.sentences); i++ {
+		s := t.sentences[i]
+		if offset < s.offset+s.length {
+			if i != t.current && offset >= s.offset {
+				t.current = i
+				t.report(Boundary{Kind: BoundarySentence, Offset: s.offset, Length: s.length})
+			}
+			break
+		}
+	}
+	t.report(Boundary{Kind: BoundaryWord, Offset: offset, Length: length})
+}
+
+// spans splits text into words and sentences. Each character of scripts
+// written without spaces, like Chinese and Japanese, counts as a word.
+func spans(text string) (words, sentences []textSpan) {
+	pos := 0 // in UTF-16 code units
+	word, sentence := -1, -1
+	letters := 0
+	endWord := func() {
+		if word >= 0 {
+			words = append(words, textSpan{offset: word, length: pos - word, weight: max(float64(letters)/avgWordRunes, 0.2)})
+			word, letters = -1, 0
+		}
+	}
+	endSentence := func() {
+		endWord()
+		if sentence >= 0 {
+			sentences = append(sentences, textSpan{offset: sentence, length: pos - sentence})
+			sentence = -1
+		}
+	}
+
+	ended := false // a Latin sentence ender was seen, the sentence ends at the next space
+	for _, r := range text {
+		size := utf16.RuneLen(r)
+		if unicode.IsSpace(r) {
+			endWord()
+			if ended || r == '\n' {
+				endSentence()
+				ended = false
+			}
+			pos += size
+			continue
+		}
+		if sentence < 0 {
+			sentence = pos
+		}
+		if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana) {
+			endWord()
+			// A character takes about a syllable to say, not a whole word
+			words = append(words, textSpan{offset: pos, length: size, weight: 0.4})
+			pos += size
+			ended = false
+			continue
+		}
+		letter := unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+		if letter {
+			if word < 0 {
+				word = pos
+			}
+			letters++
+			ended = false
+		}
+		pos += size
+		if strings.ContainsRune(sentenceEnd, r) {
+			if r >= utf8.RuneSelf {
+				// CJK punctuation ends the sentence without a following space
+				endSentence()
+			} else {
+				ended = true
+			}
+		}
+	}
+	endSentence()
+	return words, sentences
+}
+
+// estimateProgress reports the words of text as they are expected to be
+// spoken at wpm words per minute, for synthesizers that don't report their
+// progress. It returns when all words were reported or ctx is done.
+func estimateProgress(ctx context.Context, text string, wpm int, report func(Boundary)) {
+	words, sentences := spans(text)
+	tracker := &progressTracker{sentences: sentences, current: -1, report: report}
+	perWord := time.Minute / time.Duration(max(wpm, 1))
+
+	start := time.Now()
+	var at time.Duration // when the next word is expected to start
+	sentence := -1
+	for _, w := range words {
+		if s := sentenceOf(tracker.sentences, w.offset); s != sentence {
+			if sentence >= 0 {
+				at += sentencePause
+			}
+			sentence = s
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(start.Add(at))):
+		}
+		tracker.word(w.offset, w.length)
+		at += time.Duration(w.weight * float64(perWord))
+	}
+}
+
+// sentenceOf returns the index of the sentence containing offset, or -1
+func sentenceOf(sentences []textSpan, offset int) int {
+	for i, s := range sentences {
+		if offset >= s.offset && offset < s.offset+s.length {
+			return i
+		}
+	}
+	return -1
+}
+
+// progressWriter reads the "position length" lines the Windows synthesizer
+// script writes for each word it speaks
+type progressWriter struct {
+	buf     []byte
+	tracker *progressTracker
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		line := strings.TrimSpace(string(w.buf[:i]))
+		w.buf = w.buf[i+1:]
+		pos, count, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		offset, err1 := strconv.Atoi(pos)
+		length, err2 := strconv.Atoi(count)
+		if err1 == nil && err2 == nil {
+			w.tracker.word(offset, length)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// Package speech records microphone audio and converts between speech and text
+// using locally installed tools (sox, arecord or ffmpeg for recording,
+// whisper.cpp for transcription, the OS synthesizer for speech output).
+package speech
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrNoRecorder is returned when no audio recording tool is installed
+	ErrNoRecorder = errors.New("speech: no audio recorder available")
+	// ErrRecording is returned when a recording is already in progress
+	ErrRecording = errors.New("speech: already recording")
+	// ErrNotRecording is returned when stopping without an active recording
+	ErrNotRecording = errors.New("speech: not recording")
+)
+
+// Recorder records 16 kHz mono WAV audio from the default microphone
+type Recorder struct {
+	Device string // input device for ffmpeg, e.g. ":0" on macOS or "audio=Microphone" on Windows
+
+	mu    sync.Mutex
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	dir   string
+	file  string
+}
+
+// NewRecorder creates a recorder for the given input device ("" for the default)
+func NewRecorder(device string) *Recorder {
+	return &Recorder{Device: device}
+}
+
+// command returns the recording command writing to file
+func (r *Recorder) command(file string) (*exec.Cmd, error) {
+	if _, err := exec.LookPath("rec"); err == nil && runtime.GOOS != "windows" {
+		return exec.Command("rec", "-q", "-c", "1", "-r", "16000", "-b", "16", file), nil
+	}
+	if _, err := exec.LookPath("arecord"); err == nil {
+		return exec.Command("arecord", "-q", "-f", "S16_LE", "-r", "16000", "-c", "1", file), nil
+	}
+	if _, err := exec.LookPath("ffmpeg"); err == nil {
+		format, device := "pulse", "default"
+		switch runtime.GOOS {
+		case "darwin":
+			format, device = "avfoundation", ":0"
+		case "windows":
+			format, device = "dshow", ""
+		}
+		if r.Device != "" {
+			device = r.Device
+		}
+		if device == "" {
+			return nil, errors.New("speech: ffmpeg needs an input device on Windows, e.g. audio=Microphone")
+		}
+		return exec.Command("ffmpeg", "-loglevel", "error", "-f", format, "-i", device, "-ac", "1", "-ar", "16000", "-y", file), nil
+	}
+	return nil, ErrNoRecorder
+}
+
+// Available reports whether a recording tool is installed
+func (r *Recorder) Available() bool {
+	_, err := r.command(os.DevNull)
+	return err == nil
+}
+
+// Recording reports whether a recording is in progress
+func (r *Recorder) Recording() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.cmd != nil
+}
+
+// Start begins recording in the background
+func (r *Recorder) Start() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.cmd != nil {
+		return ErrRecording
+	}
+	dir, err := os.MkdirTemp("", "tons-speech")
+	if err != nil {
+		return err
+	}
+	file := filepath.Join(dir, "input.wav")
+	cmd, err := r.command(file)
+	if err != nil {
+		os.RemoveAll(dir)
+		return err
+	}
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		os.RemoveAll(dir)
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		os.RemoveAll(dir)
+		return fmt.Errorf("speech: failed to start recording: %w", err)
+	}
+	r.cmd, r.stdin, r.dir, r.file = cmd, stdin, dir, file
+	return nil
+}
+
+// Stop ends the recording and returns the recorded WAV data
+func (r *Recorder) Stop() ([]byte, error) {
+	r.mu.Lock()
+	cmd, stdin, dir, file := r.cmd, r.stdin, r.dir, r.file
+	r.cmd, r.stdin = nil, nil
+	r.mu.Unlock()
+
+	if cmd == nil {
+		return nil, ErrNotRecording
+	}
+	defer os.RemoveAll(dir)
+
+	// ffmpeg quits on "q"; sox and arecord finish the file on interrupt
+	if filepath.Base(cmd.Path) == "ffmpeg" || runtime.GOOS == "windows" {
+		io.WriteString(stdin, "q")
+		stdin.Close()
+	} else if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		cmd.Process.Kill()
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		cmd.Process.Kill()
+		<-done
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil || len(data) <= 44 {
+		return nil, errors.New("speech: nothing was recorded")
+	}
+	return data, nil
+}
@@ -0,0 +1,252 @@
+package speech
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrNoSynthesizer is returned when no speech synthesizer is installed
+var ErrNoSynthesizer = errors.New("speech: no speech synthesizer available")
+
+// Voice is a voice installed in the OS speech synthesizer
+type Voice struct {
+	ID   string `json:"id"`   // value passed to the synthesizer
+	Name string `json:"name"` // display name
+	Lang string `json:"lang"` // locale, e.g. "en_US" or "en"
+}
+
+// defaultWPM is the speaking rate, in words per minute, of rate 1.0
+const defaultWPM = 175
+
+// windowsSpeak reads the text from stdin so it never needs quoting. It
+// writes the position and length of each word as it is spoken.
+const windowsSpeak = `Add-Type -AssemblyName System.Speech
+$s = New-Object System.Speech.Synthesis.SpeechSynthesizer
+if ($env:TONS_VOICE) { $s.SelectVoice($env:TONS_VOICE) }
+$s.Rate = [int]$env:TONS_RATE
+$null = Register-ObjectEvent $s SpeakProgress -SourceIdentifier progress
+$null = Register-ObjectEvent $s SpeakCompleted -SourceIdentifier completed
+$null = $s.SpeakAsync([Console]::In.ReadToEnd())
+while ($true) {
+  $e = Wait-Event
+  Remove-Event -EventIdentifier $e.EventIdentifier
+  if ($e.SourceIdentifier -eq 'completed') { break }
+  [Console]::Out.WriteLine([string]$e.SourceEventArgs.CharacterPosition + ' ' + $e.SourceEventArgs.CharacterCount)
+  [Console]::Out.Flush()
+}`
+
+// windowsVoices lists installed voices as "name|culture" lines
+const windowsVoices = `Add-Type -AssemblyName System.Speech
+(New-Object System.Speech.Synthesis.SpeechSynthesizer).GetInstalledVoices() | ForEach-Object { $_.VoiceInfo.Name + '|' + $_.VoiceInfo.Culture.Name }`
+
+// Synthesizer speaks text with the OS speech synthesizer: say on macOS,
+// System.Speech on Windows and espeak-ng/espeak elsewhere
+type Synthesizer struct {
+	mu     sync.Mutex
+	cmd    *exec.Cmd
+	cancel context.CancelFunc
+}
+
+// NewSynthesizer creates a synthesizer
+func NewSynthesizer() *Synthesizer {
+	return &Synthesizer{}
+}
+
+// espeak returns the installed espeak executable
+func espeak() (string, bool) {
+	for _, name := range []string{"espeak-ng", "espeak"} {
+		if _, err := exec.LookPath(name); err == nil {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// Available reports whether a speech synthesizer is installed
+func (s *Synthesizer) Available() bool {
+	switch runtime.GOOS {
+	case "darwin":
+		_, err := exec.LookPath("say")
+		return err == nil
+	case "windows":
+		_, err := exec.LookPath("powershell")
+		return err == nil
+	default:
+		_, ok := espeak()
+		return ok
+	}
+}
+
+// command builds the command speaking stdin with voice at rate (1.0 is normal speed)
+func (s *Synthesizer) command(ctx context.Context, voice string, rate float64) (*exec.Cmd, error) {
+	if rate <= 0 {
+		rate = 1
+	}
+	wpm := strconv.Itoa(int(defaultWPM * rate))
+
+	switch runtime.GOOS {
+	case "darwin":
+		args := []string{"-r", wpm}
+		if voice != "" {
+			args = append(args, "-v", voice)
+		}
+		return exec.CommandContext(ctx, "say", args...), nil
+	case "windows":
+		cmd := exec.CommandContext(ctx, "powershell", "-NoProfile", "-NonInteractive", "-Command", windowsSpeak)
+		winRate := min(max(int((rate-1)*10), -10), 10)
+		cmd.Env = append(cmd.Environ(), "TONS_VOICE="+voice, "TONS_RATE="+strconv.Itoa(winRate))
+		return cmd, nil
+	default:
+		name, ok := espeak()
+		if !ok {
+			return nil, ErrNoSynthesizer
+		}
+		args := []string{"-s", wpm, "--stdin"}
+		if voice != "" {
+			args = append(args, "-v", voice)
+		}
+		return exec.CommandContext(ctx, name, args...), nil
+	}
+}
+
+// Speak starts speaking text, interrupting anything currently being spoken.
+// It returns once speech has started; done, if not nil, is called when it ends.
+// progress, if not nil, is called as each word and sentence is reached. The
+// Windows synthesizer reports its progress, elsewhere it is estimated from
+// the speaking rate.
+func (s *Synthesizer) Speak(text, voice string, rate float64, progress func(Boundary), done func(error)) error {
+	s.Stop()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd, err := s.command(ctx, voice, rate)
+	if err != nil {
+		cancel()
+		return err
+	}
+	cmd.Stdin = strings.NewReader(text)
+	if progress != nil && runtime.GOOS == "windows" {
+		cmd.Stdout = &progressWriter{tracker: newProgressTracker(text, progress)}
+	}
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return fmt.Errorf("speech: failed to start synthesizer: %w", err)
+	}
+
+	s.mu.Lock()
+	s.cmd, s.cancel = cmd, cancel
+	s.mu.Unlock()
+
+	if progress != nil && runtime.GOOS != "windows" {
+		if rate <= 0 {
+			rate = 1
+		}
+		go estimateProgress(ctx, text, int(defaultWPM*rate), progress)
+	}
+
+	go func() {
+		err := cmd.Wait()
+		s.mu.Lock()
+		if s.cmd == cmd {
+			s.cmd, s.cancel = nil, nil
+		}
+		s.mu.Unlock()
+		cancel()
+		if ctx.Err() != nil {
+			err = nil // stopped on purpose
+		}
+		if done != nil {
+			done(err)
+		}
+	}()
+	return nil
+}
+
+// Stop interrupts the current speech, if any
+func (s *Synthesizer) Stop() {
+	s.mu.Lock()
+	cancel := s.cancel
+	s.cmd, s.cancel = nil, nil
+	s.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
+}
+
+// Speaking reports whether speech is in progress
+func (s *Synthesizer) Speaking() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.cmd != nil
+}
+
+// sayVoice matches a line of `say -v ?`, e.g. "Alex    en_US    # Most people recognize me"
+var sayVoice = regexp.MustCompile(`^(.+?)\s+([a-z]{2,3}[_-][A-Za-z0-9]+)\s+#`)
+
+// Voices lists the installed voices
+func (s *Synthesizer) Voices(ctx context.Context) ([]Voice, error) {
+	var voices []Voice
+	switch runtime.GOOS {
+	case "darwin":
+		out, err := exec.CommandContext(ctx, "say", "-v", "?").Output()
+		if err != nil {
+			return nil, fmt.Errorf("speech: failed to list voices: %w", err)
+		}
+		for line := range strings.Lines(string(out)) {
+			if m := sayVoice.FindStringSubmatch(line); m != nil {
+				voices = append(voices, Voice{ID: m[1], Name: m[1], Lang: m[2]})
+			}
+		}
+	case "windows":
+		out, err := exec.CommandContext(ctx, "powershell", "-NoProfile", "-NonInteractive", "-Command", windowsVoices).Output()
+		if err != nil {
+			return nil, fmt.Errorf("speech: failed to list voices: %w", err)
+		}
+		for line := range strings.Lines(string(out)) {
+			name, lang, ok := strings.Cut(strings.TrimSpace(line), "|")
+			if ok {
+				voices = append(voices, Voice{ID: name, Name: name, Lang: lang})
+			}
+		}
+	default:
+		name, ok := espeak()
+		if !ok {
+			return nil, ErrNoSynthesizer
+		}
+		out, err := exec.CommandContext(ctx, name, "--voices").Output()
+		if err != nil {
+			return nil, fmt.Errorf("speech: failed to list voices: %w", err)
+		}
+		// Pty Language Age/Gender VoiceName File Other Languages
+		scanner := bufio.NewScanner(bytes.NewReader(out))
+		scanner.Scan() // header
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) >= 5 {
+				voices = append(voices, Voice{ID: fields[4], Name: fields[3], Lang: fields[1]})
+			}
+		}
+	}
+	return voices, nil
+}
+
+// VoiceFor returns the first voice whose locale matches lang (ISO 639-1), or ""
+func VoiceFor(voices []Voice, lang string) string {
+	for _, v := range voices {
+		base, _, _ := strings.Cut(strings.ReplaceAll(v.Lang, "_", "-"), "-")
+		if strings.EqualFold(base, lang) {
+			return v.ID
+		}
+	}
+	return ""
+}
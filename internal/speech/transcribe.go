@@ -0,0 +1,106 @@
+package speech
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrNoModel is returned when no whisper model is configured
+var ErrNoModel = errors.New("speech: no whisper model configured")
+
+// Transcriber converts recorded speech to text
+type Transcriber interface {
+	Name() string
+	// Transcribe returns the text spoken in WAV audio. lang is an ISO 639-1
+	// code or "" / "auto" to detect the spoken language.
+	Transcribe(ctx context.Context, wav []byte, lang string) (string, error)
+	Available() bool
+}
+
+// Whisper transcribes with the whisper.cpp command line tool and a local GGML model
+type Whisper struct {
+	Command   string
+	ModelPath string
+	Timeout   time.Duration
+}
+
+// NewWhisper creates a whisper.cpp transcriber for the model at modelPath
+func NewWhisper(command, modelPath string) *Whisper {
+	if command == "" {
+		command = "whisper-cli"
+	}
+	return &Whisper{
+		Command:   command,
+		ModelPath: modelPath,
+		Timeout:   2 * time.Minute,
+	}
+}
+
+// Name returns the transcriber name
+func (w *Whisper) Name() string {
+	return "whisper"
+}
+
+// Available reports whether whisper.cpp and the model are installed
+func (w *Whisper) Available() bool {
+	if _, err := exec.LookPath(w.Command); err != nil {
+		return false
+	}
+	_, err := os.Stat(w.ModelPath)
+	return err == nil
+}
+
+// Transcribe runs whisper.cpp on the audio and returns the plain text
+func (w *Whisper) Transcribe(ctx context.Context, wav []byte, lang string) (string, error) {
+	if w.ModelPath == "" {
+		return "", ErrNoModel
+	}
+
+	dir, err := os.MkdirTemp("", "tons-whisper")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "input.wav")
+	if err := os.WriteFile(file, wav, 0600); err != nil {
+		return "", err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, w.Timeout)
+	defer cancel()
+
+	if lang == "" {
+		lang = "auto"
+	}
+	cmd := exec.CommandContext(ctx, w.Command, "-m", w.ModelPath, "-f", file, "-l", lang, "--no-timestamps", "--no-prints")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return "", fmt.Errorf("transcription timed out")
+		}
+		return "", fmt.Errorf("whisper error: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return cleanTranscript(string(out)), nil
+}
+
+// cleanTranscript joins whisper's output lines and drops non-speech markers like [BLANK_AUDIO]
+func cleanTranscript(out string) string {
+	var parts []string
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || (strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]")) {
+			continue
+		}
+		parts = append(parts, line)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,122 @@
+// Package telemetry builds and sends the opt-in anonymous usage reports:
+// which engine types are used, how many translations they ran and which
+// classes of errors they hit. Reports never include text, languages, model
+// names or file paths.
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ironpark/tons/internal/metrics"
+)
+
+// Schema is the version of the report layout
+const Schema = 1
+
+// EngineReport holds the usage of one engine type
+type EngineReport struct {
+	Engine       string         `json:"engine"` // e.g. "ollama", without the model
+	Translations int            `json:"translations"`
+	Failures     int            `json:"failures"`
+	Errors       map[string]int `json:"errors,omitempty"` // failures by metrics.ErrorClass
+}
+
+// Report is one usage report
+type Report struct {
+	Schema       int            `json:"schema"`
+	InstallID    string         `json:"installId"` // random, not derived from the machine or user
+	OS           string         `json:"os"`
+	Arch         string         `json:"arch"`
+	ActiveEngine string         `json:"activeEngine"`
+	From         time.Time      `json:"from"` // to the hour
+	To           time.Time      `json:"to"`
+	Engines      []EngineReport `json:"engines"`
+}
+
+// Empty reports whether nothing was used in the report period
+func (r Report) Empty() bool {
+	return len(r.Engines) == 0
+}
+
+// New builds a report of the usage recorded in stats since baseline, the
+// statistics at the last report. Engines are grouped by type.
+func New(installID, activeEngine string, from time.Time, stats, baseline []metrics.EngineStats) Report {
+	before := make(map[string]metrics.EngineStats, len(baseline))
+	for _, s := range baseline {
+		before[s.Engine] = s
+	}
+
+	byType := make(map[string]*EngineReport)
+	for _, s := range stats {
+		base, ok := before[s.Engine]
+		if !ok || s.Requests < base.Requests || s.Failures < base.Failures {
+			base = metrics.EngineStats{} // new, or the statistics were reset since
+		}
+		requests, failures := s.Requests-base.Requests, s.Failures-base.Failures
+		if requests == 0 && failures == 0 {
+			continue
+		}
+		engineType, _, _ := strings.Cut(s.Engine, ":")
+		r, ok := byType[engineType]
+		if !ok {
+			r = &EngineReport{Engine: engineType}
+			byType[engineType] = r
+		}
+		r.Translations += requests
+		r.Failures += failures
+		for class, n := range s.Errors {
+			if n -= base.Errors[class]; n > 0 {
+				if r.Errors == nil {
+					r.Errors = make(map[string]int)
+				}
+				r.Errors[class] += n
+			}
+		}
+	}
+
+	report := Report{
+		Schema:       Schema,
+		InstallID:    installID,
+		OS:           runtime.GOOS,
+		Arch:         runtime.GOARCH,
+		ActiveEngine: activeEngine,
+		From:         from.UTC().Truncate(time.Hour),
+		To:           time.Now().UTC().Truncate(time.Hour),
+		Engines:      []EngineReport{},
+	}
+	for _, engineType := range slices.Sorted(maps.Keys(byType)) {
+		report.Engines = append(report.Engines, *byType[engineType])
+	}
+	return report
+}
+
+// Send posts the report as JSON to endpoint
+func Send(ctx context.Context, endpoint string, report Report) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("telemetry endpoint answered %s", resp.Status)
+	}
+	return nil
+}
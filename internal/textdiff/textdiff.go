@@ -0,0 +1,163 @@
+// Package textdiff computes word-level differences between two versions of
+// a text, e.g. to highlight what changed when a translation is redone.
+//
+// Texts are split into words, runs of whitespace and single punctuation
+// marks; CJK characters, written without spaces, are a word each. Joining
+// the texts of the equal and deleted operations gives the old text, joining
+// the equal and inserted ones the new text.
+package textdiff
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// Kind is what an operation does to the old text
+type Kind string
+
+const (
+	Equal  Kind = "equal"
+	Insert Kind = "insert"
+	Delete Kind = "delete"
+)
+
+// Op is a run of text that is kept, inserted or deleted
+type Op struct {
+	Kind Kind   `json:"kind"`
+	Text string `json:"text"`
+}
+
+// maxCells bounds the comparison table; longer texts differ as a whole past
+// their common prefix and suffix
+const maxCells = 4_000_000
+
+// Words returns the operations turning old into new, merging adjacent
+// operations of the same kind. Deletions come before insertions where both
+// replace the same words.
+func Words(old, new string) []Op {
+	a, b := Split(old), Split(new)
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	var ops []Op
+	add := func(kind Kind, words ...string) {
+		for _, w := range words {
+			if n := len(ops); n > 0 && ops[n-1].Kind == kind {
+				ops[n-1].Text += w
+			} else {
+				ops = append(ops, Op{Kind: kind, Text: w})
+			}
+		}
+	}
+
+	add(Equal, a[:prefix]...)
+	middle(a[prefix:len(a)-suffix], b[prefix:len(b)-suffix], add)
+	add(Equal, a[len(a)-suffix:]...)
+	return ops
+}
+
+// middle adds the operations between the common prefix and suffix, from the
+// longest common subsequence of the words
+func middle(a, b []string, add func(Kind, ...string)) {
+	if len(a)*len(b) > maxCells {
+		add(Delete, a...)
+		add(Insert, b...)
+		return
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			add(Equal, a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			add(Delete, a[i])
+			i++
+		default:
+			add(Insert, b[j])
+			j++
+		}
+	}
+	add(Delete, a[i:]...)
+	add(Insert, b[j:]...)
+}
+
+// Changed reports whether any operation inserts or deletes text
+func Changed(ops []Op) bool {
+	for _, op := range ops {
+		if op.Kind != Equal {
+			return true
+		}
+	}
+	return false
+}
+
+// Split splits text into words, whitespace runs, punctuation marks and CJK
+// characters; joining them gives the text back
+func Split(text string) []string {
+	var words []string
+	start := 0
+	for start < len(text) {
+		r, size := utf8.DecodeRuneInString(text[start:])
+		end := start + size
+		switch {
+		case unicode.IsSpace(r):
+			end = scan(text, end, unicode.IsSpace)
+		case isWordRune(r) && !isCJK(r):
+			end = scan(text, end, func(r rune) bool { return isWordRune(r) && !isCJK(r) })
+		}
+		words = append(words, text[start:end])
+		start = end
+	}
+	return words
+}
+
+// scan returns the end of the run of runes matching in from i
+func scan(text string, i int, in func(rune) bool) int {
+	for i < len(text) {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if !in(r) {
+			break
+		}
+		i += size
+	}
+	return i
+}
+
+// isWordRune reports whether r is part of a word; marks keep combining
+// accents and Indic vowel signs with their letter
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r) || r == '_'
+}
+
+// isCJK reports whether r is a Chinese, Japanese or Korean character, whose
+// words are not separated by spaces. Hangul is written with spaces but
+// compared per syllable so that changed particles show as small edits.
+func isCJK(r rune) bool {
+	return unicode.Is(unicode.Han, r) || unicode.Is(unicode.Hiragana, r) ||
+		unicode.Is(unicode.Katakana, r) || unicode.Is(unicode.Hangul, r)
+}
@@ -0,0 +1,119 @@
+// Package tm is a translation memory that remembers the translation of each
+// identified segment together with a hash of its source text, so unchanged
+// segments need not be translated again
+package tm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Match is how a segment's source compares to the remembered one
+type Match int
+
+const (
+	Missing   Match = iota // no translation remembered
+	Unchanged              // same source as the remembered translation
+	Changed                // the source changed since it was translated
+)
+
+// Entry is a remembered translation
+type Entry struct {
+	SourceHash  string    `json:"sourceHash"`
+	Translation string    `json:"translation"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// Store keeps entries in memory and on disk, keyed by segment and target language
+type Store struct {
+	mu      sync.RWMutex
+	path    string
+	entries map[string]Entry
+	dirty   bool
+}
+
+// Open loads the memory file at path, starting empty if it doesn't exist
+func Open(path string) (*Store, error) {
+	s := &Store{
+		path:    path,
+		entries: make(map[string]Entry),
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.entries); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Lookup returns the remembered translation of a segment and whether its source changed
+func (s *Store) Lookup(segment, targetLang, source string) (Entry, Match) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	e, ok := s.entries[key(segment, targetLang)]
+	switch {
+	case !ok:
+		return Entry{}, Missing
+	case e.SourceHash != Hash(source):
+		return e, Changed
+	default:
+		return e, Unchanged
+	}
+}
+
+// Put remembers the translation of a segment. Call Save to write it to disk.
+func (s *Store) Put(segment, targetLang, source, translation string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.entries[key(segment, targetLang)] = Entry{
+		SourceHash:  Hash(source),
+		Translation: translation,
+		UpdatedAt:   time.Now(),
+	}
+	s.dirty = true
+}
+
+// Save writes the memory to disk if it changed
+func (s *Store) Save() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.dirty {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(s.entries)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.path, data, 0644); err != nil {
+		return err
+	}
+	s.dirty = false
+	return nil
+}
+
+// Hash returns the hash stored for a source text
+func Hash(source string) string {
+	sum := sha256.Sum256([]byte(source))
+	return hex.EncodeToString(sum[:16])
+}
+
+func key(segment, targetLang string) string {
+	return targetLang + "\x00" + segment
+}
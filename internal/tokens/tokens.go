@@ -0,0 +1,125 @@
+// Package tokens counts and estimates prompt tokens and budgets them against
+// a model's context window.
+//
+// Estimate approximates byte-pair tokenizers such as tiktoken's cl100k
+// without their vocabularies: common words are a single token and longer
+// ones a few, digits are grouped by three, punctuation is a token of its own
+// and CJK characters count about one token each. Engines with a real
+// tokenizer implement Counter.
+package tokens
+
+import (
+	"context"
+	"unicode"
+)
+
+// Counter is implemented by engines that can count tokens with the model's
+// own tokenizer
+type Counter interface {
+	CountTokens(ctx context.Context, text string) (int, error)
+}
+
+// Count returns the number of tokens in text using c if it is a Counter,
+// otherwise the estimate, and whether the count is exact
+func Count(ctx context.Context, c any, text string) (n int, exact bool) {
+	if counter, ok := c.(Counter); ok {
+		if n, err := counter.CountTokens(ctx, text); err == nil {
+			return n, true
+		}
+	}
+	return Estimate(text), false
+}
+
+// Average letters per token of a word, by script
+const (
+	latinLetters = 6 // English and most Latin-script languages
+	otherLetters = 2 // Cyrillic, Greek, Arabic, Hebrew, Indic, ...
+	digitGroup   = 3
+)
+
+// Estimate returns the approximate number of tokens of text for a byte-pair
+// tokenizer
+func Estimate(text string) int {
+	tokens := 0
+	run, perToken := 0, 0 // letters in the current word and letters per token
+	flush := func() {
+		if run > 0 {
+			tokens += (run + perToken - 1) / perToken
+		}
+		run = 0
+	}
+	spaces := 0
+	for _, r := range text {
+		var size int
+		switch {
+		case isCJK(r):
+			flush()
+			tokens++
+			spaces = 0
+			continue
+		case unicode.IsDigit(r):
+			size = digitGroup
+		case r < 0x250 && unicode.IsLetter(r):
+			size = latinLetters
+		case unicode.IsLetter(r) || unicode.Is(unicode.Mn, r):
+			size = otherLetters
+		case unicode.IsSpace(r):
+			flush()
+			// A single space joins the next word; longer runs and line breaks are tokens
+			if spaces++; spaces > 1 || r == '\n' {
+				tokens++
+			}
+			continue
+		default:
+			flush()
+			tokens++
+			spaces = 0
+			continue
+		}
+		if size != perToken {
+			flush()
+			perToken = size
+		}
+		run++
+		spaces = 0
+	}
+	flush()
+	return tokens
+}
+
+// isCJK reports whether r is a Chinese, Japanese or Korean character
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// BytesPerToken returns the average bytes per token of text, at least 1,
+// for converting token budgets into byte sizes such as chunk limits.
+// Empty text is assumed to be Latin script.
+func BytesPerToken(text string) int {
+	n := Estimate(text)
+	if n == 0 {
+		return latinLetters
+	}
+	return max(len(text)/n, 1)
+}
+
+// Budget splits a context window between the prompt and the generation
+type Budget struct {
+	Context int // context window in tokens
+	Reserve int // tokens kept free for the generated text
+}
+
+// Input returns the tokens available for the prompt, or 0 if the context
+// window is unknown
+func (b Budget) Input() int {
+	if b.Context <= 0 {
+		return 0
+	}
+	return max(b.Context-b.Reserve, 1)
+}
+
+// Fits reports whether a prompt of n tokens fits; it always does when the
+// context window is unknown
+func (b Budget) Fits(n int) bool {
+	return b.Context <= 0 || n <= b.Input()
+}
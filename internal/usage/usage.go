@@ -0,0 +1,181 @@
+// Package usage persists daily character and token counts per engine
+package usage
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// keepDays bounds how long daily counts are kept
+const keepDays = 400
+
+// Periods budgets and totals are counted over
+const (
+	PeriodDay   = "day"
+	PeriodMonth = "month"
+)
+
+// dayLayout formats the day keys of the store
+const dayLayout = "2006-01-02"
+
+// Counts is the usage of one engine
+type Counts struct {
+	Requests         int `json:"requests"`
+	Characters       int `json:"characters"` // source characters sent for translation
+	PromptTokens     int `json:"promptTokens"`
+	CompletionTokens int `json:"completionTokens"`
+}
+
+// Tokens returns prompt and completion tokens together
+func (c Counts) Tokens() int {
+	return c.PromptTokens + c.CompletionTokens
+}
+
+// add sums two counts
+func (c Counts) add(o Counts) Counts {
+	return Counts{
+		Requests:         c.Requests + o.Requests,
+		Characters:       c.Characters + o.Characters,
+		PromptTokens:     c.PromptTokens + o.PromptTokens,
+		CompletionTokens: c.CompletionTokens + o.CompletionTokens,
+	}
+}
+
+// Day is the usage of every engine on one day
+type Day struct {
+	Date    string            `json:"date"` // YYYY-MM-DD, local time
+	Engines map[string]Counts `json:"engines"`
+}
+
+// Store keeps daily counts in memory and on disk
+type Store struct {
+	mu   sync.Mutex
+	path string
+	days map[string]map[string]Counts // date -> engine -> counts
+}
+
+// Open loads the usage file at path, starting empty if it doesn't exist
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, days: make(map[string]map[string]Counts)}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return s, nil
+		}
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.days); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Add records usage of an engine at the given time and saves
+func (s *Store) Add(engineName string, at time.Time, counts Counts) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	date := at.Format(dayLayout)
+	day, ok := s.days[date]
+	if !ok {
+		day = make(map[string]Counts)
+		s.days[date] = day
+		s.prune(at)
+	}
+	day[engineName] = day[engineName].add(counts)
+	return s.save()
+}
+
+// Total returns the usage in the day or month containing at, of one engine
+// or of all engines if engineName is empty
+func (s *Store) Total(engineName, period string, at time.Time) Counts {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var total Counts
+	for date, day := range s.days {
+		if !inPeriod(date, period, at) {
+			continue
+		}
+		for name, counts := range day {
+			if engineName == "" || name == engineName {
+				total = total.add(counts)
+			}
+		}
+	}
+	return total
+}
+
+// Engines returns the usage per engine in the day or month containing at
+func (s *Store) Engines(period string, at time.Time) map[string]Counts {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	totals := make(map[string]Counts)
+	for date, day := range s.days {
+		if !inPeriod(date, period, at) {
+			continue
+		}
+		for name, counts := range day {
+			totals[name] = totals[name].add(counts)
+		}
+	}
+	return totals
+}
+
+// Days returns the usage of the last n days up to at, oldest first, skipping days without usage
+func (s *Store) Days(n int, at time.Time) []Day {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	from := at.AddDate(0, 0, -n+1).Format(dayLayout)
+	to := at.Format(dayLayout)
+	var days []Day
+	for date, day := range s.days {
+		if date < from || date > to {
+			continue
+		}
+		engines := make(map[string]Counts, len(day))
+		for name, counts := range day {
+			engines[name] = counts
+		}
+		days = append(days, Day{Date: date, Engines: engines})
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Date < days[j].Date })
+	return days
+}
+
+// inPeriod reports whether date falls in the day or month containing at
+func inPeriod(date, period string, at time.Time) bool {
+	if period == PeriodMonth {
+		return len(date) >= 7 && date[:7] == at.Format("2006-01")
+	}
+	return date == at.Format(dayLayout)
+}
+
+// prune drops days older than keepDays. Must be called with s.mu held.
+func (s *Store) prune(now time.Time) {
+	oldest := now.AddDate(0, 0, -keepDays).Format(dayLayout)
+	for date := range s.days {
+		if date < oldest {
+			delete(s.days, date)
+		}
+	}
+}
+
+// save writes the counts to disk. Must be called with s.mu held.
+func (s *Store) save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(s.days)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, data, 0644)
+}
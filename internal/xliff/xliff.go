@@ -0,0 +1,403 @@
+// Package xliff reads XLIFF 1.2 and 2.0 documents and writes translations back.
+//
+// The document is not re-serialized: translated targets and states are
+// spliced into the original bytes, so everything else (skeletons, metadata,
+// CAT tool extensions) round-trips untouched.
+package xliff
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/ironpark/tons/internal/placeholder"
+)
+
+// Unit is a translatable segment: a 1.2 trans-unit or a 2.0 segment
+type Unit struct {
+	ID        string   `json:"id"`
+	Source    string   `json:"source"` // inner XML of <source>
+	Target    string   `json:"target"` // inner XML of <target>
+	HasTarget bool     `json:"hasTarget"`
+	State     string   `json:"state"`
+	Notes     []string `json:"notes"`
+
+	translatable bool
+	stateAttr    span // state attribute value of the target (1.2) or segment (2.0)
+	stateTag     span // start tag holding the state attribute
+	sourceEnd    int  // offset just after </source>
+	target       span // inner content of <target>
+	translated   *string
+	newState     string
+}
+
+// span is a byte range in the original document
+type span struct {
+	start, end int
+}
+
+// Document is a parsed XLIFF document
+type Document struct {
+	Version    string `json:"version"`
+	SourceLang string `json:"sourceLang"`
+	TargetLang string `json:"targetLang"`
+	Units      []*Unit
+
+	data       []byte
+	langTag    span // start tag that carries the target language attribute
+	targetLang string
+}
+
+// States for machine-translated units
+const (
+	StateTranslated12  = "translated"
+	StateNeedsReview12 = "needs-review-translation"
+	StateTranslated20  = "translated"
+)
+
+// untranslatedStates are target states that still need a translation
+var untranslatedStates = map[string]bool{"": true, "new": true, "needs-translation": true, "initial": true}
+
+// NeedsTranslation reports whether the unit has no usable translation yet
+func (u *Unit) NeedsTranslation() bool {
+	if !u.translatable {
+		return false
+	}
+	return !u.HasTarget || strings.TrimSpace(u.Target) == "" || (untranslatedStates[u.State] && u.Target == u.Source)
+}
+
+// Parse reads an XLIFF 1.2 or 2.0 document
+func Parse(data []byte) (*Document, error) {
+	doc := &Document{data: data}
+	dec := xml.NewDecoder(bytes.NewReader(data))
+
+	var unit *Unit
+	var unitID string
+	unitTranslatable := true
+	segments := 0
+	var unitNotes []string // 2.0 notes, shared by all segments of the unit
+	var stack []string
+	var capture *strings.Builder // text of the current <note>
+
+	for {
+		start := int(dec.InputOffset())
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("xliff: %w", err)
+		}
+		end := int(dec.InputOffset())
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			stack = append(stack, t.Name.Local)
+			switch t.Name.Local {
+			case "xliff":
+				doc.Version = attr(t, "version")
+				if strings.HasPrefix(doc.Version, "2") {
+					doc.SourceLang, doc.TargetLang = attr(t, "srcLang"), attr(t, "trgLang")
+					doc.langTag = span{start, end}
+				}
+			case "file":
+				if !strings.HasPrefix(doc.Version, "2") && doc.langTag == (span{}) {
+					doc.SourceLang, doc.TargetLang = attr(t, "source-language"), attr(t, "target-language")
+					doc.langTag = span{start, end}
+				}
+			case "trans-unit": // 1.2
+				unit = &Unit{ID: attr(t, "id"), translatable: attr(t, "translate") != "no"}
+			case "unit": // 2.0
+				unitID, segments, unitNotes = attr(t, "id"), 0, nil
+				unitTranslatable = attr(t, "translate") != "no"
+			case "segment": // 2.0
+				segments++
+				unit = &Unit{ID: fmt.Sprintf("%s#%d", unitID, segments), translatable: unitTranslatable, State: attr(t, "state")}
+				unit.stateTag = span{start, end}
+				unit.stateAttr = attrSpan(data, start, end, "state")
+			case "source":
+				if unit != nil && parent(stack) != "alt-trans" {
+					inner, closeEnd, err := innerXML(dec, data, end)
+					if err != nil {
+						return nil, err
+					}
+					unit.Source = inner
+					unit.sourceEnd = closeEnd
+					stack = stack[:len(stack)-1]
+				}
+			case "target":
+				if unit != nil && parent(stack) != "alt-trans" {
+					if !strings.HasPrefix(doc.Version, "2") {
+						unit.State = attr(t, "state")
+						unit.stateTag = span{start, end}
+						unit.stateAttr = attrSpan(data, start, end, "state")
+					}
+					inner, _, err := innerXML(dec, data, end)
+					if err != nil {
+						return nil, err
+					}
+					unit.Target, unit.HasTarget = inner, true
+					unit.target = span{end, end + len(inner)}
+					if bytes.HasSuffix(data[start:end], []byte("/>")) {
+						unit.target = span{start, end} // <target/>: replace the whole element
+					}
+					stack = stack[:len(stack)-1]
+				}
+			case "note":
+				capture = &strings.Builder{}
+			}
+		case xml.EndElement:
+			stack = stack[:len(stack)-1]
+			switch t.Name.Local {
+			case "trans-unit", "segment":
+				if unit != nil {
+					doc.Units = append(doc.Units, unit)
+				}
+				unit = nil
+			case "unit":
+				for _, u := range doc.Units[len(doc.Units)-segments:] {
+					u.Notes = append(u.Notes, unitNotes...)
+				}
+			case "note":
+				if capture == nil {
+					break
+				}
+				note := strings.TrimSpace(capture.String())
+				if unit != nil {
+					unit.Notes = append(unit.Notes, note)
+				} else {
+					unitNotes = append(unitNotes, note)
+				}
+				capture = nil
+			}
+		case xml.CharData:
+			if capture != nil {
+				capture.Write(t)
+			}
+		}
+	}
+	if doc.Version == "" {
+		return nil, errors.New("xliff: missing <xliff> root element")
+	}
+	return doc, nil
+}
+
+// innerXML consumes tokens up to the end of the current element and returns
+// its raw inner XML and the offset after the closing tag
+func innerXML(dec *xml.Decoder, data []byte, contentStart int) (string, int, error) {
+	depth := 1
+	for {
+		before := int(dec.InputOffset())
+		tok, err := dec.Token()
+		if err != nil {
+			return "", 0, fmt.Errorf("xliff: %w", err)
+		}
+		switch tok.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+			if depth == 0 {
+				// A self-closing element ends where it started
+				if before < contentStart {
+					before = contentStart
+				}
+				return string(data[contentStart:before]), int(dec.InputOffset()), nil
+			}
+		}
+	}
+}
+
+// parent returns the element enclosing the current one
+func parent(stack []string) string {
+	if len(stack) < 2 {
+		return ""
+	}
+	return stack[len(stack)-2]
+}
+
+// attr returns the value of the named attribute
+func attr(t xml.StartElement, name string) string {
+	for _, a := range t.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// attrSpan returns the byte range of an attribute value inside a start tag
+func attrSpan(data []byte, start, end int, name string) span {
+	re := regexp.MustCompile(`\s` + regexp.QuoteMeta(name) + `\s*=\s*("[^"]*"|'[^']*')`)
+	loc := re.FindSubmatchIndex(data[start:end])
+	if loc == nil {
+		return span{}
+	}
+	return span{start + loc[2] + 1, start + loc[3] - 1}
+}
+
+// SetTarget sets the inner XML of a unit's target (see UnmaskInline) and its
+// new state ("" to keep the current one)
+func (d *Document) SetTarget(u *Unit, targetXML, state string) {
+	u.translated = &targetXML
+	u.newState = state
+}
+
+// SetTargetLang sets the target language written to the document if it has none
+func (d *Document) SetTargetLang(lang string) {
+	d.targetLang = lang
+}
+
+// SourceText returns the unit source as plain text with inline markup replaced
+// by placeholder tokens, and the mapping to restore them with TargetXML
+func (u *Unit) SourceText() (string, placeholder.Mapping) {
+	return MaskInline(u.Source)
+}
+
+var inlineTag = regexp.MustCompile(`<[^>]+>`)
+
+// MaskInline turns inner XML into plain text, masking inline tags with tokens
+func MaskInline(inner string) (string, placeholder.Mapping) {
+	var m placeholder.Mapping
+	var b strings.Builder
+	last := 0
+	for _, loc := range inlineTag.FindAllStringIndex(inner, -1) {
+		b.WriteString(unescape(inner[last:loc[0]]))
+		var tok string
+		tok, m = m.Add(inner[loc[0]:loc[1]])
+		b.WriteString(tok)
+		last = loc[1]
+	}
+	b.WriteString(unescape(inner[last:]))
+	return b.String(), m
+}
+
+// UnmaskInline escapes translated text and restores the masked inline tags
+func UnmaskInline(text string, m placeholder.Mapping) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(text))
+	escaped := strings.ReplaceAll(b.String(), "&#xA;", "\n")
+	return m.Restore(escaped)
+}
+
+// unescape decodes XML entities in character data
+func unescape(s string) string {
+	if !strings.Contains(s, "&") {
+		return s
+	}
+	var b strings.Builder
+	dec := xml.NewDecoder(strings.NewReader("<x>" + s + "</x>"))
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		if cd, ok := tok.(xml.CharData); ok {
+			b.Write(cd)
+		}
+	}
+	return b.String()
+}
+
+// edit replaces a byte range of the original document
+type edit struct {
+	span
+	text string
+}
+
+// Bytes returns the document with all translations spliced in
+func (d *Document) Bytes() []byte {
+	v2 := strings.HasPrefix(d.Version, "2")
+	var edits []edit
+
+	if d.targetLang != "" && d.TargetLang == "" && d.langTag != (span{}) {
+		name := "target-language"
+		if v2 {
+			name = "trgLang"
+		}
+		edits = append(edits, insertAttr(d.data, d.langTag, name, d.targetLang))
+	}
+
+	for _, u := range d.Units {
+		if u.translated == nil {
+			continue
+		}
+		target := *u.translated
+		// 1.2 keeps the state on <target>, which may have to be created
+		targetState := !v2 && u.newState != ""
+
+		switch {
+		case !u.HasTarget:
+			open := "<target>"
+			if targetState {
+				open = `<target state="` + u.newState + `">`
+			}
+			edits = append(edits, edit{span{u.sourceEnd, u.sourceEnd}, open + target + "</target>"})
+			if !v2 {
+				continue
+			}
+		case bytes.HasSuffix(d.data[u.target.start:u.target.end], []byte("/>")):
+			// <target/>: rebuild the element around the original start tag
+			tag := strings.TrimSpace(strings.TrimSuffix(string(d.data[u.target.start:u.target.end]), "/>"))
+			open := tag + ">"
+			if targetState {
+				open = setAttr(tag, "state", u.newState) + ">"
+			}
+			edits = append(edits, edit{u.target, open + target + "</target>"})
+			if !v2 {
+				continue
+			}
+		default:
+			edits = append(edits, edit{u.target, target})
+		}
+
+		if u.newState == "" {
+			continue
+		}
+		switch {
+		case u.stateAttr != (span{}):
+			edits = append(edits, edit{u.stateAttr, u.newState})
+		case u.stateTag != (span{}):
+			edits = append(edits, insertAttr(d.data, u.stateTag, "state", u.newState))
+		}
+	}
+
+	sort.SliceStable(edits, func(i, j int) bool { return edits[i].start < edits[j].start })
+	var out bytes.Buffer
+	pos := 0
+	for _, e := range edits {
+		if e.start < pos {
+			continue // overlapping edit; keep the first
+		}
+		out.Write(d.data[pos:e.start])
+		out.WriteString(e.text)
+		pos = e.end
+	}
+	out.Write(d.data[pos:])
+	return out.Bytes()
+}
+
+// setAttr sets an attribute in a start tag without its closing bracket
+func setAttr(tag, name, value string) string {
+	re := regexp.MustCompile(`(\s` + regexp.QuoteMeta(name) + `\s*=\s*)("[^"]*"|'[^']*')`)
+	if re.MatchString(tag) {
+		return re.ReplaceAllString(tag, `${1}"`+value+`"`)
+	}
+	return tag + " " + name + `="` + value + `"`
+}
+
+// insertAttr returns an edit adding name="value" to the start tag at tag
+func insertAttr(data []byte, tag span, name, value string) edit {
+	pos := tag.end - 1 // before '>'
+	if data[pos-1] == '/' {
+		pos--
+	}
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(value))
+	return edit{span{pos, pos}, " " + name + `="` + b.String() + `"`}
+}
@@ -4,11 +4,24 @@ import (
 	"embed"
 	_ "embed"
 	"log"
+	"log/slog"
+	"os"
+	"path/filepath"
 	"time"
 
+	"github.com/ironpark/tons/internal/cache"
 	"github.com/ironpark/tons/internal/config"
+	"github.com/ironpark/tons/internal/engine"
+	"github.com/ironpark/tons/internal/history"
+	"github.com/ironpark/tons/internal/jobs"
+	"github.com/ironpark/tons/internal/metrics"
 	"github.com/ironpark/tons/internal/services"
+	"github.com/ironpark/tons/internal/session"
+	"github.com/ironpark/tons/internal/speech"
+	"github.com/ironpark/tons/internal/tm"
+	"github.com/ironpark/tons/internal/usage"
 	"github.com/wailsapp/wails/v3/pkg/application"
+	"github.com/wailsapp/wails/v3/pkg/services/notifications"
 )
 
 // Wails uses Go's `embed` package to embed the frontend files into the binary.
@@ -19,17 +32,91 @@ import (
 //go:embed all:frontend/dist
 var assets embed.FS
 
+//go:embed build/appicon.png
+var trayIcon []byte
+
 func init() {
 	// Register a custom event whose associated data type is string.
 	// This is not required, but the binding generator will pick up registered events
 	// and provide a strongly typed JS/TS API for them.
 	application.RegisterEvent[string]("time")
+	// Streamed translation text, tagged with the request ID
+	application.RegisterEvent[services.TranslateDelta]("translate:delta")
+	// Queue position of a request waiting for the engine, 0 once it starts
+	application.RegisterEvent[services.TranslateQueued]("translate:queued")
+	// The engine handles the language pair poorly, sent before the first delta
+	application.RegisterEvent[services.TranslateLanguageWarning]("translate:language")
+	// Chunks of a long translation completed so far
+	application.RegisterEvent[services.TranslateProgress]("translate:progress")
+	// Complete translation with usage, sent after the last delta
+	application.RegisterEvent[services.TranslateDone]("translate:done")
+	// Readings (furigana, pinyin, romanization) of a finished translation, when enabled
+	application.RegisterEvent[services.TranslateAnnotation]("translate:annotation")
+	// Translation failure, instead of "translate:done"
+	application.RegisterEvent[services.TranslateError]("translate:error")
+	// Word-level changes from a pane's previous translation into the same language
+	application.RegisterEvent[services.TranslateDiff]("translate:diff")
+	// Languages and text of a pane after swapping to translate back
+	application.RegisterEvent[services.TranslateSwapped]("translate:swapped")
+	// Detected source language code when translating from "auto"
+	application.RegisterEvent[string]("translate:detected")
+	// Streamed results of multi-target translations, tagged by target language
+	application.RegisterEvent[engine.TargetResponse]("translate:multi")
+	// Quality estimation of the last translation, when enabled
+	application.RegisterEvent[engine.Quality]("translate:quality")
+	// Emitted with the target language when the text needed no translation
+	application.RegisterEvent[string]("translate:skipped")
+	// Translator windows get the "translate:*" events of their own pane
+	// prefixed with the pane, e.g. "window:<id>:translate:delta"; the
+	// payloads are the same as above.
+
+	// Translated message of a two-way conversation
+	application.RegisterEvent[services.ConversationTurn]("conversation:turn")
+	// Text recognized in an image before it is translated
+	application.RegisterEvent[string]("ocr:text")
+	// Whether the microphone is recording
+	application.RegisterEvent[bool]("speech:listening")
+	// Transcript of recorded speech before it is translated
+	application.RegisterEvent[string]("speech:text")
+	// Whether text is being read aloud
+	application.RegisterEvent[bool]("tts:speaking")
+	// Sentence being read aloud, sent before the first of its words
+	application.RegisterEvent[speech.Boundary]("tts:sentence")
+	// Word being read aloud, as UTF-16 offsets into the text
+	application.RegisterEvent[speech.Boundary]("tts:word")
+	// New text found on the clipboard by the clipboard monitor
+	application.RegisterEvent[services.ClipboardText]("clipboard:text")
+	// Translation of clipboard text
+	application.RegisterEvent[services.ClipboardTranslation]("clipboard:translation")
+	// Text selected in another app, before it is translated into the popup
+	application.RegisterEvent[string]("selection:text")
+	// Translation put on the clipboard by "tons replace", and whether it was pasted
+	application.RegisterEvent[services.SelectionReplaced]("selection:replaced")
+	// Engine loaded ahead of the first translation, or failed to load
+	application.RegisterEvent[services.EngineWarmup]("engine:warmup")
+	// Usage reached the warning level of a budget, or exceeded it
+	application.RegisterEvent[services.UsageWarning]("usage:warning")
+	// Settings section changed outside the settings page, e.g. from the tray menu or by editing config.json
+	application.RegisterEvent[string]("config:changed")
+	// Theme or language changed, from the settings page or any other source
+	application.RegisterEvent[config.GeneralConfig]("config:general-changed")
+	// config.json was edited outside the app but could not be loaded
+	application.RegisterEvent[string]("config:invalid")
+	// A file of a batch job finished, with its output path or error
+	application.RegisterEvent[services.FileProgress]("job:file")
+	// Chunks or segments of a file translated so far
+	application.RegisterEvent[services.FileTranslationProgress]("file:progress")
+	// Overall progress of a batch job
+	application.RegisterEvent[jobs.Progress]("job:progress")
 }
 
 // main function serves as the application's entry point. It initializes the application, creates a window,
 // and starts a goroutine that emits a time-based event every second. It subsequently runs the application and
 // logs any error that might occur.
 func main() {
+	if code, ok := runCommand(os.Args[1:]); ok {
+		os.Exit(code)
+	}
 
 	// Create a new Wails application by providing the necessary options.
 	// Variables 'Name' and 'Description' are for application metadata.
@@ -40,17 +127,100 @@ func main() {
 	if err != nil {
 		return
 	}
+	logSv := services.NewLogService(cfg)
+	if backup := cfg.RestoredFrom(); backup != "" {
+		slog.Warn("config file was corrupt, restored settings from backup", "backup", backup)
+	}
+	if err := cfg.Validate(); err != nil {
+		slog.Warn("settings need attention", "error", err)
+	}
 	settingSv, err := services.NewSettingService(cfg)
 	if err != nil {
 		return
 	}
-	translateSv := services.NewTranslateService(cfg)
+	hist, err := history.Open(filepath.Join(config.Dir(), "history.json"))
+	if err != nil {
+		return
+	}
+	jobStore, err := jobs.Open(filepath.Join(config.Dir(), "jobs.json"))
+	if err != nil {
+		return
+	}
+	memory, err := tm.Open(filepath.Join(config.Dir(), "memory.json"))
+	if err != nil {
+		return
+	}
+	sessionStore, err := session.Open(filepath.Join(config.Dir(), "state.json"))
+	if err != nil {
+		return
+	}
+	usageStore, err := usage.Open(filepath.Join(config.Dir(), "usage.json"))
+	if err != nil {
+		return
+	}
+	translations, err := cache.Open(filepath.Join(config.Dir(), "cache.json"))
+	if err != nil {
+		return
+	}
+	recorder := metrics.NewRecorder()
+	translateSv := services.NewTranslateService(cfg, recorder, hist, memory, usageStore, translations)
+	metricsSv := services.NewMetricsService(recorder)
+	statusSv := services.NewStatusService(cfg, recorder, translateSv)
+	doctorSv := services.NewDoctorService(cfg, statusSv)
+	telemetrySv := services.NewTelemetryService(cfg, recorder)
+	modelSv := services.NewModelService(cfg)
+	usageSv := services.NewUsageService(cfg, usageStore)
+	apiSv := services.NewAPIService(cfg, translateSv, statusSv)
+	historySv := services.NewHistoryService(hist)
+	conversationSv := services.NewConversationService(translateSv)
+	ankiSv := services.NewAnkiService(cfg, hist, translateSv)
+	quizSv := services.NewQuizService(hist, translateSv)
+	ocrSv := services.NewOCRService(cfg, translateSv)
+	captureSv := services.NewCaptureService(ocrSv)
+	speechSv := services.NewSpeechService(cfg, translateSv)
+	ttsSv := services.NewTTSService(cfg)
+	notifier := notifications.New()
+	notificationSv := services.NewNotificationService(cfg, notifier)
+	fileSv := services.NewFileService(translateSv, notificationSv)
+	jobSv := services.NewJobService(cfg, translateSv, jobStore, notificationSv)
+	clipboardSv := services.NewClipboardService(cfg, translateSv)
+	selectionSv := services.NewSelectionService(cfg, translateSv, clipboardSv)
+	traySv := services.NewTrayService(cfg, clipboardSv, trayIcon)
+	windowSv := services.NewWindowService(cfg, translateSv)
+	sessionSv := services.NewSessionService(cfg, sessionStore)
+	hotkeySv := services.NewHotkeyService(cfg, clipboardSv, captureSv, windowSv, translateSv)
 	app := application.New(application.Options{
 		Name:        "tons",
 		Description: "A translation app powered by AI",
 		Services: []application.Service{
+			application.NewService(logSv),
 			application.NewService(settingSv),
 			application.NewService(translateSv),
+			application.NewService(metricsSv),
+			application.NewService(statusSv),
+			application.NewService(doctorSv),
+			application.NewService(telemetrySv),
+			application.NewService(modelSv),
+			application.NewService(usageSv),
+			application.NewService(apiSv),
+			application.NewService(historySv),
+			application.NewService(conversationSv),
+			application.NewService(ankiSv),
+			application.NewService(quizSv),
+			application.NewService(ocrSv),
+			application.NewService(captureSv),
+			application.NewService(speechSv),
+			application.NewService(ttsSv),
+			application.NewService(notifier),
+			application.NewService(notificationSv),
+			application.NewService(fileSv),
+			application.NewService(jobSv),
+			application.NewService(clipboardSv),
+			application.NewService(selectionSv),
+			application.NewService(traySv),
+			application.NewService(windowSv),
+			application.NewService(sessionSv),
+			application.NewService(hotkeySv),
 		},
 		Assets: application.AssetOptions{
 			Handler: application.AssetFileServerFS(assets),
@@ -65,7 +235,8 @@ func main() {
 	// 'Mac' options tailor the window when running on macOS.
 	// 'BackgroundColour' is the background colour of the window.
 	// 'URL' is the URL that will be loaded into the webview.
-	app.Window.NewWithOptions(application.WebviewWindowOptions{
+	mainOptions := application.WebviewWindowOptions{
+		Name:  "main",
 		Title: "Window 1",
 		Mac: application.MacWindow{
 			InvisibleTitleBarHeight: 50,
@@ -74,7 +245,14 @@ func main() {
 		},
 		BackgroundColour: application.NewRGB(27, 38, 54),
 		URL:              "/",
-	})
+	}
+	// Reopen where the window was when the app last quit
+	if g := sessionStore.Get().Window; g != nil && g.Width > 0 && g.Height > 0 {
+		mainOptions.InitialPosition = application.WindowXY
+		mainOptions.X, mainOptions.Y = g.X, g.Y
+		mainOptions.Width, mainOptions.Height = g.Width, g.Height
+	}
+	app.Window.NewWithOptions(mainOptions)
 
 	// Create a goroutine that emits an event containing the current time every second.
 	// The frontend can listen to this event and update the UI accordingly.
@@ -0,0 +1,240 @@
+// Package enginetest checks that an engine keeps the tons.Engine contract
+// the services rely on: empty text, Done semantics, cancellation, error
+// reporting and channel closure. New engines and forks run it from a test:
+//
+//	func TestConformance(t *testing.T) {
+//		enginetest.RunConformance(t, func(t *testing.T) tons.Engine {
+//			e := NewMyEngine()
+//			if !e.Available() {
+//				t.Skip("my engine is not installed")
+//			}
+//			return e
+//		})
+//	}
+//
+// The suite sends real translations, so engines backed by a model or a
+// service need it to be available.
+package enginetest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ironpark/tons/internal/engine"
+	"github.com/ironpark/tons/pkg/tons"
+)
+
+// Options configure the suite
+type Options struct {
+	Request tons.Request  // a short request the engine can translate
+	Timeout time.Duration // how long a translation may take
+}
+
+// DefaultOptions returns a one-sentence English to German request and a
+// timeout generous enough for a local model
+func DefaultOptions() Options {
+	return Options{
+		Request: tons.Request{
+			Text:       "The weather is nice today.",
+			SourceLang: "English",
+			TargetLang: "German",
+			Prompt:     "Translate the following text from {{.SourceLang}} to {{.TargetLang}}. Only return the translation.\n\n{{.Text}}",
+		},
+		Timeout: 2 * time.Minute,
+	}
+}
+
+// RunConformance runs the suite with DefaultOptions. newEngine creates a
+// fresh engine for each subtest, which is closed when the subtest ends.
+func RunConformance(t *testing.T, newEngine func(t *testing.T) tons.Engine) {
+	RunConformanceWith(t, newEngine, DefaultOptions())
+}
+
+// RunConformanceWith runs the suite with the given options
+func RunConformanceWith(t *testing.T, newEngine func(t *testing.T) tons.Engine, opts Options) {
+	open := func(t *testing.T) tons.Engine {
+		t.Helper()
+		e := newEngine(t)
+		t.Cleanup(func() {
+			if err := e.Close(); err != nil {
+				t.Errorf("Close: %v", err)
+			}
+		})
+		return e
+	}
+
+	t.Run("Name", func(t *testing.T) {
+		if open(t).Name() == "" {
+			t.Error("Name is empty")
+		}
+	})
+
+	t.Run("EmptyText", func(t *testing.T) {
+		e := open(t)
+		req := opts.Request
+		req.Text = ""
+		ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+		defer cancel()
+
+		res, err := e.Translate(ctx, req)
+		switch {
+		case err != nil:
+			t.Errorf("Translate: %v", err)
+		case !res.Done || res.Text != "" || res.Error != "":
+			t.Errorf("Translate = %+v, want an empty Done response", res)
+		}
+
+		responses := stream(t, e, ctx, req, opts.Timeout)
+		if len(responses) != 1 || responses[0].Text != "" || responses[0].Error != "" {
+			t.Errorf("TranslateStream sent %+v, want one empty Done response", responses)
+		}
+	})
+
+	t.Run("Translate", func(t *testing.T) {
+		e := open(t)
+		ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+		defer cancel()
+
+		res, err := e.Translate(ctx, opts.Request)
+		switch {
+		case err != nil:
+			t.Fatalf("Translate: %v", err)
+		case res.Error != "":
+			t.Fatalf("Translate returned error response without an error: %s", res.Error)
+		case !res.Done:
+			t.Error("Translate result is not Done")
+		case strings.TrimSpace(res.Text) == "":
+			t.Error("Translate returned no text")
+		case res.Partial:
+			t.Error("complete Translate result is Partial")
+		}
+	})
+
+	t.Run("Stream", func(t *testing.T) {
+		e := open(t)
+		ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+		defer cancel()
+
+		var full engine.Accumulator
+		for _, res := range stream(t, e, ctx, opts.Request, opts.Timeout) {
+			if res.Error != "" {
+				t.Fatalf("TranslateStream: %s", res.Error)
+			}
+			full.Add(res)
+		}
+		if strings.TrimSpace(full.String()) == "" {
+			t.Error("TranslateStream sent no text")
+		}
+	})
+
+	t.Run("InvalidPrompt", func(t *testing.T) {
+		// Every engine renders the prompt, so a broken template must be
+		// reported rather than translated
+		e := open(t)
+		req := opts.Request
+		req.Prompt = "{{.Text"
+		ctx, cancel := context.WithTimeout(context.Background(), opts.Timeout)
+		defer cancel()
+
+		if res, err := e.Translate(ctx, req); err == nil && res.Error == "" {
+			t.Errorf("Translate = %+v, want an error for an invalid prompt", res)
+		}
+		responses := stream(t, e, ctx, req, opts.Timeout)
+		if len(responses) > 0 && responses[len(responses)-1].Error == "" {
+			t.Errorf("TranslateStream ended with %+v, want an error response", responses[len(responses)-1])
+		}
+	})
+
+	t.Run("CancelledContext", func(t *testing.T) {
+		e := open(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			if res, err := e.Translate(ctx, opts.Request); err == nil && res.Error == "" && !res.Done {
+				t.Errorf("Translate = %+v, want an error or a Done response", res)
+			}
+		}()
+		select {
+		case <-done:
+		case <-time.After(opts.Timeout):
+			t.Fatal("Translate ignored the cancelled context")
+		}
+
+		responses := stream(t, e, ctx, opts.Request, opts.Timeout)
+		if len(responses) > 0 {
+			if last := responses[len(responses)-1]; last.Error == "" && !last.Done {
+				t.Errorf("TranslateStream ended with %+v after cancellation", last)
+			}
+		}
+	})
+
+	t.Run("CancelWhileStreaming", func(t *testing.T) {
+		e := open(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		ch, err := e.TranslateStream(ctx, opts.Request)
+		if err != nil {
+			t.Fatalf("TranslateStream: %v", err)
+		}
+		deadline := time.After(opts.Timeout)
+		first := true
+		for {
+			select {
+			case _, ok := <-ch:
+				if !ok {
+					return
+				}
+				if first {
+					// Stop reading after the first response; the engine must
+					// still wind down and close the channel
+					cancel()
+					first = false
+				}
+			case <-deadline:
+				t.Fatal("channel was not closed after the context was cancelled")
+			}
+		}
+	})
+}
+
+// stream collects the responses of a streaming translation and checks that
+// exactly the last one is Done and that the channel is closed after it
+func stream(t *testing.T, e tons.Engine, ctx context.Context, req tons.Request, timeout time.Duration) []tons.Response {
+	t.Helper()
+	ch, err := e.TranslateStream(ctx, req)
+	if err != nil {
+		return []tons.Response{engine.ErrorResponse(err.Error())}
+	}
+
+	var responses []tons.Response
+	deadline := time.After(timeout)
+	for {
+		select {
+		case res, ok := <-ch:
+			if !ok {
+				if len(responses) == 0 {
+					t.Error("TranslateStream closed the channel without a response")
+				} else if last := responses[len(responses)-1]; !last.Done {
+					t.Error("the last streamed response is not Done")
+				}
+				return responses
+			}
+			if len(responses) > 0 && responses[len(responses)-1].Done {
+				t.Errorf("TranslateStream sent %+v after a Done response", res)
+			}
+			if res.Error != "" && !res.Done {
+				t.Errorf("error response %q is not Done", res.Error)
+			}
+			responses = append(responses, res)
+		case <-deadline:
+			t.Fatalf("TranslateStream did not close the channel within %s", timeout)
+			return nil
+		}
+	}
+}
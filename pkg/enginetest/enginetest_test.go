@@ -0,0 +1,70 @@
+package enginetest
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ironpark/tons/internal/engine"
+	"github.com/ironpark/tons/pkg/tons"
+)
+
+// stubEngine "translates" by upper-casing the text word by word, keeping
+// the contract the suite checks
+type stubEngine struct{}
+
+func (stubEngine) Name() string    { return "stub" }
+func (stubEngine) Available() bool { return true }
+func (stubEngine) Close() error    { return nil }
+
+func (stubEngine) Translate(ctx context.Context, req tons.Request) (tons.Response, error) {
+	if req.Text == "" {
+		return tons.Response{Done: true}, nil
+	}
+	if _, err := req.RenderPrompt(); err != nil {
+		return tons.Response{}, err
+	}
+	if err := ctx.Err(); err != nil {
+		return tons.Response{}, err
+	}
+	return tons.Response{Text: strings.ToUpper(req.Text), Done: true}, nil
+}
+
+func (stubEngine) TranslateStream(ctx context.Context, req tons.Request) (<-chan tons.Response, error) {
+	ch := make(chan tons.Response)
+	go func() {
+		defer close(ch)
+
+		if req.Text == "" {
+			ch <- tons.Response{Done: true}
+			return
+		}
+		if _, err := req.RenderPrompt(); err != nil {
+			ch <- engine.ErrorResponse(err.Error())
+			return
+		}
+		for _, word := range strings.SplitAfter(req.Text, " ") {
+			select {
+			case <-ctx.Done():
+				ch <- engine.ErrorResponse("translation cancelled")
+				return
+			case <-time.After(time.Millisecond):
+			}
+			select {
+			case <-ctx.Done():
+				ch <- engine.ErrorResponse("translation cancelled")
+				return
+			case ch <- tons.Response{Text: strings.ToUpper(word)}:
+			}
+		}
+		ch <- tons.Response{Done: true}
+	}()
+	return ch, nil
+}
+
+func TestConformance(t *testing.T) {
+	RunConformance(t, func(t *testing.T) tons.Engine {
+		return stubEngine{}
+	})
+}
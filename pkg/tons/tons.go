@@ -0,0 +1,56 @@
+// Package tons is the public API for programs embedding the translation
+// packages of tons. Hooks registered here run around every translation, so
+// e.g. company terminology or extra scrubbing can be added without forking.
+//
+// The engine and request types are aliases of the ones the app uses, so
+// hooks see and change exactly what is sent, and engines written outside
+// this module can be checked with package enginetest.
+package tons
+
+import (
+	"github.com/ironpark/tons/internal/engine"
+	"github.com/ironpark/tons/internal/pipeline"
+)
+
+type (
+	// Engine is a translation engine
+	Engine = engine.Engine
+	// Response is a translation result, or a delta of a streamed one
+	Response = engine.Response
+	// Usage are the generation statistics an engine reports
+	Usage = engine.Usage
+	// Request is a translation request
+	Request = engine.Request
+	// Formality is the requested register of a translation
+	Formality = engine.Formality
+	// Example is a few-shot source/target pair of a request
+	Example = engine.Example
+	// GlossaryTerm is a term the translation must use
+	GlossaryTerm = engine.GlossaryTerm
+	// Constraints are the output constraints of a request
+	Constraints = engine.Constraints
+)
+
+type (
+	// BeforeHook rewrites a request before the text processors run. An
+	// error fails the translation.
+	BeforeHook = pipeline.BeforeHook
+	// AfterHook rewrites a complete translation after the text processors
+	// restored it; req is the request as the before hooks left it. An error
+	// fails the translation.
+	AfterHook = pipeline.AfterHook
+)
+
+// OnBeforeTranslate registers a hook run on every request, in registration
+// order, and returns a function that removes it
+func OnBeforeTranslate(fn BeforeHook) (remove func()) {
+	return pipeline.OnBeforeTranslate(fn)
+}
+
+// OnAfterTranslate registers a hook run on every completed translation, in
+// registration order, and returns a function that removes it. While after
+// hooks are registered, streamed translations arrive as one delta at the
+// end, as the hooks need the complete text.
+func OnAfterTranslate(fn AfterHook) (remove func()) {
+	return pipeline.OnAfterTranslate(fn)
+}